@@ -0,0 +1,447 @@
+// Package checker holds the transport-agnostic decision logic shared by the HTTP handlers and the gRPC server.
+// Other Go services can use it to decide requests in-process, from a geo.LookupService over their own copy of the
+// MaxMind database; package geoblock wraps it in HTTP middleware.
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/justfairdev/ipchecker/geo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans created by the checker.
+const tracerName = "github.com/justfairdev/ipchecker/checker"
+
+// Decision is the outcome of checking an IP address against a Policy.
+type Decision struct {
+	// Allowed reports whether the IP address satisfies the policy.
+	Allowed bool
+
+	// Country is the ISO 3166-1 alpha-2 country code resolved for the IP address; empty if none is known.
+	Country string
+
+	// ASN is the autonomous system number resolved for the IP address; 0 if unknown or not looked up.
+	// It is only looked up for policies with ASN rules.
+	ASN uint
+
+	// Reason tells whether a country rule, an ASN rule, the policy's default action or a CIDR override decided.
+	Reason Reason
+
+	// Policy is the name of the server-side policy that produced the decision; empty for inline policies.
+	Policy string
+
+	// PolicyVersion is the version of the server-side policy that produced the decision; empty for inline policies.
+	PolicyVersion string
+
+	// Override is set when a CIDR override rule decided the request instead of the country policy.
+	// Country, Policy and PolicyVersion are empty in that case, as no country lookup was made.
+	Override *OverrideMatch
+}
+
+// Reason identifies what produced a Decision.
+type Reason string
+
+const (
+	// ReasonRule means the resolved country and subdivisions were matched against the policy's country list.
+	ReasonRule Reason = "rule"
+
+	// ReasonDefault means no country is known for the IP address and the policy's default action decided.
+	ReasonDefault Reason = "default"
+
+	// ReasonOverride means a CIDR override rule decided before any country lookup.
+	ReasonOverride Reason = "override"
+
+	// ReasonASN means the autonomous system of the IP address is listed in the policy's ASN rules.
+	ReasonASN Reason = "asn"
+)
+
+// Observer is notified of the outcome of every check and lookup, e.g. to record metrics.
+// Implementations must be safe for concurrent use and should return quickly.
+type Observer interface {
+	// ObserveDecision is called for every decision Decide returns.
+	ObserveDecision(decision Decision)
+
+	// ObserveLookupError is called for every error Decide or Lookup returns, with its classification.
+	ObserveLookupError(kind Kind)
+}
+
+// PolicyRestriction returns the names of the server-side policies the caller of a request is restricted to, e.g.
+// from the credentials it authenticated with; nil if the caller may name any policy or send inline rules. An empty,
+// non-nil list permits no policy.
+type PolicyRestriction func(ctx context.Context) []string
+
+// Checker decides whether IP addresses satisfy a Policy using a geo.LookupService.
+type Checker struct {
+	geoService  geo.LookupService
+	policies    *PolicySet
+	overrides   *Overrides
+	observer    Observer          // nil if no observer is set.
+	restriction PolicyRestriction // nil if callers are not restricted.
+	tracer      trace.Tracer
+}
+
+// NewChecker constructs a Checker backed by the given geo lookup service.
+//
+// Parameters:
+//   - geoService: An implementation of geo.LookupService used to determine the country of IP addresses.
+//   - policies: The named server-side policies requests may reference; nil if none are configured.
+//   - overrides: The CIDR allow/deny lists evaluated before the country lookup; nil if none are configured.
+//
+// Returns:
+//   - *Checker: A pointer to the initialized Checker.
+func NewChecker(geoService geo.LookupService, policies *PolicySet, overrides *Overrides) *Checker {
+	return &Checker{
+		geoService: geoService,
+		policies:   policies,
+		overrides:  overrides,
+		tracer:     otel.Tracer(tracerName),
+	}
+}
+
+// SetObserver registers an observer notified of every decision and lookup error.
+// It must be called before the checker starts serving requests.
+//
+// Parameters:
+//   - observer: The observer to notify; nil removes the current observer.
+func (c *Checker) SetObserver(observer Observer) {
+	c.observer = observer
+}
+
+// SetPolicyRestriction registers the function restricting callers to certain policies in ResolvePolicy.
+// It must be called before the checker starts serving requests.
+//
+// Parameters:
+//   - restriction: The function returning the policies of the caller of a request; nil removes the restriction.
+func (c *Checker) SetPolicyRestriction(restriction PolicyRestriction) {
+	c.restriction = restriction
+}
+
+// SetTracerProvider sets the provider of the spans created around geolocation lookups. By default, the
+// OpenTelemetry global provider is used. It must be called before the checker starts serving requests.
+//
+// Parameters:
+//   - provider: The tracer provider to create spans from.
+func (c *Checker) SetTracerProvider(provider trace.TracerProvider) {
+	c.tracer = provider.Tracer(tracerName)
+}
+
+// ResolvePolicy returns the policy a request is checked against. Requests either reference a named server-side
+// policy or supply inline rules: a list of allowed or of blocked countries, and optionally a default action.
+//
+// If the caller is restricted to certain policies (see SetPolicyRestriction), the request may only name one of
+// them; a request naming none, whether or not it sends inline rules, is checked against the first. A caller
+// restricted to an empty list may not check at all.
+//
+// Inline country and subdivision codes are upper-cased, as the lookup reports them, and validated like the codes of
+// policy files.
+//
+// Parameters:
+//   - ctx: Context of the request being served; passed to the policy restriction, if any.
+//   - name: The name of a server-side policy; empty if the request supplies inline rules.
+//   - inline: The inline rules of the request; Name and Version are ignored. Zero value if a policy is named.
+//
+// Returns:
+//   - Policy: The resolved policy, with its default action filled in.
+//   - error: A *Error of KindInvalidInput if the request names a policy and supplies inline rules, supplies neither,
+//     lists both allowed and blocked countries, lists a code that is not a country or subdivision code, has an
+//     invalid default action, or the named policy does not exist;
+//     a *Error of KindForbidden if it names a policy the caller is not permitted to use, or the caller may use none.
+func (c *Checker) ResolvePolicy(ctx context.Context, name string, inline Policy) (Policy, error) {
+	// Policies granted to the caller override the inline rules of the request, and restrict the names it may use
+	if permitted := c.permittedPolicies(ctx); permitted != nil {
+		switch {
+		case len(permitted) == 0:
+			return Policy{}, &Error{Kind: KindForbidden, Message: "no policy is permitted for this caller"}
+		case name == "":
+			name = permitted[0]
+		case !contains(permitted, name):
+			return Policy{}, &Error{Kind: KindForbidden, Message: fmt.Sprintf("policy %q is not permitted for this caller", name)}
+		}
+		inline = Policy{}
+	}
+
+	hasInline := len(inline.AllowedCountries) > 0 || len(inline.BlockedCountries) > 0
+
+	switch {
+	case name != "" && (hasInline || inline.DefaultAction != ""):
+		return Policy{}, &Error{Kind: KindInvalidInput, Message: "specify either policy or inline country rules, not both"}
+	case name != "":
+		policy, ok := c.policies.Get(name)
+		if !ok {
+			return Policy{}, &Error{Kind: KindInvalidInput, Message: fmt.Sprintf("unknown policy %q", name)}
+		}
+		return policy, nil
+	case !hasInline:
+		return Policy{}, &Error{Kind: KindInvalidInput, Message: "either policy, allowed_countries or blocked_countries is required"}
+	case len(inline.AllowedCountries) > 0 && len(inline.BlockedCountries) > 0:
+		return Policy{}, &Error{Kind: KindInvalidInput, Message: "specify either allowed_countries or blocked_countries, not both"}
+	}
+
+	defaultAction, err := parseDefaultAction(string(inline.DefaultAction))
+	if err != nil {
+		return Policy{}, &Error{Kind: KindInvalidInput, Message: err.Error()}
+	}
+	allowed, err := normalizeRegionCodes(inline.AllowedCountries)
+	if err != nil {
+		return Policy{}, &Error{Kind: KindInvalidInput, Message: err.Error()}
+	}
+	blocked, err := normalizeRegionCodes(inline.BlockedCountries)
+	if err != nil {
+		return Policy{}, &Error{Kind: KindInvalidInput, Message: err.Error()}
+	}
+	return Policy{
+		AllowedCountries: allowed,
+		BlockedCountries: blocked,
+		DefaultAction:    defaultAction,
+	}, nil
+}
+
+// permittedPolicies returns the policies the caller of ctx is restricted to; nil if it is not restricted.
+func (c *Checker) permittedPolicies(ctx context.Context) []string {
+	if c.restriction == nil {
+		return nil
+	}
+	return c.restriction(ctx)
+}
+
+// Decide resolves the country of the IP address and decides whether it satisfies the policy.
+// CIDR overrides are evaluated first; if one contains the IP address, it decides and no country lookup is made.
+// Next, ASN rules decide IP addresses whose autonomous system is listed, wherever they geolocate to, blocked ASNs
+// taking precedence over allowed ones. If the geolocation database knows no country for the IP address,
+// the policy's default action decides.
+//
+// Parameters:
+//   - ctx: Context of the request being served; carries deadlines and request-scoped values.
+//   - ip: The IP address to classify.
+//   - policy: The rules the IP address is checked against.
+//
+// Returns:
+//   - Decision: The decision and resolved country code; zero value on error.
+//   - error: A *Error classifying the failure as invalid input, not found or backend failure. Not found is only
+//     returned when the policy's default action is DefaultError.
+func (c *Checker) Decide(ctx context.Context, ip string, policy Policy) (Decision, error) {
+	decision, err := c.decide(ctx, ip, policy)
+	if c.observer != nil {
+		if err != nil {
+			c.observer.ObserveLookupError(KindOf(err))
+		} else {
+			c.observer.ObserveDecision(decision)
+		}
+	}
+	return decision, err
+}
+
+// decide implements Decide, without notifying the observer.
+func (c *Checker) decide(ctx context.Context, ip string, policy Policy) (decision Decision, err error) {
+	addr, err := parseIP(ip)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	// Overrides take precedence over whatever the geolocation database says.
+	if match, ok := c.overrides.Match(addr); ok {
+		return Decision{
+			Allowed:  match.Action == OverrideAllow,
+			Reason:   ReasonOverride,
+			Override: &match,
+		}, nil
+	}
+
+	// The span covers the database lookup and the decision made from its result, which it is annotated with.
+	span := c.startLookupSpan(ctx, policy.lookupMethod(), ip)
+	span.SetAttributes(attribute.String("ipchecker.policy", policy.Name))
+	defer func() { endLookupSpan(span, decision, err) }()
+
+	location, err := c.locate(ip, policy)
+	if err != nil && !errors.Is(err, geo.ErrNotFound) {
+		return Decision{}, classifyLookupError(err)
+	}
+	country := location.Country.ISOCode
+
+	decision = Decision{
+		Country:       country,
+		ASN:           location.ASN,
+		Reason:        ReasonRule,
+		Policy:        policy.Name,
+		PolicyVersion: policy.Version,
+	}
+
+	// Carriers and hosting providers are decided by their network, no matter where their addresses geolocate.
+	if location.ASN != 0 {
+		switch {
+		case containsASN(policy.BlockedASNs, location.ASN):
+			decision.Reason = ReasonASN
+			return decision, nil
+		case containsASN(policy.AllowedASNs, location.ASN):
+			decision.Reason = ReasonASN
+			decision.Allowed = true
+			return decision, nil
+		}
+	}
+
+	// Anycast, satellite and reserved ranges resolve to no country; the policy's default action decides them.
+	if country == "" {
+		decision.Reason = ReasonDefault
+		switch policy.DefaultAction {
+		case DefaultAllow:
+			decision.Allowed = true
+		case DefaultError:
+			return Decision{}, classifyLookupError(geo.ErrNotFound)
+		}
+		return decision, nil
+	}
+
+	// A rule matches the country itself or any subdivision the IP address is located in.
+	listed := contains(policy.rules(), country)
+	for _, subdivision := range location.SubdivisionCodes() {
+		listed = listed || contains(policy.rules(), subdivision)
+	}
+	decision.Allowed = listed != policy.blocks()
+	return decision, nil
+}
+
+// locate resolves the country of the IP address and, if the policy has subdivision or ASN rules, its ISO 3166-2
+// subdivision codes and autonomous system. Policies on country codes only use the cheaper country lookup.
+// The returned location is never nil, even on error.
+func (c *Checker) locate(ip string, policy Policy) (*geo.Location, error) {
+	if policy.lookupMethod() == lookupCountry {
+		country, err := c.geoService.CountryISOCode(ip)
+		return &geo.Location{Country: geo.Country{ISOCode: country}}, err
+	}
+
+	location, err := c.geoService.Lookup(ip, geo.DefaultLocale)
+	if err != nil {
+		return &geo.Location{}, err
+	}
+	return location, nil
+}
+
+// Lookup returns the full geolocation data of the IP address. No policy or override is evaluated.
+//
+// Parameters:
+//   - ctx: Context of the request being served; carries deadlines and request-scoped values.
+//   - ip: The IP address to look up.
+//   - locale: The locale names are returned in; empty selects geo.DefaultLocale.
+//
+// Returns:
+//   - *geo.Location: The geolocation data stored for the IP address.
+//   - error: A *Error classifying the failure as invalid input, not found or backend failure.
+func (c *Checker) Lookup(ctx context.Context, ip, locale string) (*geo.Location, error) {
+	span := c.startLookupSpan(ctx, lookupFull, ip)
+	span.SetAttributes(attribute.String("ipchecker.locale", locale))
+	location, err := c.lookup(ip, locale)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.String("ipchecker.country", location.Country.ISOCode))
+	}
+	span.End()
+
+	if err != nil && c.observer != nil {
+		c.observer.ObserveLookupError(KindOf(err))
+	}
+	return location, err
+}
+
+// lookup implements Lookup, without notifying the observer.
+func (c *Checker) lookup(ip, locale string) (*geo.Location, error) {
+	if _, err := parseIP(ip); err != nil {
+		return nil, err
+	}
+
+	location, err := c.geoService.Lookup(ip, locale)
+	if err != nil {
+		return nil, classifyLookupError(err)
+	}
+	return location, nil
+}
+
+// Names of the LookupService methods, used as span names.
+const (
+	lookupCountry = "geo.LookupService/CountryISOCode"
+	lookupFull    = "geo.LookupService/Lookup"
+)
+
+// lookupMethod returns the LookupService method the policy is evaluated with.
+func (p Policy) lookupMethod() string {
+	if p.usesSubdivisions() || p.usesASN() {
+		return lookupFull
+	}
+	return lookupCountry
+}
+
+// startLookupSpan starts the child span around a LookupService call for the IP address.
+func (c *Checker) startLookupSpan(ctx context.Context, method, ip string) trace.Span {
+	_, span := c.tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attribute.String("ipchecker.ip", ip)),
+	)
+	return span
+}
+
+// endLookupSpan annotates a lookup span with the decision made from the lookup, or the error, and ends it.
+func endLookupSpan(span trace.Span, decision Decision, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(
+			attribute.Bool("ipchecker.allowed", decision.Allowed),
+			attribute.String("ipchecker.country", decision.Country),
+			attribute.String("ipchecker.reason", string(decision.Reason)),
+		)
+		if decision.ASN != 0 {
+			span.SetAttributes(attribute.Int64("ipchecker.asn", int64(decision.ASN)))
+		}
+	}
+	span.End()
+}
+
+// parseIP parses a client-supplied IP address, rejecting addresses with an IPv6 zone.
+func parseIP(ip string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || addr.Zone() != "" {
+		return netip.Addr{}, classifyLookupError(geo.ErrInvalidIP)
+	}
+	return addr, nil
+}
+
+// classifyLookupError maps an error returned by a geo.LookupService onto the checker error taxonomy.
+func classifyLookupError(err error) error {
+	switch {
+	case errors.Is(err, geo.ErrInvalidIP):
+		return &Error{Kind: KindInvalidInput, Message: "invalid IP address", Err: err}
+	case errors.Is(err, geo.ErrNotFound):
+		return &Error{Kind: KindNotFound, Message: "no geolocation data for IP address", Err: err}
+	default:
+		return &Error{Kind: KindBackend, Message: "unable to lookup country", Err: err}
+	}
+}
+
+// containsASN reports whether asn is one of the entries of list.
+func containsASN(list []uint, asn uint) bool {
+	for _, entry := range list {
+		if entry == asn {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether value is one of the entries of list.
+func contains(list []string, value string) bool {
+	for _, entry := range list {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
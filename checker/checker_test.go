@@ -0,0 +1,149 @@
+package checker_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc/codes"
+)
+
+// TestChecker_Decide_ClassifiesLookupErrors verifies that lookup failures are mapped onto the error taxonomy
+// while keeping the underlying cause available to errors.Is. Missing records only fail the check when the policy's
+// default action is DefaultError.
+func TestChecker_Decide_ClassifiesLookupErrors(t *testing.T) {
+	backendErr := errors.New("database read failed")
+
+	for _, tc := range []struct {
+		lookupErr error
+		wantKind  checker.Kind
+	}{
+		{geo.ErrInvalidIP, checker.KindInvalidInput},
+		{geo.ErrNotFound, checker.KindNotFound},
+		{backendErr, checker.KindBackend},
+	} {
+		c := checker.NewChecker(geo.NewMockGeoLookupService("", tc.lookupErr), nil, nil)
+
+		_, err := c.Decide(context.Background(), "128.101.101.101", checker.Policy{DefaultAction: checker.DefaultError})
+		assert.Equal(t, tc.wantKind, checker.KindOf(err))
+		assert.ErrorIs(t, err, tc.lookupErr)
+	}
+}
+
+// TestKind_TransportMappingsRoundTrip verifies that every Kind maps to a distinct HTTP status and gRPC code
+// and that the inverse mappings used by clients recover the original Kind.
+func TestKind_TransportMappingsRoundTrip(t *testing.T) {
+	for _, kind := range []checker.Kind{checker.KindInvalidInput, checker.KindNotFound, checker.KindBackend, checker.KindForbidden} {
+		assert.Equal(t, kind, checker.KindFromHTTPStatus(kind.HTTPStatus()), kind.String())
+		assert.Equal(t, kind, checker.KindFromGRPCCode(kind.GRPCCode()), kind.String())
+	}
+
+	assert.Equal(t, http.StatusBadRequest, checker.KindInvalidInput.HTTPStatus())
+	assert.Equal(t, codes.InvalidArgument, checker.KindInvalidInput.GRPCCode())
+}
+
+// TestChecker_Decide_TracesLookup verifies that the geolocation lookup is traced as a child span of the request,
+// annotated with the decision made from it.
+func TestChecker_Decide_TracesLookup(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	c := checker.NewChecker(geo.NewMockGeoLookupService("GB", nil), nil, nil)
+	c.SetTracerProvider(provider)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	_, err := c.Decide(ctx, "81.2.69.142", checker.Policy{AllowedCountries: []string{"US"}})
+	require.NoError(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	lookup := spans[0]
+	assert.Equal(t, "geo.LookupService/CountryISOCode", lookup.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), lookup.Parent().SpanID())
+	assert.Subset(t, lookup.Attributes(), []attribute.KeyValue{
+		attribute.String("ipchecker.ip", "81.2.69.142"),
+		attribute.Bool("ipchecker.allowed", false),
+		attribute.String("ipchecker.country", "GB"),
+		attribute.String("ipchecker.reason", "rule"),
+	})
+
+	// Failed lookups mark the span as failed.
+	c = checker.NewChecker(geo.NewMockGeoLookupService("", errors.New("database read failed")), nil, nil)
+	c.SetTracerProvider(provider)
+	_, err = c.Decide(context.Background(), "81.2.69.142", checker.Policy{AllowedCountries: []string{"US"}})
+	require.Error(t, err)
+	assert.Equal(t, otelcodes.Error, recorder.Ended()[2].Status().Code)
+}
+
+// TestChecker_ResolvePolicy_Restriction verifies that callers restricted to certain policies may only name one of
+// them, and that requests naming none, including those sending inline rules, are checked against the first.
+func TestChecker_ResolvePolicy_Restriction(t *testing.T) {
+	set, err := checker.LoadPolicyFile(writePolicyFile(t, "policies.yaml", `
+policies:
+  eu:
+    allowed_countries: [DE, FR]
+  us-only:
+    allowed_countries: [US]
+`))
+	require.NoError(t, err)
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), set, nil)
+	type restrictedKey struct{}
+	c.SetPolicyRestriction(func(ctx context.Context) []string {
+		policies, _ := ctx.Value(restrictedKey{}).([]string)
+		return policies
+	})
+	inline := checker.Policy{AllowedCountries: []string{"US"}}
+
+	// Unrestricted callers choose freely.
+	policy, err := c.ResolvePolicy(context.Background(), "", inline)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"US"}, policy.AllowedCountries)
+
+	ctx := context.WithValue(context.Background(), restrictedKey{}, []string{"eu"})
+	policy, err = c.ResolvePolicy(ctx, "", inline)
+	require.NoError(t, err)
+	assert.Equal(t, "eu", policy.Name)
+	_, err = c.ResolvePolicy(ctx, "us-only", checker.Policy{})
+	assert.Equal(t, checker.KindForbidden, checker.KindOf(err))
+	assert.EqualError(t, err, `policy "us-only" is not permitted for this caller`)
+
+	// An empty grant permits nothing rather than everything.
+	ctx = context.WithValue(context.Background(), restrictedKey{}, []string{})
+	_, err = c.ResolvePolicy(ctx, "", inline)
+	assert.Equal(t, checker.KindForbidden, checker.KindOf(err))
+}
+
+// TestChecker_ResolvePolicy_InlineCodes verifies that inline country codes are upper-cased to match the codes the
+// lookup reports, and that codes that are neither countries nor subdivisions are rejected.
+func TestChecker_ResolvePolicy_InlineCodes(t *testing.T) {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+
+	policy, err := c.ResolvePolicy(context.Background(), "", checker.Policy{AllowedCountries: []string{"us", "ca-qc"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"US", "CA-QC"}, policy.AllowedCountries)
+	decision, err := c.Decide(context.Background(), "128.101.101.101", policy)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	policy, err = c.ResolvePolicy(context.Background(), "", checker.Policy{BlockedCountries: []string{"us"}})
+	require.NoError(t, err)
+	decision, err = c.Decide(context.Background(), "128.101.101.101", policy)
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+
+	for _, code := range []string{"USA", "U", "", "US-", "united states"} {
+		_, err := c.ResolvePolicy(context.Background(), "", checker.Policy{AllowedCountries: []string{code}})
+		assert.Equal(t, checker.KindInvalidInput, checker.KindOf(err), code)
+	}
+	_, err = c.ResolvePolicy(context.Background(), "", checker.Policy{BlockedCountries: []string{"DE", "x1"}})
+	assert.EqualError(t, err, `"x1" is not an ISO 3166-1 alpha-2 country code or ISO 3166-2 subdivision code`)
+}
@@ -0,0 +1,294 @@
+// Package checkertest provides a conformance suite that every transport exposing checker.Checker must pass,
+// so that HTTP and gRPC clients observe the same decisions and the same error classification.
+package checkertest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Request is the transport-independent form of a single IP check request.
+type Request struct {
+	IP               string
+	AllowedCountries []string
+	BlockedCountries []string
+	DefaultAction    string
+	Policy           string
+}
+
+// CheckFunc performs one IP check through a concrete transport backed by the given checker.
+//
+// Implementations translate the transport's failure response back into a *checker.Error, using
+// checker.KindFromHTTPStatus or checker.KindFromGRPCCode and the message returned to the client.
+type CheckFunc func(t *testing.T, c *checker.Checker, req Request) (checker.Decision, error)
+
+// conformancePolicies is the policy file every transport under test is configured with.
+const conformancePolicies = `
+policies:
+  checkout-eu:
+    version: "v1"
+    allowed_countries: [DE, FR]
+  signup:
+    version: "v2"
+    blocked_countries: [KP]
+    default_action: allow
+  api:
+    version: "v3"
+    allowed_countries: [US]
+    allowed_asns: [3320]
+    blocked_asns: [16509]
+`
+
+// conformanceOverrides is the override file every transport under test is configured with.
+const conformanceOverrides = `
+allow:
+  - name: office
+    cidrs: [203.0.113.0/24]
+deny:
+  - name: abuse
+    cidrs: [198.51.100.0/24, 203.0.113.66]
+`
+
+// conformanceCase describes one scenario of the conformance suite.
+type conformanceCase struct {
+	name               string
+	lookupCountry      string
+	lookupSubdivisions []string
+	lookupASN          uint
+	lookupErr          error
+	req                Request
+	want               checker.Decision
+	wantKind           checker.Kind
+	wantMessage        string
+}
+
+// conformanceCases lists the scenarios every transport must handle identically.
+var conformanceCases = []conformanceCase{
+	{
+		name:          "allowed country",
+		lookupCountry: "US",
+		req:           Request{IP: "128.101.101.101", AllowedCountries: []string{"US", "CA"}},
+		want:          checker.Decision{Allowed: true, Country: "US", Reason: checker.ReasonRule},
+	},
+	{
+		name:          "denied country",
+		lookupCountry: "GB",
+		req:           Request{IP: "81.2.69.142", AllowedCountries: []string{"US", "CA"}},
+		want:          checker.Decision{Allowed: false, Country: "GB", Reason: checker.ReasonRule},
+	},
+	{
+		name:          "named policy",
+		lookupCountry: "DE",
+		req:           Request{IP: "2.160.0.1", Policy: "checkout-eu"},
+		want: checker.Decision{
+			Allowed: true, Country: "DE", Reason: checker.ReasonRule, Policy: "checkout-eu", PolicyVersion: "v1",
+		},
+	},
+	{
+		name:          "blocked country",
+		lookupCountry: "KP",
+		req:           Request{IP: "175.45.176.1", BlockedCountries: []string{"KP", "IR"}},
+		want:          checker.Decision{Allowed: false, Country: "KP", Reason: checker.ReasonRule},
+	},
+	{
+		name:          "country not blocked",
+		lookupCountry: "US",
+		req:           Request{IP: "128.101.101.101", BlockedCountries: []string{"KP", "IR"}},
+		want:          checker.Decision{Allowed: true, Country: "US", Reason: checker.ReasonRule},
+	},
+	{
+		name:               "allowed subdivision",
+		lookupCountry:      "US",
+		lookupSubdivisions: []string{"US-CA"},
+		req:                Request{IP: "128.101.101.101", AllowedCountries: []string{"US-CA", "CA-QC"}},
+		want:               checker.Decision{Allowed: true, Country: "US", Reason: checker.ReasonRule},
+	},
+	{
+		name:               "subdivision outside the allowed ones",
+		lookupCountry:      "US",
+		lookupSubdivisions: []string{"US-TX"},
+		req:                Request{IP: "128.101.101.101", AllowedCountries: []string{"US-CA", "CA-QC"}},
+		want:               checker.Decision{Allowed: false, Country: "US", Reason: checker.ReasonRule},
+	},
+	{
+		name:               "blocked subdivision",
+		lookupCountry:      "CA",
+		lookupSubdivisions: []string{"CA-QC"},
+		req:                Request{IP: "24.48.0.1", BlockedCountries: []string{"CA-QC"}},
+		want:               checker.Decision{Allowed: false, Country: "CA", Reason: checker.ReasonRule},
+	},
+	{
+		name:          "allowed ASN outside the allowed countries",
+		lookupCountry: "DE",
+		lookupASN:     3320,
+		req:           Request{IP: "2.160.0.1", Policy: "api"},
+		want: checker.Decision{
+			Allowed: true, Country: "DE", ASN: 3320, Reason: checker.ReasonASN, Policy: "api", PolicyVersion: "v3",
+		},
+	},
+	{
+		name:          "blocked ASN inside the allowed countries",
+		lookupCountry: "US",
+		lookupASN:     16509,
+		req:           Request{IP: "3.5.140.1", Policy: "api"},
+		want: checker.Decision{
+			Allowed: false, Country: "US", ASN: 16509, Reason: checker.ReasonASN, Policy: "api", PolicyVersion: "v3",
+		},
+	},
+	{
+		name:          "unlisted ASN falls through to the country rules",
+		lookupCountry: "US",
+		lookupASN:     7018,
+		req:           Request{IP: "12.0.0.1", Policy: "api"},
+		want: checker.Decision{
+			Allowed: true, Country: "US", ASN: 7018, Reason: checker.ReasonRule, Policy: "api", PolicyVersion: "v3",
+		},
+	},
+	{
+		name: "unknown country denied by default",
+		req:  Request{IP: "192.0.2.1", AllowedCountries: []string{"US"}},
+		want: checker.Decision{Allowed: false, Reason: checker.ReasonDefault},
+	},
+	{
+		name: "unknown country allowed by default action",
+		req:  Request{IP: "192.0.2.1", BlockedCountries: []string{"KP"}, DefaultAction: "allow"},
+		want: checker.Decision{Allowed: true, Reason: checker.ReasonDefault},
+	},
+	{
+		name:      "no geolocation record uses policy default action",
+		lookupErr: geo.ErrNotFound,
+		req:       Request{IP: "10.0.0.1", Policy: "signup"},
+		want:      checker.Decision{Allowed: true, Reason: checker.ReasonDefault, Policy: "signup", PolicyVersion: "v2"},
+	},
+	{
+		name:          "allow override skips the country list",
+		lookupCountry: "GB",
+		req:           Request{IP: "203.0.113.10", AllowedCountries: []string{"US"}},
+		want: checker.Decision{
+			Allowed:  true,
+			Reason:   checker.ReasonOverride,
+			Override: &checker.OverrideMatch{Action: checker.OverrideAllow, Name: "office", CIDR: "203.0.113.0/24"},
+		},
+	},
+	{
+		name:          "deny override skips the country list",
+		lookupCountry: "US",
+		req:           Request{IP: "198.51.100.7", AllowedCountries: []string{"US"}},
+		want: checker.Decision{
+			Allowed:  false,
+			Reason:   checker.ReasonOverride,
+			Override: &checker.OverrideMatch{Action: checker.OverrideDeny, Name: "abuse", CIDR: "198.51.100.0/24"},
+		},
+	},
+	{
+		name:          "more specific override wins",
+		lookupCountry: "US",
+		req:           Request{IP: "203.0.113.66", AllowedCountries: []string{"US"}},
+		want: checker.Decision{
+			Allowed:  false,
+			Reason:   checker.ReasonOverride,
+			Override: &checker.OverrideMatch{Action: checker.OverrideDeny, Name: "abuse", CIDR: "203.0.113.66/32"},
+		},
+	},
+	{
+		name:        "unknown policy",
+		req:         Request{IP: "2.160.0.1", Policy: "checkout-mars"},
+		wantKind:    checker.KindInvalidInput,
+		wantMessage: `unknown policy "checkout-mars"`,
+	},
+	{
+		name:        "policy and inline list together",
+		req:         Request{IP: "2.160.0.1", Policy: "checkout-eu", AllowedCountries: []string{"US"}},
+		wantKind:    checker.KindInvalidInput,
+		wantMessage: "specify either policy or inline country rules, not both",
+	},
+	{
+		name:        "policy and default action together",
+		req:         Request{IP: "2.160.0.1", Policy: "checkout-eu", DefaultAction: "allow"},
+		wantKind:    checker.KindInvalidInput,
+		wantMessage: "specify either policy or inline country rules, not both",
+	},
+	{
+		name:        "neither policy nor inline list",
+		req:         Request{IP: "2.160.0.1"},
+		wantKind:    checker.KindInvalidInput,
+		wantMessage: "either policy, allowed_countries or blocked_countries is required",
+	},
+	{
+		name:        "allowed and blocked lists together",
+		req:         Request{IP: "2.160.0.1", AllowedCountries: []string{"DE"}, BlockedCountries: []string{"KP"}},
+		wantKind:    checker.KindInvalidInput,
+		wantMessage: "specify either allowed_countries or blocked_countries, not both",
+	},
+	{
+		name:        "invalid default action",
+		req:         Request{IP: "2.160.0.1", AllowedCountries: []string{"DE"}, DefaultAction: "maybe"},
+		wantKind:    checker.KindInvalidInput,
+		wantMessage: `invalid default_action "maybe": must be allow, deny or error`,
+	},
+	{
+		name:        "invalid IP address",
+		lookupErr:   geo.ErrInvalidIP,
+		req:         Request{IP: "not-an-ip", AllowedCountries: []string{"US"}},
+		wantKind:    checker.KindInvalidInput,
+		wantMessage: "invalid IP address",
+	},
+	{
+		name:        "unknown country with error default action",
+		req:         Request{IP: "192.0.2.1", AllowedCountries: []string{"US"}, DefaultAction: "error"},
+		wantKind:    checker.KindNotFound,
+		wantMessage: "no geolocation data for IP address",
+	},
+	{
+		name:        "backend failure",
+		lookupErr:   errors.New("database read failed"),
+		req:         Request{IP: "128.101.101.101", AllowedCountries: []string{"US"}},
+		wantKind:    checker.KindBackend,
+		wantMessage: "unable to lookup country",
+	},
+}
+
+// Run executes the conformance suite against the transport implemented by check.
+//
+// Parameters:
+//   - t: The running test.
+//   - check: Adapter performing a single check through the transport under test.
+func Run(t *testing.T, check CheckFunc) {
+	policyPath := filepath.Join(t.TempDir(), "policies.yaml")
+	require.NoError(t, os.WriteFile(policyPath, []byte(conformancePolicies), 0o644))
+	policies, err := checker.LoadPolicyFile(policyPath)
+	require.NoError(t, err)
+
+	overridePath := filepath.Join(t.TempDir(), "overrides.yaml")
+	require.NoError(t, os.WriteFile(overridePath, []byte(conformanceOverrides), 0o644))
+	overrides, err := checker.LoadOverrideFile(overridePath)
+	require.NoError(t, err)
+
+	for _, tc := range conformanceCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockGeo := geo.NewMockGeoLookupService(tc.lookupCountry, tc.lookupErr)
+			mockGeo.MockSubdivisions = tc.lookupSubdivisions
+			mockGeo.MockASN = tc.lookupASN
+
+			decision, err := check(t, checker.NewChecker(mockGeo, policies, overrides), tc.req)
+
+			if tc.wantKind == 0 {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, decision)
+				return
+			}
+
+			if assert.Error(t, err) {
+				assert.Equal(t, tc.wantKind, checker.KindOf(err), "Expected the error kind to survive the transport.")
+				assert.Equal(t, tc.wantMessage, err.Error(), "Expected the client-facing message to match.")
+			}
+		})
+	}
+}
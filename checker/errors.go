@@ -0,0 +1,138 @@
+package checker
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Kind classifies why a decision could not be made. Every transport maps a Kind onto its own status codes
+// through HTTPStatus and GRPCCode, so the same failure is reported the same way everywhere.
+type Kind int
+
+const (
+	// KindInvalidInput means the request itself is malformed, e.g. the IP address cannot be parsed.
+	KindInvalidInput Kind = iota + 1
+
+	// KindNotFound means the IP address is well-formed but the database holds no data for it.
+	KindNotFound
+
+	// KindBackend means the lookup backend failed, e.g. the database could not be read.
+	KindBackend
+
+	// KindForbidden means the authenticated caller is not permitted to make the request, e.g. it names a policy
+	// the caller's token does not grant.
+	KindForbidden
+)
+
+// String returns a short, stable name for the Kind, suitable for logs and metrics labels.
+func (k Kind) String() string {
+	switch k {
+	case KindInvalidInput:
+		return "invalid_input"
+	case KindNotFound:
+		return "not_found"
+	case KindBackend:
+		return "backend"
+	case KindForbidden:
+		return "forbidden"
+	default:
+		return "unknown"
+	}
+}
+
+// HTTPStatus returns the HTTP status code used to report errors of this Kind.
+func (k Kind) HTTPStatus() int {
+	switch k {
+	case KindInvalidInput:
+		return http.StatusBadRequest
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindForbidden:
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GRPCCode returns the gRPC status code used to report errors of this Kind.
+func (k Kind) GRPCCode() codes.Code {
+	switch k {
+	case KindInvalidInput:
+		return codes.InvalidArgument
+	case KindNotFound:
+		return codes.NotFound
+	case KindForbidden:
+		return codes.PermissionDenied
+	default:
+		return codes.Internal
+	}
+}
+
+// Error is returned by Checker when a decision cannot be made.
+type Error struct {
+	// Kind classifies the failure.
+	Kind Kind
+
+	// Message is the client-facing description of the failure. It never includes internal details.
+	Message string
+
+	// Err is the underlying cause, kept for logging; it may be nil.
+	Err error
+}
+
+// Error satisfies the error interface and returns the client-facing message.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying cause so errors.Is and errors.As can inspect it.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the Kind of err, or KindBackend if err is not a *Error.
+//
+// Parameters:
+//   - err: A non-nil error returned by a Checker.
+//
+// Returns:
+//   - Kind: The classification of the failure.
+func KindOf(err error) Kind {
+	var checkErr *Error
+	if errors.As(err, &checkErr) {
+		return checkErr.Kind
+	}
+	return KindBackend
+}
+
+// KindFromHTTPStatus returns the Kind reported by the given non-2xx HTTP status code.
+// It is the inverse of Kind.HTTPStatus and lets clients classify failures received over HTTP.
+func KindFromHTTPStatus(statusCode int) Kind {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return KindInvalidInput
+	case http.StatusNotFound:
+		return KindNotFound
+	case http.StatusForbidden:
+		return KindForbidden
+	default:
+		return KindBackend
+	}
+}
+
+// KindFromGRPCCode returns the Kind reported by the given non-OK gRPC status code.
+// It is the inverse of Kind.GRPCCode and lets clients classify failures received over gRPC.
+func KindFromGRPCCode(code codes.Code) Kind {
+	switch code {
+	case codes.InvalidArgument:
+		return KindInvalidInput
+	case codes.NotFound:
+		return KindNotFound
+	case codes.PermissionDenied:
+		return KindForbidden
+	default:
+		return KindBackend
+	}
+}
@@ -0,0 +1,170 @@
+// Package cidr provides a path-compressed binary radix trie for longest-prefix matching of IP addresses
+// against large lists of IPv4 and IPv6 networks.
+package cidr
+
+import (
+	"net/netip"
+)
+
+// Trie maps IP prefixes to values and finds the most specific prefix containing an address.
+//
+// Lookups cost at most one node visit per stored prefix length on the path (bounded by 32 for IPv4 and 128 for IPv6),
+// independent of how many prefixes are stored. Chains of single-child nodes are collapsed, so the trie holds fewer
+// than two nodes per inserted prefix.
+//
+// A Trie is not safe for concurrent modification, but any number of goroutines may call Lookup concurrently once
+// all prefixes have been inserted.
+type Trie[V any] struct {
+	v4 *node[V]
+	v6 *node[V]
+	n  int
+}
+
+// node is a trie node covering prefix. Nodes without a value only exist to join two diverging branches.
+type node[V any] struct {
+	prefix   netip.Prefix // Always masked.
+	children [2]*node[V]  // Indexed by the bit of the address right after prefix.
+	value    V
+	hasValue bool
+}
+
+// Insert stores value under prefix, replacing any value already stored under the same prefix.
+// IPv4-mapped IPv6 prefixes (::ffff:0:0/96 and longer) are stored as their IPv4 equivalent.
+//
+// Parameters:
+//   - prefix: A valid IPv4 or IPv6 prefix; host bits are ignored.
+//   - value: The value returned by Lookup for addresses whose most specific match is prefix.
+func (t *Trie[V]) Insert(prefix netip.Prefix, value V) {
+	prefix = normalize(prefix)
+
+	slot := &t.v6
+	if prefix.Addr().Is4() {
+		slot = &t.v4
+	}
+
+	for {
+		cur := *slot
+		if cur == nil {
+			*slot = &node[V]{prefix: prefix, value: value, hasValue: true}
+			t.n++
+			return
+		}
+
+		common := commonBits(cur.prefix, prefix)
+		switch {
+		case common == cur.prefix.Bits() && common == prefix.Bits():
+			// Same prefix: store (or replace) the value on the existing node.
+			if !cur.hasValue {
+				t.n++
+			}
+			cur.value, cur.hasValue = value, true
+			return
+
+		case common == cur.prefix.Bits():
+			// The new prefix lies below cur; descend towards it.
+			slot = &cur.children[bitAt(prefix.Addr(), common)]
+
+		case common == prefix.Bits():
+			// The new prefix contains cur; insert it above cur.
+			parent := &node[V]{prefix: prefix, value: value, hasValue: true}
+			parent.children[bitAt(cur.prefix.Addr(), common)] = cur
+			*slot = parent
+			t.n++
+			return
+
+		default:
+			// The prefixes diverge after common bits; join them under a valueless branch node.
+			branch := &node[V]{prefix: netip.PrefixFrom(prefix.Addr(), common).Masked()}
+			branch.children[bitAt(cur.prefix.Addr(), common)] = cur
+			branch.children[bitAt(prefix.Addr(), common)] = &node[V]{prefix: prefix, value: value, hasValue: true}
+			*slot = branch
+			t.n++
+			return
+		}
+	}
+}
+
+// Lookup returns the value stored under the most specific prefix containing addr.
+//
+// Parameters:
+//   - addr: The address to match; IPv4-mapped IPv6 addresses match IPv4 prefixes.
+//
+// Returns:
+//   - netip.Prefix: The matching prefix.
+//   - V: The value stored under the matching prefix.
+//   - bool: Whether any stored prefix contains addr.
+func (t *Trie[V]) Lookup(addr netip.Addr) (netip.Prefix, V, bool) {
+	addr = addr.Unmap()
+
+	n := t.v6
+	if addr.Is4() {
+		n = t.v4
+	}
+
+	var best *node[V]
+	for n != nil && n.prefix.Contains(addr) {
+		if n.hasValue {
+			best = n
+		}
+		if n.prefix.Bits() == addr.BitLen() {
+			break
+		}
+		n = n.children[bitAt(addr, n.prefix.Bits())]
+	}
+
+	if best == nil {
+		var zero V
+		return netip.Prefix{}, zero, false
+	}
+	return best.prefix, best.value, true
+}
+
+// Len returns the number of prefixes stored in the trie.
+func (t *Trie[V]) Len() int {
+	return t.n
+}
+
+// normalize masks prefix and converts IPv4-mapped IPv6 prefixes to IPv4.
+func normalize(prefix netip.Prefix) netip.Prefix {
+	addr := prefix.Addr()
+	if addr.Is4In6() && prefix.Bits() >= 96 {
+		return netip.PrefixFrom(addr.Unmap(), prefix.Bits()-96).Masked()
+	}
+	return prefix.Masked()
+}
+
+// commonBits returns the length of the longest prefix shared by a and b, capped at the shorter of the two.
+// Both prefixes must be of the same address family.
+func commonBits(a, b netip.Prefix) int {
+	limit := min(a.Bits(), b.Bits())
+
+	x, y := a.Addr().As16(), b.Addr().As16()
+	offset := 0
+	if a.Addr().Is4() {
+		// As16 stores IPv4 addresses in the last four bytes.
+		offset = 12
+	}
+
+	bits := 0
+	for i := offset; i < 16 && bits < limit; i++ {
+		diff := x[i] ^ y[i]
+		if diff == 0 {
+			bits += 8
+			continue
+		}
+		for mask := byte(0x80); diff&mask == 0; mask >>= 1 {
+			bits++
+		}
+		break
+	}
+	return min(bits, limit)
+}
+
+// bitAt returns bit i (0 = most significant) of addr.
+func bitAt(addr netip.Addr, i int) int {
+	b := addr.As16()
+	if addr.Is4() {
+		i += 96
+	}
+	return int(b[i/8]>>(7-uint(i%8))) & 1
+}
@@ -0,0 +1,93 @@
+package cidr_test
+
+import (
+	"math/rand"
+	"net/netip"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/checker/internal/cidr"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTrie_LongestPrefixMatch verifies that the most specific prefix wins for both address families,
+// including prefixes inserted above, below and beside existing ones.
+func TestTrie_LongestPrefixMatch(t *testing.T) {
+	var trie cidr.Trie[string]
+	for _, p := range []string{
+		"10.1.2.0/24", "10.0.0.0/8", "10.1.0.0/16", "192.168.0.0/16", "0.0.0.0/0",
+		"2001:db8::/32", "2001:db8:1::/48", "::ffff:203.0.113.0/120",
+	} {
+		trie.Insert(netip.MustParsePrefix(p), p)
+	}
+
+	for addr, want := range map[string]string{
+		"10.1.2.3":          "10.1.2.0/24",
+		"10.1.3.1":          "10.1.0.0/16",
+		"10.200.0.1":        "10.0.0.0/8",
+		"192.168.5.5":       "192.168.0.0/16",
+		"8.8.8.8":           "0.0.0.0/0",
+		"2001:db8:1::1":     "2001:db8:1::/48",
+		"2001:db8:2::1":     "2001:db8::/32",
+		"203.0.113.9":       "::ffff:203.0.113.0/120",
+		"::ffff:10.1.2.200": "10.1.2.0/24",
+	} {
+		_, got, ok := trie.Lookup(netip.MustParseAddr(addr))
+		assert.True(t, ok, addr)
+		assert.Equal(t, want, got, addr)
+	}
+
+	_, _, ok := trie.Lookup(netip.MustParseAddr("2001:db9::1"))
+	assert.False(t, ok, "Expected no IPv6 match; IPv4 prefixes must not match IPv6 addresses.")
+	assert.Equal(t, 8, trie.Len())
+}
+
+// TestTrie_MatchesLinearScan cross-checks the trie against a brute-force scan over random prefixes and addresses.
+func TestTrie_MatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomAddr := func() netip.Addr {
+		// Draw from a small space so prefixes overlap often.
+		return netip.AddrFrom4([4]byte{10, byte(rng.Intn(4)), byte(rng.Intn(256)), byte(rng.Intn(256))})
+	}
+
+	var trie cidr.Trie[netip.Prefix]
+	var prefixes []netip.Prefix
+	for i := 0; i < 500; i++ {
+		p := netip.PrefixFrom(randomAddr(), 8+rng.Intn(25)).Masked()
+		trie.Insert(p, p)
+		prefixes = append(prefixes, p)
+	}
+
+	for i := 0; i < 5000; i++ {
+		addr := randomAddr()
+
+		var want netip.Prefix
+		found := false
+		for _, p := range prefixes {
+			if p.Contains(addr) && (!found || p.Bits() > want.Bits()) {
+				want, found = p, true
+			}
+		}
+
+		_, got, ok := trie.Lookup(addr)
+		assert.Equal(t, found, ok, addr.String())
+		if found {
+			assert.Equal(t, want, got, addr.String())
+		}
+	}
+}
+
+// BenchmarkTrie_Lookup measures lookups against a large IPv4 prefix list.
+func BenchmarkTrie_Lookup(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	var trie cidr.Trie[struct{}]
+	for i := 0; i < 100000; i++ {
+		addr := netip.AddrFrom4([4]byte{byte(rng.Intn(256)), byte(rng.Intn(256)), byte(rng.Intn(256)), 0})
+		trie.Insert(netip.PrefixFrom(addr, 16+rng.Intn(9)), struct{}{})
+	}
+	addr := netip.MustParseAddr("81.2.69.142")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie.Lookup(addr)
+	}
+}
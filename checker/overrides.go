@@ -0,0 +1,148 @@
+package checker
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/justfairdev/ipchecker/checker/internal/cidr"
+	"gopkg.in/yaml.v3"
+)
+
+// OverrideAction is the decision forced by a matching CIDR override.
+type OverrideAction string
+
+const (
+	// OverrideAllow always allows matching addresses, whatever their country.
+	OverrideAllow OverrideAction = "allow"
+
+	// OverrideDeny always denies matching addresses, whatever their country.
+	OverrideDeny OverrideAction = "deny"
+)
+
+// OverrideMatch identifies the override rule that decided a request.
+type OverrideMatch struct {
+	// Action is the decision forced by the rule.
+	Action OverrideAction
+
+	// Name is the name of the override rule (e.g., "office-berlin").
+	Name string
+
+	// CIDR is the network of the rule that contained the address.
+	CIDR string
+}
+
+// Overrides holds the CIDR allow and deny lists evaluated before the country lookup.
+//
+// When an address is contained in several networks, the most specific network decides. If the same network is listed
+// under both allow and deny, deny wins.
+type Overrides struct {
+	trie cidr.Trie[overrideRule]
+}
+
+// overrideRule is the value stored for each network in the override trie.
+type overrideRule struct {
+	action OverrideAction
+	name   string
+}
+
+// overrideFile is the on-disk layout of an override file.
+//
+// Example (YAML; the equivalent JSON document is accepted as well):
+//
+//	allow:
+//	  - name: office-berlin
+//	    cidrs: [203.0.113.0/24, "2001:db8:1::/48"]
+//	deny:
+//	  - name: known-bad
+//	    cidrs: [198.51.100.0/24]
+type overrideFile struct {
+	Allow []overrideEntry `yaml:"allow" json:"allow"`
+	Deny  []overrideEntry `yaml:"deny" json:"deny"`
+}
+
+// overrideEntry is a named group of networks in an override file.
+type overrideEntry struct {
+	Name  string   `yaml:"name" json:"name"`
+	CIDRs []string `yaml:"cidrs" json:"cidrs"`
+}
+
+// LoadOverrideFile reads and validates the CIDR allow and deny lists stored in a YAML or JSON file.
+//
+// Parameters:
+//   - path: Filesystem path to the override file.
+//
+// Returns:
+//   - *Overrides: The override lists, ready for matching.
+//   - error: An error naming the offending rule if the file cannot be read or a network is invalid.
+func LoadOverrideFile(path string) (*Overrides, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading override file: %w", err)
+	}
+
+	var file overrideFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing override file %s: %w", path, err)
+	}
+
+	overrides := &Overrides{}
+	// Insert allow rules first so a deny rule for the identical network replaces it.
+	if err := overrides.add(OverrideAllow, file.Allow); err != nil {
+		return nil, fmt.Errorf("override file %s: %w", path, err)
+	}
+	if err := overrides.add(OverrideDeny, file.Deny); err != nil {
+		return nil, fmt.Errorf("override file %s: %w", path, err)
+	}
+	return overrides, nil
+}
+
+// Match returns the override rule deciding addr, if any.
+//
+// Parameters:
+//   - addr: The address being checked.
+//
+// Returns:
+//   - OverrideMatch: The most specific matching rule.
+//   - bool: Whether any override rule contains addr.
+func (o *Overrides) Match(addr netip.Addr) (OverrideMatch, bool) {
+	if o == nil {
+		return OverrideMatch{}, false
+	}
+
+	prefix, rule, ok := o.trie.Lookup(addr)
+	if !ok {
+		return OverrideMatch{}, false
+	}
+	return OverrideMatch{Action: rule.action, Name: rule.name, CIDR: prefix.String()}, true
+}
+
+// add validates entries and inserts their networks with the given action.
+func (o *Overrides) add(action OverrideAction, entries []overrideEntry) error {
+	for _, entry := range entries {
+		if entry.Name == "" {
+			return fmt.Errorf("%s override rules must have a name", action)
+		}
+		for _, raw := range entry.CIDRs {
+			prefix, err := parseNetwork(raw)
+			if err != nil {
+				return fmt.Errorf("%s override %q: invalid network %q: %w", action, entry.Name, raw, err)
+			}
+			o.trie.Insert(prefix, overrideRule{action: action, name: entry.Name})
+		}
+	}
+	return nil
+}
+
+// parseNetwork parses a CIDR network, accepting a bare address as a single-host network.
+func parseNetwork(raw string) (netip.Prefix, error) {
+	if !strings.Contains(raw, "/") {
+		addr, err := netip.ParseAddr(raw)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	return netip.ParsePrefix(raw)
+}
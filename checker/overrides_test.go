@@ -0,0 +1,47 @@
+package checker_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadOverrideFile_Match verifies JSON override files, bare host addresses, IPv6 networks and that deny wins when
+// the same network is listed under both allow and deny.
+func TestLoadOverrideFile_Match(t *testing.T) {
+	overrides, err := checker.LoadOverrideFile(writePolicyFile(t, "overrides.json", `{
+		"allow": [{"name": "office", "cidrs": ["203.0.113.0/24", "2001:db8:1::/48", "192.0.2.1"]}],
+		"deny":  [{"name": "abuse", "cidrs": ["192.0.2.1/32"]}]
+	}`))
+	require.NoError(t, err)
+
+	for addr, want := range map[string]checker.OverrideMatch{
+		"203.0.113.9":   {Action: checker.OverrideAllow, Name: "office", CIDR: "203.0.113.0/24"},
+		"2001:db8:1::5": {Action: checker.OverrideAllow, Name: "office", CIDR: "2001:db8:1::/48"},
+		"192.0.2.1":     {Action: checker.OverrideDeny, Name: "abuse", CIDR: "192.0.2.1/32"},
+	} {
+		got, ok := overrides.Match(netip.MustParseAddr(addr))
+		assert.True(t, ok, addr)
+		assert.Equal(t, want, got, addr)
+	}
+
+	_, ok := overrides.Match(netip.MustParseAddr("198.51.100.1"))
+	assert.False(t, ok, "Expected no override for an address outside every rule.")
+}
+
+// TestLoadOverrideFile_RejectsInvalidRules verifies that malformed override rules are rejected at load time.
+func TestLoadOverrideFile_RejectsInvalidRules(t *testing.T) {
+	for name, contents := range map[string]string{
+		"invalid network": "deny:\n  - name: bad\n    cidrs: [10.0.0.0/33]\n",
+		"missing name":    "allow:\n  - cidrs: [10.0.0.0/8]\n",
+		"malformed yaml":  "allow: [",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := checker.LoadOverrideFile(writePolicyFile(t, "overrides.yaml", contents))
+			assert.Error(t, err)
+		})
+	}
+}
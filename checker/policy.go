@@ -0,0 +1,331 @@
+package checker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy describes the rules an IP address is checked against.
+type Policy struct {
+	// Name identifies a server-side policy; empty for policies supplied inline by the caller.
+	Name string
+
+	// Version identifies the revision of a server-side policy; empty for inline policies.
+	Version string
+
+	// AllowedCountries is the list of ISO 3166-1 alpha-2 country codes or ISO 3166-2 subdivision codes
+	// (e.g., "US-CA") an IP address must originate from. Mutually exclusive with BlockedCountries.
+	AllowedCountries []string
+
+	// BlockedCountries is the list of ISO 3166-1 alpha-2 country codes or ISO 3166-2 subdivision codes
+	// an IP address must not originate from. Mutually exclusive with AllowedCountries.
+	BlockedCountries []string
+
+	// DefaultAction decides IP addresses for which no country is known; empty means DefaultDeny.
+	DefaultAction DefaultAction
+
+	// AllowedASNs lists autonomous system numbers whose IP addresses are allowed regardless of their country.
+	// Server-side policies only; requires an ASN database.
+	AllowedASNs []uint
+
+	// BlockedASNs lists autonomous system numbers whose IP addresses are denied regardless of their country.
+	// Takes precedence over AllowedASNs. Server-side policies only; requires an ASN database.
+	BlockedASNs []uint
+}
+
+// DefaultAction is the decision applied to IP addresses the geolocation database has no country for,
+// such as anycast, satellite or reserved ranges.
+type DefaultAction string
+
+const (
+	// DefaultDeny denies IP addresses without a country. This is the default.
+	DefaultDeny DefaultAction = "deny"
+
+	// DefaultAllow allows IP addresses without a country.
+	DefaultAllow DefaultAction = "allow"
+
+	// DefaultError fails the check for IP addresses without a country with a KindNotFound error.
+	DefaultError DefaultAction = "error"
+)
+
+// parseDefaultAction validates a default action, mapping the empty string to DefaultDeny.
+func parseDefaultAction(raw string) (DefaultAction, error) {
+	switch action := DefaultAction(raw); action {
+	case "":
+		return DefaultDeny, nil
+	case DefaultDeny, DefaultAllow, DefaultError:
+		return action, nil
+	default:
+		return "", fmt.Errorf("invalid default_action %q: must be allow, deny or error", raw)
+	}
+}
+
+// blocks reports whether the policy is a deny list, i.e. lists the countries that are not allowed.
+func (p Policy) blocks() bool {
+	return len(p.BlockedCountries) > 0
+}
+
+// rules returns the country list of the policy, whichever of the allow or deny list is in use.
+func (p Policy) rules() []string {
+	if p.blocks() {
+		return p.BlockedCountries
+	}
+	return p.AllowedCountries
+}
+
+// usesSubdivisions reports whether the policy has rules on ISO 3166-2 subdivision codes, which can only be
+// evaluated with a full location lookup.
+func (p Policy) usesSubdivisions() bool {
+	for _, code := range p.rules() {
+		if strings.Contains(code, "-") {
+			return true
+		}
+	}
+	return false
+}
+
+// usesASN reports whether the policy has rules on autonomous system numbers, which can only be evaluated with
+// a full location lookup.
+func (p Policy) usesASN() bool {
+	return len(p.AllowedASNs) > 0 || len(p.BlockedASNs) > 0
+}
+
+// PolicySet holds the named server-side policies that requests can reference instead of sending their own rules.
+type PolicySet struct {
+	policies map[string]Policy
+}
+
+// policyFile is the on-disk layout of a policy file.
+//
+// Example (YAML; the equivalent JSON document is accepted as well):
+//
+//	policies:
+//	  checkout-eu:
+//	    version: "2024-06-01"
+//	    allowed_countries: [DE, FR, NL]
+//	  signup:
+//	    blocked_countries: [KP, IR]
+//	    default_action: allow
+//	  sweepstakes-na:
+//	    allowed_countries: [US-CA, US-NY, CA-QC]
+//	  api:
+//	    allowed_countries: [US]
+//	    allowed_asns: [3320]          # a carrier whose users roam abroad
+//	    blocked_asns: [14061, 16509]  # hosting providers
+type policyFile struct {
+	Policies map[string]policyEntry `yaml:"policies" json:"policies"`
+}
+
+// policyEntry is a single policy as written in a policy file.
+type policyEntry struct {
+	Version          string   `yaml:"version" json:"version"`
+	AllowedCountries []string `yaml:"allowed_countries" json:"allowed_countries"`
+	BlockedCountries []string `yaml:"blocked_countries" json:"blocked_countries,omitempty"`
+	DefaultAction    string   `yaml:"default_action" json:"default_action,omitempty"`
+	AllowedASNs      []uint   `yaml:"allowed_asns" json:"allowed_asns,omitempty"`
+	BlockedASNs      []uint   `yaml:"blocked_asns" json:"blocked_asns,omitempty"`
+}
+
+// regionCodePattern matches an upper-case ISO 3166-1 alpha-2 country code or ISO 3166-2 subdivision code.
+var regionCodePattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3})?$`)
+
+// normalizeRegionCodes upper-cases the country and subdivision codes of inline rules and checks them against
+// regionCodePattern.
+func normalizeRegionCodes(codes []string) ([]string, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	normalized := make([]string, len(codes))
+	for i, code := range codes {
+		normalized[i] = strings.ToUpper(code)
+		if !regionCodePattern.MatchString(normalized[i]) {
+			return nil, fmt.Errorf("%q is not an ISO 3166-1 alpha-2 country code or ISO 3166-2 subdivision code", code)
+		}
+	}
+	return normalized, nil
+}
+
+// LoadPolicyFile reads and validates the named policies stored in a YAML or JSON file.
+//
+// A policy without an explicit version is given one derived from a hash of its rules, so that the version
+// echoed in responses changes whenever the rules do.
+//
+// Parameters:
+//   - path: Filesystem path to the policy file.
+//
+// Returns:
+//   - *PolicySet: The validated set of named policies.
+//   - error: An error naming the offending policy if the file cannot be read or a policy is invalid.
+func LoadPolicyFile(path string) (*PolicySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy file: %w", err)
+	}
+
+	// YAML is a superset of JSON, so a single decoder handles both formats.
+	var file policyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing policy file %s: %w", path, err)
+	}
+
+	set := &PolicySet{policies: make(map[string]Policy, len(file.Policies))}
+	for name, entry := range file.Policies {
+		policy, err := newNamedPolicy(name, entry)
+		if err != nil {
+			return nil, fmt.Errorf("policy file %s: %w", path, err)
+		}
+		set.policies[name] = policy
+	}
+	return set, nil
+}
+
+// Get returns the policy registered under name.
+//
+// Parameters:
+//   - name: The policy name referenced by a request (e.g., "checkout-eu").
+//
+// Returns:
+//   - Policy: The named policy, if found.
+//   - bool: Whether a policy with that name exists.
+func (s *PolicySet) Get(name string) (Policy, bool) {
+	if s == nil {
+		return Policy{}, false
+	}
+	policy, ok := s.policies[name]
+	return policy, ok
+}
+
+// SubdivisionPolicies returns the names of the policies that have rules on ISO 3166-2 subdivision codes.
+// Such rules only match when a City database is loaded.
+//
+// Returns:
+//   - []string: The sorted policy names; nil if no policy uses subdivision codes.
+func (s *PolicySet) SubdivisionPolicies() []string {
+	if s == nil {
+		return nil
+	}
+
+	var names []string
+	for name, policy := range s.policies {
+		if policy.usesSubdivisions() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ASNPolicies returns the names of the policies that have rules on autonomous system numbers.
+// Such rules only match when an ASN database is loaded.
+//
+// Returns:
+//   - []string: The sorted policy names; nil if no policy uses ASN rules.
+func (s *PolicySet) ASNPolicies() []string {
+	if s == nil {
+		return nil
+	}
+
+	var names []string
+	for name, policy := range s.policies {
+		if policy.usesASN() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newNamedPolicy validates a policy file entry and converts it into a Policy.
+func newNamedPolicy(name string, entry policyEntry) (Policy, error) {
+	if name == "" {
+		return Policy{}, fmt.Errorf("policy names must not be empty")
+	}
+	switch {
+	case len(entry.AllowedCountries) > 0 && len(entry.BlockedCountries) > 0:
+		return Policy{}, fmt.Errorf("policy %q: specify either allowed_countries or blocked_countries, not both", name)
+	case len(entry.AllowedCountries) == 0 && len(entry.BlockedCountries) == 0:
+		return Policy{}, fmt.Errorf("policy %q: allowed_countries or blocked_countries must list at least one country", name)
+	}
+	for _, country := range append(entry.AllowedCountries, entry.BlockedCountries...) {
+		if !regionCodePattern.MatchString(country) {
+			return Policy{}, fmt.Errorf("policy %q: %q is not an upper-case ISO 3166-1 alpha-2 country code or ISO 3166-2 subdivision code", name, country)
+		}
+	}
+	defaultAction, err := parseDefaultAction(entry.DefaultAction)
+	if err != nil {
+		return Policy{}, fmt.Errorf("policy %q: %w", name, err)
+	}
+	if err := validateASNs(entry.AllowedASNs, entry.BlockedASNs); err != nil {
+		return Policy{}, fmt.Errorf("policy %q: %w", name, err)
+	}
+
+	version := entry.Version
+	if version == "" {
+		version = contentVersion(entry)
+	}
+
+	return Policy{
+		Name:             name,
+		Version:          version,
+		AllowedCountries: entry.AllowedCountries,
+		BlockedCountries: entry.BlockedCountries,
+		DefaultAction:    defaultAction,
+		AllowedASNs:      entry.AllowedASNs,
+		BlockedASNs:      entry.BlockedASNs,
+	}, nil
+}
+
+// validateASNs rejects the reserved AS number 0 and numbers listed as both allowed and blocked.
+func validateASNs(allowed, blocked []uint) error {
+	for _, asn := range append(append([]uint(nil), allowed...), blocked...) {
+		if asn == 0 {
+			return fmt.Errorf("0 is not a valid autonomous system number")
+		}
+	}
+	for _, asn := range allowed {
+		if containsASN(blocked, asn) {
+			return fmt.Errorf("AS%d is listed in both allowed_asns and blocked_asns", asn)
+		}
+	}
+	return nil
+}
+
+// contentVersion derives a short, stable version string from the rules of a policy entry.
+func contentVersion(entry policyEntry) string {
+	entry.AllowedCountries = sortedCopy(entry.AllowedCountries)
+	entry.BlockedCountries = sortedCopy(entry.BlockedCountries)
+	entry.AllowedASNs = sortedASNs(entry.AllowedASNs)
+	entry.BlockedASNs = sortedASNs(entry.BlockedASNs)
+
+	canonical, _ := json.Marshal(entry)
+	sum := sha256.Sum256(canonical)
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+// sortedCopy returns a sorted copy of list, leaving list itself untouched.
+func sortedCopy(list []string) []string {
+	if list == nil {
+		return nil
+	}
+	sorted := append([]string(nil), list...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// sortedASNs returns a sorted copy of list, leaving list itself untouched.
+func sortedASNs(list []uint) []uint {
+	if list == nil {
+		return nil
+	}
+	sorted := append([]uint(nil), list...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
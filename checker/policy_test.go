@@ -0,0 +1,95 @@
+package checker_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePolicyFile stores contents in a temporary policy file and returns its path.
+func writePolicyFile(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	return path
+}
+
+// TestLoadPolicyFile_JSONAndDerivedVersion verifies that JSON policy files are accepted and that a policy without an
+// explicit version gets a content-derived one that ignores the order of countries.
+func TestLoadPolicyFile_JSONAndDerivedVersion(t *testing.T) {
+	first, err := checker.LoadPolicyFile(writePolicyFile(t, "a.json",
+		`{"policies": {"checkout-eu": {"allowed_countries": ["DE", "FR"]}}}`))
+	require.NoError(t, err)
+	second, err := checker.LoadPolicyFile(writePolicyFile(t, "b.json",
+		`{"policies": {"checkout-eu": {"allowed_countries": ["FR", "DE"]}}}`))
+	require.NoError(t, err)
+
+	a, ok := first.Get("checkout-eu")
+	require.True(t, ok)
+	b, _ := second.Get("checkout-eu")
+
+	assert.Equal(t, "checkout-eu", a.Name)
+	assert.NotEmpty(t, a.Version)
+	assert.Equal(t, a.Version, b.Version, "Expected the derived version to be independent of country order.")
+}
+
+// TestLoadPolicyFile_RejectsInvalidPolicies verifies that malformed policies are rejected at load time.
+func TestLoadPolicyFile_RejectsInvalidPolicies(t *testing.T) {
+	for name, contents := range map[string]string{
+		"lower-case country": "policies:\n  p:\n    allowed_countries: [de]\n",
+		"bad subdivision":    "policies:\n  p:\n    allowed_countries: [US-]\n",
+		"no countries":       "policies:\n  p:\n    version: v1\n",
+		"both lists":         "policies:\n  p:\n    allowed_countries: [DE]\n    blocked_countries: [KP]\n",
+		"bad default action": "policies:\n  p:\n    blocked_countries: [KP]\n    default_action: maybe\n",
+		"ASN zero":           "policies:\n  p:\n    allowed_countries: [DE]\n    blocked_asns: [0]\n",
+		"ASN in both lists":  "policies:\n  p:\n    allowed_countries: [DE]\n    allowed_asns: [3320]\n    blocked_asns: [3320]\n",
+		"malformed yaml":     "policies: [",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := checker.LoadPolicyFile(writePolicyFile(t, "policies.yaml", contents))
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestPolicySet_SubdivisionPolicies verifies that policies with ISO 3166-2 subdivision rules are reported, so that
+// operators can be warned when no City database is loaded.
+func TestPolicySet_SubdivisionPolicies(t *testing.T) {
+	set, err := checker.LoadPolicyFile(writePolicyFile(t, "policies.yaml", `
+policies:
+  licensing:
+    allowed_countries: [US-CA, CA-QC, DE]
+  eu:
+    allowed_countries: [DE, FR]
+  no-quebec:
+    blocked_countries: [CA-QC]
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"licensing", "no-quebec"}, set.SubdivisionPolicies())
+}
+
+// TestPolicySet_ASNPolicies verifies that policies with ASN rules are loaded and reported, so that operators can be
+// warned when no ASN database is loaded.
+func TestPolicySet_ASNPolicies(t *testing.T) {
+	set, err := checker.LoadPolicyFile(writePolicyFile(t, "policies.yaml", `
+policies:
+  api:
+    allowed_countries: [US]
+    allowed_asns: [3320]
+    blocked_asns: [16509, 14061]
+  eu:
+    allowed_countries: [DE, FR]
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"api"}, set.ASNPolicies())
+	api, ok := set.Get("api")
+	require.True(t, ok)
+	assert.Equal(t, []uint{3320}, api.AllowedASNs)
+	assert.Equal(t, []uint{16509, 14061}, api.BlockedASNs)
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// batcher collects concurrent CheckIP calls with the same rules and sends them in one batch request, once the
+// first of them has waited MaxDelay or the batch holds MaxSize IP addresses.
+type batcher struct {
+	opts BatchOptions
+	send func(ips []string, rules Rules) ([]Result, error)
+
+	mu      sync.Mutex
+	pending map[string]*batch // Batches being collected, keyed by Rules.key.
+}
+
+// batch is a batch request being collected.
+type batch struct {
+	rules   Rules
+	ips     []string
+	replies []chan Result // One per IP address, buffered so that sending never blocks.
+	timer   *time.Timer
+}
+
+// newBatcher constructs a batcher.
+//
+// Parameters:
+//   - opts: When batches are sent; MaxDelay must be positive.
+//   - send: Sends a batch request, with retries.
+//
+// Returns:
+//   - *batcher: The initialized batcher.
+func newBatcher(opts BatchOptions, send func(ips []string, rules Rules) ([]Result, error)) *batcher {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = 100
+	}
+	opts.MaxSize = min(opts.MaxSize, MaxBatchSize)
+	return &batcher{opts: opts, send: send, pending: make(map[string]*batch)}
+}
+
+// checkIP adds ip to the batch of its rules and waits for the result.
+//
+// Parameters:
+//   - ctx: Context of the call; the call stops waiting when it is done, but the batch is still sent.
+//   - ip: The IP address to check.
+//   - rules: The rules the IP address is checked against.
+//
+// Returns:
+//   - Decision: The decision; zero value on error.
+//   - error: A gRPC status error if the batch request failed, the IP address could not be checked, or ctx is done.
+func (b *batcher) checkIP(ctx context.Context, ip string, rules Rules) (Decision, error) {
+	reply := make(chan Result, 1)
+	key := rules.key()
+
+	b.mu.Lock()
+	pending, ok := b.pending[key]
+	if !ok {
+		pending = &batch{rules: rules}
+		b.pending[key] = pending
+		pending.timer = time.AfterFunc(b.opts.MaxDelay, func() { b.flush(key, pending) })
+	}
+	pending.ips = append(pending.ips, ip)
+	pending.replies = append(pending.replies, reply)
+	full := len(pending.ips) >= b.opts.MaxSize
+	if full {
+		delete(b.pending, key)
+		pending.timer.Stop()
+	}
+	b.mu.Unlock()
+
+	if full {
+		go b.run(pending)
+	}
+
+	select {
+	case result := <-reply:
+		return result.Decision, result.Err
+	case <-ctx.Done():
+		return Decision{}, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// flush sends the batch collected under key when its delay has passed, unless it was already sent because it
+// became full.
+func (b *batcher) flush(key string, pending *batch) {
+	b.mu.Lock()
+	if b.pending[key] != pending {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, key)
+	b.mu.Unlock()
+
+	b.run(pending)
+}
+
+// run sends a batch and hands every call its result.
+func (b *batcher) run(pending *batch) {
+	results, err := b.send(pending.ips, pending.rules)
+	for i, reply := range pending.replies {
+		if err != nil {
+			reply <- Result{Err: err}
+			continue
+		}
+		reply <- results[i]
+	}
+}
+
+// close sends every batch being collected without waiting for its delay, and returns once they are answered.
+func (b *batcher) close() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[string]*batch)
+	b.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, p := range pending {
+		p.timer.Stop()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.run(p)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// decisionCache is a bounded LRU cache of decisions with a TTL, keyed by IP address and rules. A nil
+// *decisionCache caches nothing.
+type decisionCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	order   *list.List // Elements hold *cacheEntry; the front is the most recently used.
+}
+
+// cacheKey identifies a cached decision.
+type cacheKey struct {
+	ip    string
+	rules string // Rules.key of the rules the IP address was checked against.
+}
+
+// cacheEntry is a cached decision.
+type cacheEntry struct {
+	key      cacheKey
+	decision Decision
+	expires  time.Time // Zero if the entry never expires.
+}
+
+// newDecisionCache constructs an empty decisionCache.
+//
+// Parameters:
+//   - opts: The size bound and TTL of the cache; Size must be positive.
+//
+// Returns:
+//   - *decisionCache: The initialized cache.
+func newDecisionCache(opts CacheOptions) *decisionCache {
+	return &decisionCache{
+		size:    opts.Size,
+		ttl:     opts.TTL,
+		entries: make(map[cacheKey]*list.Element, opts.Size),
+		order:   list.New(),
+	}
+}
+
+// get returns the cached decision for ip and rules, if any has not expired.
+//
+// Parameters:
+//   - ip: The IP address checked.
+//   - rules: The rules it was checked against.
+//
+// Returns:
+//   - Decision: The cached decision.
+//   - bool: Whether the cache held one.
+func (c *decisionCache) get(ip string, rules Rules) (Decision, bool) {
+	if c == nil {
+		return Decision{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[cacheKey{ip: ip, rules: rules.key()}]
+	if !ok {
+		return Decision{}, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, entry.key)
+		return Decision{}, false
+	}
+	c.order.MoveToFront(element)
+	return entry.decision, true
+}
+
+// put caches the decision for ip and rules, evicting the least recently used decision if the cache is full.
+//
+// Parameters:
+//   - ip: The IP address checked.
+//   - rules: The rules it was checked against.
+//   - decision: The decision to cache.
+func (c *decisionCache) put(ip string, rules Rules, decision Decision) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: cacheKey{ip: ip, rules: rules.key()}, decision: decision}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	if element, ok := c.entries[entry.key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
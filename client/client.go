@@ -0,0 +1,415 @@
+// Package client is the Go SDK of the IP checker service.
+//
+// It wraps the gRPC and HTTP APIs behind one interface, Client, and adds what every caller would otherwise write
+// itself: retries with backoff on transient failures, per-call deadlines, an optional local cache of decisions and
+// the batching of concurrent checks into batch requests. Fake implements Client in memory for the tests of
+// consumers.
+//
+// Errors are gRPC status errors on both transports, so that callers classify them the same way with status.Code:
+// InvalidArgument for malformed IP addresses and invalid rules or unknown policies, NotFound for IP addresses
+// without a known country when the default action is "error", Unauthenticated and PermissionDenied for rejected
+// credentials, ResourceExhausted when rate limited, and Unavailable or Internal when the service fails.
+//
+// Example:
+//
+//	c, err := client.DialGRPC("ipchecker:50051", client.Options{APIKey: key, Timeout: time.Second},
+//		grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//	decision, err := c.CheckIP(ctx, "81.2.69.142", client.Rules{Policy: "checkout-eu"})
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MaxBatchSize is the maximum number of IP addresses the service accepts in one batch request; CheckIPBatch
+// splits larger batches into several requests.
+const MaxBatchSize = 1000
+
+// Rules are the rules an IP address is checked against: either the name of a server-side policy, or inline lists
+// of allowed or blocked countries with an optional default action.
+type Rules struct {
+	// AllowedCountries lists the ISO 3166-1 alpha-2 country codes or ISO 3166-2 subdivision codes that are allowed.
+	AllowedCountries []string
+
+	// BlockedCountries lists the country or subdivision codes that are denied; every other country is allowed.
+	BlockedCountries []string
+
+	// DefaultAction decides IP addresses without a known country: "allow", "deny" (default) or "error".
+	DefaultAction string
+
+	// Policy is the name of a server-side policy; it must not be combined with inline rules.
+	Policy string
+}
+
+// key returns a string identifying the rules, for caching and batching.
+func (r Rules) key() string {
+	return strings.Join([]string{
+		r.Policy,
+		r.DefaultAction,
+		strings.Join(r.AllowedCountries, ","),
+		strings.Join(r.BlockedCountries, ","),
+	}, "\x00")
+}
+
+// Decision is the outcome of checking one IP address.
+type Decision struct {
+	// IPAddress is the IP address the decision is about, as sent in the request.
+	IPAddress string
+
+	// Allowed indicates whether the IP address satisfies the rules.
+	Allowed bool
+
+	// Country is the ISO 3166-1 alpha-2 country code of the IP address; empty if none is known.
+	Country string
+
+	// Reason tells what decided: "rule", "asn", "default" or "override".
+	Reason string
+
+	// ASN is the autonomous system number of the IP address; only resolved for policies with ASN rules.
+	ASN uint
+
+	// Policy and PolicyVersion identify the server-side policy that decided; empty for inline rules.
+	Policy        string
+	PolicyVersion string
+
+	// Override is the CIDR override rule that decided instead of the country lookup; nil if none did.
+	Override *Override
+}
+
+// Override identifies the CIDR override rule that decided a request.
+type Override struct {
+	// Action is the decision forced by the rule: "allow" or "deny".
+	Action string
+
+	// Name is the name of the override rule.
+	Name string
+
+	// CIDR is the network of the rule that contained the IP address.
+	CIDR string
+}
+
+// Result is the outcome of one IP address of a batch.
+type Result struct {
+	Decision
+
+	// Err is why this IP address could not be checked; nil on success. It is a status error with the code a single
+	// CheckIP call would have failed with, e.g. codes.InvalidArgument for a malformed IP address, or codes.Unknown
+	// if the service does not report item error codes.
+	Err error
+}
+
+// Client checks IP addresses against the IP checker service. Implementations are safe for concurrent use.
+type Client interface {
+	// CheckIP checks one IP address against rules.
+	CheckIP(ctx context.Context, ip string, rules Rules) (Decision, error)
+
+	// CheckIPBatch checks several IP addresses against the same rules. It returns one result per IP address, in
+	// order; an IP address that cannot be checked is reported in the Err of its result without failing the rest.
+	CheckIPBatch(ctx context.Context, ips []string, rules Rules) ([]Result, error)
+
+	// Close sends the checks waiting to be batched and releases the resources of the client.
+	Close() error
+}
+
+// Options configures a Client; the zero value retries transient failures with the defaults and disables
+// caching and batching.
+type Options struct {
+	// APIKey is sent in the X-API-Key header or x-api-key metadata of every request; empty sends none.
+	APIKey string
+
+	// BearerToken is sent as "Authorization: Bearer <token>" on every request; empty sends none.
+	BearerToken string
+
+	// Timeout is the deadline of each call, retries included; 0 leaves it to the context of the call.
+	Timeout time.Duration
+
+	// Retry configures the retries of failed requests.
+	Retry RetryOptions
+
+	// Cache configures the local cache of decisions.
+	Cache CacheOptions
+
+	// Batch configures the batching of concurrent CheckIP calls.
+	Batch BatchOptions
+}
+
+// RetryOptions configures the retries of failed requests. Checks are idempotent, so every request can be retried.
+type RetryOptions struct {
+	// MaxAttempts is the number of attempts of a request, the first included; 0 defaults to 3, 1 disables retries.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry, doubled for every further retry; 0 defaults to 100ms.
+	// Waits are jittered between half and all of the backoff.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between two attempts; 0 defaults to 2s.
+	MaxBackoff time.Duration
+
+	// PerAttemptTimeout is the deadline of each attempt; an attempt exceeding it is retried as long as the call
+	// itself has time left. 0 disables it.
+	PerAttemptTimeout time.Duration
+
+	// Codes are the status codes of the failures that are retried; nil defaults to DefaultRetryCodes.
+	Codes []codes.Code
+}
+
+// DefaultRetryCodes are the status codes of the transient failures retried by default: the service or a proxy in
+// front of it is unavailable, or the request was aborted.
+var DefaultRetryCodes = []codes.Code{codes.Unavailable, codes.Aborted}
+
+// CacheOptions configures the local cache of decisions. Only successful decisions are cached, keyed by IP address
+// and rules.
+type CacheOptions struct {
+	// Size is the maximum number of cached decisions, the least recently used being evicted beyond it; 0 disables
+	// the cache.
+	Size int
+
+	// TTL is how long a decision is served from the cache; 0 keeps decisions until they are evicted. Server-side
+	// policies and databases change, so a TTL of minutes is recommended.
+	TTL time.Duration
+}
+
+// BatchOptions configures the batching of concurrent CheckIP calls with the same rules into batch requests.
+type BatchOptions struct {
+	// MaxDelay is how long a CheckIP call waits for others to share its batch request; 0 disables batching.
+	MaxDelay time.Duration
+
+	// MaxSize sends a batch as soon as it holds this many IP addresses; 0 defaults to 100, and it is capped at
+	// MaxBatchSize.
+	MaxSize int
+}
+
+// transport sends requests to the service. Implementations make exactly one attempt per call and return gRPC
+// status errors.
+type transport interface {
+	checkIP(ctx context.Context, ip string, rules Rules) (Decision, error)
+	checkIPBatch(ctx context.Context, ips []string, rules Rules) ([]Result, error)
+	close() error
+}
+
+// client implements Client over a transport, adding deadlines, retries, caching and batching.
+type client struct {
+	transport transport
+	opts      Options
+	cache     *decisionCache // nil if caching is disabled.
+	batcher   *batcher       // nil if batching is disabled.
+}
+
+// newClient constructs a client over t, filling in the defaults of opts.
+//
+// Parameters:
+//   - t: The transport requests are sent with.
+//   - opts: The options of the client.
+//
+// Returns:
+//   - *client: The initialized client.
+func newClient(t transport, opts Options) *client {
+	if opts.Retry.MaxAttempts <= 0 {
+		opts.Retry.MaxAttempts = 3
+	}
+	if opts.Retry.InitialBackoff <= 0 {
+		opts.Retry.InitialBackoff = 100 * time.Millisecond
+	}
+	if opts.Retry.MaxBackoff <= 0 {
+		opts.Retry.MaxBackoff = 2 * time.Second
+	}
+	if opts.Retry.Codes == nil {
+		opts.Retry.Codes = DefaultRetryCodes
+	}
+
+	c := &client{transport: t, opts: opts}
+	if opts.Cache.Size > 0 {
+		c.cache = newDecisionCache(opts.Cache)
+	}
+	if opts.Batch.MaxDelay > 0 {
+		c.batcher = newBatcher(opts.Batch, c.sendBatch)
+	}
+	return c
+}
+
+// CheckIP checks one IP address against rules, from the cache if possible, otherwise batched with concurrent
+// calls if batching is enabled.
+//
+// Parameters:
+//   - ctx: Context of the call; its deadline and cancellation apply to every attempt.
+//   - ip: The IP address to check.
+//   - rules: The rules the IP address is checked against.
+//
+// Returns:
+//   - Decision: The decision; zero value on error.
+//   - error: A gRPC status error if the IP address could not be checked.
+func (c *client) CheckIP(ctx context.Context, ip string, rules Rules) (Decision, error) {
+	if decision, ok := c.cache.get(ip, rules); ok {
+		return decision, nil
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	var decision Decision
+	var err error
+	if c.batcher != nil {
+		decision, err = c.batcher.checkIP(ctx, ip, rules)
+	} else {
+		err = c.retry(ctx, func(ctx context.Context) error {
+			decision, err = c.transport.checkIP(ctx, ip, rules)
+			return err
+		})
+	}
+	if err != nil {
+		return Decision{}, err
+	}
+	c.cache.put(ip, rules, decision)
+	return decision, nil
+}
+
+// CheckIPBatch checks several IP addresses against the same rules. Cached decisions are not requested again, and
+// the others are sent in batch requests of at most MaxBatchSize IP addresses.
+//
+// Parameters:
+//   - ctx: Context of the call; its deadline and cancellation apply to every request.
+//   - ips: The IP addresses to check.
+//   - rules: The rules every IP address is checked against.
+//
+// Returns:
+//   - []Result: One result per IP address, in order.
+//   - error: A gRPC status error if a batch request failed as a whole.
+func (c *client) CheckIPBatch(ctx context.Context, ips []string, rules Rules) ([]Result, error) {
+	results := make([]Result, len(ips))
+	var missing []int
+	for i, ip := range ips {
+		if decision, ok := c.cache.get(ip, rules); ok {
+			results[i] = Result{Decision: decision}
+		} else {
+			missing = append(missing, i)
+		}
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	for start := 0; start < len(missing); start += MaxBatchSize {
+		chunk := missing[start:min(start+MaxBatchSize, len(missing))]
+		chunkIPs := make([]string, len(chunk))
+		for j, i := range chunk {
+			chunkIPs[j] = ips[i]
+		}
+
+		var chunkResults []Result
+		err := c.retry(ctx, func(ctx context.Context) error {
+			var err error
+			chunkResults, err = c.transport.checkIPBatch(ctx, chunkIPs, rules)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range chunk {
+			results[i] = chunkResults[j]
+			if chunkResults[j].Err == nil {
+				c.cache.put(ips[i], rules, chunkResults[j].Decision)
+			}
+		}
+	}
+	return results, nil
+}
+
+// Close sends the checks waiting to be batched, then closes the transport.
+//
+// Returns:
+//   - error: An error if the transport could not be closed.
+func (c *client) Close() error {
+	if c.batcher != nil {
+		c.batcher.close()
+	}
+	return c.transport.close()
+}
+
+// sendBatch sends the batch request of CheckIP calls batched together. The calls may have different contexts, so
+// the request is bounded by the timeout of the client only; each call stops waiting for it when its own context
+// is done.
+//
+// Parameters:
+//   - ips: The IP addresses of the batched calls.
+//   - rules: The rules shared by the batched calls.
+//
+// Returns:
+//   - []Result: One result per IP address, in order.
+//   - error: A gRPC status error if the request failed as a whole.
+func (c *client) sendBatch(ips []string, rules Rules) ([]Result, error) {
+	ctx, cancel := c.withTimeout(context.Background())
+	defer cancel()
+
+	var results []Result
+	err := c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		results, err = c.transport.checkIPBatch(ctx, ips, rules)
+		return err
+	})
+	return results, err
+}
+
+// withTimeout returns ctx bounded by the timeout of the client, if any.
+func (c *client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opts.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.opts.Timeout)
+}
+
+// retry calls attempt until it succeeds, fails with a status code that is not retried, or the attempts or the
+// time of ctx run out, waiting with exponential backoff between attempts.
+//
+// Parameters:
+//   - ctx: Context of the call.
+//   - attempt: Makes one attempt with the given context, bounded by the per-attempt timeout if any.
+//
+// Returns:
+//   - error: nil if an attempt succeeded, otherwise the error of the last attempt.
+func (c *client) retry(ctx context.Context, attempt func(ctx context.Context) error) error {
+	backoff := c.opts.Retry.InitialBackoff
+	for n := 1; ; n++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if c.opts.Retry.PerAttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, c.opts.Retry.PerAttemptTimeout)
+		}
+		err := attempt(attemptCtx)
+		cancel()
+
+		if err == nil || n >= c.opts.Retry.MaxAttempts || ctx.Err() != nil || !c.retryable(err) {
+			return err
+		}
+
+		// Full jitter between half and all of the backoff spreads the retries of concurrent callers.
+		wait := backoff/2 + rand.N(backoff/2+1)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, c.opts.Retry.MaxBackoff)
+	}
+}
+
+// retryable reports whether a failed attempt is retried: its status code is one of the retried codes, or it
+// exceeded the per-attempt timeout.
+func (c *client) retryable(err error) bool {
+	code := status.Code(err)
+	if code == codes.DeadlineExceeded && c.opts.Retry.PerAttemptTimeout > 0 {
+		return true
+	}
+	return slices.Contains(c.opts.Retry.Codes, code)
+}
@@ -0,0 +1,356 @@
+package client_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/checker/checkertest"
+	"github.com/justfairdev/ipchecker/client"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/internal/grpcserver"
+	"github.com/justfairdev/ipchecker/internal/handler"
+	"github.com/justfairdev/ipchecker/internal/server"
+	pb "github.com/justfairdev/ipchecker/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newGRPCConn serves the IPChecker service of c in memory, with the given interceptors, and returns a connection
+// to it.
+func newGRPCConn(t *testing.T, c *checker.Checker, interceptors ...grpc.UnaryServerInterceptor) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	pb.RegisterIPCheckerServer(grpcServer, grpcserver.NewIPCheckerServer(c))
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// newHTTPServer serves the versioned HTTP API of c, behind the given middleware.
+func newHTTPServer(t *testing.T, c *checker.Checker, middleware ...gin.HandlerFunc) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware...)
+	server.RegisterRoutes(router, handler.NewIPChecker(c), nil, nil)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// countCalls returns an interceptor counting the calls of each method, and the counts.
+func countCalls() (grpc.UnaryServerInterceptor, func(method string) int) {
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		mu.Lock()
+		counts[info.FullMethod]++
+		mu.Unlock()
+		return handler(ctx, req)
+	}
+	return interceptor, func(method string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return counts[method]
+	}
+}
+
+// TestClient_Conformance runs the shared transport conformance suite against the client over both transports,
+// ensuring the SDK reports the same decisions and error classification as the raw APIs.
+func TestClient_Conformance(t *testing.T) {
+	transports := map[string]func(t *testing.T, c *checker.Checker) client.Client{
+		"grpc": func(t *testing.T, c *checker.Checker) client.Client {
+			return client.NewGRPC(newGRPCConn(t, c), client.Options{})
+		},
+		"http": func(t *testing.T, c *checker.Checker) client.Client {
+			sdk, err := client.NewHTTP(newHTTPServer(t, c).URL, nil, client.Options{})
+			require.NoError(t, err)
+			return sdk
+		},
+	}
+	for name, newSDK := range transports {
+		t.Run(name, func(t *testing.T) {
+			checkertest.Run(t, func(t *testing.T, c *checker.Checker, r checkertest.Request) (checker.Decision, error) {
+				sdk := newSDK(t, c)
+				defer sdk.Close()
+
+				decision, err := sdk.CheckIP(context.Background(), r.IP, client.Rules{
+					AllowedCountries: r.AllowedCountries,
+					BlockedCountries: r.BlockedCountries,
+					DefaultAction:    r.DefaultAction,
+					Policy:           r.Policy,
+				})
+				if err != nil {
+					st := status.Convert(err)
+					return checker.Decision{}, &checker.Error{Kind: checker.KindFromGRPCCode(st.Code()), Message: st.Message()}
+				}
+				assert.Equal(t, r.IP, decision.IPAddress)
+				result := checker.Decision{
+					Allowed:       decision.Allowed,
+					Country:       decision.Country,
+					Reason:        checker.Reason(decision.Reason),
+					ASN:           decision.ASN,
+					Policy:        decision.Policy,
+					PolicyVersion: decision.PolicyVersion,
+				}
+				if o := decision.Override; o != nil {
+					result.Override = &checker.OverrideMatch{Action: checker.OverrideAction(o.Action), Name: o.Name, CIDR: o.CIDR}
+				}
+				return result, nil
+			})
+		})
+	}
+}
+
+// TestClient_CheckIPBatch verifies that batch checks return one result per IP address over both transports, with
+// item errors on their own result.
+func TestClient_CheckIPBatch(t *testing.T) {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	httpSDK, err := client.NewHTTP(newHTTPServer(t, c).URL+"/", nil, client.Options{})
+	require.NoError(t, err)
+
+	for name, sdk := range map[string]client.Client{
+		"grpc": client.NewGRPC(newGRPCConn(t, c), client.Options{}),
+		"http": httpSDK,
+	} {
+		t.Run(name, func(t *testing.T) {
+			results, err := sdk.CheckIPBatch(context.Background(), []string{"128.101.101.101", "not-an-ip"}, client.Rules{AllowedCountries: []string{"US"}})
+			require.NoError(t, err)
+			require.Len(t, results, 2)
+			assert.NoError(t, results[0].Err)
+			assert.Equal(t, client.Decision{IPAddress: "128.101.101.101", Allowed: true, Country: "US", Reason: "rule"}, results[0].Decision)
+			assert.Equal(t, "not-an-ip", results[1].IPAddress)
+			assert.Equal(t, codes.InvalidArgument, status.Code(results[1].Err))
+			assert.Equal(t, "invalid IP address", status.Convert(results[1].Err).Message())
+
+			_, err = sdk.CheckIPBatch(context.Background(), []string{"128.101.101.101"}, client.Rules{})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+// TestClient_Retry verifies that transient failures are retried with backoff until an attempt succeeds or the
+// attempts run out, and that other failures are not retried.
+func TestClient_Retry(t *testing.T) {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	var failures atomic.Int32
+	var calls atomic.Int32
+	flaky := func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		calls.Add(1)
+		if failures.Add(-1) >= 0 {
+			return nil, status.Error(codes.Unavailable, "restarting")
+		}
+		return handler(ctx, req)
+	}
+	sdk := client.NewGRPC(newGRPCConn(t, c, flaky), client.Options{Retry: client.RetryOptions{InitialBackoff: time.Millisecond}})
+	rules := client.Rules{AllowedCountries: []string{"US"}}
+
+	failures.Store(2)
+	decision, err := sdk.CheckIP(context.Background(), "128.101.101.101", rules)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.EqualValues(t, 3, calls.Load())
+
+	calls.Store(0)
+	failures.Store(3)
+	_, err = sdk.CheckIP(context.Background(), "128.101.101.101", rules)
+	assert.Equal(t, codes.Unavailable, status.Code(err), "Expected the error of the last attempt.")
+	assert.EqualValues(t, 3, calls.Load())
+
+	calls.Store(0)
+	failures.Store(0)
+	_, err = sdk.CheckIP(context.Background(), "not-an-ip", rules)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.EqualValues(t, 1, calls.Load(), "Expected invalid input not to be retried.")
+}
+
+// TestClient_HTTPErrors verifies that HTTP error responses are reported with the matching gRPC status codes, that
+// unavailable responses are retried, and that the credentials are sent.
+func TestClient_HTTPErrors(t *testing.T) {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	var unavailable atomic.Int32
+	srv := newHTTPServer(t, c, func(ctx *gin.Context) {
+		switch {
+		case ctx.GetHeader("X-API-Key") != "secret" || ctx.GetHeader("Authorization") != "Bearer token":
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing API key"})
+		case unavailable.Add(-1) >= 0:
+			ctx.AbortWithStatus(http.StatusServiceUnavailable)
+		case ctx.Query("limited") != "":
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+		}
+	})
+	opts := client.Options{APIKey: "secret", BearerToken: "token", Retry: client.RetryOptions{InitialBackoff: time.Millisecond}}
+	rules := client.Rules{AllowedCountries: []string{"US"}}
+
+	sdk, err := client.NewHTTP(srv.URL, srv.Client(), opts)
+	require.NoError(t, err)
+	unavailable.Store(2)
+	decision, err := sdk.CheckIP(context.Background(), "128.101.101.101", rules)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	unavailable.Store(3)
+	_, err = sdk.CheckIP(context.Background(), "128.101.101.101", rules)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	unavailable.Store(0)
+
+	sdk, err = client.NewHTTP(srv.URL, nil, client.Options{})
+	require.NoError(t, err)
+	_, err = sdk.CheckIP(context.Background(), "128.101.101.101", rules)
+	assert.Equal(t, status.Error(codes.Unauthenticated, "missing API key"), err)
+
+	_, err = client.NewHTTP("ipchecker:8080", nil, client.Options{})
+	assert.Error(t, err, "Expected a URL without a scheme to be rejected.")
+}
+
+// TestClient_Timeout verifies that the timeout bounds each call, retries included, and that an attempt exceeding
+// the per-attempt timeout is retried.
+func TestClient_Timeout(t *testing.T) {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	var stalls atomic.Int32
+	stall := func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if stalls.Add(-1) >= 0 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return handler(ctx, req)
+	}
+	conn := newGRPCConn(t, c, stall)
+	rules := client.Rules{AllowedCountries: []string{"US"}}
+
+	stalls.Store(100)
+	start := time.Now()
+	_, err := client.NewGRPC(conn, client.Options{Timeout: 50 * time.Millisecond}).CheckIP(context.Background(), "128.101.101.101", rules)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Less(t, time.Since(start), time.Second)
+
+	stalls.Store(1)
+	sdk := client.NewGRPC(conn, client.Options{
+		Timeout: 5 * time.Second,
+		Retry:   client.RetryOptions{PerAttemptTimeout: 50 * time.Millisecond, InitialBackoff: time.Millisecond},
+	})
+	decision, err := sdk.CheckIP(context.Background(), "128.101.101.101", rules)
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+}
+
+// TestClient_Cache verifies that decisions are served from the cache per IP address and rules, in single and
+// batch checks, until they expire.
+func TestClient_Cache(t *testing.T) {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	count, calls := countCalls()
+	sdk := client.NewGRPC(newGRPCConn(t, c, count), client.Options{Cache: client.CacheOptions{Size: 10, TTL: 100 * time.Millisecond}})
+	ctx := context.Background()
+	us := client.Rules{AllowedCountries: []string{"US"}}
+
+	for i := 0; i < 3; i++ {
+		decision, err := sdk.CheckIP(ctx, "128.101.101.101", us)
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+	}
+	assert.Equal(t, 1, calls(pb.IPChecker_CheckIP_FullMethodName))
+
+	decision, err := sdk.CheckIP(ctx, "128.101.101.101", client.Rules{BlockedCountries: []string{"US"}})
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed, "Expected decisions to be cached per rules.")
+	assert.Equal(t, 2, calls(pb.IPChecker_CheckIP_FullMethodName))
+
+	// Only the IP address missing from the cache is requested; results of batches are cached too.
+	results, err := sdk.CheckIPBatch(ctx, []string{"128.101.101.101", "81.2.69.142"}, us)
+	require.NoError(t, err)
+	assert.Equal(t, "81.2.69.142", results[1].IPAddress)
+	_, err = sdk.CheckIP(ctx, "81.2.69.142", us)
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls(pb.IPChecker_CheckIPBatch_FullMethodName))
+	assert.Equal(t, 2, calls(pb.IPChecker_CheckIP_FullMethodName))
+
+	// Errors are not cached, and decisions expire.
+	for i := 0; i < 2; i++ {
+		_, err = sdk.CheckIP(ctx, "not-an-ip", us)
+		assert.Error(t, err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	_, err = sdk.CheckIP(ctx, "128.101.101.101", us)
+	require.NoError(t, err)
+	assert.Equal(t, 5, calls(pb.IPChecker_CheckIP_FullMethodName))
+}
+
+// TestClient_Batching verifies that concurrent CheckIP calls with the same rules are sent in one batch request,
+// each call receiving its own result.
+func TestClient_Batching(t *testing.T) {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	count, calls := countCalls()
+	sdk := client.NewGRPC(newGRPCConn(t, c, count), client.Options{Batch: client.BatchOptions{MaxDelay: 50 * time.Millisecond, MaxSize: 5}})
+
+	ips := []string{"128.101.101.1", "128.101.101.2", "128.101.101.3", "128.101.101.4", "128.101.101.5", "not-an-ip"}
+	decisions := make([]client.Decision, len(ips))
+	errs := make([]error, len(ips))
+	var wg sync.WaitGroup
+	for i, ip := range ips {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decisions[i], errs[i] = sdk.CheckIP(context.Background(), ip, client.Rules{AllowedCountries: []string{"US"}})
+		}()
+	}
+	wg.Wait()
+
+	for i, ip := range ips[:5] {
+		require.NoError(t, errs[i])
+		assert.Equal(t, ip, decisions[i].IPAddress)
+		assert.True(t, decisions[i].Allowed)
+	}
+	assert.Equal(t, codes.InvalidArgument, status.Code(errs[5]))
+	assert.Equal(t, "invalid IP address", status.Convert(errs[5]).Message())
+
+	// Five calls filled a batch, the sixth was sent on its own after the delay.
+	assert.Equal(t, 2, calls(pb.IPChecker_CheckIPBatch_FullMethodName))
+	assert.Equal(t, 0, calls(pb.IPChecker_CheckIP_FullMethodName))
+
+	// Closing sends the waiting calls at once.
+	sdk = client.NewGRPC(newGRPCConn(t, c), client.Options{Batch: client.BatchOptions{MaxDelay: time.Hour}})
+	done := make(chan error, 1)
+	go func() {
+		_, err := sdk.CheckIP(context.Background(), "128.101.101.101", client.Rules{AllowedCountries: []string{"US"}})
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, sdk.Close())
+	assert.NoError(t, <-done)
+}
+
+// TestClient_Metadata verifies that the gRPC transport sends the credentials along with the caller's metadata.
+func TestClient_Metadata(t *testing.T) {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	var seen metadata.MD
+	capture := func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		seen, _ = metadata.FromIncomingContext(ctx)
+		return handler(ctx, req)
+	}
+	sdk := client.NewGRPC(newGRPCConn(t, c, capture), client.Options{APIKey: "secret"})
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "42")
+	_, err := sdk.CheckIP(ctx, "128.101.101.101", client.Rules{AllowedCountries: []string{"US"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"secret"}, seen.Get("x-api-key"))
+	assert.Equal(t, []string{"42"}, seen.Get("x-request-id"))
+}
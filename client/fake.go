@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Fake is an in-memory Client for the tests of code that depends on Client. It decides like the service does for
+// country rules, from a fixed table of countries, and reports errors with the same status codes and messages;
+// subdivision, ASN and override rules are not supported.
+//
+// Fields must not be modified while the Fake is in use.
+type Fake struct {
+	// Countries maps IP addresses to their ISO 3166-1 alpha-2 country code; the IP addresses not listed have no
+	// known country, so the default action of the rules decides them.
+	Countries map[string]string
+
+	// Policies maps the names of server-side policies to their rules; checks naming any other policy fail with
+	// InvalidArgument.
+	Policies map[string]Rules
+
+	// Err, if set, is returned by every call instead of a decision, to simulate an unavailable service.
+	Err error
+
+	mu      sync.Mutex
+	checked []string
+}
+
+// NewFake constructs a Fake locating IP addresses by the given table.
+//
+// Parameters:
+//   - countries: The country code of each known IP address.
+//
+// Returns:
+//   - *Fake: The fake client; set Policies to support named policies.
+func NewFake(countries map[string]string) *Fake {
+	return &Fake{Countries: countries}
+}
+
+// CheckIP checks one IP address against rules.
+//
+// Parameters:
+//   - ctx: Context of the call; a done context fails the call.
+//   - ip: The IP address to check.
+//   - rules: The rules the IP address is checked against.
+//
+// Returns:
+//   - Decision: The decision; zero value on error.
+//   - error: Err if set, otherwise a gRPC status error like the service's if the rules or IP address are invalid.
+func (f *Fake) CheckIP(ctx context.Context, ip string, rules Rules) (Decision, error) {
+	if err := f.fail(ctx); err != nil {
+		return Decision{}, err
+	}
+	policy, err := f.resolve(rules)
+	if err != nil {
+		return Decision{}, err
+	}
+	f.record(ip)
+	return f.decide(ip, policy)
+}
+
+// CheckIPBatch checks several IP addresses against the same rules, reporting invalid IP addresses in their
+// results with codes.InvalidArgument, as the service's batch APIs do.
+//
+// Parameters:
+//   - ctx: Context of the call; a done context fails the call.
+//   - ips: The IP addresses to check.
+//   - rules: The rules every IP address is checked against.
+//
+// Returns:
+//   - []Result: One result per IP address, in order.
+//   - error: Err if set, otherwise a gRPC status error if the rules are invalid.
+func (f *Fake) CheckIPBatch(ctx context.Context, ips []string, rules Rules) ([]Result, error) {
+	if err := f.fail(ctx); err != nil {
+		return nil, err
+	}
+	policy, err := f.resolve(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(ips))
+	for i, ip := range ips {
+		f.record(ip)
+		decision, err := f.decide(ip, policy)
+		if err != nil {
+			decision.IPAddress = ip
+		}
+		results[i] = Result{Decision: decision, Err: err}
+	}
+	return results, nil
+}
+
+// Close does nothing.
+//
+// Returns:
+//   - error: Always nil.
+func (f *Fake) Close() error {
+	return nil
+}
+
+// Checked returns the IP addresses checked so far, in order, for assertions on what the code under test checked.
+//
+// Returns:
+//   - []string: A copy of the checked IP addresses.
+func (f *Fake) Checked() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.checked)
+}
+
+// fail returns the error a call fails with before any check: Err, or the error of a done context.
+func (f *Fake) fail(ctx context.Context) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return nil
+}
+
+// record appends ip to the checked IP addresses.
+func (f *Fake) record(ip string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checked = append(f.checked, ip)
+}
+
+// resolve validates rules like the service does and returns the rules IP addresses are decided by, with the
+// name of the policy in their Policy field if one is named.
+func (f *Fake) resolve(rules Rules) (Rules, error) {
+	hasInline := len(rules.AllowedCountries) > 0 || len(rules.BlockedCountries) > 0
+	switch {
+	case rules.Policy != "" && (hasInline || rules.DefaultAction != ""):
+		return Rules{}, status.Error(codes.InvalidArgument, "specify either policy or inline country rules, not both")
+	case rules.Policy != "":
+		policy, ok := f.Policies[rules.Policy]
+		if !ok {
+			return Rules{}, status.Errorf(codes.InvalidArgument, "unknown policy %q", rules.Policy)
+		}
+		policy.Policy = rules.Policy
+		return policy, nil
+	case !hasInline:
+		return Rules{}, status.Error(codes.InvalidArgument, "either policy, allowed_countries or blocked_countries is required")
+	case len(rules.AllowedCountries) > 0 && len(rules.BlockedCountries) > 0:
+		return Rules{}, status.Error(codes.InvalidArgument, "specify either allowed_countries or blocked_countries, not both")
+	}
+	switch rules.DefaultAction {
+	case "", "allow", "deny", "error":
+	default:
+		return Rules{}, status.Errorf(codes.InvalidArgument, "invalid default_action %q: must be allow, deny or error", rules.DefaultAction)
+	}
+
+	// Country codes are matched upper-cased, as the service does.
+	countries := make([]string, 0, len(rules.AllowedCountries)+len(rules.BlockedCountries))
+	for _, code := range append(slices.Clone(rules.AllowedCountries), rules.BlockedCountries...) {
+		if !regionCodePattern.MatchString(strings.ToUpper(code)) {
+			return Rules{}, status.Errorf(codes.InvalidArgument, "%q is not an ISO 3166-1 alpha-2 country code or ISO 3166-2 subdivision code", code)
+		}
+		countries = append(countries, strings.ToUpper(code))
+	}
+	if len(rules.BlockedCountries) > 0 {
+		rules.BlockedCountries = countries
+	} else {
+		rules.AllowedCountries = countries
+	}
+	return rules, nil
+}
+
+// regionCodePattern matches an upper-case ISO 3166-1 alpha-2 country code or ISO 3166-2 subdivision code.
+var regionCodePattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3})?$`)
+
+// decide decides one IP address by its country in the table.
+func (f *Fake) decide(ip string, policy Rules) (Decision, error) {
+	if _, err := netip.ParseAddr(ip); err != nil {
+		return Decision{}, status.Error(codes.InvalidArgument, "invalid IP address")
+	}
+
+	decision := Decision{IPAddress: ip, Country: f.Countries[ip], Reason: "rule", Policy: policy.Policy}
+	if decision.Country == "" {
+		decision.Reason = "default"
+		switch policy.DefaultAction {
+		case "allow":
+			decision.Allowed = true
+		case "error":
+			return Decision{}, status.Error(codes.NotFound, "no geolocation data for IP address")
+		}
+		return decision, nil
+	}
+	if len(policy.BlockedCountries) > 0 {
+		decision.Allowed = !slices.Contains(policy.BlockedCountries, decision.Country)
+	} else {
+		decision.Allowed = slices.Contains(policy.AllowedCountries, decision.Country)
+	}
+	return decision, nil
+}
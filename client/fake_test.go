@@ -0,0 +1,56 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestFake verifies that the fake client decides from its table of countries and policies, reports errors like
+// the service does, and records the checked IP addresses.
+func TestFake(t *testing.T) {
+	fake := client.NewFake(map[string]string{"128.101.101.101": "US", "81.2.69.142": "GB"})
+	fake.Policies = map[string]client.Rules{"eu-only": {AllowedCountries: []string{"GB", "FR"}}}
+	ctx := context.Background()
+
+	decision, err := fake.CheckIP(ctx, "128.101.101.101", client.Rules{BlockedCountries: []string{"US"}})
+	require.NoError(t, err)
+	assert.Equal(t, client.Decision{IPAddress: "128.101.101.101", Country: "US", Reason: "rule"}, decision)
+
+	decision, err = fake.CheckIP(ctx, "81.2.69.142", client.Rules{Policy: "eu-only"})
+	require.NoError(t, err)
+	assert.Equal(t, client.Decision{IPAddress: "81.2.69.142", Allowed: true, Country: "GB", Reason: "rule", Policy: "eu-only"}, decision)
+
+	decision, err = fake.CheckIP(ctx, "10.0.0.1", client.Rules{AllowedCountries: []string{"US"}, DefaultAction: "allow"})
+	require.NoError(t, err)
+	assert.Equal(t, client.Decision{IPAddress: "10.0.0.1", Allowed: true, Reason: "default"}, decision)
+
+	_, err = fake.CheckIP(ctx, "10.0.0.1", client.Rules{AllowedCountries: []string{"US"}, DefaultAction: "error"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = fake.CheckIP(ctx, "128.101.101.101", client.Rules{Policy: "missing"})
+	assert.Equal(t, status.Error(codes.InvalidArgument, `unknown policy "missing"`), err)
+
+	results, err := fake.CheckIPBatch(ctx, []string{"81.2.69.142", "not-an-ip"}, client.Rules{AllowedCountries: []string{"US"}})
+	require.NoError(t, err)
+	assert.False(t, results[0].Allowed)
+	assert.Equal(t, status.Error(codes.InvalidArgument, "invalid IP address"), results[1].Err)
+
+	assert.Equal(t, []string{"128.101.101.101", "81.2.69.142", "10.0.0.1", "10.0.0.1", "81.2.69.142", "not-an-ip"}, fake.Checked())
+
+	// Country codes are upper-cased and validated like the service does.
+	decision, err = fake.CheckIP(ctx, "128.101.101.101", client.Rules{AllowedCountries: []string{"us"}})
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	_, err = fake.CheckIP(ctx, "128.101.101.101", client.Rules{BlockedCountries: []string{"USA"}})
+	assert.Equal(t, status.Error(codes.InvalidArgument, `"USA" is not an ISO 3166-1 alpha-2 country code or ISO 3166-2 subdivision code`), err)
+
+	fake.Err = status.Error(codes.Unavailable, "down")
+	_, err = fake.CheckIP(ctx, "128.101.101.101", client.Rules{AllowedCountries: []string{"US"}})
+	assert.True(t, errors.Is(err, fake.Err))
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+
+	pb "github.com/justfairdev/ipchecker/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcTransport sends requests to the ipchecker.v1.IPChecker gRPC service.
+type grpcTransport struct {
+	client pb.IPCheckerClient
+	conn   *grpc.ClientConn // Closed by close if the transport dialed it; nil otherwise.
+	md     metadata.MD      // Credentials sent with every call.
+}
+
+// NewGRPC constructs a Client calling the gRPC service over an existing connection, which the caller keeps
+// ownership of: Close does not close it.
+//
+// Parameters:
+//   - conn: The connection to the gRPC listener of the service.
+//   - opts: The credentials, deadlines, retries, caching and batching of the client.
+//
+// Returns:
+//   - Client: The client.
+func NewGRPC(conn grpc.ClientConnInterface, opts Options) Client {
+	return newClient(newGRPCTransport(conn, nil, opts), opts)
+}
+
+// DialGRPC constructs a Client calling the gRPC service at target over a new connection, closed by Close.
+//
+// Parameters:
+//   - target: The address of the gRPC listener of the service, e.g. "ipchecker:50051".
+//   - opts: The credentials, deadlines, retries, caching and batching of the client.
+//   - dialOpts: Options of the connection; grpc.WithTransportCredentials is required.
+//
+// Returns:
+//   - Client: The client.
+//   - error: An error if the connection could not be set up.
+func DialGRPC(target string, opts Options, dialOpts ...grpc.DialOption) (Client, error) {
+	conn, err := grpc.NewClient(target, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(newGRPCTransport(conn, conn, opts), opts), nil
+}
+
+// newGRPCTransport constructs a grpcTransport.
+//
+// Parameters:
+//   - conn: The connection calls are made on.
+//   - owned: The connection closed by close; nil if the caller owns conn.
+//   - opts: The credentials sent with every call.
+//
+// Returns:
+//   - *grpcTransport: The initialized transport.
+func newGRPCTransport(conn grpc.ClientConnInterface, owned *grpc.ClientConn, opts Options) *grpcTransport {
+	md := metadata.MD{}
+	if opts.APIKey != "" {
+		md.Set("x-api-key", opts.APIKey)
+	}
+	if opts.BearerToken != "" {
+		md.Set("authorization", "Bearer "+opts.BearerToken)
+	}
+	return &grpcTransport{client: pb.NewIPCheckerClient(conn), conn: owned, md: md}
+}
+
+// checkIP calls CheckIP.
+func (t *grpcTransport) checkIP(ctx context.Context, ip string, rules Rules) (Decision, error) {
+	resp, err := t.client.CheckIP(t.outgoing(ctx), &pb.IPCheckRequest{
+		IpAddress:        ip,
+		AllowedCountries: rules.AllowedCountries,
+		BlockedCountries: rules.BlockedCountries,
+		DefaultAction:    rules.DefaultAction,
+		Policy:           rules.Policy,
+	})
+	if err != nil {
+		return Decision{}, err
+	}
+	return Decision{
+		IPAddress:     ip,
+		Allowed:       resp.GetAllowed(),
+		Country:       resp.GetCountry(),
+		Reason:        resp.GetReason(),
+		ASN:           uint(resp.GetAsn()),
+		Policy:        resp.GetPolicy(),
+		PolicyVersion: resp.GetPolicyVersion(),
+		Override:      fromOverrideProto(resp.GetOverride()),
+	}, nil
+}
+
+// checkIPBatch calls CheckIPBatch.
+func (t *grpcTransport) checkIPBatch(ctx context.Context, ips []string, rules Rules) ([]Result, error) {
+	resp, err := t.client.CheckIPBatch(t.outgoing(ctx), &pb.IPBatchCheckRequest{
+		IpAddresses:      ips,
+		AllowedCountries: rules.AllowedCountries,
+		BlockedCountries: rules.BlockedCountries,
+		DefaultAction:    rules.DefaultAction,
+		Policy:           rules.Policy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.GetResults()) != len(ips) {
+		return nil, status.Errorf(codes.Internal, "batch response holds %d results for %d IP addresses", len(resp.GetResults()), len(ips))
+	}
+
+	results := make([]Result, len(ips))
+	for i, item := range resp.GetResults() {
+		if item.GetError() != "" {
+			// Servers predating error_code leave it zero (OK), which is reported as Unknown.
+			code := codes.Code(item.GetErrorCode())
+			if code == codes.OK {
+				code = codes.Unknown
+			}
+			results[i] = Result{Decision: Decision{IPAddress: ips[i]}, Err: status.Error(code, item.GetError())}
+			continue
+		}
+		results[i] = Result{Decision: Decision{
+			IPAddress:     ips[i],
+			Allowed:       item.GetAllowed(),
+			Country:       item.GetCountry(),
+			Reason:        item.GetReason(),
+			ASN:           uint(item.GetAsn()),
+			Policy:        resp.GetPolicy(),
+			PolicyVersion: resp.GetPolicyVersion(),
+			Override:      fromOverrideProto(item.GetOverride()),
+		}}
+	}
+	return results, nil
+}
+
+// close closes the connection, if the transport dialed it.
+func (t *grpcTransport) close() error {
+	if t.conn == nil {
+		return nil
+	}
+	return t.conn.Close()
+}
+
+// outgoing returns ctx carrying the credentials of the client in its outgoing metadata.
+func (t *grpcTransport) outgoing(ctx context.Context) context.Context {
+	if len(t.md) == 0 {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return metadata.NewOutgoingContext(ctx, metadata.Join(t.md, md))
+}
+
+// fromOverrideProto converts the override rule of a response.
+func fromOverrideProto(match *pb.OverrideMatch) *Override {
+	if match == nil {
+		return nil
+	}
+	return &Override{Action: match.GetAction(), Name: match.GetName(), CIDR: match.GetCidr()}
+}
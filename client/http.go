@@ -0,0 +1,212 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/justfairdev/ipchecker/internal/dtos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxErrorBodySize bounds how much of an error response is read for its message.
+const maxErrorBodySize = 64 << 10
+
+// httpTransport sends requests to the versioned HTTP API of the service.
+type httpTransport struct {
+	baseURL     string
+	httpClient  *http.Client
+	apiKey      string
+	bearerToken string
+}
+
+// NewHTTP constructs a Client calling the HTTP API of the service.
+//
+// Parameters:
+//   - baseURL: The URL of the HTTP listener of the service, e.g. "https://ipchecker:8080"; the /api/v1 paths are
+//     appended to it.
+//   - httpClient: The client requests are sent with, e.g. to configure TLS; nil uses http.DefaultClient.
+//   - opts: The credentials, deadlines, retries, caching and batching of the client.
+//
+// Returns:
+//   - Client: The client.
+//   - error: An error if baseURL is not an absolute http or https URL.
+func NewHTTP(baseURL string, httpClient *http.Client, opts Options) (Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("client: %q is not an absolute http or https URL", baseURL)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return newClient(&httpTransport{
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		httpClient:  httpClient,
+		apiKey:      opts.APIKey,
+		bearerToken: opts.BearerToken,
+	}, opts), nil
+}
+
+// checkIP calls POST /api/v1/ip-check.
+func (t *httpTransport) checkIP(ctx context.Context, ip string, rules Rules) (Decision, error) {
+	var resp dtos.IPCheckResponse
+	err := t.post(ctx, "/api/v1/ip-check", dtos.IPCheckRequest{
+		IPAddress:        ip,
+		AllowedCountries: rules.AllowedCountries,
+		BlockedCountries: rules.BlockedCountries,
+		DefaultAction:    rules.DefaultAction,
+		Policy:           rules.Policy,
+	}, &resp)
+	if err != nil {
+		return Decision{}, err
+	}
+	return Decision{
+		IPAddress:     ip,
+		Allowed:       resp.Allowed,
+		Country:       resp.Country,
+		Reason:        resp.Reason,
+		ASN:           resp.ASN,
+		Policy:        resp.Policy,
+		PolicyVersion: resp.PolicyVersion,
+		Override:      fromOverrideDTO(resp.Override),
+	}, nil
+}
+
+// checkIPBatch calls POST /api/v1/ip-check/batch.
+func (t *httpTransport) checkIPBatch(ctx context.Context, ips []string, rules Rules) ([]Result, error) {
+	var resp dtos.IPBatchCheckResponse
+	err := t.post(ctx, "/api/v1/ip-check/batch", dtos.IPBatchCheckRequest{
+		IPAddresses:      ips,
+		AllowedCountries: rules.AllowedCountries,
+		BlockedCountries: rules.BlockedCountries,
+		DefaultAction:    rules.DefaultAction,
+		Policy:           rules.Policy,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != len(ips) {
+		return nil, status.Errorf(codes.Internal, "batch response holds %d results for %d IP addresses", len(resp.Results), len(ips))
+	}
+
+	results := make([]Result, len(ips))
+	for i, item := range resp.Results {
+		if item.Error != "" {
+			// Servers predating error_status leave it zero, which is reported as Unknown.
+			code := codes.Unknown
+			if item.ErrorStatus != 0 {
+				code = codeFromHTTP(item.ErrorStatus)
+			}
+			results[i] = Result{Decision: Decision{IPAddress: ips[i]}, Err: status.Error(code, item.Error)}
+			continue
+		}
+		results[i] = Result{Decision: Decision{
+			IPAddress:     ips[i],
+			Allowed:       item.Allowed,
+			Country:       item.Country,
+			Reason:        item.Reason,
+			ASN:           item.ASN,
+			Policy:        resp.Policy,
+			PolicyVersion: resp.PolicyVersion,
+			Override:      fromOverrideDTO(item.Override),
+		}}
+	}
+	return results, nil
+}
+
+// close releases nothing: the HTTP client is shared.
+func (t *httpTransport) close() error {
+	return nil
+}
+
+// post sends body as JSON to path and decodes the JSON response into out.
+//
+// Parameters:
+//   - ctx: Context of the request.
+//   - path: The path of the endpoint, below the base URL.
+//   - body: The request payload.
+//   - out: Receives the response payload of a 200 OK response.
+//
+// Returns:
+//   - error: A gRPC status error with the code matching the HTTP status of an error response (see codeFromHTTP),
+//     Unavailable if the service could not be reached, or the error of ctx.
+func (t *httpTransport) post(ctx context.Context, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.apiKey != "" {
+		req.Header.Set("X-API-Key", t.apiKey)
+	}
+	if t.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+t.bearerToken)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		if json.Unmarshal(data, &failure) != nil || failure.Error == "" {
+			failure.Error = http.StatusText(resp.StatusCode)
+		}
+		return status.Error(codeFromHTTP(resp.StatusCode), failure.Error)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return status.FromContextError(err).Err()
+		}
+		return status.Errorf(codes.Unavailable, "reading response: %v", err)
+	}
+	return nil
+}
+
+// codeFromHTTP returns the gRPC status code matching the HTTP status of an error response, so that failures are
+// classified the same way on both transports.
+func codeFromHTTP(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// fromOverrideDTO converts the override rule of a response.
+func fromOverrideDTO(match *dtos.OverrideMatch) *Override {
+	if match == nil {
+		return nil
+	}
+	return &Override{Action: match.Action, Name: match.Name, CIDR: match.CIDR}
+}
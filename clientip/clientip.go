@@ -0,0 +1,182 @@
+// Package clientip finds the IP address of the client of a request: the address of the connection, or the address
+// forwarded by a trusted proxy in a header (HTTP) or metadata entry (gRPC), and carries it through request contexts
+// to handlers along with the source it was taken from.
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// SourcePeer is the source of addresses taken from the connection rather than from a forwarding header.
+const SourcePeer = "peer"
+
+// Address is the IP address of the client of a request.
+type Address struct {
+	// IP is the client IP address; IPv4-mapped IPv6 addresses are unmapped. Empty if the connection has no IP
+	// address, e.g. a Unix socket.
+	IP string
+
+	// Source tells where IP was taken from: SourcePeer for the connection, otherwise the lower-cased name of the
+	// forwarding header or metadata key (e.g., "x-forwarded-for").
+	Source string
+}
+
+// Resolver finds client IP addresses, trusting the forwarding headers of the configured proxies only.
+//
+// Headers are read as gin.Context.ClientIP does: the first header, in order, holding a valid address wins; lists such
+// as X-Forwarded-For are walked from the right, skipping the addresses of trusted proxies, so that the entries a
+// client prepends itself are ignored. The zero Resolver trusts no proxy.
+type Resolver struct {
+	trusted []netip.Prefix
+	headers []string
+}
+
+// NewResolver constructs a new Resolver instance.
+//
+// Parameters:
+//   - trustedProxies: The IP addresses or CIDR ranges of the proxies whose forwarding headers are trusted; empty
+//     trusts none, so the peer address is always the client.
+//   - headers: The headers or metadata keys carrying the client IP, read in order when the peer is trusted.
+//
+// Returns:
+//   - *Resolver: The initialized resolver.
+//   - error: An error if a trusted proxy is neither an IP address nor a CIDR range.
+func NewResolver(trustedProxies, headers []string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, proxy := range trustedProxies {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("clientip: %q is neither an IP address nor a CIDR range", proxy)
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+	for _, header := range headers {
+		r.headers = append(r.headers, strings.ToLower(header))
+	}
+	return r, nil
+}
+
+// Resolve returns the client IP address of a request.
+//
+// Parameters:
+//   - peer: The remote address of the connection, with or without a port.
+//   - header: Returns the comma-joined values of a header or metadata key; its name is passed in lower case.
+//
+// Returns:
+//   - Address: The address forwarded by the peer, if it is a trusted proxy and sent a valid one, otherwise the
+//     peer address.
+func (r *Resolver) Resolve(peer string, header func(name string) string) Address {
+	peerAddr, ok := parseAddr(peer)
+	if !ok {
+		return Address{Source: SourcePeer}
+	}
+	if r.isTrusted(peerAddr) {
+		for _, name := range r.headers {
+			if addr, ok := r.forwarded(header(name)); ok {
+				return Address{IP: addr.String(), Source: name}
+			}
+		}
+	}
+	return Address{IP: peerAddr.String(), Source: SourcePeer}
+}
+
+// ResolveRequest returns the client IP address of an HTTP request. Repeated forwarding headers are joined in order,
+// so that the line a client sends itself cannot hide the one a trusted proxy adds after it.
+//
+// Parameters:
+//   - req: The request whose client is resolved.
+//
+// Returns:
+//   - Address: The client address, as returned by Resolve.
+func (r *Resolver) ResolveRequest(req *http.Request) Address {
+	return r.Resolve(req.RemoteAddr, func(name string) string {
+		return strings.Join(req.Header.Values(name), ",")
+	})
+}
+
+// forwarded returns the client address of a forwarding header value: the rightmost address that is not a trusted
+// proxy, or the leftmost address if all of them are.
+//
+// Parameters:
+//   - value: The comma-separated addresses of the header, oldest first.
+//
+// Returns:
+//   - netip.Addr: The client address.
+//   - bool: Whether the header holds one; false if it is empty or an entry on the way is not an IP address.
+func (r *Resolver) forwarded(value string) (netip.Addr, bool) {
+	if value == "" {
+		return netip.Addr{}, false
+	}
+	items := strings.Split(value, ",")
+	for i := len(items) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(items[i])
+		if !ok {
+			return netip.Addr{}, false
+		}
+		if i == 0 || !r.isTrusted(addr) {
+			return addr, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// isTrusted reports whether addr belongs to a trusted proxy.
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAddr parses an IP address taken from a connection or header, dropping any port and IPv6 zone and
+// unmapping IPv4-mapped IPv6 addresses.
+func parseAddr(raw string) (netip.Addr, bool) {
+	raw = strings.TrimSpace(raw)
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		raw = host
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+// contextKey is the type of the context key of the client address, unexported to avoid collisions.
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the client address of the request.
+//
+// Parameters:
+//   - ctx: The parent context.
+//   - addr: The client address of the request.
+//
+// Returns:
+//   - context.Context: The derived context.
+func NewContext(ctx context.Context, addr Address) context.Context {
+	return context.WithValue(ctx, contextKey{}, addr)
+}
+
+// FromContext returns the client address stored in ctx by NewContext.
+//
+// Parameters:
+//   - ctx: The request context.
+//
+// Returns:
+//   - Address: The client address of the request.
+//   - bool: Whether ctx carries one.
+func FromContext(ctx context.Context) (Address, bool) {
+	addr, ok := ctx.Value(contextKey{}).(Address)
+	return addr, ok
+}
@@ -0,0 +1,108 @@
+package clientip_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/clientip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// headers returns a header lookup function over a map keyed by lower-case name.
+func headers(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+// TestResolver_Resolve verifies that forwarding headers are only read from trusted proxies, in the configured order,
+// and that X-Forwarded-For is walked from the right up to the first untrusted address.
+func TestResolver_Resolve(t *testing.T) {
+	r, err := clientip.NewResolver([]string{"10.0.0.0/8", "::ffff:192.0.2.1"}, []string{"X-Forwarded-For", "X-Real-IP"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		peer    string
+		headers map[string]string
+		want    clientip.Address
+	}{
+		{
+			name:    "untrusted peer cannot spoof",
+			peer:    "198.51.100.7:41000",
+			headers: map[string]string{"x-forwarded-for": "81.2.69.142", "x-real-ip": "81.2.69.142"},
+			want:    clientip.Address{IP: "198.51.100.7", Source: clientip.SourcePeer},
+		},
+		{
+			name:    "trusted peer without headers",
+			peer:    "10.0.0.5:41000",
+			headers: nil,
+			want:    clientip.Address{IP: "10.0.0.5", Source: clientip.SourcePeer},
+		},
+		{
+			name:    "entry prepended by the client is skipped",
+			peer:    "10.0.0.5:41000",
+			headers: map[string]string{"x-forwarded-for": "128.101.101.101, 81.2.69.142 ,10.1.2.3"},
+			want:    clientip.Address{IP: "81.2.69.142", Source: "x-forwarded-for"},
+		},
+		{
+			name:    "only trusted proxies forwarded",
+			peer:    "10.0.0.5:41000",
+			headers: map[string]string{"x-forwarded-for": "10.1.2.3, 10.4.5.6"},
+			want:    clientip.Address{IP: "10.1.2.3", Source: "x-forwarded-for"},
+		},
+		{
+			name:    "invalid header falls through to the next one",
+			peer:    "10.0.0.5:41000",
+			headers: map[string]string{"x-forwarded-for": "unknown", "x-real-ip": "81.2.69.142"},
+			want:    clientip.Address{IP: "81.2.69.142", Source: "x-real-ip"},
+		},
+		{
+			name:    "mapped addresses are unmapped",
+			peer:    "[::ffff:192.0.2.1]:41000",
+			headers: map[string]string{"x-real-ip": "::ffff:81.2.69.142"},
+			want:    clientip.Address{IP: "81.2.69.142", Source: "x-real-ip"},
+		},
+		{
+			name: "peer without an IP address",
+			peer: "bufconn",
+			want: clientip.Address{Source: clientip.SourcePeer},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, r.Resolve(tt.peer, headers(tt.headers)))
+		})
+	}
+}
+
+// TestResolver_ResolveRequest verifies that every line of a repeated forwarding header is read, so that a client
+// cannot spoof its address when a trusted proxy adds its own line instead of appending to the client's.
+func TestResolver_ResolveRequest(t *testing.T) {
+	r, err := clientip.NewResolver([]string{"10.0.0.0/8"}, []string{"X-Forwarded-For"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.5:41000"
+	req.Header.Add("X-Forwarded-For", "81.2.69.142")
+	req.Header.Add("X-Forwarded-For", "128.101.101.101")
+	assert.Equal(t, clientip.Address{IP: "128.101.101.101", Source: "x-forwarded-for"}, r.ResolveRequest(req))
+}
+
+// TestNewResolver_InvalidProxy verifies that trusted proxies must be IP addresses or CIDR ranges.
+func TestNewResolver_InvalidProxy(t *testing.T) {
+	_, err := clientip.NewResolver([]string{"proxy.local"}, nil)
+	assert.EqualError(t, err, `clientip: "proxy.local" is neither an IP address nor a CIDR range`)
+}
+
+// TestContext verifies that the client address stored in a context is returned by FromContext.
+func TestContext(t *testing.T) {
+	_, ok := clientip.FromContext(context.Background())
+	assert.False(t, ok)
+
+	addr := clientip.Address{IP: "81.2.69.142", Source: "x-forwarded-for"}
+	got, ok := clientip.FromContext(clientip.NewContext(context.Background(), addr))
+	assert.True(t, ok)
+	assert.Equal(t, addr, got)
+}
@@ -1,30 +1,60 @@
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        X-API-Key
+// @description                 API key, accepted when API key authentication is enabled (auth.api_keys_file).
+
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 "Bearer " followed by a JWT, accepted when bearer tokens are enabled (auth.jwt.jwks_file or auth.jwt.jwks_url).
 package main
 
 import (
+	"errors"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/justfairdev/ipchecker/internal/config"
+	"github.com/justfairdev/ipchecker/internal/logger"
 	"github.com/justfairdev/ipchecker/internal/server"
 )
 
 // main is the entry point for the IPChecker application.
 //
 // Application Overview:
-//   - Loads configuration settings (ports, database paths, etc.).
+//   - Loads and validates configuration settings (addresses, ports, database paths, etc.) from the config file,
+//     environment variables and command-line flags; --print-config prints them instead of starting the servers.
 //   - Initializes combined HTTP (Gin) and gRPC servers along with shared dependencies.
 //   - Starts the servers concurrently, making services available to HTTP and gRPC clients.
-//   - Gracefully handles system interrupts (SIGINT, SIGTERM) to safely shut down servers.
+//   - Gracefully handles system interrupts (SIGINT, SIGTERM), or a server failing, to safely shut down servers:
+//     in-flight HTTP requests and gRPC calls are drained within the configured shutdown timeout.
+//   - Exits with a non-zero status if a server failed or could not be drained in time.
 //
 // This structure allows the application to serve multiple client types concurrently, manage graceful shutdown,
 // and provides clear logging for observability and debugging.
 func main() {
-	// Load application configuration from environment variables, files, or defaults.
-	cfg, err := config.Load()
+	// Load application configuration from defaults, the config file, environment variables and flags.
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	// Print the effective configuration instead of starting, to check what a deployment resolves to.
+	if cfg.PrintConfig {
+		if err := cfg.WriteYAML(os.Stdout); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+
+	if err := logger.SetLevel(cfg.Log.Level); err != nil {
+		log.Fatalf("Failed to set log level: %v", err)
 	}
 
 	// Initialize HTTP/gRPC AppServer with shared dependencies (e.g., GeoLookup database).
@@ -33,25 +63,33 @@ func main() {
 		log.Fatalf("Failed to create AppServer: %v", err)
 	}
 
-	// Start the combined HTTP and gRPC servers concurrently in a separate goroutine.
-	// HTTP listens on the port defined in cfg.HTTPPort, gRPC listens on port "50051" (can be customized).
-	go func() {
-		if err := appServer.Start(cfg.HTTPPort, "50051"); err != nil {
-			log.Fatalf("Server encountered an error during startup: %v", err)
-		}
-	}()
-
 	// Set up OS signal channel to listen for termination signals (Ctrl+C, Docker/Kubernetes shutdown, etc.).
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	// Block execution until a shutdown signal is received.
-	<-quit
+	// Start the combined HTTP and gRPC servers; they serve in the background on the addresses of cfg.HTTP and
+	// cfg.GRPC.
+	if err := appServer.Start(); err != nil {
+		log.Fatalf("Server encountered an error during startup: %v", err)
+	}
 
-	log.Println("Shutdown signal received, gracefully stopping servers...")
+	// Block execution until a shutdown signal is received or a server stops serving on its own.
+	failed := false
+	select {
+	case <-quit:
+		log.Println("Shutdown signal received, gracefully stopping servers...")
+	case err := <-appServer.Err():
+		log.Printf("Server failed, gracefully stopping the other: %v", err)
+		failed = true
+	}
 
-	// Gracefully shut down both gRPC server and safely release resources (GeoLookup database connections, etc.).
-	appServer.Stop()
+	// Gracefully shut down both servers and safely release resources (GeoLookup database connections, etc.).
+	if err := appServer.Stop(); err != nil {
+		log.Fatalf("Servers did not stop cleanly: %v", err)
+	}
+	if failed {
+		os.Exit(1)
+	}
 
 	log.Println("All servers stopped successfully. Exiting.")
 }
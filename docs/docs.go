@@ -15,9 +15,309 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/auth": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Checks the IP address of the client of the request, as forwarded by a trusted reverse proxy in X-Forwarded-For or X-Real-IP (otherwise the address of the connection), against the policy named by the policy query parameter or the X-IPChecker-Policy header. Answers 204 if the client is allowed and 403 otherwise, with the X-Country, X-Client-IP and X-IPChecker-Reason headers. Requests that cannot be decided, because of a missing or unknown policy or a failed lookup, are denied with 403 and the X-IPChecker-Error header, as proxies treat other statuses as internal errors. No request body is read.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "IP"
+                ],
+                "summary": "Allow or deny the client of a proxied request (NGINX auth_request, Traefik forwardAuth).",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the server-side policy; takes precedence over the X-IPChecker-Policy header.",
+                        "name": "policy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the server-side policy.",
+                        "name": "X-IPChecker-Policy",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "The client is allowed.",
+                        "headers": {
+                            "X-Client-IP": {
+                                "type": "string",
+                                "description": "Client IP address the decision was made for."
+                            },
+                            "X-Country": {
+                                "type": "string",
+                                "description": "Country code resolved for the client; omitted if none is known."
+                            },
+                            "X-IPChecker-Reason": {
+                                "type": "string",
+                                "description": "What decided: rule, asn, default or override."
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key or bearer token.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "The client is denied, or the request cannot be decided (see X-IPChecker-Error), or the caller lacks the check scope.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        },
+                        "headers": {
+                            "X-Client-IP": {
+                                "type": "string",
+                                "description": "Client IP address the decision was made for."
+                            },
+                            "X-Country": {
+                                "type": "string",
+                                "description": "Country code resolved for the client; omitted if none is known."
+                            },
+                            "X-IPChecker-Error": {
+                                "type": "string",
+                                "description": "Why the request could not be decided, e.g. an unknown policy or a failed lookup."
+                            },
+                            "X-IPChecker-Reason": {
+                                "type": "string",
+                                "description": "What decided: rule, asn, default or override."
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit or daily quota of the caller exceeded; see the Retry-After header.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Reports that the process is up and serving HTTP requests. It does not depend on the MaxMind database, so an instance is never restarted merely because its database is unavailable.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Liveness probe.",
+                "responses": {
+                    "200": {
+                        "description": "The process is alive.",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/ip-check": {
             "post": {
-                "description": "Takes an IP address \u0026 list of allowed countries, returns whether it's allowed.",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Accepts an IP address and either a list of allowed or blocked countries (with an optional default action for IPs without a known country) or the name of a server-side policy; returns whether the IP address is permitted based on its location and whether a country rule, the default action or a CIDR override decided.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "IP"
+                ],
+                "summary": "Verify if an IP address originates from allowed countries.",
+                "parameters": [
+                    {
+                        "description": "IP check request payload.",
+                        "name": "requestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.IPCheckRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful IP check operation.",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.IPCheckResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload, unknown policy or malformed IP address.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key or bearer token.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "The caller lacks the check scope, or may not use the requested policy.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No country is known for the IP address and the default action is error.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit or daily quota of the caller exceeded; see the Retry-After header.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error during IP geolocation lookup.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ip-check/batch": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Accepts up to 1000 IP addresses and shared inline country rules or server-side policy name; returns one result per IP address in request order. A malformed IP or failed lookup is reported in that item's error field and does not fail the batch.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "IP"
+                ],
+                "summary": "Verify several IP addresses against one list of allowed countries.",
+                "parameters": [
+                    {
+                        "description": "IP batch check request payload.",
+                        "name": "requestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dtos.IPBatchCheckRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Per-item results of the batch check.",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.IPBatchCheckResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or unknown policy.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key or bearer token.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "The caller lacks the check scope, or may not use the requested policy.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit or daily quota of the caller exceeded; see the Retry-After header.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ip-check/caller": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Checks the IP address of the request itself, for browser and mobile clients asking whether they are allowed: the address forwarded by a trusted reverse proxy in X-Forwarded-For or X-Real-IP, otherwise the address of the connection. Forwarding headers sent by other peers are ignored. Accepts either a list of allowed or blocked countries or the name of a server-side policy; returns the decision, the IP address checked and the source it was taken from.",
                 "consumes": [
                     "application/json"
                 ],
@@ -27,27 +327,161 @@ const docTemplate = `{
                 "tags": [
                     "IP"
                 ],
-                "summary": "Check if IP is in allowed countries",
+                "summary": "Verify if the caller itself originates from allowed countries.",
                 "parameters": [
                     {
-                        "description": "IP Check Payload",
+                        "description": "Caller check request payload.",
                         "name": "requestBody",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dtos.IPCheckRequest"
+                            "$ref": "#/definitions/dtos.CallerCheckRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful caller check operation.",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.CallerCheckResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload, unknown policy or no valid caller IP address.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key or bearer token.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "The caller lacks the check scope, or may not use the requested policy.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "No country is known for the caller and the default action is error.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit or daily quota of the caller exceeded; see the Retry-After header.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error during IP geolocation lookup.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lookup/{ip}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the continent, country, registered country and represented country of an IP address, with names in the requested locale, together with the European Union flag and the anonymous-proxy, anycast and satellite-provider traits. When a City database is loaded, the city, subdivisions, postal code and accuracy radius are returned as well. No allow/deny rules are evaluated.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "IP"
+                ],
+                "summary": "Look up the full geolocation data of an IP address.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "IPv4 or IPv6 address to look up.",
+                        "name": "ip",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "en",
+                        "description": "Locale of the returned names (e.g., de, ja, pt-BR); names missing in it fall back to English.",
+                        "name": "locale",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Geolocation data of the IP address.",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.LookupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed IP address.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid API key or bearer token.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "The caller lacks the lookup scope.",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "404": {
+                        "description": "No geolocation data for the IP address.",
                         "schema": {
-                            "$ref": "#/definitions/dtos.IPCheckResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "429": {
+                        "description": "Rate limit or daily quota of the caller exceeded; see the Retry-After header.",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -56,7 +490,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
+                        "description": "Internal server error during IP geolocation lookup.",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -66,23 +500,323 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Reports whether the instance should receive traffic. It is not ready while the MaxMind database file is missing, a replacement file was rejected as corrupt, a database is being reloaded, or graceful shutdown has started.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Readiness probe.",
+                "responses": {
+                    "200": {
+                        "description": "The instance is ready to serve traffic.",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.HealthResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "The instance is not ready; checks lists the reasons.",
+                        "schema": {
+                            "$ref": "#/definitions/dtos.HealthResponse"
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
+        "dtos.ASNInfo": {
+            "type": "object",
+            "properties": {
+                "number": {
+                    "description": "Number is the autonomous system number (e.g., 3320).",
+                    "type": "integer"
+                },
+                "organization": {
+                    "description": "Organization is the organization owning the autonomous system (e.g., \"Deutsche Telekom AG\").",
+                    "type": "string"
+                }
+            }
+        },
+        "dtos.CallerCheckRequest": {
+            "type": "object",
+            "properties": {
+                "allowed_countries": {
+                    "description": "AllowedCountries is a list of acceptable country codes (ISO 3166-1 alpha-2 format) or subdivision codes\n(ISO 3166-2 format, e.g. \"US-CA\"; requires a City database).\nExactly one of Policy, AllowedCountries or BlockedCountries is required.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "blocked_countries": {
+                    "description": "BlockedCountries is a list of rejected country codes (ISO 3166-1 alpha-2 format) or subdivision codes\n(ISO 3166-2 format, e.g. \"CA-QC\"; requires a City database).",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "default_action": {
+                    "description": "DefaultAction decides callers without a known country: \"allow\", \"deny\" (default) or \"error\".\nOnly valid with AllowedCountries or BlockedCountries; named policies carry their own default action.",
+                    "type": "string",
+                    "enum": [
+                        "allow",
+                        "deny",
+                        "error"
+                    ]
+                },
+                "policy": {
+                    "description": "Policy is the name of a server-side policy (e.g., \"checkout-eu\") to check the caller against.\nMust not be combined with AllowedCountries, BlockedCountries or DefaultAction.",
+                    "type": "string"
+                }
+            }
+        },
+        "dtos.CallerCheckResponse": {
+            "type": "object",
+            "properties": {
+                "allowed": {
+                    "description": "Allowed indicates whether the given IP address is from one of the allowed countries.",
+                    "type": "boolean"
+                },
+                "asn": {
+                    "description": "ASN is the autonomous system number of the IP address; only resolved for server-side policies with\nASN rules, omitted otherwise.",
+                    "type": "integer"
+                },
+                "country": {
+                    "description": "Country is the ISO 3166-1 alpha-2 country code associated with the IP address; empty if none is known.",
+                    "type": "string"
+                },
+                "ip_address": {
+                    "description": "IPAddress is the IP address of the caller that was checked.",
+                    "type": "string"
+                },
+                "ip_source": {
+                    "description": "IPSource tells where IPAddress was taken from: \"peer\" for the connection, otherwise the lower-cased name of\nthe forwarding header set by a trusted proxy (e.g., \"x-forwarded-for\").",
+                    "type": "string"
+                },
+                "override": {
+                    "description": "Override identifies the CIDR override rule that decided the request instead of the country lookup.\nWhen set, Country is empty.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dtos.OverrideMatch"
+                        }
+                    ]
+                },
+                "policy": {
+                    "description": "Policy is the name of the server-side policy that produced the decision; omitted for inline lists.",
+                    "type": "string"
+                },
+                "policy_version": {
+                    "description": "PolicyVersion is the version of the server-side policy that produced the decision; omitted for inline lists.",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Reason tells what decided: \"rule\" (the country list), \"asn\" (the ASN rules of the policy), \"default\"\n(no country is known, so the default action applied) or \"override\" (a CIDR override rule).",
+                    "type": "string",
+                    "enum": [
+                        "rule",
+                        "asn",
+                        "default",
+                        "override"
+                    ]
+                }
+            }
+        },
+        "dtos.ContinentInfo": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is the two-letter continent code (e.g., \"EU\").",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name is the continent name in the requested locale.",
+                    "type": "string"
+                }
+            }
+        },
+        "dtos.CountryInfo": {
+            "type": "object",
+            "properties": {
+                "is_in_european_union": {
+                    "description": "IsInEuropeanUnion indicates whether the country is a member state of the European Union.",
+                    "type": "boolean"
+                },
+                "iso_code": {
+                    "description": "ISOCode is the ISO 3166-1 alpha-2 country code (e.g., \"DE\").",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name is the country name in the requested locale.",
+                    "type": "string"
+                }
+            }
+        },
+        "dtos.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "description": "Checks maps the name of each failed readiness check (e.g., \"geo\" or \"shutdown\") to the reason it failed;\nomitted if the probe passed.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "description": "Status is \"ok\" if the probe passed and \"unavailable\" otherwise.",
+                    "type": "string"
+                }
+            }
+        },
+        "dtos.IPBatchCheckRequest": {
+            "type": "object",
+            "required": [
+                "ip_addresses"
+            ],
+            "properties": {
+                "allowed_countries": {
+                    "description": "AllowedCountries is a list of acceptable country codes (ISO 3166-1 alpha-2 format) or subdivision codes\n(ISO 3166-2 format) applied to every IP address in the batch.\nExactly one of Policy, AllowedCountries or BlockedCountries is required.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "blocked_countries": {
+                    "description": "BlockedCountries is a list of rejected country codes (ISO 3166-1 alpha-2 format) or subdivision codes\n(ISO 3166-2 format) applied to every IP address in the batch.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "default_action": {
+                    "description": "DefaultAction decides IP addresses without a known country: \"allow\", \"deny\" (default) or \"error\".\nOnly valid with AllowedCountries or BlockedCountries.",
+                    "type": "string",
+                    "enum": [
+                        "allow",
+                        "deny",
+                        "error"
+                    ]
+                },
+                "ip_addresses": {
+                    "description": "IPAddresses is the list of IP addresses to be verified (at most 1000 per request).\nRequired field.",
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "policy": {
+                    "description": "Policy is the name of a server-side policy applied to every IP address in the batch.\nMust not be combined with AllowedCountries, BlockedCountries or DefaultAction.",
+                    "type": "string"
+                }
+            }
+        },
+        "dtos.IPBatchCheckResponse": {
+            "type": "object",
+            "properties": {
+                "policy": {
+                    "description": "Policy is the name of the server-side policy applied to the batch; omitted for inline lists.",
+                    "type": "string"
+                },
+                "policy_version": {
+                    "description": "PolicyVersion is the version of the server-side policy applied to the batch; omitted for inline lists.",
+                    "type": "string"
+                },
+                "results": {
+                    "description": "Results holds one entry per requested IP address, in request order.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dtos.IPBatchCheckResult"
+                    }
+                }
+            }
+        },
+        "dtos.IPBatchCheckResult": {
+            "type": "object",
+            "properties": {
+                "allowed": {
+                    "description": "Allowed indicates whether the IP address is from one of the allowed countries.",
+                    "type": "boolean"
+                },
+                "asn": {
+                    "description": "ASN is the autonomous system number of the IP address; only resolved for server-side policies with\nASN rules, omitted otherwise.",
+                    "type": "integer"
+                },
+                "country": {
+                    "description": "Country is the ISO 3166-1 alpha-2 country code associated with the IP address; empty if none is known.",
+                    "type": "string"
+                },
+                "error": {
+                    "description": "Error describes why this item could not be checked; empty on success.",
+                    "type": "string"
+                },
+                "error_status": {
+                    "description": "ErrorStatus is the HTTP status code a single check of this item would have answered with, e.g. 400 for a\nmalformed IP address; zero on success.",
+                    "type": "integer"
+                },
+                "ip_address": {
+                    "description": "IPAddress is the IP address this result refers to, as sent in the request.",
+                    "type": "string"
+                },
+                "override": {
+                    "description": "Override identifies the CIDR override rule that decided this item instead of the country lookup.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dtos.OverrideMatch"
+                        }
+                    ]
+                },
+                "reason": {
+                    "description": "Reason tells what decided: \"rule\", \"asn\", \"default\" or \"override\"; omitted when Error is set.",
+                    "type": "string",
+                    "enum": [
+                        "rule",
+                        "asn",
+                        "default",
+                        "override"
+                    ]
+                }
+            }
+        },
         "dtos.IPCheckRequest": {
             "type": "object",
             "required": [
-                "allowed_countries",
                 "ip_address"
             ],
             "properties": {
                 "allowed_countries": {
+                    "description": "AllowedCountries is a list of acceptable country codes (ISO 3166-1 alpha-2 format) or subdivision codes\n(ISO 3166-2 format, e.g. \"US-CA\"; requires a City database).\nThe IP address must originate from one of these countries or subdivisions.\nExactly one of Policy, AllowedCountries or BlockedCountries is required.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "blocked_countries": {
+                    "description": "BlockedCountries is a list of rejected country codes (ISO 3166-1 alpha-2 format) or subdivision codes\n(ISO 3166-2 format, e.g. \"CA-QC\"; requires a City database).\nThe IP address must not originate from any of these countries or subdivisions.",
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
                 },
+                "default_action": {
+                    "description": "DefaultAction decides IP addresses without a known country: \"allow\", \"deny\" (default) or \"error\".\nOnly valid with AllowedCountries or BlockedCountries; named policies carry their own default action.",
+                    "type": "string",
+                    "enum": [
+                        "allow",
+                        "deny",
+                        "error"
+                    ]
+                },
                 "ip_address": {
+                    "description": "IPAddress is the IP address to be verified.\nRequired field.",
+                    "type": "string"
+                },
+                "policy": {
+                    "description": "Policy is the name of a server-side policy (e.g., \"checkout-eu\") to check the IP address against.\nMust not be combined with AllowedCountries, BlockedCountries or DefaultAction.",
                     "type": "string"
                 }
             }
@@ -91,12 +825,206 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "allowed": {
+                    "description": "Allowed indicates whether the given IP address is from one of the allowed countries.",
                     "type": "boolean"
                 },
+                "asn": {
+                    "description": "ASN is the autonomous system number of the IP address; only resolved for server-side policies with\nASN rules, omitted otherwise.",
+                    "type": "integer"
+                },
+                "country": {
+                    "description": "Country is the ISO 3166-1 alpha-2 country code associated with the IP address; empty if none is known.",
+                    "type": "string"
+                },
+                "override": {
+                    "description": "Override identifies the CIDR override rule that decided the request instead of the country lookup.\nWhen set, Country is empty.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dtos.OverrideMatch"
+                        }
+                    ]
+                },
+                "policy": {
+                    "description": "Policy is the name of the server-side policy that produced the decision; omitted for inline lists.",
+                    "type": "string"
+                },
+                "policy_version": {
+                    "description": "PolicyVersion is the version of the server-side policy that produced the decision; omitted for inline lists.",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Reason tells what decided: \"rule\" (the country list), \"asn\" (the ASN rules of the policy), \"default\"\n(no country is known, so the default action applied) or \"override\" (a CIDR override rule).",
+                    "type": "string",
+                    "enum": [
+                        "rule",
+                        "asn",
+                        "default",
+                        "override"
+                    ]
+                }
+            }
+        },
+        "dtos.LookupResponse": {
+            "type": "object",
+            "properties": {
+                "accuracy_radius": {
+                    "description": "AccuracyRadius is the radius in kilometers around the location within which the IP address is likely\nto be; omitted unless a City database is loaded.",
+                    "type": "integer"
+                },
+                "asn": {
+                    "description": "ASN is the autonomous system the IP address is announced by; omitted unless an ASN database is loaded\nand has a record for the IP address.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dtos.ASNInfo"
+                        }
+                    ]
+                },
+                "city": {
+                    "description": "City is the city name in the requested locale; omitted unless a City database is loaded.",
+                    "type": "string"
+                },
+                "continent": {
+                    "description": "Continent is the continent the IP address is located in; omitted if unknown.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dtos.ContinentInfo"
+                        }
+                    ]
+                },
                 "country": {
+                    "description": "Country is the country the IP address is located in; omitted if unknown.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dtos.CountryInfo"
+                        }
+                    ]
+                },
+                "ip_address": {
+                    "description": "IPAddress is the IP address that was looked up, as sent in the request.",
+                    "type": "string"
+                },
+                "locale": {
+                    "description": "Locale is the locale names were requested in; names unavailable in it are returned in English.",
+                    "type": "string"
+                },
+                "postal_code": {
+                    "description": "PostalCode is the postal code of the location; omitted unless a City database is loaded.",
+                    "type": "string"
+                },
+                "registered_country": {
+                    "description": "RegisteredCountry is the country the ISP registered the network in; omitted if unknown.\nIt differs from Country for e.g. mobile roaming or networks of multinational companies.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dtos.CountryInfo"
+                        }
+                    ]
+                },
+                "represented_country": {
+                    "description": "RepresentedCountry is the country represented by users of the IP address, such as the home country\nof a military base; omitted unless the database records one.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dtos.RepresentedCountryInfo"
+                        }
+                    ]
+                },
+                "subdivisions": {
+                    "description": "Subdivisions lists the subdivisions (states, provinces, ...) of the location, from the largest to the\nsmallest; omitted unless a City database is loaded.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dtos.SubdivisionInfo"
+                    }
+                },
+                "traits": {
+                    "description": "Traits holds flags describing the network of the IP address.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/dtos.TraitsInfo"
+                        }
+                    ]
+                }
+            }
+        },
+        "dtos.OverrideMatch": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "Action is the decision forced by the rule: \"allow\" or \"deny\".",
+                    "type": "string"
+                },
+                "cidr": {
+                    "description": "CIDR is the network of the rule that contained the IP address.",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name is the name of the override rule.",
+                    "type": "string"
+                }
+            }
+        },
+        "dtos.RepresentedCountryInfo": {
+            "type": "object",
+            "properties": {
+                "is_in_european_union": {
+                    "description": "IsInEuropeanUnion indicates whether the country is a member state of the European Union.",
+                    "type": "boolean"
+                },
+                "iso_code": {
+                    "description": "ISOCode is the ISO 3166-1 alpha-2 country code (e.g., \"US\").",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name is the country name in the requested locale.",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Type describes the kind of representation (e.g., \"military\").",
+                    "type": "string"
+                }
+            }
+        },
+        "dtos.SubdivisionInfo": {
+            "type": "object",
+            "properties": {
+                "iso_code": {
+                    "description": "ISOCode is the ISO 3166-2 subdivision code (e.g., \"US-CA\").",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name is the subdivision name in the requested locale.",
                     "type": "string"
                 }
             }
+        },
+        "dtos.TraitsInfo": {
+            "type": "object",
+            "properties": {
+                "is_anonymous_proxy": {
+                    "description": "IsAnonymousProxy indicates whether the IP address belongs to an anonymous proxy.",
+                    "type": "boolean"
+                },
+                "is_anycast": {
+                    "description": "IsAnycast indicates whether the network is announced from several locations.",
+                    "type": "boolean"
+                },
+                "is_satellite_provider": {
+                    "description": "IsSatelliteProvider indicates whether the IP address belongs to a satellite internet provider.",
+                    "type": "boolean"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "API key, accepted when API key authentication is enabled (auth.api_keys_file).",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "\"Bearer \" followed by a JWT, accepted when bearer tokens are enabled (auth.jwt.jwks_file or auth.jwt.jwks_url).",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,317 @@
+package geo
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Generational is implemented by lookup services whose data can be replaced at run time, such as
+// GeoLookupService. The generation changes whenever lookups may start returning different results.
+type Generational interface {
+	// Generation returns a counter that changes whenever the underlying data is replaced.
+	Generation() uint64
+}
+
+// CacheOptions configures a CachedLookupService.
+type CacheOptions struct {
+	// Size is the maximum number of cached results; the least recently used result is evicted beyond it.
+	Size int
+
+	// TTL is how long a result is served from the cache; 0 keeps results until they are evicted or invalidated.
+	TTL time.Duration
+}
+
+// CacheStats is a snapshot of the counters of a CachedLookupService.
+type CacheStats struct {
+	// Hits is the number of lookups answered from the cache.
+	Hits uint64
+
+	// Misses is the number of lookups passed on to the underlying service, including those collapsed into a
+	// lookup of the same IP address that was already running.
+	Misses uint64
+
+	// Evictions is the number of results dropped to stay within the size bound.
+	Evictions uint64
+
+	// Entries is the number of results currently cached.
+	Entries int
+}
+
+// CachedLookupService is a LookupService decorator that caches the results of another LookupService in a bounded
+// LRU with a TTL.
+//
+// Concurrent lookups of the same IP address that miss the cache are collapsed into a single lookup of the
+// underlying service. "Not found" results are cached as well; other errors are not. If the underlying service
+// implements Generational, the whole cache is invalidated as soon as its generation changes, so results of a
+// replaced database are never served after a reload.
+//
+// Locations returned by Lookup are shared between callers and must not be modified.
+type CachedLookupService struct {
+	inner       LookupService
+	generations Generational // nil if inner cannot be reloaded.
+	size        int
+	ttl         time.Duration
+
+	mu         sync.Mutex
+	entries    map[cacheKey]*list.Element
+	order      *list.List // Elements hold *cacheEntry; the front is the most recently used.
+	generation uint64     // Generation of inner the cached entries belong to; guarded by mu.
+
+	group singleflight.Group
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// cacheKey identifies a cached result. Country lookups and full lookups are cached separately.
+type cacheKey struct {
+	ip     string // Canonical form of the IP address.
+	locale string // Empty for country lookups.
+	full   bool   // Whether the entry holds a Lookup result rather than a CountryISOCode result.
+}
+
+// String returns the key as used for collapsing concurrent lookups.
+func (k cacheKey) String() string {
+	if !k.full {
+		return "country|" + k.ip
+	}
+	return "lookup|" + k.locale + "|" + k.ip
+}
+
+// cacheEntry is one cached lookup result.
+type cacheEntry struct {
+	key      cacheKey
+	country  string    // Result of CountryISOCode.
+	location *Location // Result of Lookup.
+	err      error     // ErrNotFound (possibly wrapped) for negative results; nil otherwise.
+	expires  time.Time // Zero if the entry never expires.
+}
+
+// NewCachedLookupService wraps inner in a cache.
+//
+// Parameters:
+//   - inner: The lookup service whose results are cached.
+//   - opts: The size bound and TTL of the cache.
+//
+// Returns:
+//   - *CachedLookupService: The caching decorator.
+//   - error: An error if the size is not positive or the TTL is negative.
+func NewCachedLookupService(inner LookupService, opts CacheOptions) (*CachedLookupService, error) {
+	if opts.Size <= 0 {
+		return nil, fmt.Errorf("cache size must be positive, got %d", opts.Size)
+	}
+	if opts.TTL < 0 {
+		return nil, fmt.Errorf("cache TTL must not be negative, got %s", opts.TTL)
+	}
+
+	c := &CachedLookupService{
+		inner:   inner,
+		size:    opts.Size,
+		ttl:     opts.TTL,
+		entries: make(map[cacheKey]*list.Element, opts.Size),
+		order:   list.New(),
+	}
+	if generations, ok := inner.(Generational); ok {
+		c.generations = generations
+		c.generation = generations.Generation()
+	}
+	return c, nil
+}
+
+// CountryISOCode returns the ISO 3166-1 alpha-2 country code of the IP address, from the cache if possible.
+//
+// Parameters:
+//   - ipStr: String representation of the IP address to be looked up.
+//
+// Returns:
+//   - string: The country code, as returned by the underlying service.
+//   - error: The error of the underlying service, if any.
+func (c *CachedLookupService) CountryISOCode(ipStr string) (string, error) {
+	addr, err := netip.ParseAddr(ipStr)
+	if err != nil {
+		// Malformed input is rejected by the underlying service; caching it would only crowd out real entries.
+		return c.inner.CountryISOCode(ipStr)
+	}
+
+	entry, err := c.get(cacheKey{ip: addr.String()}, func() (*cacheEntry, error) {
+		country, err := c.inner.CountryISOCode(ipStr)
+		return &cacheEntry{country: country}, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return entry.country, entry.err
+}
+
+// Lookup returns the full geolocation data of the IP address, from the cache if possible.
+// The returned Location is shared with other callers and must not be modified.
+//
+// Parameters:
+//   - ipStr: String representation of the IP address to be looked up.
+//   - locale: The locale names are returned in; empty selects DefaultLocale.
+//
+// Returns:
+//   - *Location: The geolocation data, as returned by the underlying service.
+//   - error: The error of the underlying service, if any.
+func (c *CachedLookupService) Lookup(ipStr, locale string) (*Location, error) {
+	addr, err := netip.ParseAddr(ipStr)
+	if err != nil {
+		return c.inner.Lookup(ipStr, locale)
+	}
+	if locale == "" {
+		locale = DefaultLocale
+	}
+
+	entry, err := c.get(cacheKey{ip: addr.String(), locale: locale, full: true}, func() (*cacheEntry, error) {
+		location, err := c.inner.Lookup(ipStr, locale)
+		return &cacheEntry{location: location}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry.location, entry.err
+}
+
+// Stats returns a snapshot of the cache counters.
+//
+// Returns:
+//   - CacheStats: The hit, miss and eviction counts and the current number of entries.
+func (c *CachedLookupService) Stats() CacheStats {
+	c.mu.Lock()
+	entries := c.order.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+// Close drops every cached result and closes the underlying service.
+//
+// Returns:
+//   - error: The error returned by closing the underlying service, if any.
+func (c *CachedLookupService) Close() error {
+	c.mu.Lock()
+	c.purge()
+	c.mu.Unlock()
+	return c.inner.Close()
+}
+
+// get returns the cached entry for key, calling load on a miss. Only one load per key runs at a time; concurrent
+// callers missing on the same key share its result. Errors other than ErrNotFound are returned and not cached.
+func (c *CachedLookupService) get(key cacheKey, load func() (*cacheEntry, error)) (*cacheEntry, error) {
+	// Read the generation before looking up, so that a result loaded from a database that is replaced meanwhile
+	// is recognised as stale when it is stored.
+	generation := c.currentGeneration()
+
+	if entry, ok := c.cached(key, generation); ok {
+		c.hits.Add(1)
+		return entry, nil
+	}
+	c.misses.Add(1)
+
+	// Key the flight by generation too, so that a caller arriving after a reload does not share a load still
+	// running against the replaced database.
+	result, err, _ := c.group.Do(fmt.Sprintf("%d/%s", generation, key), func() (any, error) {
+		entry, err := load()
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		entry.key = key
+		entry.err = err
+		c.store(entry, generation)
+		return entry, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*cacheEntry), nil
+}
+
+// currentGeneration returns the generation of the underlying service, or 0 if it cannot be reloaded.
+func (c *CachedLookupService) currentGeneration() uint64 {
+	if c.generations == nil {
+		return 0
+	}
+	return c.generations.Generation()
+}
+
+// cached returns the live entry for key and marks it as most recently used. Entries of an older generation
+// are dropped first.
+func (c *CachedLookupService) cached(key cacheKey, generation uint64) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidateBefore(generation)
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry, true
+}
+
+// store adds entry, loaded at the given generation, evicting the least recently used entries beyond the size bound.
+func (c *CachedLookupService) store(entry *cacheEntry, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidateBefore(generation)
+	if generation != c.generation {
+		// The database was replaced while the entry was being loaded.
+		return
+	}
+
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	if element, ok := c.entries[entry.key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// invalidateBefore drops every entry if generation is newer than the one the entries were loaded at.
+// Callers must hold mu.
+func (c *CachedLookupService) invalidateBefore(generation uint64) {
+	if generation > c.generation {
+		c.purge()
+		c.generation = generation
+	}
+}
+
+// purge drops every entry. Callers must hold mu.
+func (c *CachedLookupService) purge() {
+	clear(c.entries)
+	c.order.Init()
+}
+
+// remove drops a single entry. Callers must hold mu.
+func (c *CachedLookupService) remove(element *list.Element) {
+	delete(c.entries, element.Value.(*cacheEntry).key)
+	c.order.Remove(element)
+}
@@ -0,0 +1,307 @@
+package geo_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/geo/geotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingLookupService wraps a mock service, counting the lookups that reach it and optionally blocking them
+// until release is closed.
+type countingLookupService struct {
+	*geo.MockGeoLookupService
+	calls   atomic.Int64
+	release chan struct{}
+}
+
+func (s *countingLookupService) CountryISOCode(ip string) (string, error) {
+	s.calls.Add(1)
+	if s.release != nil {
+		<-s.release
+	}
+	return s.MockGeoLookupService.CountryISOCode(ip)
+}
+
+func (s *countingLookupService) Lookup(ip, locale string) (*geo.Location, error) {
+	s.calls.Add(1)
+	return s.MockGeoLookupService.Lookup(ip, locale)
+}
+
+// TestCachedLookupService_HitsAndMisses verifies that repeated lookups are answered from the cache, that country
+// and full lookups are cached separately, and that equivalent spellings of an IP address share one entry.
+func TestCachedLookupService_HitsAndMisses(t *testing.T) {
+	inner := &countingLookupService{MockGeoLookupService: geo.NewMockGeoLookupService("US", nil)}
+	cache, err := geo.NewCachedLookupService(inner, geo.CacheOptions{Size: 10})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		country, err := cache.CountryISOCode("2001:db8::1")
+		require.NoError(t, err)
+		assert.Equal(t, "US", country)
+	}
+	_, err = cache.CountryISOCode("2001:0db8:0000::1")
+	require.NoError(t, err)
+
+	location, err := cache.Lookup("2001:db8::1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "US", location.Country.ISOCode)
+
+	assert.Equal(t, int64(2), inner.calls.Load())
+	assert.Equal(t, geo.CacheStats{Hits: 3, Misses: 2, Entries: 2}, cache.Stats())
+}
+
+// TestCachedLookupService_CachesNotFoundOnly verifies that "not found" results are cached, while other errors and
+// malformed IP addresses always reach the underlying service.
+func TestCachedLookupService_CachesNotFoundOnly(t *testing.T) {
+	inner := &countingLookupService{MockGeoLookupService: geo.NewMockGeoLookupService("", geo.ErrNotFound)}
+	cache, err := geo.NewCachedLookupService(inner, geo.CacheOptions{Size: 10})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.CountryISOCode("192.0.2.1")
+		assert.ErrorIs(t, err, geo.ErrNotFound)
+	}
+	assert.Equal(t, int64(1), inner.calls.Load(), "Expected the not-found result to be cached.")
+
+	inner.MockError = errors.New("backend down")
+	for i := 0; i < 2; i++ {
+		_, err := cache.CountryISOCode("192.0.2.2")
+		assert.EqualError(t, err, "backend down")
+	}
+	assert.Equal(t, int64(3), inner.calls.Load(), "Expected backend errors not to be cached.")
+
+	for i := 0; i < 2; i++ {
+		_, _ = cache.CountryISOCode("not-an-ip")
+	}
+	assert.Equal(t, int64(5), inner.calls.Load(), "Expected malformed IP addresses to bypass the cache.")
+	assert.Equal(t, 1, cache.Stats().Entries)
+}
+
+// TestCachedLookupService_EvictsLeastRecentlyUsed verifies that the cache stays within its size bound by evicting
+// the least recently used entry.
+func TestCachedLookupService_EvictsLeastRecentlyUsed(t *testing.T) {
+	inner := &countingLookupService{MockGeoLookupService: geo.NewMockGeoLookupService("US", nil)}
+	cache, err := geo.NewCachedLookupService(inner, geo.CacheOptions{Size: 2})
+	require.NoError(t, err)
+
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.1", "192.0.2.3"} {
+		_, err := cache.CountryISOCode(ip)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(3), inner.calls.Load())
+
+	// 192.0.2.2 was the least recently used entry when 192.0.2.3 was added.
+	_, _ = cache.CountryISOCode("192.0.2.1")
+	assert.Equal(t, int64(3), inner.calls.Load())
+	_, _ = cache.CountryISOCode("192.0.2.2")
+	assert.Equal(t, int64(4), inner.calls.Load())
+
+	assert.Equal(t, uint64(2), cache.Stats().Evictions)
+	assert.Equal(t, 2, cache.Stats().Entries)
+}
+
+// TestCachedLookupService_ExpiresEntries verifies that entries are looked up again once their TTL has passed.
+func TestCachedLookupService_ExpiresEntries(t *testing.T) {
+	inner := &countingLookupService{MockGeoLookupService: geo.NewMockGeoLookupService("US", nil)}
+	cache, err := geo.NewCachedLookupService(inner, geo.CacheOptions{Size: 10, TTL: 20 * time.Millisecond})
+	require.NoError(t, err)
+
+	_, _ = cache.CountryISOCode("192.0.2.1")
+	_, _ = cache.CountryISOCode("192.0.2.1")
+	assert.Equal(t, int64(1), inner.calls.Load())
+
+	time.Sleep(40 * time.Millisecond)
+	_, _ = cache.CountryISOCode("192.0.2.1")
+	assert.Equal(t, int64(2), inner.calls.Load(), "Expected the expired entry to be looked up again.")
+}
+
+// TestCachedLookupService_CollapsesConcurrentLookups verifies that concurrent misses on the same IP address cause a
+// single lookup of the underlying service.
+func TestCachedLookupService_CollapsesConcurrentLookups(t *testing.T) {
+	inner := &countingLookupService{
+		MockGeoLookupService: geo.NewMockGeoLookupService("US", nil),
+		release:              make(chan struct{}),
+	}
+	cache, err := geo.NewCachedLookupService(inner, geo.CacheOptions{Size: 10})
+	require.NoError(t, err)
+
+	const callers = 20
+	var started, done sync.WaitGroup
+	started.Add(callers)
+	done.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+			country, err := cache.CountryISOCode("192.0.2.1")
+			assert.NoError(t, err)
+			assert.Equal(t, "US", country)
+		}()
+	}
+
+	// Give every caller the chance to join the lookup in flight before letting it complete.
+	started.Wait()
+	time.Sleep(20 * time.Millisecond)
+	close(inner.release)
+	done.Wait()
+
+	assert.Equal(t, int64(1), inner.calls.Load())
+}
+
+// TestCachedLookupService_InvalidatedOnReload verifies that results of a replaced database are not served after
+// the GeoLookupService is reloaded.
+func TestCachedLookupService_InvalidatedOnReload(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB"})
+
+	svc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	cache, err := geo.NewCachedLookupService(svc, geo.CacheOptions{Size: 10})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	country, err := cache.CountryISOCode("81.2.69.142")
+	require.NoError(t, err)
+	assert.Equal(t, "GB", country)
+
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "IE"})
+	require.NoError(t, svc.Reload())
+
+	country, err = cache.CountryISOCode("81.2.69.142")
+	require.NoError(t, err)
+	assert.Equal(t, "IE", country, "Expected the reload to invalidate the cached result.")
+}
+
+// generationalLookupService is a countingLookupService whose generation is set by the test, as if reloaded.
+type generationalLookupService struct {
+	*countingLookupService
+	generation atomic.Uint64
+}
+
+func (s *generationalLookupService) Generation() uint64 {
+	return s.generation.Load()
+}
+
+// TestCachedLookupService_ReloadDuringLookup verifies that a miss after a reload does not share a lookup still in
+// flight against the replaced database.
+func TestCachedLookupService_ReloadDuringLookup(t *testing.T) {
+	inner := &generationalLookupService{countingLookupService: &countingLookupService{
+		MockGeoLookupService: geo.NewMockGeoLookupService("US", nil),
+		release:              make(chan struct{}),
+	}}
+	cache, err := geo.NewCachedLookupService(inner, geo.CacheOptions{Size: 10})
+	require.NoError(t, err)
+
+	var done sync.WaitGroup
+	lookup := func() {
+		defer done.Done()
+		_, err := cache.CountryISOCode("192.0.2.1")
+		assert.NoError(t, err)
+	}
+
+	done.Add(1)
+	go lookup()
+	require.Eventually(t, func() bool { return inner.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	inner.generation.Add(1)
+	done.Add(1)
+	go lookup()
+	assert.Eventually(t, func() bool { return inner.calls.Load() == 2 }, time.Second, time.Millisecond,
+		"Expected the lookup after the reload to reach the underlying service.")
+
+	close(inner.release)
+	done.Wait()
+}
+
+// TestNewCachedLookupService_RejectsInvalidOptions verifies that a non-positive size or negative TTL is rejected.
+func TestNewCachedLookupService_RejectsInvalidOptions(t *testing.T) {
+	inner := geo.NewMockGeoLookupService("US", nil)
+
+	_, err := geo.NewCachedLookupService(inner, geo.CacheOptions{Size: 0})
+	assert.Error(t, err)
+	_, err = geo.NewCachedLookupService(inner, geo.CacheOptions{Size: 10, TTL: -time.Second})
+	assert.Error(t, err)
+}
+
+// benchmarkIPs is the skewed working set of the lookup benchmarks: a few thousand distinct client addresses.
+func benchmarkIPs() []string {
+	ips := make([]string, 4096)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("81.2.%d.%d", i/256, i%256)
+	}
+	return ips
+}
+
+// newBenchmarkService opens a Country database covering the benchmark working set.
+func newBenchmarkService(b *testing.B) *geo.GeoLookupService {
+	dbPath := filepath.Join(b.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(b, dbPath, map[string]string{"81.2.0.0/20": "GB", "81.2.16.0/20": "IE"})
+
+	svc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(b, err)
+	b.Cleanup(func() { svc.Close() })
+	return svc
+}
+
+// BenchmarkGeoLookupService_CountryISOCode measures uncached country lookups over the working set.
+func BenchmarkGeoLookupService_CountryISOCode(b *testing.B) {
+	benchmarkCountryISOCode(b, newBenchmarkService(b))
+}
+
+// BenchmarkCachedLookupService_CountryISOCode measures cached country lookups over the working set.
+func BenchmarkCachedLookupService_CountryISOCode(b *testing.B) {
+	cache, err := geo.NewCachedLookupService(newBenchmarkService(b), geo.CacheOptions{Size: 10000, TTL: time.Hour})
+	require.NoError(b, err)
+	benchmarkCountryISOCode(b, cache)
+}
+
+// BenchmarkGeoLookupService_Lookup measures uncached full lookups over the working set.
+func BenchmarkGeoLookupService_Lookup(b *testing.B) {
+	benchmarkLookup(b, newBenchmarkService(b))
+}
+
+// BenchmarkCachedLookupService_Lookup measures cached full lookups over the working set.
+func BenchmarkCachedLookupService_Lookup(b *testing.B) {
+	cache, err := geo.NewCachedLookupService(newBenchmarkService(b), geo.CacheOptions{Size: 10000, TTL: time.Hour})
+	require.NoError(b, err)
+	benchmarkLookup(b, cache)
+}
+
+// benchmarkCountryISOCode runs parallel country lookups cycling through the working set.
+func benchmarkCountryISOCode(b *testing.B, svc geo.LookupService) {
+	ips := benchmarkIPs()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if _, err := svc.CountryISOCode(ips[i%len(ips)]); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// benchmarkLookup runs parallel full lookups cycling through the working set.
+func benchmarkLookup(b *testing.B, svc geo.LookupService) {
+	ips := benchmarkIPs()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if _, err := svc.Lookup(ips[i%len(ips)], geo.DefaultLocale); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
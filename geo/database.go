@@ -0,0 +1,273 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// databaseKind is the kind of lookups a database file must support to be accepted.
+type databaseKind int
+
+const (
+	// kindLocation databases answer country lookups (GeoLite2/GeoIP2 Country, City or Enterprise).
+	kindLocation databaseKind = iota
+
+	// kindASN databases answer autonomous system lookups (GeoLite2-ASN or GeoIP2-ISP).
+	kindASN
+)
+
+// String returns the short name of the kind, as used in DatabaseInfo.
+func (k databaseKind) String() string {
+	if k == kindASN {
+		return "asn"
+	}
+	return "location"
+}
+
+// database is one MaxMind database file that can be replaced while lookups are running.
+//
+// Lookups always run against a consistent reader: a replacement reader is swapped in atomically, and the
+// previous reader is only closed once every lookup that started on it has finished.
+type database struct {
+	path string
+	kind databaseKind
+
+	mu      sync.RWMutex // Guards current; held exclusively only for the pointer swap.
+	current *dbHandle    // Reader serving new lookups; nil once the database is closed.
+
+	reloadMu  sync.Mutex // Serializes reload calls so replacement readers are swapped in order.
+	lastStamp fileStamp  // Stamp of the file most recently loaded or rejected; guarded by reloadMu.
+
+	generation atomic.Uint64 // Number of replacement readers swapped in so far.
+
+	stateMu   sync.Mutex // Guards reloading and reloadErr, which readiness checks read without waiting on reloadMu.
+	reloading bool       // Whether a replacement file is being opened and validated.
+	reloadErr error      // Why the most recent reload was rejected; nil once a reload succeeds.
+}
+
+// dbHandle pairs a database reader with a counter of the lookups currently using it.
+type dbHandle struct {
+	reader   *geoip2.Reader
+	raw      *maxminddb.Reader // Same in-memory database as reader; reports whether a record exists at all.
+	city     bool              // Whether the database has city-level data (GeoLite2/GeoIP2 City or Enterprise).
+	inFlight sync.WaitGroup
+}
+
+// fileStamp identifies a version of the database file on disk.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// openReloadable opens the database file at path, which must support lookups of the given kind.
+func openReloadable(path string, kind databaseKind) (*database, error) {
+	handle, stamp, err := openDatabase(path, kind)
+	if err != nil {
+		return nil, err
+	}
+	return &database{path: path, kind: kind, current: handle, lastStamp: stamp}, nil
+}
+
+// acquire returns the reader currently serving lookups and registers the caller as one of its users.
+// Callers must call inFlight.Done on the returned handle once the lookup has completed.
+func (d *database) acquire() (*dbHandle, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.current == nil {
+		return nil, ErrServiceClosed
+	}
+	d.current.inFlight.Add(1)
+	return d.current, nil
+}
+
+// reload opens the file again and swaps it in; see GeoLookupService.Reload.
+func (d *database) reload() error {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	d.setReloadState(true, nil)
+	handle, stamp, err := openDatabase(d.path, d.kind)
+	if err != nil {
+		// Remember the rejected file so the watcher does not retry it until it changes again. A missing file
+		// has no stamp; forget the loaded one instead, so the file is picked up whenever it reappears.
+		d.lastStamp = fileStamp{}
+		if st, statErr := statFile(d.path); statErr == nil {
+			d.lastStamp = st
+		}
+		err = fmt.Errorf("rejected replacement database %s: %w", d.path, err)
+		d.setReloadState(false, err)
+		return err
+	}
+	d.lastStamp = stamp
+	defer d.setReloadState(false, nil)
+
+	d.mu.Lock()
+	previous := d.current
+	if previous == nil {
+		d.mu.Unlock()
+		handle.reader.Close()
+		return ErrServiceClosed
+	}
+	d.current = handle
+	d.generation.Add(1)
+	d.mu.Unlock()
+
+	// Let lookups that started on the previous reader finish before releasing it.
+	previous.inFlight.Wait()
+	return previous.reader.Close()
+}
+
+// setReloadState records whether a reload is running and the error of the most recent one.
+func (d *database) setReloadState(reloading bool, err error) {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+	d.reloading = reloading
+	d.reloadErr = err
+}
+
+// ready reports why the database cannot be relied on to serve lookups, or nil if it can.
+//
+// A database is not ready while it is closed or being reloaded, after a replacement file was rejected as corrupt
+// (until a later reload succeeds), and while its file is missing from disk, even though the reader loaded
+// earlier keeps answering lookups in each of these cases but the closed one.
+func (d *database) ready() error {
+	d.mu.RLock()
+	closed := d.current == nil
+	d.mu.RUnlock()
+	if closed {
+		return ErrServiceClosed
+	}
+
+	d.stateMu.Lock()
+	reloading, reloadErr := d.reloading, d.reloadErr
+	d.stateMu.Unlock()
+	switch {
+	case reloading:
+		return ErrReloading
+	case reloadErr != nil:
+		return reloadErr
+	}
+
+	if _, err := statFile(d.path); err != nil {
+		return fmt.Errorf("database file unavailable: %w", err)
+	}
+	return nil
+}
+
+// info describes the reader currently serving lookups; ok is false once the database is closed.
+func (d *database) info() (DatabaseInfo, bool) {
+	handle, err := d.acquire()
+	if err != nil {
+		return DatabaseInfo{}, false
+	}
+	defer handle.inFlight.Done()
+
+	metadata := handle.reader.Metadata()
+	return DatabaseInfo{
+		Role:       d.kind.String(),
+		Path:       d.path,
+		Type:       metadata.DatabaseType,
+		BuildEpoch: time.Unix(int64(metadata.BuildEpoch), 0).UTC(),
+	}, true
+}
+
+// changedOnDisk reports whether the file differs from the version last loaded or rejected.
+func (d *database) changedOnDisk() bool {
+	stamp, err := statFile(d.path)
+	if err != nil {
+		// A missing file is treated as unchanged; the current reader keeps serving until a new file appears.
+		return false
+	}
+
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+	return !stamp.modTime.Equal(d.lastStamp.modTime) || stamp.size != d.lastStamp.size
+}
+
+// close waits for running lookups to finish and releases the reader; later lookups fail with ErrServiceClosed.
+func (d *database) close() error {
+	d.mu.Lock()
+	handle := d.current
+	d.current = nil
+	d.mu.Unlock()
+
+	if handle == nil {
+		return nil
+	}
+
+	handle.inFlight.Wait()
+	return handle.reader.Close()
+}
+
+// openDatabase reads and validates the database file at dbPath and returns a reader over its contents.
+//
+// The file is read into memory instead of memory-mapped, so that the file on disk can be overwritten
+// in place by an updater without corrupting the reader that is serving lookups.
+func openDatabase(dbPath string, kind databaseKind) (*dbHandle, fileStamp, error) {
+	stamp, err := statFile(dbPath)
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+
+	data, err := os.ReadFile(dbPath)
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+
+	// Walk the whole search tree and data section so truncated or corrupt files are caught up front
+	// rather than on the first unlucky lookup.
+	raw, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+	if err := raw.Verify(); err != nil {
+		return nil, fileStamp{}, fmt.Errorf("database verification failed: %w", err)
+	}
+
+	reader, err := geoip2.FromBytes(data)
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+
+	// Reject databases that cannot answer the lookups they are configured for (e.g. an ASN database dropped in
+	// place of the country database by mistake).
+	probe := func(ip net.IP) error { _, err := reader.Country(ip); return err }
+	if kind == kindASN {
+		probe = func(ip net.IP) error { _, err := reader.ASN(ip); return err }
+	}
+	if err := probe(net.IPv4zero); err != nil {
+		var methodErr geoip2.InvalidMethodError
+		if errors.As(err, &methodErr) {
+			reader.Close()
+			return nil, fileStamp{}, fmt.Errorf("unsupported database type %q", reader.Metadata().DatabaseType)
+		}
+	}
+
+	return &dbHandle{reader: reader, raw: raw, city: hasCityData(reader.Metadata().DatabaseType)}, stamp, nil
+}
+
+// hasCityData reports whether a database_type metadata value names a database with city-level data,
+// e.g. "GeoLite2-City", "GeoIP2-Enterprise" or "DBIP-Location (compat=City)". geoip2 accepts City lookups on
+// Country databases too, so the reader's method checks cannot tell them apart.
+func hasCityData(databaseType string) bool {
+	return strings.Contains(databaseType, "City") || strings.Contains(databaseType, "Enterprise")
+}
+
+// statFile returns the modification time and size of the file at path.
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
@@ -0,0 +1,382 @@
+// Package geo looks up the location of IP addresses in MaxMind GeoIP2/GeoLite2 databases, with optional caching
+// and hot reload of the database file.
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// LookupService defines methods for IP-based geolocation queries.
+type LookupService interface {
+	// CountryISOCode retrieves the ISO 3166-1 alpha-2 country code (e.g., "US") for the given IP address.
+	CountryISOCode(ipStr string) (string, error)
+
+	// Lookup retrieves the full geolocation data for the given IP address, with names in the given locale.
+	Lookup(ipStr, locale string) (*Location, error)
+
+	// Close safely releases any underlying resources associated with the LookupService.
+	Close() error
+}
+
+// GeoLookupService implements the LookupService interface using the MaxMind GeoIP2 database.
+//
+// Next to the required country (or city) database, an ASN database can be loaded to add the autonomous system of
+// an IP address to lookups (see WithASNDatabase).
+//
+// The databases can be replaced while the service is running (see Reload and Watch). Lookups always run
+// against a consistent reader: a replacement reader is swapped in atomically, and the previous reader
+// is only closed once every lookup that started on it has finished.
+type GeoLookupService struct {
+	location *database // Country, City or Enterprise database; always present.
+	asn      *database // GeoLite2-ASN or GeoIP2-ISP database; nil unless configured.
+}
+
+// Option configures optional databases of a GeoLookupService.
+type Option func(*options)
+
+// options collects the settings applied by Option values.
+type options struct {
+	asnPath string
+}
+
+// WithASNDatabase loads the GeoLite2-ASN (or GeoIP2-ISP) database at path next to the country database,
+// so that lookups include the autonomous system number and organization of IP addresses.
+//
+// Parameters:
+//   - path: File system path to the ASN database file; an empty path leaves ASN lookups disabled.
+//
+// Returns:
+//   - Option: The option to pass to NewGeoLookupService.
+func WithASNDatabase(path string) Option {
+	return func(o *options) {
+		o.asnPath = path
+	}
+}
+
+// NewGeoLookupService initializes and returns a new GeoLookupService instance.
+// It opens the GeoIP2 database located at the specified file path, and any optional database requested by opts.
+//
+// Parameters:
+//   - dbPath: File system path to the MaxMind GeoLite2 or GeoIP2 Country or City database file.
+//   - opts: Optional databases to load as well (e.g., WithASNDatabase).
+//
+// Returns:
+//   - *GeoLookupService: An initialized GeoLookupService instance.
+//   - error: Error if any database could not be opened successfully.
+func NewGeoLookupService(dbPath string, opts ...Option) (*GeoLookupService, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	location, err := openReloadable(dbPath, kindLocation)
+	if err != nil {
+		return nil, err
+	}
+	g := &GeoLookupService{location: location}
+
+	if o.asnPath != "" {
+		if g.asn, err = openReloadable(o.asnPath, kindASN); err != nil {
+			location.close()
+			return nil, fmt.Errorf("opening ASN database: %w", err)
+		}
+	}
+	return g, nil
+}
+
+// CountryISOCode takes an IP address string and returns the corresponding two-letter country ISO code.
+// It is suitable for quick lookup operations.
+//
+// Parameters:
+//   - ipStr: String representation of the IP address to be checked.
+//
+// Returns:
+//   - string: The ISO 3166-1 alpha-2 country code (e.g., "US") associated with the provided IP.
+//   - error: An error if the IP format is invalid, or if the lookup operation fails.
+func (g *GeoLookupService) CountryISOCode(ipStr string) (string, error) {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return "", ErrInvalidIP
+	}
+
+	handle, err := g.location.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer handle.inFlight.Done()
+
+	record, err := handle.reader.Country(ip)
+	if err != nil {
+		return "", err
+	}
+
+	return record.Country.IsoCode, nil
+}
+
+// Lookup takes an IP address string and returns everything the databases store about its location:
+// continent, country, registered and represented country, and the anonymous-proxy, anycast and satellite flags.
+// City databases additionally provide the city, subdivisions, postal code and accuracy radius, and an ASN database
+// the autonomous system number and organization.
+//
+// Parameters:
+//   - ipStr: String representation of the IP address to be looked up.
+//   - locale: The locale names are returned in (e.g., "de"); names missing in that locale fall back to DefaultLocale.
+//
+// Returns:
+//   - *Location: The geolocation data associated with the provided IP.
+//   - error: ErrInvalidIP if the IP format is invalid, ErrNotFound if no database has a record for the IP,
+//     or an error if the lookup operation fails.
+func (g *GeoLookupService) Lookup(ipStr, locale string) (*Location, error) {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return nil, ErrInvalidIP
+	}
+	if locale == "" {
+		locale = DefaultLocale
+	}
+
+	location, foundLocation, err := g.lookupLocation(ip, locale)
+	if err != nil {
+		return nil, err
+	}
+	foundASN, err := g.lookupASN(ip, location)
+	if err != nil {
+		return nil, err
+	}
+
+	if !foundLocation && !foundASN {
+		return nil, ErrNotFound
+	}
+	return location, nil
+}
+
+// lookupLocation fills a Location from the country or city database.
+func (g *GeoLookupService) lookupLocation(ip net.IP, locale string) (*Location, bool, error) {
+	handle, err := g.location.acquire()
+	if err != nil {
+		return nil, false, err
+	}
+	defer handle.inFlight.Done()
+
+	// City records are a superset of country records, so one decoding target serves both database types.
+	var record geoip2.City
+	_, found, err := handle.raw.LookupNetwork(ip, &record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	location := &Location{
+		Continent: Continent{
+			Code: record.Continent.Code,
+			Name: localizedName(record.Continent.Names, locale),
+		},
+		Country: Country{
+			ISOCode:           record.Country.IsoCode,
+			Name:              localizedName(record.Country.Names, locale),
+			IsInEuropeanUnion: record.Country.IsInEuropeanUnion,
+		},
+		RegisteredCountry: Country{
+			ISOCode:           record.RegisteredCountry.IsoCode,
+			Name:              localizedName(record.RegisteredCountry.Names, locale),
+			IsInEuropeanUnion: record.RegisteredCountry.IsInEuropeanUnion,
+		},
+		RepresentedCountry: RepresentedCountry{
+			Country: Country{
+				ISOCode:           record.RepresentedCountry.IsoCode,
+				Name:              localizedName(record.RepresentedCountry.Names, locale),
+				IsInEuropeanUnion: record.RepresentedCountry.IsInEuropeanUnion,
+			},
+			Type: record.RepresentedCountry.Type,
+		},
+		City:                localizedName(record.City.Names, locale),
+		PostalCode:          record.Postal.Code,
+		AccuracyRadius:      record.Location.AccuracyRadius,
+		IsAnonymousProxy:    record.Traits.IsAnonymousProxy,
+		IsAnycast:           record.Traits.IsAnycast,
+		IsSatelliteProvider: record.Traits.IsSatelliteProvider,
+	}
+	for _, subdivision := range record.Subdivisions {
+		location.Subdivisions = append(location.Subdivisions, Subdivision{
+			ISOCode: subdivisionCode(record.Country.IsoCode, subdivision.IsoCode),
+			Name:    localizedName(subdivision.Names, locale),
+		})
+	}
+	return location, found, nil
+}
+
+// lookupASN adds the autonomous system of ip to location, if an ASN database is loaded.
+func (g *GeoLookupService) lookupASN(ip net.IP, location *Location) (bool, error) {
+	if g.asn == nil {
+		return false, nil
+	}
+
+	handle, err := g.asn.acquire()
+	if err != nil {
+		return false, err
+	}
+	defer handle.inFlight.Done()
+
+	var record geoip2.ASN
+	_, found, err := handle.raw.LookupNetwork(ip, &record)
+	if err != nil {
+		return false, err
+	}
+
+	location.ASN = record.AutonomousSystemNumber
+	location.ASOrganization = record.AutonomousSystemOrganization
+	return found, nil
+}
+
+// HasCityData reports whether the database currently loaded is a City (or Enterprise) database, i.e. whether
+// lookups can return cities and subdivisions. The answer may change when the database is reloaded.
+//
+// Returns:
+//   - bool: true if the loaded database has city-level data; false for Country databases or a closed service.
+func (g *GeoLookupService) HasCityData() bool {
+	handle, err := g.location.acquire()
+	if err != nil {
+		return false
+	}
+	defer handle.inFlight.Done()
+	return handle.city
+}
+
+// HasASNData reports whether an ASN database is loaded, i.e. whether lookups can return autonomous systems.
+//
+// Returns:
+//   - bool: true if the service was created with WithASNDatabase.
+func (g *GeoLookupService) HasASNData() bool {
+	return g.asn != nil
+}
+
+// Reload opens every database file again and atomically swaps each in for the reader currently serving lookups.
+//
+// Each replacement file is fully validated before it is used. If it is missing, truncated or not a MaxMind
+// database of the expected type, its error is returned and the existing reader keeps serving; the other databases
+// are still reloaded. On success, Reload waits for lookups still running on each previous reader to drain and
+// then closes it.
+//
+// Returns:
+//   - error: An error if a replacement database was rejected or a previous reader failed to close.
+func (g *GeoLookupService) Reload() error {
+	var errs []error
+	for _, db := range g.databases() {
+		errs = append(errs, db.reload())
+	}
+	return errors.Join(errs...)
+}
+
+// Close releases the internal resources used by the GeoLookupService.
+// This should be called when the service is no longer needed to avoid resource leaks.
+// Lookups still running are allowed to finish before the databases are closed; lookups issued afterwards
+// fail with ErrServiceClosed.
+//
+// Returns:
+//   - error: An error if closing a database resource fails.
+func (g *GeoLookupService) Close() error {
+	var errs []error
+	for _, db := range g.databases() {
+		errs = append(errs, db.close())
+	}
+	return errors.Join(errs...)
+}
+
+// Ready reports whether every database is in a state to serve lookups reliably.
+//
+// A database is not ready while its file is missing from disk, after a replacement file was rejected as corrupt
+// (until a later reload succeeds), while it is being reloaded, and once the service is closed. Readiness probes
+// use it to take the instance out of rotation until the database is healthy again.
+//
+// Returns:
+//   - error: nil if every database is ready; otherwise an error naming each database that is not, and why.
+func (g *GeoLookupService) Ready() error {
+	var errs []error
+	for _, db := range g.databases() {
+		if err := db.ready(); err != nil {
+			errs = append(errs, fmt.Errorf("%s database: %w", db.kind, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DatabaseInfo describes a database loaded by a GeoLookupService.
+type DatabaseInfo struct {
+	// Role is what the database is used for: "location" (country or city data) or "asn".
+	Role string
+
+	// Path is the file system path the database was loaded from.
+	Path string
+
+	// Type is the database_type metadata value (e.g., "GeoLite2-Country").
+	Type string
+
+	// BuildEpoch is when MaxMind built the database.
+	BuildEpoch time.Time
+}
+
+// Databases describes the databases currently serving lookups. The result reflects reloads.
+//
+// Returns:
+//   - []DatabaseInfo: One entry per loaded database, the location database first; nil once the service is closed.
+func (g *GeoLookupService) Databases() []DatabaseInfo {
+	var infos []DatabaseInfo
+	for _, db := range g.databases() {
+		if info, ok := db.info(); ok {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// Generation returns a counter that changes whenever a reload swaps in a replacement database, so that results
+// derived from lookups (e.g., cached ones) can be recognised as stale.
+//
+// Returns:
+//   - uint64: The current generation; it only ever increases.
+func (g *GeoLookupService) Generation() uint64 {
+	var generation uint64
+	for _, db := range g.databases() {
+		generation += db.generation.Load()
+	}
+	return generation
+}
+
+// databases returns every database loaded by the service.
+func (g *GeoLookupService) databases() []*database {
+	if g.asn == nil {
+		return []*database{g.location}
+	}
+	return []*database{g.location, g.asn}
+}
+
+// ErrInvalidIP represents an error returned when the provided IP address is incorrectly formatted.
+var ErrInvalidIP = &InvalidIPError{"invalid IP address format"}
+
+// ErrNotFound is returned by LookupService implementations that can tell a well-formed IP address has no
+// geolocation record. GeoLookupService returns it from Lookup, but reports such addresses with an empty country
+// code from CountryISOCode.
+var ErrNotFound = errors.New("no geolocation record for IP address")
+
+// ErrServiceClosed is returned by lookups issued after the GeoLookupService has been closed.
+var ErrServiceClosed = errors.New("geo lookup service is closed")
+
+// ErrReloading is reported by Ready while a replacement database is being opened and validated.
+var ErrReloading = errors.New("database is being reloaded")
+
+// InvalidIPError indicates an error encountered during IP parsing due to invalid format.
+type InvalidIPError struct {
+	msg string
+}
+
+// Error satisfies the error interface for InvalidIPError and returns the error message.
+//
+// Returns:
+//   - string: Descriptive error message indicating why the IP was considered invalid.
+func (e *InvalidIPError) Error() string {
+	return e.msg
+}
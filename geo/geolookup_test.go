@@ -0,0 +1,285 @@
+package geo_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/geo/geotest"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestGeoLookupService_Reload_SwapsDatabase verifies that Reload serves answers from the replacement file.
+func TestGeoLookupService_Reload_SwapsDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB"})
+
+	svc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	defer svc.Close()
+
+	country, err := svc.CountryISOCode("81.2.69.142")
+	require.NoError(t, err)
+	assert.Equal(t, "GB", country)
+
+	// Replace the database on disk and reload it.
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "IE"})
+	require.NoError(t, svc.Reload())
+
+	country, err = svc.CountryISOCode("81.2.69.142")
+	require.NoError(t, err)
+	assert.Equal(t, "IE", country, "Expected lookups to use the reloaded database.")
+}
+
+// TestGeoLookupService_Reload_RejectsCorruptFile verifies that a truncated or empty replacement file is rejected
+// and the previously loaded database keeps serving lookups.
+func TestGeoLookupService_Reload_RejectsCorruptFile(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB"})
+
+	svc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	defer svc.Close()
+
+	valid, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+
+	for name, contents := range map[string][]byte{
+		"truncated": valid[:len(valid)/2],
+		"empty":     {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(dbPath, contents, 0o644))
+			assert.Error(t, svc.Reload(), "Expected the corrupt replacement to be rejected.")
+
+			country, err := svc.CountryISOCode("81.2.69.142")
+			require.NoError(t, err)
+			assert.Equal(t, "GB", country, "Expected the previous database to keep serving.")
+		})
+	}
+}
+
+// TestGeoLookupService_Reload_ConcurrentLookups verifies that lookups running while the database is swapped
+// never observe a closed reader. Run with -race to also check for data races.
+func TestGeoLookupService_Reload_ConcurrentLookups(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB"})
+
+	svc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	defer svc.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				country, err := svc.CountryISOCode("81.2.69.142")
+				assert.NoError(t, err)
+				assert.Equal(t, "GB", country)
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, svc.Reload())
+	}
+	cancel()
+	wg.Wait()
+}
+
+// TestGeoLookupService_Watch_ReloadsOnFileChange verifies that the watcher picks up a replaced database file.
+func TestGeoLookupService_Watch_ReloadsOnFileChange(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB"})
+
+	svc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	defer svc.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go svc.Watch(ctx, 10*time.Millisecond, zap.NewNop())
+
+	// Write the replacement next to the original and rename it into place, as database updaters do.
+	next := dbPath + ".next"
+	geotest.WriteCountryDB(t, next, map[string]string{"81.2.69.0/24": "IE", "2.125.160.0/24": "GB"})
+	require.NoError(t, os.Rename(next, dbPath))
+
+	assert.Eventually(t, func() bool {
+		country, err := svc.CountryISOCode("81.2.69.142")
+		return err == nil && country == "IE"
+	}, 2*time.Second, 10*time.Millisecond, "Expected the watcher to reload the changed database.")
+}
+
+// TestGeoLookupService_Close verifies that lookups after Close fail instead of touching a released reader.
+func TestGeoLookupService_Close(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB"})
+
+	svc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, svc.Close())
+
+	_, err = svc.CountryISOCode("81.2.69.142")
+	assert.ErrorIs(t, err, geo.ErrServiceClosed)
+}
+
+// TestGeoLookupService_Ready verifies that the service is reported as not ready while its database file is missing,
+// after a corrupt replacement was rejected, and once closed, and as ready again after a successful reload.
+func TestGeoLookupService_Ready(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB"})
+
+	svc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	assert.NoError(t, svc.Ready())
+
+	// A missing file is not ready, although the loaded reader keeps serving.
+	require.NoError(t, os.Rename(dbPath, dbPath+".bak"))
+	assert.ErrorContains(t, svc.Ready(), "location database: database file unavailable")
+	_, err = svc.CountryISOCode("81.2.69.142")
+	assert.NoError(t, err)
+	require.NoError(t, os.Rename(dbPath+".bak", dbPath))
+	assert.NoError(t, svc.Ready())
+
+	// A rejected replacement is not ready until a valid file is reloaded.
+	require.NoError(t, os.WriteFile(dbPath, []byte("not a database"), 0o644))
+	require.Error(t, svc.Reload())
+	assert.ErrorContains(t, svc.Ready(), "rejected replacement database")
+
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "IE"})
+	require.NoError(t, svc.Reload())
+	assert.NoError(t, svc.Ready())
+
+	require.NoError(t, svc.Close())
+	assert.ErrorIs(t, svc.Ready(), geo.ErrServiceClosed)
+}
+
+// TestGeoLookupService_Lookup verifies that the full record is returned with names in the requested locale,
+// falling back to English, and that addresses without a record are reported as not found.
+func TestGeoLookupService_Lookup(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteDatabase(t, dbPath, "GeoLite2-Country", map[string]mmdbtype.Map{"81.2.69.0/24": geotest.SatelliteRecord()})
+
+	svc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	defer svc.Close()
+
+	location, err := svc.Lookup("81.2.69.142", "de")
+	require.NoError(t, err)
+	assert.Equal(t, &geo.Location{
+		Continent:           geo.Continent{Code: "EU", Name: "Europa"},
+		Country:             geo.Country{ISOCode: "DE", Name: "Deutschland", IsInEuropeanUnion: true},
+		RegisteredCountry:   geo.Country{ISOCode: "CH", Name: "Schweiz"},
+		IsSatelliteProvider: true,
+	}, location)
+
+	location, err = svc.Lookup("81.2.69.142", "ja")
+	require.NoError(t, err)
+	assert.Equal(t, "Germany", location.Country.Name, "Expected names missing in the locale to fall back to English.")
+
+	_, err = svc.Lookup("192.0.2.1", "en")
+	assert.ErrorIs(t, err, geo.ErrNotFound)
+
+	_, err = svc.Lookup("not-an-ip", "en")
+	assert.ErrorIs(t, err, geo.ErrInvalidIP)
+}
+
+// TestGeoLookupService_Lookup_CityDatabase verifies that City databases are detected from their metadata and that
+// lookups return city, ISO 3166-2 subdivision, postal code and accuracy radius.
+func TestGeoLookupService_Lookup_CityDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "city.mmdb")
+	geotest.WriteDatabase(t, dbPath, "GeoLite2-City", map[string]mmdbtype.Map{
+		"128.101.101.0/24": geotest.CityRecord("US", "CA", "San Francisco", "94107"),
+	})
+
+	svc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	defer svc.Close()
+	assert.True(t, svc.HasCityData())
+
+	location, err := svc.Lookup("128.101.101.101", "en")
+	require.NoError(t, err)
+	assert.Equal(t, "US", location.Country.ISOCode)
+	assert.Equal(t, "San Francisco", location.City)
+	assert.Equal(t, []string{"US-CA"}, location.SubdivisionCodes())
+	assert.Equal(t, "94107", location.PostalCode)
+	assert.Equal(t, uint16(20), location.AccuracyRadius)
+
+	country, err := svc.CountryISOCode("128.101.101.101")
+	require.NoError(t, err)
+	assert.Equal(t, "US", country, "Expected country lookups to work on City databases.")
+
+	// Swapping in a Country database drops the city-level data.
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"128.101.101.0/24": "US"})
+	require.NoError(t, svc.Reload())
+	assert.False(t, svc.HasCityData())
+}
+
+// TestGeoLookupService_Lookup_ASNDatabase verifies that lookups include the autonomous system from the ASN database,
+// that a record in either database is enough for a result, and that the ASN database is reloaded too.
+func TestGeoLookupService_Lookup_ASNDatabase(t *testing.T) {
+	dir := t.TempDir()
+	countryPath := filepath.Join(dir, "country.mmdb")
+	geotest.WriteCountryDB(t, countryPath, map[string]string{"2.160.0.0/16": "DE"})
+	asnPath := filepath.Join(dir, "asn.mmdb")
+	geotest.WriteDatabase(t, asnPath, "GeoLite2-ASN", map[string]mmdbtype.Map{
+		"2.160.0.0/16": geotest.ASNRecord(3320, "Deutsche Telekom AG"),
+		"192.0.2.0/24": geotest.ASNRecord(64496, "Example Anycast"),
+	})
+
+	svc, err := geo.NewGeoLookupService(countryPath, geo.WithASNDatabase(asnPath))
+	require.NoError(t, err)
+	defer svc.Close()
+	assert.True(t, svc.HasASNData())
+
+	location, err := svc.Lookup("2.160.0.1", "en")
+	require.NoError(t, err)
+	assert.Equal(t, "DE", location.Country.ISOCode)
+	assert.Equal(t, uint(3320), location.ASN)
+	assert.Equal(t, "Deutsche Telekom AG", location.ASOrganization)
+
+	// A record in the ASN database alone is enough for a result.
+	location, err = svc.Lookup("192.0.2.1", "en")
+	require.NoError(t, err)
+	assert.Empty(t, location.Country.ISOCode)
+	assert.Equal(t, uint(64496), location.ASN)
+
+	_, err = svc.Lookup("10.0.0.1", "en")
+	assert.ErrorIs(t, err, geo.ErrNotFound)
+
+	// Reloading picks up a replaced ASN database as well.
+	geotest.WriteDatabase(t, asnPath, "GeoLite2-ASN", map[string]mmdbtype.Map{
+		"2.160.0.0/16": geotest.ASNRecord(3320, "Telekom Deutschland GmbH"),
+	})
+	require.NoError(t, svc.Reload())
+	location, err = svc.Lookup("2.160.0.1", "en")
+	require.NoError(t, err)
+	assert.Equal(t, "Telekom Deutschland GmbH", location.ASOrganization)
+}
+
+// TestNewGeoLookupService_RejectsMismatchedASNDatabase verifies that a database that cannot answer ASN lookups is
+// rejected as the ASN database.
+func TestNewGeoLookupService_RejectsMismatchedASNDatabase(t *testing.T) {
+	countryPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, countryPath, map[string]string{"2.160.0.0/16": "DE"})
+
+	// A country database configured as the ASN database cannot answer ASN lookups.
+	_, err := geo.NewGeoLookupService(countryPath, geo.WithASNDatabase(countryPath))
+	assert.ErrorContains(t, err, "unsupported database type")
+
+	svc, err := geo.NewGeoLookupService(countryPath)
+	require.NoError(t, err)
+	defer svc.Close()
+	assert.False(t, svc.HasASNData())
+}
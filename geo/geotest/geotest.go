@@ -0,0 +1,143 @@
+// Package geotest builds small MaxMind databases on disk for tests that need a real GeoLookupService.
+package geotest
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+)
+
+// WriteDatabase writes a MaxMind database of the given type to path, storing each record under its network.
+//
+// Parameters:
+//   - tb: The running test; any failure aborts it.
+//   - path: Destination file path; an existing file is replaced.
+//   - databaseType: The database_type metadata value (e.g., "GeoLite2-Country").
+//   - records: Map of CIDR network (e.g., "81.2.69.0/24") to the record stored for it.
+func WriteDatabase(tb testing.TB, path, databaseType string, records map[string]mmdbtype.Map) {
+	tb.Helper()
+
+	tree, err := mmdbwriter.New(mmdbwriter.Options{
+		DatabaseType:            databaseType,
+		Description:             map[string]string{"en": "ipchecker test database"},
+		Languages:               []string{"en"},
+		IncludeReservedNetworks: true,
+	})
+	if err != nil {
+		tb.Fatalf("geotest: creating tree: %v", err)
+	}
+
+	for cidr, record := range records {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			tb.Fatalf("geotest: parsing network %q: %v", cidr, err)
+		}
+		if err := tree.Insert(network, record); err != nil {
+			tb.Fatalf("geotest: inserting %q: %v", cidr, err)
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		tb.Fatalf("geotest: creating %s: %v", path, err)
+	}
+	defer f.Close()
+
+	if _, err := tree.WriteTo(f); err != nil {
+		tb.Fatalf("geotest: writing %s: %v", path, err)
+	}
+}
+
+// WriteCountryDB writes a GeoLite2-Country database to path mapping each CIDR network to an ISO country code.
+//
+// Parameters:
+//   - tb: The running test; any failure aborts it.
+//   - path: Destination file path; an existing file is replaced.
+//   - countries: Map of CIDR network to ISO 3166-1 alpha-2 country code.
+func WriteCountryDB(tb testing.TB, path string, countries map[string]string) {
+	tb.Helper()
+
+	records := make(map[string]mmdbtype.Map, len(countries))
+	for cidr, iso := range countries {
+		records[cidr] = CountryRecord(iso)
+	}
+	WriteDatabase(tb, path, "GeoLite2-Country", records)
+}
+
+// CountryRecord returns a minimal country record with the given ISO 3166-1 alpha-2 code.
+func CountryRecord(iso string) mmdbtype.Map {
+	return mmdbtype.Map{
+		"country": mmdbtype.Map{
+			"iso_code": mmdbtype.String(iso),
+		},
+	}
+}
+
+// CityRecord returns a minimal city record: a country, one subdivision, a city name and postal code, and an
+// accuracy radius of 20 km.
+//
+// Parameters:
+//   - iso: The ISO 3166-1 alpha-2 country code (e.g., "US").
+//   - subdivision: The subdivision part of the ISO 3166-2 code as MaxMind stores it (e.g., "CA" for "US-CA").
+//   - city: The English city name.
+//   - postal: The postal code.
+func CityRecord(iso, subdivision, city, postal string) mmdbtype.Map {
+	return mmdbtype.Map{
+		"country": mmdbtype.Map{
+			"iso_code": mmdbtype.String(iso),
+		},
+		"subdivisions": mmdbtype.Slice{
+			mmdbtype.Map{"iso_code": mmdbtype.String(subdivision)},
+		},
+		"city": mmdbtype.Map{
+			"names": mmdbtype.Map{"en": mmdbtype.String(city)},
+		},
+		"postal": mmdbtype.Map{
+			"code": mmdbtype.String(postal),
+		},
+		"location": mmdbtype.Map{
+			"accuracy_radius": mmdbtype.Uint16(20),
+		},
+	}
+}
+
+// SatelliteRecord returns a complete country record for a satellite provider located in Germany (EU) whose network
+// is registered in Switzerland, with English and German names.
+func SatelliteRecord() mmdbtype.Map {
+	names := func(en, de string) mmdbtype.Map {
+		return mmdbtype.Map{"en": mmdbtype.String(en), "de": mmdbtype.String(de)}
+	}
+	return mmdbtype.Map{
+		"continent": mmdbtype.Map{
+			"code":  mmdbtype.String("EU"),
+			"names": names("Europe", "Europa"),
+		},
+		"country": mmdbtype.Map{
+			"iso_code":             mmdbtype.String("DE"),
+			"names":                names("Germany", "Deutschland"),
+			"is_in_european_union": mmdbtype.Bool(true),
+		},
+		"registered_country": mmdbtype.Map{
+			"iso_code": mmdbtype.String("CH"),
+			"names":    names("Switzerland", "Schweiz"),
+		},
+		"traits": mmdbtype.Map{
+			"is_satellite_provider": mmdbtype.Bool(true),
+		},
+	}
+}
+
+// ASNRecord returns a GeoLite2-ASN record for the given autonomous system.
+//
+// Parameters:
+//   - number: The autonomous system number (e.g., 3320).
+//   - organization: The organization owning the autonomous system (e.g., "Deutsche Telekom AG").
+func ASNRecord(number uint32, organization string) mmdbtype.Map {
+	return mmdbtype.Map{
+		"autonomous_system_number":       mmdbtype.Uint32(number),
+		"autonomous_system_organization": mmdbtype.String(organization),
+	}
+}
@@ -0,0 +1,121 @@
+package geo
+
+// DefaultLocale is the locale names are reported in when the requested locale is empty or not present in the
+// database. Every MaxMind database includes English names.
+const DefaultLocale = "en"
+
+// Location holds the geolocation data a database stores for an IP address.
+//
+// Places the database has no data for are left as their zero value (empty ISO code and name).
+type Location struct {
+	// Continent is the continent the IP address is located in.
+	Continent Continent
+
+	// Country is the country the IP address is located in.
+	Country Country
+
+	// RegisteredCountry is the country the ISP registered the network in, which may differ from Country.
+	RegisteredCountry Country
+
+	// RepresentedCountry is the country represented by users of the IP address, such as the home country of
+	// a military base or embassy; usually empty.
+	RepresentedCountry RepresentedCountry
+
+	// City is the city name in the requested locale; City databases only.
+	City string
+
+	// Subdivisions lists the subdivisions (states, provinces, ...) the IP address is located in, from the largest
+	// to the smallest; City databases only.
+	Subdivisions []Subdivision
+
+	// PostalCode is the postal code of the location; City databases only.
+	PostalCode string
+
+	// AccuracyRadius is the radius in kilometers around the location within which the IP address is likely to be;
+	// City databases only, 0 if unknown.
+	AccuracyRadius uint16
+
+	// ASN is the autonomous system number the IP address is announced by; ASN database only, 0 if unknown.
+	ASN uint
+
+	// ASOrganization is the organization owning the autonomous system (e.g., "Deutsche Telekom AG");
+	// ASN database only.
+	ASOrganization string
+
+	// IsAnonymousProxy reports whether the IP address belongs to an anonymous proxy.
+	IsAnonymousProxy bool
+
+	// IsAnycast reports whether the network is announced from several locations.
+	IsAnycast bool
+
+	// IsSatelliteProvider reports whether the IP address belongs to a satellite internet provider, whose users
+	// may be located anywhere in its coverage area.
+	IsSatelliteProvider bool
+}
+
+// Continent identifies a continent.
+type Continent struct {
+	// Code is the two-letter continent code (e.g., "EU").
+	Code string
+
+	// Name is the continent name in the requested locale.
+	Name string
+}
+
+// Country identifies a country.
+type Country struct {
+	// ISOCode is the ISO 3166-1 alpha-2 country code (e.g., "DE").
+	ISOCode string
+
+	// Name is the country name in the requested locale.
+	Name string
+
+	// IsInEuropeanUnion reports whether the country is a member state of the European Union.
+	IsInEuropeanUnion bool
+}
+
+// RepresentedCountry identifies the country represented by the users of an IP address.
+type RepresentedCountry struct {
+	Country
+
+	// Type describes the kind of representation (e.g., "military").
+	Type string
+}
+
+// Subdivision identifies a subdivision of a country, such as a state or province.
+type Subdivision struct {
+	// ISOCode is the ISO 3166-2 subdivision code including the country prefix (e.g., "US-CA").
+	ISOCode string
+
+	// Name is the subdivision name in the requested locale.
+	Name string
+}
+
+// SubdivisionCodes returns the ISO 3166-2 codes of the subdivisions of the location, from the largest to the smallest.
+//
+// Returns:
+//   - []string: The subdivision codes (e.g., ["US-CA"]); nil if none are known.
+func (l *Location) SubdivisionCodes() []string {
+	var codes []string
+	for _, subdivision := range l.Subdivisions {
+		codes = append(codes, subdivision.ISOCode)
+	}
+	return codes
+}
+
+// subdivisionCode builds an ISO 3166-2 code from a country code and the subdivision part MaxMind stores
+// (e.g., "US" and "CA" give "US-CA").
+func subdivisionCode(country, subdivision string) string {
+	if country == "" || subdivision == "" {
+		return subdivision
+	}
+	return country + "-" + subdivision
+}
+
+// localizedName returns the name for locale from a MaxMind names map, falling back to DefaultLocale.
+func localizedName(names map[string]string, locale string) string {
+	if name, ok := names[locale]; ok {
+		return name
+	}
+	return names[DefaultLocale]
+}
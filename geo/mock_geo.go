@@ -8,6 +8,12 @@ type MockGeoLookupService struct {
 
 	// MockError is the predefined error to return when simulating error scenarios.
 	MockError error
+
+	// MockSubdivisions are the predefined ISO 3166-2 subdivision codes (e.g., "US-CA") returned by Lookup.
+	MockSubdivisions []string
+
+	// MockASN is the predefined autonomous system number returned by Lookup; 0 simulates no ASN database.
+	MockASN uint
 }
 
 // NewMockGeoLookupService initializes a new MockGeoLookupService with the specified
@@ -42,6 +48,28 @@ func (m *MockGeoLookupService) CountryISOCode(ipAddress string) (string, error)
 	return m.MockCountryCode, nil
 }
 
+// Lookup simulates retrieving the full geolocation data for a given IP address.
+// Returns either a location holding only the configured mock country code or the configured mock error.
+//
+// Parameters:
+//   - ipAddress: The IP address string to look up (ignored by the mock implementation).
+//   - locale: The requested locale (ignored by the mock implementation).
+//
+// Returns:
+//   - *Location: A location holding the predefined mock country code, subdivisions and ASN, if no mock error is specified.
+//   - error: The predefined mock error, if any; otherwise nil.
+func (m *MockGeoLookupService) Lookup(ipAddress, locale string) (*Location, error) {
+	if m.MockError != nil {
+		return nil, m.MockError
+	}
+
+	location := &Location{Country: Country{ISOCode: m.MockCountryCode}, ASN: m.MockASN}
+	for _, code := range m.MockSubdivisions {
+		location.Subdivisions = append(location.Subdivisions, Subdivision{ISOCode: code})
+	}
+	return location, nil
+}
+
 // Close is a mock implementation to satisfy the LookupService interface.
 // It performs no operation and always returns nil.
 //
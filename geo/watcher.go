@@ -0,0 +1,73 @@
+package geo
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Watch keeps the GeoLookupService in sync with its database files on disk until ctx is cancelled.
+//
+// A reload is triggered when:
+//   - A file's modification time or size changes (checked every interval; polling is disabled if interval <= 0).
+//     Only the changed database is reloaded.
+//   - The process receives SIGHUP, which forces a reload of every database even if the files look unchanged.
+//
+// Rejected replacement files are logged and the current reader keeps serving; the same file is not
+// retried until it changes again or another SIGHUP arrives.
+//
+// Parameters:
+//   - ctx: Context whose cancellation stops the watcher.
+//   - interval: How often the database file is checked for changes.
+//   - logger: A Zap logger used to report reload outcomes.
+func (g *GeoLookupService) Watch(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// A nil channel blocks forever, which disables polling when no interval is configured.
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			for _, db := range g.databases() {
+				reloadAndLog(db, logger, "SIGHUP")
+			}
+		case <-tick:
+			for _, db := range g.databases() {
+				if db.changedOnDisk() {
+					reloadAndLog(db, logger, "file change")
+				}
+			}
+		}
+	}
+}
+
+// reloadAndLog reloads db and records the outcome.
+func reloadAndLog(db *database, logger *zap.Logger, trigger string) {
+	if err := db.reload(); err != nil {
+		logger.Error("GeoIP database reload failed; keeping current database",
+			zap.String("path", db.path),
+			zap.String("trigger", trigger),
+			zap.Error(err),
+		)
+		return
+	}
+
+	logger.Info("GeoIP database reloaded",
+		zap.String("path", db.path),
+		zap.String("trigger", trigger),
+	)
+}
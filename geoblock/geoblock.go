@@ -0,0 +1,251 @@
+// Package geoblock decides the requests of a Go HTTP server in-process, with the same geolocation database,
+// policies and overrides as the ipchecker service but without a network hop. A Guard wraps a checker.Checker and
+// one policy into net/http and Gin middleware that either blocks denied clients or annotates every request with
+// its decision:
+//
+//	geoService, err := geo.NewGeoLookupService("GeoLite2-Country.mmdb")
+//	...
+//	guard, err := geoblock.New(checker.NewChecker(geoService, nil, nil),
+//		checker.Policy{AllowedCountries: []string{"DE", "FR"}}, geoblock.Options{})
+//	...
+//	http.ListenAndServe(":8080", guard.Handler(mux))
+package geoblock
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/clientip"
+)
+
+// Mode selects what a Guard does with the requests of clients that are denied or cannot be decided.
+type Mode int
+
+const (
+	// ModeBlock answers the requests of denied clients with the Deny function instead of passing them on.
+	// This is the default.
+	ModeBlock Mode = iota
+
+	// ModeAnnotate passes every request on; handlers read the decision with FromContext.
+	ModeAnnotate
+)
+
+// Result is the outcome of checking the client of a request.
+type Result struct {
+	// IP is the client IP address the decision was made for, as returned by the ClientIP function.
+	IP string
+
+	// Decision is the decision for the client; zero value if Err is set.
+	Decision checker.Decision
+
+	// Err is the *checker.Error that prevented a decision, e.g. an invalid client address or a failed lookup.
+	Err error
+}
+
+// Allowed reports whether the client may proceed: it was decided and satisfies the policy.
+//
+// Returns:
+//   - bool: True if the client is allowed, false if it is denied or could not be decided.
+func (r Result) Allowed() bool {
+	return r.Err == nil && r.Decision.Allowed
+}
+
+// ClientIPFunc returns the client IP address of a request, e.g. from the forwarding headers of a trusted proxy
+// (see ResolverClientIP).
+type ClientIPFunc func(r *http.Request) string
+
+// ResolverClientIP returns a ClientIPFunc taking the client IP address from the forwarding headers sent by the
+// trusted proxies of a resolver, or from the connection otherwise.
+//
+// Parameters:
+//   - resolver: The resolver of client addresses, configured with the trusted proxies and headers.
+//
+// Returns:
+//   - ClientIPFunc: The client IP extractor for Options.ClientIP.
+func ResolverClientIP(resolver *clientip.Resolver) ClientIPFunc {
+	return func(r *http.Request) string {
+		return resolver.ResolveRequest(r).IP
+	}
+}
+
+// DenyFunc writes the response to a request whose client is denied or could not be decided.
+type DenyFunc func(w http.ResponseWriter, r *http.Request, result Result)
+
+// Options configures a Guard.
+type Options struct {
+	// Mode selects whether denied requests are blocked or annotated; zero value is ModeBlock.
+	Mode Mode
+
+	// ClientIP returns the client IP address of a request. If nil, the address of the connection is used;
+	// the Gin middleware uses gin.Context.ClientIP instead, which honours the trusted proxies of the engine.
+	ClientIP ClientIPFunc
+
+	// Deny writes the response to blocked requests; nil uses DefaultDeny.
+	Deny DenyFunc
+
+	// FailOpen passes on the requests whose client could not be decided, e.g. because the lookup failed,
+	// instead of blocking them. Only used in ModeBlock.
+	FailOpen bool
+}
+
+// Guard checks the clients of HTTP requests against one policy.
+type Guard struct {
+	checker *checker.Checker
+	policy  checker.Policy
+	opts    Options
+}
+
+// New constructs a Guard checking clients against a policy.
+//
+// Parameters:
+//   - c: The checker making the decisions, with its geolocation database, named policies and overrides.
+//   - policy: Either the Name of a policy registered with the checker, or inline country rules and default action.
+//   - opts: The mode, client IP extractor and denial response of the middleware.
+//
+// Returns:
+//   - *Guard: The initialized guard.
+//   - error: A *checker.Error if the policy names an unknown policy or its rules are invalid.
+func New(c *checker.Checker, policy checker.Policy, opts Options) (*Guard, error) {
+	// Policies are fixed for the lifetime of the checker, so they are resolved once rather than per request.
+	resolved, err := c.ResolvePolicy(context.Background(), policy.Name, policy)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Deny == nil {
+		opts.Deny = DefaultDeny
+	}
+	return &Guard{checker: c, policy: resolved, opts: opts}, nil
+}
+
+// Check decides the client of a request, for integrations other than the provided middleware.
+//
+// Parameters:
+//   - r: The request whose client is checked.
+//
+// Returns:
+//   - Result: The client IP address and its decision, or the error that prevented one.
+func (g *Guard) Check(r *http.Request) Result {
+	ip := remoteIP(r)
+	if g.opts.ClientIP != nil {
+		ip = g.opts.ClientIP(r)
+	}
+	return g.check(r.Context(), ip)
+}
+
+// Handler returns net/http middleware checking the client of every request before next serves it.
+//
+// Parameters:
+//   - next: The handler serving the requests that are passed on.
+//
+// Returns:
+//   - http.Handler: A handler blocking or annotating requests according to the mode of the guard.
+func (g *Guard) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result := g.Check(r)
+		if g.blocks(result) {
+			g.opts.Deny(w, r, result)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), result)))
+	})
+}
+
+// Gin returns Gin middleware checking the client of every request before the next handlers serve it.
+//
+// Returns:
+//   - gin.HandlerFunc: Middleware handler function suitable for inclusion in a Gin router's middleware chain.
+func (g *Guard) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		if g.opts.ClientIP != nil {
+			ip = g.opts.ClientIP(c.Request)
+		}
+		result := g.check(c.Request.Context(), ip)
+		if g.blocks(result) {
+			g.opts.Deny(c.Writer, c.Request, result)
+			c.Abort()
+			return
+		}
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), result))
+		c.Next()
+	}
+}
+
+// check decides the client IP address of a request.
+func (g *Guard) check(ctx context.Context, ip string) Result {
+	decision, err := g.checker.Decide(ctx, ip, g.policy)
+	return Result{IP: ip, Decision: decision, Err: err}
+}
+
+// blocks reports whether the request of a client with the given result must be answered by Deny.
+func (g *Guard) blocks(result Result) bool {
+	switch {
+	case g.opts.Mode == ModeAnnotate || result.Allowed():
+		return false
+	case result.Err != nil:
+		return !g.opts.FailOpen
+	default:
+		return true
+	}
+}
+
+// DefaultDeny answers 403 Forbidden with {"error": "access denied"} for denied clients, and with the error
+// message for clients that could not be decided; failed lookups are answered with 500 Internal Server Error.
+//
+// Parameters:
+//   - w: The response writer of the request.
+//   - r: The blocked request.
+//   - result: The outcome of the check of its client.
+func DefaultDeny(w http.ResponseWriter, r *http.Request, result Result) {
+	status, message := http.StatusForbidden, "access denied"
+	if result.Err != nil {
+		message = result.Err.Error()
+		if checker.KindOf(result.Err) == checker.KindBackend {
+			status = http.StatusInternalServerError
+		}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// remoteIP returns the IP address of the connection of a request, without its port.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// contextKey is the type of the context key of the Result, unexported to avoid collisions.
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the result of the check of the request's client.
+//
+// Parameters:
+//   - ctx: The parent context.
+//   - result: The outcome of the check.
+//
+// Returns:
+//   - context.Context: The derived context.
+func NewContext(ctx context.Context, result Result) context.Context {
+	return context.WithValue(ctx, contextKey{}, result)
+}
+
+// FromContext returns the result of the check of the request's client, stored by the middleware of a Guard.
+//
+// Parameters:
+//   - ctx: The context of the request.
+//
+// Returns:
+//   - Result: The outcome of the check.
+//   - bool: Whether the request passed through the middleware of a Guard.
+func FromContext(ctx context.Context) (Result, bool) {
+	result, ok := ctx.Value(contextKey{}).(Result)
+	return result, ok
+}
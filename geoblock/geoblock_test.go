@@ -0,0 +1,174 @@
+package geoblock_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/clientip"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/geoblock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// annotated returns a handler answering 200 with the client IP address and country of the check stored in the
+// request context, or 500 if there is none.
+func annotated() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, ok := geoblock.FromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Allowed", map[bool]string{true: "true", false: "false"}[result.Allowed()])
+		w.Write([]byte(result.IP + " " + result.Decision.Country))
+	})
+}
+
+// serve sends a GET request from remoteAddr through handler and returns the response.
+func serve(handler http.Handler, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+// TestGuard_Handler verifies that the net/http middleware passes allowed clients on with their decision in the
+// request context, and blocks denied clients with the default response.
+func TestGuard_Handler(t *testing.T) {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+
+	guard, err := geoblock.New(c, checker.Policy{AllowedCountries: []string{"US"}}, geoblock.Options{})
+	require.NoError(t, err)
+	w := serve(guard.Handler(annotated()), "128.101.101.101:4711", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "128.101.101.101 US", w.Body.String())
+
+	guard, err = geoblock.New(c, checker.Policy{BlockedCountries: []string{"US"}}, geoblock.Options{})
+	require.NoError(t, err)
+	w = serve(guard.Handler(annotated()), "128.101.101.101:4711", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error": "access denied"}`, w.Body.String())
+
+	// Forwarding headers are ignored by default.
+	w = serve(guard.Handler(annotated()), "not-an-address", http.Header{"X-Forwarded-For": {"128.101.101.101"}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error": "invalid IP address"}`, w.Body.String())
+}
+
+// TestGuard_Annotate verifies that in annotate mode denied clients are passed on, with their decision in the
+// request context.
+func TestGuard_Annotate(t *testing.T) {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	guard, err := geoblock.New(c, checker.Policy{BlockedCountries: []string{"US"}}, geoblock.Options{Mode: geoblock.ModeAnnotate})
+	require.NoError(t, err)
+
+	w := serve(guard.Handler(annotated()), "128.101.101.101:4711", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "false", w.Header().Get("X-Allowed"))
+	assert.Equal(t, "128.101.101.101 US", w.Body.String())
+}
+
+// TestGuard_Options verifies that the custom client IP extractor and denial response are used, and that clients
+// that cannot be decided are blocked unless the guard fails open.
+func TestGuard_Options(t *testing.T) {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("", errors.New("database unreadable")), nil, nil)
+	var denied geoblock.Result
+	opts := geoblock.Options{
+		ClientIP: func(r *http.Request) string { return r.Header.Get("X-Test-IP") },
+		Deny: func(w http.ResponseWriter, r *http.Request, result geoblock.Result) {
+			denied = result
+			w.WriteHeader(http.StatusUnavailableForLegalReasons)
+		},
+	}
+	guard, err := geoblock.New(c, checker.Policy{AllowedCountries: []string{"US"}}, opts)
+	require.NoError(t, err)
+
+	w := serve(guard.Handler(annotated()), "10.0.0.1:4711", http.Header{"X-Test-Ip": {"128.101.101.101"}})
+	assert.Equal(t, http.StatusUnavailableForLegalReasons, w.Code)
+	assert.Equal(t, "128.101.101.101", denied.IP)
+	assert.Equal(t, checker.KindBackend, checker.KindOf(denied.Err))
+
+	opts.FailOpen = true
+	guard, err = geoblock.New(c, checker.Policy{AllowedCountries: []string{"US"}}, opts)
+	require.NoError(t, err)
+	w = serve(guard.Handler(annotated()), "10.0.0.1:4711", http.Header{"X-Test-Ip": {"128.101.101.101"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "false", w.Header().Get("X-Allowed"))
+
+	// The default response reports failed lookups as internal errors.
+	guard, err = geoblock.New(c, checker.Policy{AllowedCountries: []string{"US"}}, geoblock.Options{})
+	require.NoError(t, err)
+	w = serve(guard.Handler(annotated()), "128.101.101.101:4711", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+// TestGuard_Gin verifies that the Gin middleware takes the client IP address from gin.Context.ClientIP, which
+// honours the trusted proxies of the engine, and blocks or passes on requests like the net/http middleware.
+func TestGuard_Gin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	allow, err := geoblock.New(c, checker.Policy{AllowedCountries: []string{"US"}}, geoblock.Options{})
+	require.NoError(t, err)
+	block, err := geoblock.New(c, checker.Policy{BlockedCountries: []string{"US"}}, geoblock.Options{})
+	require.NoError(t, err)
+
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies([]string{"10.0.0.0/8"}))
+	router.GET("/allow", allow.Gin(), gin.WrapH(annotated()))
+	router.GET("/block", block.Gin(), gin.WrapH(annotated()))
+
+	req := httptest.NewRequest(http.MethodGet, "/allow", nil)
+	req.RemoteAddr = "10.0.0.1:4711"
+	req.Header.Set("X-Forwarded-For", "81.2.69.142")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "81.2.69.142 US", w.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/block", nil)
+	req.RemoteAddr = "128.101.101.101:4711"
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error": "access denied"}`, w.Body.String())
+}
+
+// TestNew verifies that guards are built from named policies of the checker, and that unknown policies and
+// invalid rules are rejected.
+func TestNew(t *testing.T) {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+
+	_, err := geoblock.New(c, checker.Policy{Name: "checkout-eu"}, geoblock.Options{})
+	assert.EqualError(t, err, `unknown policy "checkout-eu"`)
+	_, err = geoblock.New(c, checker.Policy{AllowedCountries: []string{"US"}, BlockedCountries: []string{"DE"}}, geoblock.Options{})
+	assert.Equal(t, checker.KindInvalidInput, checker.KindOf(err))
+}
+
+// TestResolverClientIP verifies that the resolver-based extractor reads forwarding headers from trusted proxies
+// only.
+func TestResolverClientIP(t *testing.T) {
+	resolver, err := clientip.NewResolver([]string{"10.0.0.0/8"}, []string{"X-Forwarded-For"})
+	require.NoError(t, err)
+	extract := geoblock.ResolverClientIP(resolver)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Forwarded-For", "81.2.69.142")
+	req.RemoteAddr = "10.0.0.1:4711"
+	assert.Equal(t, "81.2.69.142", extract(req))
+	req.RemoteAddr = "128.101.101.101:4711"
+	assert.Equal(t, "128.101.101.101", extract(req))
+
+	// The line a trusted proxy adds after the one forged by the client wins.
+	req.RemoteAddr = "10.0.0.1:4711"
+	req.Header.Add("X-Forwarded-For", "128.101.101.101")
+	assert.Equal(t, "128.101.101.101", extract(req))
+}
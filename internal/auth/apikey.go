@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIKeyHeader is the HTTP header, and lower-cased the gRPC metadata key, API keys are presented in.
+const APIKeyHeader = "X-API-Key"
+
+// ErrMissingAPIKey is returned when a request presents no API key.
+var ErrMissingAPIKey = errors.New("missing API key")
+
+// ErrInvalidAPIKey is returned when a request presents an API key that is not in the key file.
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// KeyStore holds the API keys accepted by the service, indexed by the SHA-256 hash of their secret. Secrets
+// themselves are never stored.
+type KeyStore struct {
+	keys map[string]Principal // Keyed by the hex-encoded SHA-256 hash of the secret.
+}
+
+// keyFile is the on-disk layout of an API key file.
+//
+// Example (YAML; the equivalent JSON document is accepted as well):
+//
+//	keys:
+//	  billing:
+//	    sha256: 2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b
+//	    scopes: [check]
+//	    policy: checkout-eu   # every check of this key uses the checkout-eu policy
+//	  ops:
+//	    sha256: 6ca13d52ca70c883e0f0bb101e425a89e8624de51db2d2392593af6a84118090
+//	    scopes: [check, lookup]
+type keyFile struct {
+	Keys map[string]keyEntry `yaml:"keys" json:"keys"`
+}
+
+// keyEntry is a single API key as written in a key file.
+type keyEntry struct {
+	SHA256 string   `yaml:"sha256" json:"sha256"`
+	Scopes []string `yaml:"scopes" json:"scopes"`
+	Policy string   `yaml:"policy" json:"policy,omitempty"`
+}
+
+// LoadKeyFile reads and validates the API keys stored in a YAML or JSON file.
+//
+// Parameters:
+//   - path: Filesystem path to the key file.
+//
+// Returns:
+//   - *KeyStore: The validated keys.
+//   - error: An error naming the offending key if the file cannot be read or a key is invalid.
+func LoadKeyFile(path string) (*KeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading API key file: %w", err)
+	}
+
+	// YAML is a superset of JSON, so a single decoder handles both formats.
+	var file keyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing API key file %s: %w", path, err)
+	}
+	if len(file.Keys) == 0 {
+		return nil, fmt.Errorf("API key file %s: no keys defined", path)
+	}
+
+	store := &KeyStore{keys: make(map[string]Principal, len(file.Keys))}
+	for name, entry := range file.Keys {
+		hash, principal, err := newKey(name, entry)
+		if err != nil {
+			return nil, fmt.Errorf("API key file %s: %w", path, err)
+		}
+		if other, ok := store.keys[hash]; ok {
+			return nil, fmt.Errorf("API key file %s: keys %q and %q have the same secret", path, other.Name, name)
+		}
+		store.keys[hash] = principal
+	}
+	return store, nil
+}
+
+// newKey validates a key file entry and returns the hash it is indexed by along with the caller it authenticates.
+func newKey(name string, entry keyEntry) (string, Principal, error) {
+	hash, err := hex.DecodeString(entry.SHA256)
+	if err != nil || len(hash) != sha256.Size {
+		return "", Principal{}, fmt.Errorf("key %q: sha256 must be the 64 hex digits of the SHA-256 hash of the secret", name)
+	}
+	if len(entry.Scopes) == 0 {
+		return "", Principal{}, fmt.Errorf("key %q: at least one scope is required", name)
+	}
+
+	principal := Principal{Name: name}
+	if entry.Policy != "" {
+		principal.Policies = []string{entry.Policy}
+	}
+	for _, raw := range entry.Scopes {
+		scope, err := parseScope(raw)
+		if err != nil {
+			return "", Principal{}, fmt.Errorf("key %q: %w", name, err)
+		}
+		principal.Scopes = append(principal.Scopes, scope)
+	}
+	return hex.EncodeToString(hash), principal, nil
+}
+
+// Authenticate returns the caller an API key belongs to.
+//
+// Parameters:
+//   - key: The API key presented by the request.
+//
+// Returns:
+//   - Principal: The caller the key was issued to.
+//   - error: ErrMissingAPIKey if key is empty, ErrInvalidAPIKey if it is not a known key.
+func (s *KeyStore) Authenticate(key string) (Principal, error) {
+	if key == "" {
+		return Principal{}, ErrMissingAPIKey
+	}
+	principal, ok := s.keys[HashAPIKey(key)]
+	if !ok {
+		return Principal{}, ErrInvalidAPIKey
+	}
+	return principal, nil
+}
+
+// Policies returns the names of the server-side policies bound to keys, so that they can be checked to exist.
+//
+// Returns:
+//   - []string: The sorted, distinct policy names; nil if no key is bound to a policy.
+func (s *KeyStore) Policies() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, principal := range s.keys {
+		for _, name := range principal.Policies {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HashAPIKey returns the hash of an API key secret as written in key files.
+//
+// Parameters:
+//   - key: The API key secret.
+//
+// Returns:
+//   - string: The hex-encoded SHA-256 hash, as printed by `printf %s "$KEY" | sha256sum`.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,78 @@
+package auth_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyFile writes an API key file into a temporary directory and returns its path.
+func writeKeyFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// TestLoadKeyFile verifies that keys authenticate by their secret only, carry their scopes and bound policy, and
+// that unknown or missing keys are rejected.
+func TestLoadKeyFile(t *testing.T) {
+	path := writeKeyFile(t, `
+keys:
+  billing:
+    sha256: `+auth.HashAPIKey("billing-secret")+`
+    scopes: [check]
+    policy: checkout-eu
+  ops:
+    sha256: `+auth.HashAPIKey("ops-secret")+`
+    scopes: [check, lookup]
+`)
+	keys, err := auth.LoadKeyFile(path)
+	require.NoError(t, err)
+
+	billing, err := keys.Authenticate("billing-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "billing", billing.Name)
+	assert.Equal(t, []string{"checkout-eu"}, billing.Policies)
+	assert.True(t, billing.HasScope(auth.ScopeCheck))
+	assert.False(t, billing.HasScope(auth.ScopeLookup))
+
+	ops, err := keys.Authenticate("ops-secret")
+	require.NoError(t, err)
+	assert.True(t, ops.HasScope(auth.ScopeCheck))
+	assert.True(t, ops.HasScope(auth.ScopeLookup))
+
+	_, err = keys.Authenticate(auth.HashAPIKey("billing-secret"))
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey, "Expected the hash itself not to authenticate.")
+	_, err = keys.Authenticate("")
+	assert.ErrorIs(t, err, auth.ErrMissingAPIKey)
+
+	assert.Equal(t, []string{"checkout-eu"}, keys.Policies())
+}
+
+// TestLoadKeyFile_RejectsInvalidKeys verifies that malformed key files are rejected with an error naming the key.
+func TestLoadKeyFile_RejectsInvalidKeys(t *testing.T) {
+	hash := auth.HashAPIKey("secret")
+
+	for name, tc := range map[string]struct {
+		content string
+		errText string
+	}{
+		"no keys":      {"keys: {}\n", "no keys defined"},
+		"bad hash":     {"keys:\n  a:\n    sha256: secret\n    scopes: [check]\n", `key "a": sha256 must be`},
+		"no scopes":    {"keys:\n  a:\n    sha256: " + hash + "\n", `key "a": at least one scope`},
+		"bad scope":    {"keys:\n  a:\n    sha256: " + hash + "\n    scopes: [write]\n", `key "a": unknown scope "write"`},
+		"same secret":  {"keys:\n  a:\n    sha256: " + hash + "\n    scopes: [check]\n  b:\n    sha256: " + hash + "\n    scopes: [check]\n", "have the same secret"},
+		"invalid yaml": {"keys: [", "parsing API key file"},
+	} {
+		_, err := auth.LoadKeyFile(writeKeyFile(t, tc.content))
+		if assert.Error(t, err, name) {
+			assert.Contains(t, err.Error(), tc.errText, name)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package auth authenticates the callers of the service by API key or bearer token (JWT), describes what they
+// may do, and carries them through request contexts to handlers and the decision core.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Scope is a permission granted to a caller.
+type Scope string
+
+const (
+	// ScopeCheck permits checking IP addresses against policies (CheckIP, CheckIPBatch, CheckIPStream).
+	ScopeCheck Scope = "check"
+
+	// ScopeLookup permits geolocation lookups (Lookup).
+	ScopeLookup Scope = "lookup"
+)
+
+// parseScope validates a scope name as written in a key file.
+func parseScope(raw string) (Scope, error) {
+	switch scope := Scope(raw); scope {
+	case ScopeCheck, ScopeLookup:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("unknown scope %q (must be %q or %q)", raw, ScopeCheck, ScopeLookup)
+	}
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	// Name identifies the caller, e.g. the name of its API key or the subject of its token.
+	Name string
+
+	// Scopes are the permissions granted to the caller.
+	Scopes []Scope
+
+	// Policies are the names of the server-side policies the caller may check against. If non-nil, requests may
+	// only name one of them, and requests naming none (including those sending inline country rules) are checked
+	// against the first; an empty list permits no policy. If nil, the caller chooses.
+	Policies []string
+}
+
+// HasScope reports whether the caller was granted scope.
+//
+// Parameters:
+//   - scope: The permission required.
+//
+// Returns:
+//   - bool: true if the caller holds scope.
+func (p Principal) HasScope(scope Scope) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Credentials are the credentials presented with a request.
+type Credentials struct {
+	// APIKey is the API key (X-API-Key header or x-api-key metadata); empty if none.
+	APIKey string
+
+	// BearerToken is the token of an "Authorization: Bearer" header or metadata, without the prefix; empty if none.
+	BearerToken string
+}
+
+// Authenticator authenticates callers by API key, by bearer token, or by either.
+type Authenticator struct {
+	keys   *KeyStore
+	tokens *TokenVerifier
+}
+
+// NewAuthenticator creates an Authenticator accepting the given kinds of credentials.
+//
+// Parameters:
+//   - keys: The accepted API keys; nil if API keys are not accepted.
+//   - tokens: The verifier of bearer tokens; nil if bearer tokens are not accepted.
+//
+// Returns:
+//   - *Authenticator: The authenticator; nil if neither kind of credentials is accepted, i.e. authentication is
+//     disabled.
+func NewAuthenticator(keys *KeyStore, tokens *TokenVerifier) *Authenticator {
+	if keys == nil && tokens == nil {
+		return nil
+	}
+	return &Authenticator{keys: keys, tokens: tokens}
+}
+
+// Authenticate returns the caller the credentials belong to. A bearer token takes precedence over an API key
+// when both are presented.
+//
+// Parameters:
+//   - creds: The credentials presented with the request.
+//
+// Returns:
+//   - Principal: The authenticated caller.
+//   - error: An error describing the missing or rejected credentials, e.g. ErrInvalidAPIKey or an error wrapping
+//     ErrInvalidToken.
+func (a *Authenticator) Authenticate(creds Credentials) (Principal, error) {
+	switch {
+	case creds.BearerToken != "" && a.tokens != nil:
+		return a.tokens.Verify(creds.BearerToken)
+	case creds.APIKey != "" && a.keys != nil:
+		return a.keys.Authenticate(creds.APIKey)
+	case a.tokens == nil:
+		return Principal{}, ErrMissingAPIKey
+	case a.keys == nil:
+		return Principal{}, errors.New("missing bearer token")
+	default:
+		return Principal{}, errors.New("missing API key or bearer token")
+	}
+}
+
+// BearerToken extracts the token of an Authorization header value using the Bearer scheme.
+//
+// Parameters:
+//   - authorization: The Authorization header or metadata value, e.g. "Bearer eyJhbGciOi...".
+//
+// Returns:
+//   - string: The token; empty if the value does not use the Bearer scheme.
+func BearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// principalKey is the context key of the authenticated caller.
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying the authenticated caller.
+//
+// Parameters:
+//   - ctx: The parent context.
+//   - principal: The authenticated caller.
+//
+// Returns:
+//   - context.Context: The derived context.
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the authenticated caller carried by ctx.
+//
+// Parameters:
+//   - ctx: The request context.
+//
+// Returns:
+//   - Principal: The caller, if any.
+//   - bool: Whether the request was authenticated; false when authentication is disabled.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// PoliciesFromContext returns the policies the authenticated caller carried by ctx is restricted to, for use as a
+// checker.PolicyRestriction.
+//
+// Parameters:
+//   - ctx: The request context.
+//
+// Returns:
+//   - []string: The Policies of the caller; nil if it is unrestricted or the request was not authenticated, and
+//     empty if it may use no policy at all.
+func PoliciesFromContext(ctx context.Context) []string {
+	principal, _ := FromContext(ctx)
+	return principal.Policies
+}
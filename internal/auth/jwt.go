@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidToken is returned when a bearer token cannot be verified or its claims are not accepted.
+var ErrInvalidToken = errors.New("invalid bearer token")
+
+// signatureAlgorithms are the asymmetric algorithms tokens may be signed with. Symmetric (HS*) algorithms are not
+// accepted: a JWKS only ever holds public keys, and accepting them would let anyone holding the JWKS sign tokens.
+var signatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// jwksFetchTimeout bounds how long fetching a JWKS from a URL may take.
+const jwksFetchTimeout = 10 * time.Second
+
+// TokenOptions configures the verification of bearer tokens (JWTs).
+type TokenOptions struct {
+	// JWKSFile is the path to the JSON Web Key Set holding the public keys tokens are signed with.
+	JWKSFile string
+
+	// JWKSURL is the URL the JSON Web Key Set is fetched from, as an alternative to JWKSFile
+	// (e.g., an OIDC provider's jwks_uri).
+	JWKSURL string
+
+	// Issuer is the required "iss" claim.
+	Issuer string
+
+	// Audience is a value the "aud" claim must contain.
+	Audience string
+
+	// ScopesClaim names the claim holding the scopes of the caller, either a space-separated string (as in
+	// OAuth 2.0 "scope") or a list of strings; scopes not known to the service are ignored.
+	ScopesClaim string
+
+	// PoliciesClaim names the claim listing the server-side policies the caller may check against, as a list of
+	// strings or a single string. If set, tokens must carry the claim with at least one policy; if empty, callers
+	// may use any policy or inline rules.
+	PoliciesClaim string
+
+	// Leeway is the clock skew tolerated when checking the "exp", "nbf" and "iat" claims.
+	Leeway time.Duration
+}
+
+// TokenVerifier verifies bearer tokens against a JSON Web Key Set that can be refreshed while it is in use.
+type TokenVerifier struct {
+	opts TokenOptions
+	keys atomic.Pointer[jose.JSONWebKeySet] // Public keys of the last successfully loaded JWKS.
+}
+
+// NewTokenVerifier loads the JSON Web Key Set and returns a verifier for tokens signed with its keys.
+//
+// Parameters:
+//   - opts: The JWKS location and the claims required of tokens; exactly one of JWKSFile and JWKSURL must be set.
+//
+// Returns:
+//   - *TokenVerifier: The verifier.
+//   - error: An error if the options are incomplete or the JWKS cannot be loaded.
+func NewTokenVerifier(opts TokenOptions) (*TokenVerifier, error) {
+	switch {
+	case (opts.JWKSFile == "") == (opts.JWKSURL == ""):
+		return nil, errors.New("exactly one of a JWKS file and a JWKS URL is required")
+	case opts.Issuer == "":
+		return nil, errors.New("an issuer is required")
+	case opts.Audience == "":
+		return nil, errors.New("an audience is required")
+	case opts.ScopesClaim == "":
+		return nil, errors.New("a scopes claim is required")
+	}
+
+	v := &TokenVerifier{opts: opts}
+	if err := v.Refresh(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Source returns where the JSON Web Key Set is loaded from, for logs.
+//
+// Returns:
+//   - string: The JWKS file path or URL.
+func (v *TokenVerifier) Source() string {
+	if v.opts.JWKSURL != "" {
+		return v.opts.JWKSURL
+	}
+	return v.opts.JWKSFile
+}
+
+// Refresh reloads the JSON Web Key Set from its file or URL. On failure, the previous keys stay in use.
+//
+// Returns:
+//   - error: An error if the JWKS cannot be read, is malformed, holds no keys or holds non-public keys.
+func (v *TokenVerifier) Refresh() error {
+	data, err := v.readJWKS()
+	if err != nil {
+		return fmt.Errorf("reading JWKS from %s: %w", v.Source(), err)
+	}
+
+	var set jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("parsing JWKS from %s: %w", v.Source(), err)
+	}
+	if len(set.Keys) == 0 {
+		return fmt.Errorf("JWKS from %s holds no keys", v.Source())
+	}
+	for i, key := range set.Keys {
+		if !key.Valid() || !key.IsPublic() {
+			return fmt.Errorf("JWKS from %s: key %d (kid %q) is not a valid public key", v.Source(), i, key.KeyID)
+		}
+	}
+
+	v.keys.Store(&set)
+	return nil
+}
+
+// readJWKS returns the raw JSON Web Key Set from its file or URL.
+func (v *TokenVerifier) readJWKS() ([]byte, error) {
+	if v.opts.JWKSFile != "" {
+		return os.ReadFile(v.opts.JWKSFile)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.opts.JWKSURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// Verify checks the signature and claims of a bearer token and returns the caller it was issued to.
+//
+// The token must be signed with an asymmetric algorithm by a key of the JWKS (selected by the "kid" header if
+// present), carry the configured issuer and audience, have an expiry ("exp") that has not passed, and not be
+// used before its "nbf" time.
+//
+// Parameters:
+//   - token: The compact-serialized JWT, without the "Bearer " prefix.
+//
+// Returns:
+//   - Principal: The caller, named by the "sub" claim, with the scopes and permitted policies of its claims.
+//   - error: An error wrapping ErrInvalidToken if the token is rejected.
+func (v *TokenVerifier) Verify(token string) (Principal, error) {
+	parsed, err := jwt.ParseSigned(token, signatureAlgorithms)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	// Verify the signature with the key named by the token, or with each key if it names none.
+	set := v.keys.Load()
+	candidates := set.Keys
+	if kid := parsed.Headers[0].KeyID; kid != "" {
+		candidates = set.Key(kid)
+	}
+	var std jwt.Claims
+	var claims map[string]interface{}
+	verified := false
+	for _, key := range candidates {
+		if parsed.Claims(key.Key, &std, &claims) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return Principal{}, fmt.Errorf("%w: signature not verified by any key of the JWKS", ErrInvalidToken)
+	}
+
+	// Check the registered claims.
+	if std.Expiry == nil {
+		return Principal{}, fmt.Errorf("%w: token has no expiry", ErrInvalidToken)
+	}
+	expected := jwt.Expected{Issuer: v.opts.Issuer, AnyAudience: jwt.Audience{v.opts.Audience}, Time: time.Now()}
+	if err := std.ValidateWithLeeway(expected, v.opts.Leeway); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	// Map the custom claims onto the scopes and policies of the caller.
+	principal := Principal{Name: std.Subject}
+	rawScopes, err := claimStrings(claims, v.opts.ScopesClaim, true)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	for _, raw := range rawScopes {
+		if scope, err := parseScope(raw); err == nil {
+			principal.Scopes = append(principal.Scopes, scope)
+		}
+	}
+	if v.opts.PoliciesClaim != "" {
+		if principal.Policies, err = claimStrings(claims, v.opts.PoliciesClaim, false); err != nil {
+			return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		}
+		// A missing or empty grant must not leave the caller unrestricted.
+		if len(principal.Policies) == 0 {
+			return Principal{}, fmt.Errorf("%w: claim %q must list at least one policy", ErrInvalidToken, v.opts.PoliciesClaim)
+		}
+	}
+	return principal, nil
+}
+
+// claimStrings returns a claim holding a string or a list of strings. Strings are split at spaces if split is set.
+func claimStrings(claims map[string]interface{}, name string, split bool) ([]string, error) {
+	switch value := claims[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if split {
+			return strings.Fields(value), nil
+		}
+		return []string{value}, nil
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("claim %q must hold strings", name)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("claim %q must be a string or a list of strings", name)
+	}
+}
+
+// Watch keeps the JSON Web Key Set up to date until ctx is cancelled, so that rotated signing keys are accepted.
+//
+// The JWKS is reloaded every interval (polling is disabled if interval <= 0) and when the process receives
+// SIGHUP. A JWKS that cannot be loaded is logged and the current keys stay in use.
+//
+// Parameters:
+//   - ctx: Context whose cancellation stops the watcher.
+//   - interval: How often the JWKS is reloaded.
+//   - logger: A Zap logger used to report reload outcomes.
+func (v *TokenVerifier) Watch(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// A nil channel blocks forever, which disables polling when no interval is configured.
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			v.refreshAndLog(logger, "SIGHUP")
+		case <-tick:
+			v.refreshAndLog(logger, "interval")
+		}
+	}
+}
+
+// refreshAndLog reloads the JWKS and records failures; successful periodic reloads are only logged at debug level.
+func (v *TokenVerifier) refreshAndLog(logger *zap.Logger, trigger string) {
+	if err := v.Refresh(); err != nil {
+		logger.Error("JWKS reload failed; keeping current keys",
+			zap.String("source", v.Source()),
+			zap.String("trigger", trigger),
+			zap.Error(err),
+		)
+		return
+	}
+
+	logger.Debug("JWKS reloaded",
+		zap.String("source", v.Source()),
+		zap.String("trigger", trigger),
+		zap.Int("keys", len(v.keys.Load().Keys)),
+	)
+}
@@ -0,0 +1,282 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/justfairdev/ipchecker/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signingKey is a locally generated token signing key.
+type signingKey struct {
+	kid string
+	alg jose.SignatureAlgorithm
+	key interface{} // Private key, or the shared secret of HMAC algorithms
+}
+
+// newECKey generates an ES256 signing key.
+func newECKey(t *testing.T, kid string) signingKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return signingKey{kid: kid, alg: jose.ES256, key: key}
+}
+
+// newRSAKey generates an RS256 signing key.
+func newRSAKey(t *testing.T, kid string) signingKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return signingKey{kid: kid, alg: jose.RS256, key: key}
+}
+
+// jwks returns the JSON Web Key Set publishing the public halves of keys.
+func jwks(t *testing.T, keys ...signingKey) []byte {
+	t.Helper()
+
+	var set jose.JSONWebKeySet
+	for _, k := range keys {
+		jwk := jose.JSONWebKey{Key: k.key, KeyID: k.kid, Algorithm: string(k.alg), Use: "sig"}
+		set.Keys = append(set.Keys, jwk.Public())
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	return data
+}
+
+// writeJWKS writes a JSON Web Key Set into a temporary directory and returns its path.
+func writeJWKS(t *testing.T, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// sign returns a compact JWT carrying claims and signed with k, naming its key ID if withKID is set.
+func sign(t *testing.T, k signingKey, withKID bool, claims map[string]interface{}) string {
+	t.Helper()
+
+	opts := (&jose.SignerOptions{}).WithType("JWT")
+	if withKID {
+		opts = opts.WithHeader(jose.HeaderKey("kid"), k.kid)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: k.alg, Key: k.key}, opts)
+	require.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(claims).Serialize()
+	require.NoError(t, err)
+	return token
+}
+
+// validClaims returns the claims of a token accepted by tokenOptions, which may be adjusted by the caller.
+func validClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":      "https://idp.example.com",
+		"aud":      []string{"ipchecker", "other-service"},
+		"sub":      "checkout-service",
+		"exp":      now.Add(time.Hour).Unix(),
+		"iat":      now.Unix(),
+		"scope":    "check openid",
+		"policies": []string{"checkout-eu"},
+	}
+}
+
+// tokenOptions returns the verification options matching validClaims for the JWKS file at path.
+func tokenOptions(path string) auth.TokenOptions {
+	return auth.TokenOptions{
+		JWKSFile:      path,
+		Issuer:        "https://idp.example.com",
+		Audience:      "ipchecker",
+		ScopesClaim:   "scope",
+		PoliciesClaim: "policies",
+		Leeway:        30 * time.Second,
+	}
+}
+
+// TestTokenVerifier_Verify verifies that tokens signed by a key of the JWKS with the expected issuer, audience and
+// expiry are accepted and mapped onto the caller's scopes and permitted policies, and that all others are rejected.
+func TestTokenVerifier_Verify(t *testing.T) {
+	ecKey, rsaKey := newECKey(t, "ec-1"), newRSAKey(t, "rsa-1")
+	verifier, err := auth.NewTokenVerifier(tokenOptions(writeJWKS(t, jwks(t, ecKey, rsaKey))))
+	require.NoError(t, err)
+
+	principal, err := verifier.Verify(sign(t, ecKey, true, validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, "checkout-service", principal.Name)
+	assert.Equal(t, []auth.Scope{auth.ScopeCheck}, principal.Scopes, "Expected unknown scopes to be ignored.")
+	assert.Equal(t, []string{"checkout-eu"}, principal.Policies)
+
+	// Tokens without a key ID are verified against every key of the set; scopes may be a list as well, and
+	// policies a single string.
+	claims := validClaims()
+	claims["scope"] = []string{"lookup"}
+	claims["policies"] = "checkout-us"
+	principal, err = verifier.Verify(sign(t, rsaKey, false, claims))
+	require.NoError(t, err)
+	assert.Equal(t, []auth.Scope{auth.ScopeLookup}, principal.Scopes)
+	assert.Equal(t, []string{"checkout-us"}, principal.Policies)
+
+	// Expiry within the leeway is tolerated.
+	claims = validClaims()
+	claims["exp"] = time.Now().Add(-10 * time.Second).Unix()
+	_, err = verifier.Verify(sign(t, ecKey, true, claims))
+	assert.NoError(t, err)
+
+	otherKey := newECKey(t, "ec-1")
+	for name, token := range map[string]string{
+		"expired":          sign(t, ecKey, true, with(validClaims(), "exp", time.Now().Add(-time.Hour).Unix())),
+		"no expiry":        sign(t, ecKey, true, with(validClaims(), "exp", nil)),
+		"not yet valid":    sign(t, ecKey, true, with(validClaims(), "nbf", time.Now().Add(time.Hour).Unix())),
+		"wrong issuer":     sign(t, ecKey, true, with(validClaims(), "iss", "https://evil.example.com")),
+		"wrong audience":   sign(t, ecKey, true, with(validClaims(), "aud", "other-service")),
+		"unknown key":      sign(t, otherKey, true, validClaims()),
+		"unknown key ID":   sign(t, signingKey{kid: "ec-2", alg: ecKey.alg, key: ecKey.key}, true, validClaims()),
+		"symmetric key":    sign(t, signingKey{kid: "ec-1", alg: jose.HS256, key: []byte("0123456789abcdef0123456789abcdef")}, true, validClaims()),
+		"malformed scopes": sign(t, ecKey, true, with(validClaims(), "scope", 42)),
+		"no policies":      sign(t, ecKey, true, with(validClaims(), "policies", nil)),
+		"empty policies":   sign(t, ecKey, true, with(validClaims(), "policies", []string{})),
+		"not a token":      "not-a-jwt",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := verifier.Verify(token)
+			assert.ErrorIs(t, err, auth.ErrInvalidToken)
+		})
+	}
+}
+
+// TestTokenVerifier_Verify_WithoutPoliciesClaim verifies that callers are unrestricted when no policies claim is
+// configured, whatever their tokens carry.
+func TestTokenVerifier_Verify_WithoutPoliciesClaim(t *testing.T) {
+	ecKey := newECKey(t, "ec-1")
+	opts := tokenOptions(writeJWKS(t, jwks(t, ecKey)))
+	opts.PoliciesClaim = ""
+	verifier, err := auth.NewTokenVerifier(opts)
+	require.NoError(t, err)
+
+	principal, err := verifier.Verify(sign(t, ecKey, true, with(validClaims(), "policies", nil)))
+	require.NoError(t, err)
+	assert.Nil(t, principal.Policies)
+}
+
+// with returns claims with name set to value, or removed if value is nil.
+func with(claims map[string]interface{}, name string, value interface{}) map[string]interface{} {
+	if value == nil {
+		delete(claims, name)
+	} else {
+		claims[name] = value
+	}
+	return claims
+}
+
+// TestTokenVerifier_RefreshFromURL verifies that a JWKS served over HTTP is fetched at startup and that Refresh
+// picks up rotated keys, keeping the current ones if the JWKS cannot be fetched.
+func TestTokenVerifier_RefreshFromURL(t *testing.T) {
+	oldKey, newKey := newECKey(t, "old"), newECKey(t, "new")
+	var served atomic.Value
+	served.Store(jwks(t, oldKey))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		data := served.Load().([]byte)
+		if data == nil {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	opts := tokenOptions("")
+	opts.JWKSURL = srv.URL
+	verifier, err := auth.NewTokenVerifier(opts)
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL, verifier.Source())
+
+	_, err = verifier.Verify(sign(t, oldKey, true, validClaims()))
+	assert.NoError(t, err)
+	_, err = verifier.Verify(sign(t, newKey, true, validClaims()))
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+
+	served.Store(jwks(t, newKey))
+	require.NoError(t, verifier.Refresh())
+	_, err = verifier.Verify(sign(t, newKey, true, validClaims()))
+	assert.NoError(t, err, "Expected the rotated key to be accepted after a refresh.")
+
+	served.Store([]byte(nil))
+	assert.Error(t, verifier.Refresh())
+	_, err = verifier.Verify(sign(t, newKey, true, validClaims()))
+	assert.NoError(t, err, "Expected a failed refresh to keep the current keys.")
+}
+
+// TestNewTokenVerifier_RejectsInvalidOptions verifies that incomplete options and key sets that must not be
+// trusted are rejected at startup.
+func TestNewTokenVerifier_RejectsInvalidOptions(t *testing.T) {
+	key := newECKey(t, "ec-1")
+	path := writeJWKS(t, jwks(t, key))
+
+	private, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key.key, KeyID: key.kid}}})
+	require.NoError(t, err)
+
+	for name, opts := range map[string]auth.TokenOptions{
+		"no JWKS":           {Issuer: "iss", Audience: "aud", ScopesClaim: "scope"},
+		"file and URL":      {JWKSFile: path, JWKSURL: "https://idp.example.com/jwks", Issuer: "iss", Audience: "aud", ScopesClaim: "scope"},
+		"no issuer":         {JWKSFile: path, Audience: "aud", ScopesClaim: "scope"},
+		"no audience":       {JWKSFile: path, Issuer: "iss", ScopesClaim: "scope"},
+		"missing file":      {JWKSFile: filepath.Join(t.TempDir(), "missing.json"), Issuer: "iss", Audience: "aud", ScopesClaim: "scope"},
+		"empty key set":     {JWKSFile: writeJWKS(t, []byte(`{"keys": []}`)), Issuer: "iss", Audience: "aud", ScopesClaim: "scope"},
+		"private key":       {JWKSFile: writeJWKS(t, private), Issuer: "iss", Audience: "aud", ScopesClaim: "scope"},
+		"malformed key set": {JWKSFile: writeJWKS(t, []byte(`keys:`)), Issuer: "iss", Audience: "aud", ScopesClaim: "scope"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := auth.NewTokenVerifier(opts)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestAuthenticator verifies that bearer tokens take precedence over API keys, and that missing credentials are
+// described by the kinds the service accepts.
+func TestAuthenticator(t *testing.T) {
+	key := newECKey(t, "ec-1")
+	verifier, err := auth.NewTokenVerifier(tokenOptions(writeJWKS(t, jwks(t, key))))
+	require.NoError(t, err)
+	keys, err := auth.LoadKeyFile(writeKeyFile(t, "keys:\n  ops:\n    sha256: "+auth.HashAPIKey("ops-secret")+"\n    scopes: [check]\n"))
+	require.NoError(t, err)
+
+	assert.Nil(t, auth.NewAuthenticator(nil, nil), "Expected authentication to be disabled without credentials.")
+
+	both := auth.NewAuthenticator(keys, verifier)
+	principal, err := both.Authenticate(auth.Credentials{APIKey: "ops-secret", BearerToken: sign(t, key, true, validClaims())})
+	require.NoError(t, err)
+	assert.Equal(t, "checkout-service", principal.Name)
+	principal, err = both.Authenticate(auth.Credentials{APIKey: "ops-secret"})
+	require.NoError(t, err)
+	assert.Equal(t, "ops", principal.Name)
+	_, err = both.Authenticate(auth.Credentials{})
+	assert.EqualError(t, err, "missing API key or bearer token")
+
+	_, err = auth.NewAuthenticator(nil, verifier).Authenticate(auth.Credentials{APIKey: "ops-secret"})
+	assert.EqualError(t, err, "missing bearer token", "Expected API keys to be ignored when only tokens are accepted.")
+	_, err = auth.NewAuthenticator(keys, nil).Authenticate(auth.Credentials{BearerToken: "eyJ"})
+	assert.ErrorIs(t, err, auth.ErrMissingAPIKey)
+
+	assert.Equal(t, "abc.def.ghi", auth.BearerToken("Bearer abc.def.ghi"))
+	assert.Equal(t, "abc.def.ghi", auth.BearerToken("bearer abc.def.ghi"))
+	assert.Empty(t, auth.BearerToken("Basic dXNlcjpwYXNz"))
+	assert.Empty(t, auth.BearerToken(""))
+}
@@ -1,45 +1,778 @@
+// Package config assembles the application configuration from defaults, an optional config file (YAML, JSON or
+// TOML), environment variables and command-line flags, and validates it before anything is started.
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justfairdev/ipchecker/internal/tlsconfig"
+	"github.com/pelletier/go-toml/v2"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
 )
 
-// Config represents the application configuration loaded from environment variables.
+// ConfigFileEnv is the environment variable naming the config file, unless the --config flag is given.
+const ConfigFileEnv = "IPCHECKER_CONFIG"
+
+// Config represents the application configuration.
+//
+// Every setting can be given, from lowest to highest precedence, as a key in the config file (e.g., "http.port"),
+// as an environment variable (e.g., HTTP_PORT) or as a command-line flag (e.g., --http-port); see Load.
 type Config struct {
-	HTTPPort      string // Server listening port, defaults to "8080" if not specified.
-	MaxMindDBPath string // Filesystem path to the MaxMind GeoLite2 database, defaults to "./GeoLite2-Country.mmdb".
+	HTTP      HTTPConfig      // HTTP listener.
+	GRPC      GRPCConfig      // gRPC listener.
+	GeoIP     GeoIPConfig     // MaxMind databases.
+	Cache     CacheConfig     // Lookup cache.
+	Policies  PoliciesConfig  // Named policies and CIDR overrides.
+	Auth      AuthConfig      // Caller authentication.
+	RateLimit RateLimitConfig // Per-client rate limits and daily quotas.
+	ExtAuthz  ExtAuthzConfig  // Envoy external authorization service.
+	Tracing   TracingConfig   // OpenTelemetry tracing.
+	Log       LogConfig       // Application logs.
+	Shutdown  ShutdownConfig  // Graceful shutdown.
+
+	TLSReloadInterval time.Duration // How often the TLS certificate files are checked for changes, defaults to 1m; 0 disables polling.
+
+	// PrintConfig is set by the --print-config flag: the effective configuration should be written out
+	// (see WriteYAML) instead of starting the servers. It is not a setting.
+	PrintConfig bool
+
+	sources map[string]string // Where each setting was taken from, keyed by setting key.
+}
+
+// HTTPConfig configures the HTTP listener.
+type HTTPConfig struct {
+	Address           string            // Bind address (host or IP); empty listens on all interfaces.
+	Port              int               // Listening port, defaults to 8080.
+	ReadHeaderTimeout time.Duration     // Maximum time to read request headers, defaults to 10s.
+	ReadTimeout       time.Duration     // Maximum time to read a whole request, defaults to 30s.
+	WriteTimeout      time.Duration     // Maximum time to write a response, defaults to 30s.
+	IdleTimeout       time.Duration     // How long idle keep-alive connections are kept open, defaults to 2m.
+	TLS               tlsconfig.Options // TLS of the listener; plaintext unless a certificate is configured.
+	TrustedProxies    []string          // IP addresses or CIDR ranges of the proxies whose client IP headers are trusted; empty trusts none.
+	ClientIPHeaders   []string          // Headers carrying the client IP, read in order when the peer is a trusted proxy.
+}
+
+// ListenAddress returns the host:port the HTTP listener binds to.
+//
+// Returns:
+//   - string: The address, e.g. ":8080" or "127.0.0.1:8080".
+func (c HTTPConfig) ListenAddress() string {
+	return net.JoinHostPort(c.Address, strconv.Itoa(c.Port))
+}
+
+// GRPCConfig configures the gRPC listener.
+type GRPCConfig struct {
+	Address           string            // Bind address (host or IP); empty listens on all interfaces.
+	Port              int               // Listening port, defaults to 50051.
+	ConnectionTimeout time.Duration     // Maximum time to establish a connection, including the TLS handshake, defaults to 20s.
+	TLS               tlsconfig.Options // TLS of the listener; plaintext unless a certificate is configured.
+	TrustedProxies    []string          // IP addresses or CIDR ranges of the proxies whose client IP metadata is trusted; empty trusts none.
+	ClientIPMetadata  []string          // Metadata keys carrying the client IP, read in order when the peer is a trusted proxy.
+}
+
+// ListenAddress returns the host:port the gRPC listener binds to.
+//
+// Returns:
+//   - string: The address, e.g. ":50051" or "127.0.0.1:50051".
+func (c GRPCConfig) ListenAddress() string {
+	return net.JoinHostPort(c.Address, strconv.Itoa(c.Port))
+}
+
+// GeoIPConfig configures the MaxMind databases.
+type GeoIPConfig struct {
+	DatabasePath    string        // Path to the GeoLite2/GeoIP2 Country or City database, defaults to "./GeoLite2-Country.mmdb".
+	ASNDatabasePath string        // Path to the GeoLite2-ASN database; empty disables ASN lookups.
+	ReloadInterval  time.Duration // How often the database files are checked for changes, defaults to 30s; 0 disables polling.
+}
+
+// CacheConfig configures the lookup cache.
+type CacheConfig struct {
+	Size int           // Maximum number of cached lookup results, defaults to 10000; 0 disables the cache.
+	TTL  time.Duration // How long a lookup result is cached, defaults to 10m; 0 caches until evicted or reloaded.
+}
+
+// PoliciesConfig configures the server-side policies.
+type PoliciesConfig struct {
+	FilePath         string // Path to the YAML/JSON file of named policies; empty disables named policies.
+	OverrideFilePath string // Path to the YAML/JSON file of CIDR allow/deny overrides; empty disables overrides.
+}
+
+// AuthConfig configures the authentication of callers of the versioned HTTP API and the IPChecker gRPC service.
+type AuthConfig struct {
+	APIKeysFile string    // Path to the YAML/JSON file of hashed API keys; empty disables API key authentication.
+	JWT         JWTConfig // Bearer-token (JWT) authentication; disabled unless a JWKS file or URL is set.
+}
+
+// JWTConfig configures the verification of bearer tokens (JWTs) issued by an identity provider.
+type JWTConfig struct {
+	JWKSFile        string        // Path to the JSON Web Key Set tokens are verified against.
+	JWKSURL         string        // URL the JSON Web Key Set is fetched from, as an alternative to JWKSFile.
+	Issuer          string        // Required "iss" claim.
+	Audience        string        // Value the "aud" claim must contain.
+	ScopesClaim     string        // Claim holding the caller's scopes, defaults to "scope".
+	PoliciesClaim   string        // Claim listing the policies the caller may use, defaults to "policies".
+	Leeway          time.Duration // Clock skew tolerated on "exp" and "nbf", defaults to 30s.
+	RefreshInterval time.Duration // How often the JWKS is reloaded, defaults to 5m; 0 disables polling.
+}
+
+// Enabled reports whether bearer tokens are accepted, i.e. whether a JWKS is configured.
+//
+// Returns:
+//   - bool: true if a JWKS file or URL is set.
+func (j JWTConfig) Enabled() bool {
+	return j.JWKSFile != "" || j.JWKSURL != ""
+}
+
+// RateLimitConfig configures the rate limits and daily quotas of the clients of the versioned HTTP API and the
+// IPChecker gRPC service.
+type RateLimitConfig struct {
+	FilePath      string        // Path to the YAML/JSON file of client limits; empty disables rate limiting.
+	QuotaStore    string        // Path to the file daily quota counters are persisted to; empty keeps them in memory.
+	FlushInterval time.Duration // How often quota counters are persisted, defaults to 10s.
+}
+
+// ExtAuthzConfig configures the Envoy external authorization service served on the gRPC listener.
+type ExtAuthzConfig struct {
+	TrustedHops   int    // Proxies appending to x-forwarded-for whose entries are trusted, defaults to 0 (use the downstream address).
+	DefaultPolicy string // Policy of the routes that do not name one; empty requires every route to name one.
+}
+
+// TracingConfig configures OpenTelemetry tracing.
+type TracingConfig struct {
+	Exporter string // Where trace spans are sent: "none" (default), "stdout" or "otlp".
+}
+
+// LogConfig configures the application logs.
+type LogConfig struct {
+	Level string // Minimum level logged: "debug", "info" (default), "warn" or "error".
+}
+
+// ShutdownConfig configures the graceful shutdown of both listeners.
+type ShutdownConfig struct {
+	DrainDelay time.Duration // How long readiness fails before the listeners stop accepting connections, defaults to 0s.
+	Timeout    time.Duration // Deadline for in-flight requests to complete once draining starts, defaults to 30s.
 }
 
-// Load returns a Config object populated with values from environment variables.
-// If an environment variable is not set, the default value is used instead.
+// setting describes one configuration value and every way it can be set.
+type setting struct {
+	key   string // Config file key; the flag name is derived from it.
+	env   string // Environment variable.
+	def   string // Default value.
+	usage string // Description shown by --help.
+	tag   string // YAML tag of the value when printed.
+
+	set func(*Config, string) error // Parses a value into the Config.
+	get func(*Config) string        // Formats the value of the Config.
+}
+
+// flagName returns the command-line flag of the setting, e.g. "http-port" for "http.port".
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// settings lists every setting, in the order they are printed.
+var settings = []setting{
+	stringSetting("http.address", "HTTP_ADDRESS", "", "HTTP bind address (host or IP); empty listens on all interfaces",
+		func(c *Config) *string { return &c.HTTP.Address }),
+	intSetting("http.port", "HTTP_PORT", "8080", "HTTP listening port",
+		func(c *Config) *int { return &c.HTTP.Port }),
+	durationSetting("http.read_header_timeout", "HTTP_READ_HEADER_TIMEOUT", "10s", "maximum time to read HTTP request headers",
+		func(c *Config) *time.Duration { return &c.HTTP.ReadHeaderTimeout }),
+	durationSetting("http.read_timeout", "HTTP_READ_TIMEOUT", "30s", "maximum time to read a whole HTTP request",
+		func(c *Config) *time.Duration { return &c.HTTP.ReadTimeout }),
+	durationSetting("http.write_timeout", "HTTP_WRITE_TIMEOUT", "30s", "maximum time to write an HTTP response",
+		func(c *Config) *time.Duration { return &c.HTTP.WriteTimeout }),
+	durationSetting("http.idle_timeout", "HTTP_IDLE_TIMEOUT", "2m", "how long idle HTTP keep-alive connections are kept open",
+		func(c *Config) *time.Duration { return &c.HTTP.IdleTimeout }),
+	stringSetting("http.tls.cert_file", "HTTP_TLS_CERT_FILE", "", "PEM server certificate of the HTTP listener; empty serves plaintext",
+		func(c *Config) *string { return &c.HTTP.TLS.CertFile }),
+	stringSetting("http.tls.key_file", "HTTP_TLS_KEY_FILE", "", "PEM private key of the HTTP server certificate",
+		func(c *Config) *string { return &c.HTTP.TLS.KeyFile }),
+	stringSetting("http.tls.client_ca_file", "HTTP_TLS_CLIENT_CA_FILE", "", "PEM CA bundle HTTP client certificates are verified against",
+		func(c *Config) *string { return &c.HTTP.TLS.ClientCAFile }),
+	stringSetting("http.tls.client_auth", "HTTP_TLS_CLIENT_AUTH", "", `HTTP client certificates: "none", "optional" or "require" (empty: require if a client CA is set)`,
+		func(c *Config) *tlsconfig.ClientAuth { return &c.HTTP.TLS.ClientAuth }),
+
+	listSetting("http.trusted_proxies", "HTTP_TRUSTED_PROXIES", "", "comma-separated IP addresses or CIDR ranges of the proxies whose client IP headers are trusted",
+		func(c *Config) *[]string { return &c.HTTP.TrustedProxies }),
+	listSetting("http.client_ip_headers", "HTTP_CLIENT_IP_HEADERS", "X-Forwarded-For,X-Real-IP", "comma-separated headers carrying the client IP, read in order when the peer is a trusted proxy",
+		func(c *Config) *[]string { return &c.HTTP.ClientIPHeaders }),
+
+	stringSetting("grpc.address", "GRPC_ADDRESS", "", "gRPC bind address (host or IP); empty listens on all interfaces",
+		func(c *Config) *string { return &c.GRPC.Address }),
+	intSetting("grpc.port", "GRPC_PORT", "50051", "gRPC listening port",
+		func(c *Config) *int { return &c.GRPC.Port }),
+	durationSetting("grpc.connection_timeout", "GRPC_CONNECTION_TIMEOUT", "20s", "maximum time to establish a gRPC connection, including the TLS handshake",
+		func(c *Config) *time.Duration { return &c.GRPC.ConnectionTimeout }),
+	stringSetting("grpc.tls.cert_file", "GRPC_TLS_CERT_FILE", "", "PEM server certificate of the gRPC listener; empty serves plaintext",
+		func(c *Config) *string { return &c.GRPC.TLS.CertFile }),
+	stringSetting("grpc.tls.key_file", "GRPC_TLS_KEY_FILE", "", "PEM private key of the gRPC server certificate",
+		func(c *Config) *string { return &c.GRPC.TLS.KeyFile }),
+	stringSetting("grpc.tls.client_ca_file", "GRPC_TLS_CLIENT_CA_FILE", "", "PEM CA bundle gRPC client certificates are verified against",
+		func(c *Config) *string { return &c.GRPC.TLS.ClientCAFile }),
+	stringSetting("grpc.tls.client_auth", "GRPC_TLS_CLIENT_AUTH", "", `gRPC client certificates: "none", "optional" or "require" (empty: require if a client CA is set)`,
+		func(c *Config) *tlsconfig.ClientAuth { return &c.GRPC.TLS.ClientAuth }),
+
+	listSetting("grpc.trusted_proxies", "GRPC_TRUSTED_PROXIES", "", "comma-separated IP addresses or CIDR ranges of the proxies whose client IP metadata is trusted",
+		func(c *Config) *[]string { return &c.GRPC.TrustedProxies }),
+	listSetting("grpc.client_ip_metadata", "GRPC_CLIENT_IP_METADATA", "x-forwarded-for,x-real-ip", "comma-separated metadata keys carrying the client IP, read in order when the peer is a trusted proxy",
+		func(c *Config) *[]string { return &c.GRPC.ClientIPMetadata }),
+
+	durationSetting("tls.reload_interval", "TLS_RELOAD_INTERVAL", "1m", "how often TLS certificate files are checked for changes; 0 disables polling",
+		func(c *Config) *time.Duration { return &c.TLSReloadInterval }),
+
+	stringSetting("geoip.database", "MAXMIND_DB_PATH", "./GeoLite2-Country.mmdb", "path to the MaxMind Country or City database",
+		func(c *Config) *string { return &c.GeoIP.DatabasePath }),
+	stringSetting("geoip.asn_database", "MAXMIND_ASN_DB_PATH", "", "path to the MaxMind GeoLite2-ASN database; empty disables ASN lookups",
+		func(c *Config) *string { return &c.GeoIP.ASNDatabasePath }),
+	durationSetting("geoip.reload_interval", "MAXMIND_RELOAD_INTERVAL", "30s", "how often the database files are checked for changes; 0 disables polling",
+		func(c *Config) *time.Duration { return &c.GeoIP.ReloadInterval }),
+
+	intSetting("cache.size", "LOOKUP_CACHE_SIZE", "10000", "maximum number of cached lookup results; 0 disables the cache",
+		func(c *Config) *int { return &c.Cache.Size }),
+	durationSetting("cache.ttl", "LOOKUP_CACHE_TTL", "10m", "how long a lookup result is cached; 0 caches until evicted or reloaded",
+		func(c *Config) *time.Duration { return &c.Cache.TTL }),
+
+	stringSetting("policies.file", "POLICY_FILE", "", "path to the YAML/JSON file of named policies",
+		func(c *Config) *string { return &c.Policies.FilePath }),
+	stringSetting("policies.overrides_file", "OVERRIDE_FILE", "", "path to the YAML/JSON file of CIDR allow/deny overrides",
+		func(c *Config) *string { return &c.Policies.OverrideFilePath }),
+
+	stringSetting("auth.api_keys_file", "API_KEYS_FILE", "", "path to the YAML/JSON file of hashed API keys; empty disables API key authentication",
+		func(c *Config) *string { return &c.Auth.APIKeysFile }),
+	stringSetting("auth.jwt.jwks_file", "JWT_JWKS_FILE", "", "path to the JSON Web Key Set bearer tokens are verified against",
+		func(c *Config) *string { return &c.Auth.JWT.JWKSFile }),
+	stringSetting("auth.jwt.jwks_url", "JWT_JWKS_URL", "", "URL the JSON Web Key Set is fetched from, instead of a file",
+		func(c *Config) *string { return &c.Auth.JWT.JWKSURL }),
+	stringSetting("auth.jwt.issuer", "JWT_ISSUER", "", `required "iss" claim of bearer tokens`,
+		func(c *Config) *string { return &c.Auth.JWT.Issuer }),
+	stringSetting("auth.jwt.audience", "JWT_AUDIENCE", "", `value the "aud" claim of bearer tokens must contain`,
+		func(c *Config) *string { return &c.Auth.JWT.Audience }),
+	stringSetting("auth.jwt.scopes_claim", "JWT_SCOPES_CLAIM", "scope", "claim holding the scopes of the caller",
+		func(c *Config) *string { return &c.Auth.JWT.ScopesClaim }),
+	stringSetting("auth.jwt.policies_claim", "JWT_POLICIES_CLAIM", "policies", "claim listing the policies the caller may check against",
+		func(c *Config) *string { return &c.Auth.JWT.PoliciesClaim }),
+	durationSetting("auth.jwt.leeway", "JWT_LEEWAY", "30s", "clock skew tolerated when checking token expiry",
+		func(c *Config) *time.Duration { return &c.Auth.JWT.Leeway }),
+	durationSetting("auth.jwt.refresh_interval", "JWT_JWKS_REFRESH_INTERVAL", "5m", "how often the JSON Web Key Set is reloaded; 0 disables polling",
+		func(c *Config) *time.Duration { return &c.Auth.JWT.RefreshInterval }),
+
+	stringSetting("ratelimit.file", "RATE_LIMIT_FILE", "", "path to the YAML/JSON file of per-client rate limits and daily quotas; empty disables rate limiting",
+		func(c *Config) *string { return &c.RateLimit.FilePath }),
+	stringSetting("ratelimit.quota_store", "RATE_LIMIT_QUOTA_STORE", "", "path to the file daily quota counters are persisted to; empty keeps them in memory",
+		func(c *Config) *string { return &c.RateLimit.QuotaStore }),
+	durationSetting("ratelimit.flush_interval", "RATE_LIMIT_FLUSH_INTERVAL", "10s", "how often daily quota counters are persisted",
+		func(c *Config) *time.Duration { return &c.RateLimit.FlushInterval }),
+
+	intSetting("ext_authz.trusted_hops", "EXT_AUTHZ_TRUSTED_HOPS", "0", "number of proxies appending to x-forwarded-for in front of ext_authz; 0 checks the downstream address",
+		func(c *Config) *int { return &c.ExtAuthz.TrustedHops }),
+	stringSetting("ext_authz.default_policy", "EXT_AUTHZ_DEFAULT_POLICY", "", "policy of the Envoy routes that do not name one",
+		func(c *Config) *string { return &c.ExtAuthz.DefaultPolicy }),
+
+	stringSetting("tracing.exporter", "TRACING_EXPORTER", "none", `where trace spans are sent: "none", "stdout" or "otlp"`,
+		func(c *Config) *string { return &c.Tracing.Exporter }),
+
+	stringSetting("log.level", "LOG_LEVEL", "info", `minimum log level: "debug", "info", "warn" or "error"`,
+		func(c *Config) *string { return &c.Log.Level }),
+
+	durationSetting("shutdown.drain_delay", "SHUTDOWN_DRAIN_DELAY", "0s", "how long readiness fails before the listeners stop accepting connections",
+		func(c *Config) *time.Duration { return &c.Shutdown.DrainDelay }),
+	durationSetting("shutdown.timeout", "SHUTDOWN_TIMEOUT", "30s", "deadline for in-flight requests to complete on shutdown",
+		func(c *Config) *time.Duration { return &c.Shutdown.Timeout }),
+}
+
+// stringSetting describes a setting holding a string.
+func stringSetting[T ~string](key, env, def, usage string, field func(*Config) *T) setting {
+	return setting{
+		key: key, env: env, def: def, usage: usage, tag: "!!str",
+		set: func(c *Config, value string) error {
+			*field(c) = T(value)
+			return nil
+		},
+		get: func(c *Config) string { return string(*field(c)) },
+	}
+}
+
+// intSetting describes a setting holding an integer.
+func intSetting(key, env, def, usage string, field func(*Config) *int) setting {
+	return setting{
+		key: key, env: env, def: def, usage: usage, tag: "!!int",
+		set: func(c *Config, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.New("must be an integer")
+			}
+			*field(c) = n
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Itoa(*field(c)) },
+	}
+}
+
+// listSetting describes a setting holding a comma-separated list of strings; blank entries are dropped.
+func listSetting(key, env, def, usage string, field func(*Config) *[]string) setting {
+	return setting{
+		key: key, env: env, def: def, usage: usage, tag: "!!str",
+		set: func(c *Config, value string) error {
+			var list []string
+			for _, entry := range strings.Split(value, ",") {
+				if entry = strings.TrimSpace(entry); entry != "" {
+					list = append(list, entry)
+				}
+			}
+			*field(c) = list
+			return nil
+		},
+		get: func(c *Config) string { return strings.Join(*field(c), ",") },
+	}
+}
+
+// durationSetting describes a setting holding a Go duration.
+func durationSetting(key, env, def, usage string, field func(*Config) *time.Duration) setting {
+	return setting{
+		key: key, env: env, def: def, usage: usage, tag: "!!str",
+		set: func(c *Config, value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return errors.New(`must be a Go duration such as "30s", "5m" or "1h30m"`)
+			}
+			*field(c) = d
+			return nil
+		},
+		get: func(c *Config) string { return field(c).String() },
+	}
+}
+
+// Load assembles the configuration from, in increasing order of precedence:
 //
-// Environment Variables:
-//   - HTTP_PORT: specifies the server HTTP port (default: "8080").
-//   - MAXMIND_DB_PATH: specifies the file path to the MaxMind GeoLite2 database (default: "./GeoLite2-Country.mmdb").
+//  1. The defaults.
+//  2. The config file named by the --config flag or the IPCHECKER_CONFIG environment variable, if any. Its format
+//     follows its extension: .yaml/.yml, .json or .toml. Settings are nested by the dots of their key, e.g.
+//     "http.port" is the port key of the http section.
+//  3. Environment variables (e.g., HTTP_PORT, MAXMIND_DB_PATH); empty variables are ignored.
+//  4. Command-line flags (e.g., --http-port, --geoip-database).
+//
+// The result is then validated: every invalid value is reported, along with where it was set.
+//
+// Parameters:
+//   - args: The command-line arguments, without the program name.
 //
 // Returns:
-//   - *Config: pointer to initialized Config struct.
-//   - error: currently always returns nil; future revisions may include actual error handling as needed.
-func Load() (*Config, error) {
-	cfg := &Config{
-		HTTPPort:      getEnv("HTTP_PORT", "8080"),
-		MaxMindDBPath: getEnv("MAXMIND_DB_PATH", "./GeoLite2-Country.mmdb"),
+//   - *Config: The validated configuration.
+//   - error: An error listing every problem found, or flag.ErrHelp if --help was requested.
+func Load(args []string) (*Config, error) {
+	cfg := &Config{sources: make(map[string]string)}
+	var errs []error
+	apply := func(s setting, value, source string) {
+		if err := s.set(cfg, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q from %s: %w", s.key, value, source, err))
+			return
+		}
+		cfg.sources[s.key] = source
+	}
+
+	// Parse the flags first to find the config file, but apply them last.
+	fs := flag.NewFlagSet("ipchecker", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv(ConfigFileEnv), "path to a YAML, JSON or TOML config file (env "+ConfigFileEnv+")")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the effective configuration as YAML and exit")
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var flagValues []flagValue
+	for _, s := range settings {
+		s := s
+		fs.Func(s.flagName(), fmt.Sprintf("%s (env %s, default %q)", s.usage, s.env, s.def), func(value string) error {
+			flagValues = append(flagValues, flagValue{setting: s, value: value})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments %q: settings are passed as flags, e.g. --http-port=8080", fs.Args())
+	}
+
+	for _, s := range settings {
+		apply(s, s.def, "default")
+	}
+
+	if *configFile != "" {
+		values, err := readConfigFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range settings {
+			if value, ok := values[s.key]; ok {
+				apply(s, value, "config file "+*configFile)
+				delete(values, s.key)
+			}
+		}
+		for key := range values {
+			errs = append(errs, fmt.Errorf("%s: unknown setting in config file %s", key, *configFile))
+		}
+	}
+
+	for _, s := range settings {
+		if value := os.Getenv(s.env); value != "" {
+			apply(s, value, "env "+s.env)
+		}
+	}
+
+	for _, fv := range flagValues {
+		apply(fv.setting, fv.value, "flag --"+fv.setting.flagName())
+	}
+
+	if len(errs) == 0 {
+		errs = cfg.validate()
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 	return cfg, nil
 }
 
-// getEnv retrieves an environment variable using the provided key.
-// If the environment variable is not set or empty, it returns the specified default value.
+// readConfigFile reads a config file and flattens its sections into setting keys, e.g. {"http": {"port": 8080}}
+// into "http.port" = "8080".
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var doc map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml", ".json":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported extension %q: must be .yaml, .yml, .json or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", doc, values); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// flatten stores the scalar values of a config file section in values, keyed by their dotted path. Lists of
+// scalars are joined with commas, as list settings are given in environment variables and flags.
+func flatten(prefix string, section map[string]interface{}, values map[string]string) error {
+	for name, value := range section {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := flatten(key, v, values); err != nil {
+				return err
+			}
+		case []interface{}:
+			entries := make([]string, len(v))
+			for i, entry := range v {
+				switch entry.(type) {
+				case map[string]interface{}, []interface{}:
+					return fmt.Errorf("%s: only lists of values are supported", key)
+				}
+				entries[i] = fmt.Sprint(entry)
+			}
+			values[key] = strings.Join(entries, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+// validate checks the assembled configuration, returning one error per problem found.
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, c.invalid(key, err))
+		}
+	}
+
+	check("http.address", validateAddress(c.HTTP.Address))
+	check("http.port", validatePort(c.HTTP.Port))
+	check("http.trusted_proxies", validateProxies(c.HTTP.TrustedProxies))
+	check("grpc.address", validateAddress(c.GRPC.Address))
+	check("grpc.port", validatePort(c.GRPC.Port))
+	check("grpc.trusted_proxies", validateProxies(c.GRPC.TrustedProxies))
+	if c.HTTP.Port == c.GRPC.Port && c.HTTP.Address == c.GRPC.Address {
+		check("grpc.port", fmt.Errorf("%d is also the HTTP port: the listeners need different ports", c.GRPC.Port))
+	}
+
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"http.read_header_timeout", c.HTTP.ReadHeaderTimeout},
+		{"http.read_timeout", c.HTTP.ReadTimeout},
+		{"http.write_timeout", c.HTTP.WriteTimeout},
+		{"http.idle_timeout", c.HTTP.IdleTimeout},
+		{"tls.reload_interval", c.TLSReloadInterval},
+		{"geoip.reload_interval", c.GeoIP.ReloadInterval},
+		{"cache.ttl", c.Cache.TTL},
+		{"shutdown.drain_delay", c.Shutdown.DrainDelay},
+		{"auth.jwt.leeway", c.Auth.JWT.Leeway},
+		{"auth.jwt.refresh_interval", c.Auth.JWT.RefreshInterval},
+	} {
+		if d.value < 0 {
+			check(d.key, errors.New("must not be negative"))
+		}
+	}
+	if c.GRPC.ConnectionTimeout <= 0 {
+		check("grpc.connection_timeout", errors.New("must be positive"))
+	}
+	if c.RateLimit.FlushInterval <= 0 {
+		check("ratelimit.flush_interval", errors.New("must be positive"))
+	}
+	if c.Shutdown.Timeout <= 0 {
+		check("shutdown.timeout", errors.New("must be positive"))
+	}
+	if c.ExtAuthz.TrustedHops < 0 {
+		check("ext_authz.trusted_hops", errors.New("must be 0 (use the downstream address) or a positive number of proxies"))
+	}
+	if c.Cache.Size < 0 {
+		check("cache.size", errors.New("must be 0 (disabled) or a positive number of entries"))
+	}
+
+	check("geoip.database", validateFile(c.GeoIP.DatabasePath, true))
+	check("geoip.asn_database", validateFile(c.GeoIP.ASNDatabasePath, false))
+	check("policies.file", validateFile(c.Policies.FilePath, false))
+	check("policies.overrides_file", validateFile(c.Policies.OverrideFilePath, false))
+	check("auth.api_keys_file", validateFile(c.Auth.APIKeysFile, false))
+	errs = append(errs, c.validateJWT()...)
+	check("ratelimit.file", validateFile(c.RateLimit.FilePath, false))
+	if c.RateLimit.QuotaStore != "" {
+		if info, err := os.Stat(filepath.Dir(c.RateLimit.QuotaStore)); err != nil || !info.IsDir() {
+			check("ratelimit.quota_store", errors.New("must be in an existing directory"))
+		}
+	}
+
+	errs = append(errs, c.validateTLS("http.tls", c.HTTP.TLS)...)
+	errs = append(errs, c.validateTLS("grpc.tls", c.GRPC.TLS)...)
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		check("tracing.exporter", errors.New(`must be "none", "stdout" or "otlp"`))
+	}
+	if _, err := zapcore.ParseLevel(c.Log.Level); err != nil || c.Log.Level == "" {
+		check("log.level", errors.New(`must be "debug", "info", "warn" or "error"`))
+	}
+	return errs
+}
+
+// validateTLS checks the TLS settings of one listener.
+func (c *Config) validateTLS(prefix string, opts tlsconfig.Options) []error {
+	var errs []error
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, c.invalid(prefix+"."+key, err))
+		}
+	}
+
+	if opts.Enabled() {
+		if opts.CertFile == "" {
+			check("cert_file", errors.New("is required when a key file is set"))
+		}
+		if opts.KeyFile == "" {
+			check("key_file", errors.New("is required when a certificate file is set"))
+		}
+	} else if opts.ClientCAFile != "" {
+		check("client_ca_file", errors.New("client certificates need TLS: set cert_file and key_file as well"))
+	}
+	check("cert_file", validateFile(opts.CertFile, false))
+	check("key_file", validateFile(opts.KeyFile, false))
+	check("client_ca_file", validateFile(opts.ClientCAFile, false))
+
+	switch opts.ClientAuth {
+	case "", tlsconfig.ClientAuthNone:
+	case tlsconfig.ClientAuthOptional, tlsconfig.ClientAuthRequire:
+		if opts.ClientCAFile == "" {
+			check("client_auth", fmt.Errorf("%q needs a client_ca_file to verify client certificates against", opts.ClientAuth))
+		}
+	default:
+		check("client_auth", errors.New(`must be "none", "optional" or "require"`))
+	}
+	return errs
+}
+
+// validateJWT checks the bearer-token settings, which only need to be complete once a JWKS is configured.
+func (c *Config) validateJWT() []error {
+	var errs []error
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, c.invalid("auth.jwt."+key, err))
+		}
+	}
+
+	jwt := c.Auth.JWT
+	check("jwks_file", validateFile(jwt.JWKSFile, false))
+	if jwt.JWKSFile != "" && jwt.JWKSURL != "" {
+		check("jwks_url", errors.New("set either a JWKS file or a JWKS URL, not both"))
+	}
+	if jwt.JWKSURL != "" {
+		if u, err := url.Parse(jwt.JWKSURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			check("jwks_url", errors.New("must be an http:// or https:// URL"))
+		}
+	}
+	if !jwt.Enabled() {
+		return errs
+	}
+	if jwt.Issuer == "" {
+		check("issuer", errors.New("is required to accept bearer tokens"))
+	}
+	if jwt.Audience == "" {
+		check("audience", errors.New("is required to accept bearer tokens"))
+	}
+	if jwt.ScopesClaim == "" {
+		check("scopes_claim", errors.New("is required to accept bearer tokens"))
+	}
+	return errs
+}
+
+// invalid wraps a validation error with the key of the setting, where its value was set, and how to change it.
+func (c *Config) invalid(key string, err error) error {
+	s := c.lookup(key)
+	return fmt.Errorf("%s: %w (value %q from %s; set it with --%s, %s or %s in the config file)",
+		key, err, s.get(c), c.sources[key], s.flagName(), s.env, key)
+}
+
+// lookup returns the setting with the given key.
+func (c *Config) lookup(key string) setting {
+	for _, s := range settings {
+		if s.key == key {
+			return s
+		}
+	}
+	panic("config: unknown setting " + key)
+}
+
+// validateProxies checks that every trusted proxy is an IP address or a CIDR range.
+func validateProxies(proxies []string) error {
+	for _, proxy := range proxies {
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			return fmt.Errorf("%q is neither an IP address nor a CIDR range", proxy)
+		}
+	}
+	return nil
+}
+
+// validatePort checks that port is a TCP port number.
+func validatePort(port int) error {
+	if port < 1 || port > 65535 {
+		return errors.New("must be a port number between 1 and 65535")
+	}
+	return nil
+}
+
+// validateAddress checks that address is empty, an IP address or a host name, without a port.
+func validateAddress(address string) error {
+	if address == "" || net.ParseIP(address) != nil {
+		return nil
+	}
+	for _, r := range address {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-') {
+			return errors.New("must be empty, an IP address or a host name, without a port")
+		}
+	}
+	return nil
+}
+
+// validateFile checks that path names a readable regular file; an empty path is accepted unless required.
+func validateFile(path string, required bool) error {
+	if path == "" {
+		if required {
+			return errors.New("is required")
+		}
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory, not a file", path)
+	}
+	return nil
+}
+
+// WriteYAML writes the effective configuration as a YAML config file, in the format Load reads. Each value set
+// other than by default is annotated with where it was set.
 //
 // Parameters:
-//   - key (string): the environment variable key to retrieve.
-//   - defaultVal (string): the default value to return if the environment variable is not found.
+//   - w: The destination, e.g. os.Stdout for --print-config.
 //
 // Returns:
-//   - string: the environment value if present; otherwise, the provided default value.
-func getEnv(key, defaultVal string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
+//   - error: An error if writing fails.
+func (c *Config) WriteYAML(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range settings {
+		// Find or create the section of each dotted key part, then append the value to the innermost one.
+		parts := strings.Split(s.key, ".")
+		section := root
+		for _, part := range parts[:len(parts)-1] {
+			section = childSection(section, part)
+		}
+
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: s.tag, Value: s.get(c)}
+		if source := c.sources[s.key]; source != "" && source != "default" {
+			value.LineComment = source
+		}
+		section.Content = append(section.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: parts[len(parts)-1]}, value)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// childSection returns the mapping stored under name in section, creating it if needed.
+func childSection(section *yaml.Node, name string) *yaml.Node {
+	for i := 0; i+1 < len(section.Content); i += 2 {
+		if section.Content[i].Value == name {
+			return section.Content[i+1]
+		}
 	}
-	return defaultVal
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	section.Content = append(section.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, child)
+	return child
 }
@@ -0,0 +1,174 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/justfairdev/ipchecker/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes a file named name into a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// TestLoad_Defaults verifies that settings not given anywhere take their defaults.
+func TestLoad_Defaults(t *testing.T) {
+	db := writeFile(t, "country.mmdb", "")
+
+	cfg, err := config.Load([]string{"--geoip-database", db})
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8080", cfg.HTTP.ListenAddress())
+	assert.Equal(t, ":50051", cfg.GRPC.ListenAddress())
+	assert.Equal(t, 10*time.Second, cfg.HTTP.ReadHeaderTimeout)
+	assert.Equal(t, 20*time.Second, cfg.GRPC.ConnectionTimeout)
+	assert.Equal(t, 30*time.Second, cfg.GeoIP.ReloadInterval)
+	assert.Equal(t, 10000, cfg.Cache.Size)
+	assert.Equal(t, "none", cfg.Tracing.Exporter)
+	assert.Equal(t, "info", cfg.Log.Level)
+	assert.False(t, cfg.HTTP.TLS.Enabled())
+	assert.Empty(t, cfg.HTTP.TrustedProxies)
+	assert.Equal(t, []string{"X-Forwarded-For", "X-Real-IP"}, cfg.HTTP.ClientIPHeaders)
+	assert.Empty(t, cfg.GRPC.TrustedProxies)
+	assert.Equal(t, []string{"x-forwarded-for", "x-real-ip"}, cfg.GRPC.ClientIPMetadata)
+	assert.False(t, cfg.PrintConfig)
+}
+
+// TestLoad_Precedence verifies that environment variables override the config file and flags override both.
+func TestLoad_Precedence(t *testing.T) {
+	db := writeFile(t, "country.mmdb", "")
+	file := writeFile(t, "ipchecker.yaml", `
+http:
+  address: 127.0.0.1
+  port: 9000
+  trusted_proxies: [10.0.0.0/8, 192.0.2.1]
+grpc:
+  port: 9001
+cache:
+  size: 5
+geoip:
+  database: `+db+`
+log:
+  level: debug
+`)
+	t.Setenv(config.ConfigFileEnv, file)
+	t.Setenv("HTTP_PORT", "9100")
+	t.Setenv("LOG_LEVEL", "warn")
+
+	cfg, err := config.Load([]string{"--http-port=9200"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "127.0.0.1:9200", cfg.HTTP.ListenAddress(), "Expected the flag to win over env and file.")
+	assert.Equal(t, 9001, cfg.GRPC.Port, "Expected the file to win over the default.")
+	assert.Equal(t, 5, cfg.Cache.Size)
+	assert.Equal(t, "warn", cfg.Log.Level, "Expected env to win over the file.")
+	assert.Equal(t, db, cfg.GeoIP.DatabasePath)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.0.2.1"}, cfg.HTTP.TrustedProxies, "Expected file lists to be read.")
+}
+
+// TestLoad_FileFormats verifies that JSON and TOML config files are read like YAML ones.
+func TestLoad_FileFormats(t *testing.T) {
+	db := writeFile(t, "country.mmdb", "")
+
+	for name, content := range map[string]string{
+		"ipchecker.json": `{"grpc": {"port": 6000, "connection_timeout": "5s"}, "geoip": {"database": "` + db + `"}}`,
+		"ipchecker.toml": "[grpc]\nport = 6000\nconnection_timeout = \"5s\"\n\n[geoip]\ndatabase = \"" + db + "\"\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := config.Load([]string{"--config", writeFile(t, name, content)})
+			require.NoError(t, err)
+			assert.Equal(t, 6000, cfg.GRPC.Port)
+			assert.Equal(t, 5*time.Second, cfg.GRPC.ConnectionTimeout)
+		})
+	}
+}
+
+// TestLoad_ReportsEveryProblem verifies that all invalid values are reported at once, each naming where it was set
+// and how to change it, and that unknown config file keys are rejected.
+func TestLoad_ReportsEveryProblem(t *testing.T) {
+	db := writeFile(t, "country.mmdb", "")
+	t.Setenv("HTTP_PORT", "70000")
+
+	_, err := config.Load([]string{
+		"--geoip-database", db,
+		"--grpc-port", "8080",
+		"--http-port", "8080",
+		"--cache-ttl", "-1m",
+		"--http-tls-client-ca-file", db,
+		"--ext-authz-trusted-hops", "-1",
+		"--http-trusted-proxies", "10.0.0.0/8, proxy.local",
+		"--grpc-trusted-proxies", "10.0.0.0/33",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "grpc.port: 8080 is also the HTTP port")
+	assert.Contains(t, err.Error(), `cache.ttl: must not be negative (value "-1m0s" from flag --cache-ttl`)
+	assert.Contains(t, err.Error(), "http.tls.client_ca_file: client certificates need TLS")
+	assert.Contains(t, err.Error(), "ext_authz.trusted_hops: must be 0")
+	assert.Contains(t, err.Error(), `http.trusted_proxies: "proxy.local" is neither an IP address nor a CIDR range`)
+	assert.Contains(t, err.Error(), `grpc.trusted_proxies: "10.0.0.0/33" is neither an IP address nor a CIDR range`)
+	assert.NotContains(t, err.Error(), "http.port:", "Expected the flag to replace the invalid env value.")
+
+	_, err = config.Load([]string{"--geoip-database", db, "--http-port", "0"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `http.port: must be a port number between 1 and 65535 (value "0" from flag --http-port; set it with --http-port, HTTP_PORT or http.port in the config file)`)
+
+	_, err = config.Load([]string{"--geoip-database", filepath.Join(t.TempDir(), "missing.mmdb")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "geoip.database: ")
+
+	file := writeFile(t, "ipchecker.yaml", "http:\n  prot: 9000\n")
+	_, err = config.Load([]string{"--config", file, "--geoip-database", db})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http.prot: unknown setting in config file")
+
+	_, err = config.Load([]string{"--geoip-database", db, "--geoip-reload-interval", "soon"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `geoip.reload_interval: invalid value "soon" from flag --geoip-reload-interval`)
+
+	_, err = config.Load([]string{"--geoip-database", db, "--auth-jwt-jwks-url", "file:///etc/jwks.json"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "auth.jwt.jwks_url: must be an http:// or https:// URL")
+	assert.Contains(t, err.Error(), "auth.jwt.issuer: is required to accept bearer tokens")
+	assert.Contains(t, err.Error(), "auth.jwt.audience: is required to accept bearer tokens")
+
+	_, err = config.Load([]string{"--help"})
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+}
+
+// TestConfig_WriteYAML verifies that the printed configuration annotates where values were set and loads back into
+// the same configuration.
+func TestConfig_WriteYAML(t *testing.T) {
+	db := writeFile(t, "country.mmdb", "")
+	t.Setenv("LOOKUP_CACHE_TTL", "90s")
+
+	cfg, err := config.Load([]string{"--geoip-database", db, "--grpc-address", "127.0.0.1", "--print-config"})
+	require.NoError(t, err)
+	assert.True(t, cfg.PrintConfig)
+
+	var out bytes.Buffer
+	require.NoError(t, cfg.WriteYAML(&out))
+	assert.Contains(t, out.String(), "address: 127.0.0.1 # flag --grpc-address")
+	assert.Contains(t, out.String(), "ttl: 1m30s # env LOOKUP_CACHE_TTL")
+	assert.Contains(t, out.String(), "port: 50051\n")
+
+	t.Setenv("LOOKUP_CACHE_TTL", "")
+	reloaded, err := config.Load([]string{"--config", writeFile(t, "printed.yaml", out.String())})
+	require.NoError(t, err)
+	assert.Equal(t, cfg.HTTP, reloaded.HTTP)
+	assert.Equal(t, cfg.GRPC, reloaded.GRPC)
+	assert.Equal(t, cfg.GeoIP, reloaded.GeoIP)
+	assert.Equal(t, cfg.Cache, reloaded.Cache)
+	assert.Equal(t, cfg.Log, reloaded.Log)
+}
@@ -0,0 +1,13 @@
+package dtos
+
+// HealthResponse represents the outcome of a liveness or readiness probe.
+//
+// swagger:model HealthResponse
+type HealthResponse struct {
+	// Status is "ok" if the probe passed and "unavailable" otherwise.
+	Status string `json:"status"`
+
+	// Checks maps the name of each failed readiness check (e.g., "geo" or "shutdown") to the reason it failed;
+	// omitted if the probe passed.
+	Checks map[string]string `json:"checks,omitempty"`
+}
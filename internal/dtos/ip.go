@@ -9,10 +9,24 @@ type IPCheckRequest struct {
 	// Required field.
 	IPAddress string `json:"ip_address" binding:"required"`
 
-	// AllowedCountries is a list of acceptable country codes (ISO 3166-1 alpha-2 format).
-	// The IP address must originate from one of these countries.
-	// Required field.
-	AllowedCountries []string `json:"allowed_countries" binding:"required"`
+	// AllowedCountries is a list of acceptable country codes (ISO 3166-1 alpha-2 format) or subdivision codes
+	// (ISO 3166-2 format, e.g. "US-CA"; requires a City database).
+	// The IP address must originate from one of these countries or subdivisions.
+	// Exactly one of Policy, AllowedCountries or BlockedCountries is required.
+	AllowedCountries []string `json:"allowed_countries"`
+
+	// BlockedCountries is a list of rejected country codes (ISO 3166-1 alpha-2 format) or subdivision codes
+	// (ISO 3166-2 format, e.g. "CA-QC"; requires a City database).
+	// The IP address must not originate from any of these countries or subdivisions.
+	BlockedCountries []string `json:"blocked_countries,omitempty"`
+
+	// DefaultAction decides IP addresses without a known country: "allow", "deny" (default) or "error".
+	// Only valid with AllowedCountries or BlockedCountries; named policies carry their own default action.
+	DefaultAction string `json:"default_action,omitempty" enums:"allow,deny,error"`
+
+	// Policy is the name of a server-side policy (e.g., "checkout-eu") to check the IP address against.
+	// Must not be combined with AllowedCountries, BlockedCountries or DefaultAction.
+	Policy string `json:"policy"`
 }
 
 // IPCheckResponse represents the response payload after checking the requested IP address.
@@ -22,6 +36,148 @@ type IPCheckResponse struct {
 	// Allowed indicates whether the given IP address is from one of the allowed countries.
 	Allowed bool `json:"allowed"`
 
-	// Country is the ISO 3166-1 alpha-2 country code associated with the IP address.
+	// Country is the ISO 3166-1 alpha-2 country code associated with the IP address; empty if none is known.
+	Country string `json:"country"`
+
+	// Reason tells what decided: "rule" (the country list), "asn" (the ASN rules of the policy), "default"
+	// (no country is known, so the default action applied) or "override" (a CIDR override rule).
+	Reason string `json:"reason" enums:"rule,asn,default,override"`
+
+	// ASN is the autonomous system number of the IP address; only resolved for server-side policies with
+	// ASN rules, omitted otherwise.
+	ASN uint `json:"asn,omitempty"`
+
+	// Policy is the name of the server-side policy that produced the decision; omitted for inline lists.
+	Policy string `json:"policy,omitempty"`
+
+	// PolicyVersion is the version of the server-side policy that produced the decision; omitted for inline lists.
+	PolicyVersion string `json:"policy_version,omitempty"`
+
+	// Override identifies the CIDR override rule that decided the request instead of the country lookup.
+	// When set, Country is empty.
+	Override *OverrideMatch `json:"override,omitempty"`
+}
+
+// CallerCheckRequest represents the request payload for checking whether the caller itself, identified by the IP
+// address of the request, originates from one of the allowed countries.
+//
+// swagger:model CallerCheckRequest
+type CallerCheckRequest struct {
+	// AllowedCountries is a list of acceptable country codes (ISO 3166-1 alpha-2 format) or subdivision codes
+	// (ISO 3166-2 format, e.g. "US-CA"; requires a City database).
+	// Exactly one of Policy, AllowedCountries or BlockedCountries is required.
+	AllowedCountries []string `json:"allowed_countries"`
+
+	// BlockedCountries is a list of rejected country codes (ISO 3166-1 alpha-2 format) or subdivision codes
+	// (ISO 3166-2 format, e.g. "CA-QC"; requires a City database).
+	BlockedCountries []string `json:"blocked_countries,omitempty"`
+
+	// DefaultAction decides callers without a known country: "allow", "deny" (default) or "error".
+	// Only valid with AllowedCountries or BlockedCountries; named policies carry their own default action.
+	DefaultAction string `json:"default_action,omitempty" enums:"allow,deny,error"`
+
+	// Policy is the name of a server-side policy (e.g., "checkout-eu") to check the caller against.
+	// Must not be combined with AllowedCountries, BlockedCountries or DefaultAction.
+	Policy string `json:"policy"`
+}
+
+// CallerCheckResponse represents the response payload after checking the caller: the decision, as for an
+// IPCheckResponse, and the IP address that was checked.
+//
+// swagger:model CallerCheckResponse
+type CallerCheckResponse struct {
+	IPCheckResponse
+
+	// IPAddress is the IP address of the caller that was checked.
+	IPAddress string `json:"ip_address"`
+
+	// IPSource tells where IPAddress was taken from: "peer" for the connection, otherwise the lower-cased name of
+	// the forwarding header set by a trusted proxy (e.g., "x-forwarded-for").
+	IPSource string `json:"ip_source"`
+}
+
+// OverrideMatch identifies the CIDR override rule that decided a request.
+//
+// swagger:model OverrideMatch
+type OverrideMatch struct {
+	// Action is the decision forced by the rule: "allow" or "deny".
+	Action string `json:"action"`
+
+	// Name is the name of the override rule.
+	Name string `json:"name"`
+
+	// CIDR is the network of the rule that contained the IP address.
+	CIDR string `json:"cidr"`
+}
+
+// IPBatchCheckRequest represents the request payload for checking several IP addresses
+// against one shared list of allowed countries.
+//
+// swagger:model IPBatchCheckRequest
+type IPBatchCheckRequest struct {
+	// IPAddresses is the list of IP addresses to be verified (at most 1000 per request).
+	// Required field.
+	IPAddresses []string `json:"ip_addresses" binding:"required,min=1,max=1000"`
+
+	// AllowedCountries is a list of acceptable country codes (ISO 3166-1 alpha-2 format) or subdivision codes
+	// (ISO 3166-2 format) applied to every IP address in the batch.
+	// Exactly one of Policy, AllowedCountries or BlockedCountries is required.
+	AllowedCountries []string `json:"allowed_countries"`
+
+	// BlockedCountries is a list of rejected country codes (ISO 3166-1 alpha-2 format) or subdivision codes
+	// (ISO 3166-2 format) applied to every IP address in the batch.
+	BlockedCountries []string `json:"blocked_countries,omitempty"`
+
+	// DefaultAction decides IP addresses without a known country: "allow", "deny" (default) or "error".
+	// Only valid with AllowedCountries or BlockedCountries.
+	DefaultAction string `json:"default_action,omitempty" enums:"allow,deny,error"`
+
+	// Policy is the name of a server-side policy applied to every IP address in the batch.
+	// Must not be combined with AllowedCountries, BlockedCountries or DefaultAction.
+	Policy string `json:"policy"`
+}
+
+// IPBatchCheckResult represents the outcome for a single IP address of a batch check.
+//
+// swagger:model IPBatchCheckResult
+type IPBatchCheckResult struct {
+	// IPAddress is the IP address this result refers to, as sent in the request.
+	IPAddress string `json:"ip_address"`
+
+	// Allowed indicates whether the IP address is from one of the allowed countries.
+	Allowed bool `json:"allowed"`
+
+	// Country is the ISO 3166-1 alpha-2 country code associated with the IP address; empty if none is known.
 	Country string `json:"country"`
+
+	// Reason tells what decided: "rule", "asn", "default" or "override"; omitted when Error is set.
+	Reason string `json:"reason,omitempty" enums:"rule,asn,default,override"`
+
+	// ASN is the autonomous system number of the IP address; only resolved for server-side policies with
+	// ASN rules, omitted otherwise.
+	ASN uint `json:"asn,omitempty"`
+
+	// Error describes why this item could not be checked; empty on success.
+	Error string `json:"error,omitempty"`
+
+	// ErrorStatus is the HTTP status code a single check of this item would have answered with, e.g. 400 for a
+	// malformed IP address; zero on success.
+	ErrorStatus int `json:"error_status,omitempty"`
+
+	// Override identifies the CIDR override rule that decided this item instead of the country lookup.
+	Override *OverrideMatch `json:"override,omitempty"`
+}
+
+// IPBatchCheckResponse represents the response payload of a batch check.
+//
+// swagger:model IPBatchCheckResponse
+type IPBatchCheckResponse struct {
+	// Results holds one entry per requested IP address, in request order.
+	Results []IPBatchCheckResult `json:"results"`
+
+	// Policy is the name of the server-side policy applied to the batch; omitted for inline lists.
+	Policy string `json:"policy,omitempty"`
+
+	// PolicyVersion is the version of the server-side policy applied to the batch; omitted for inline lists.
+	PolicyVersion string `json:"policy_version,omitempty"`
 }
@@ -0,0 +1,125 @@
+package dtos
+
+// LookupResponse represents the full geolocation data known for an IP address.
+//
+// swagger:model LookupResponse
+type LookupResponse struct {
+	// IPAddress is the IP address that was looked up, as sent in the request.
+	IPAddress string `json:"ip_address"`
+
+	// Locale is the locale names were requested in; names unavailable in it are returned in English.
+	Locale string `json:"locale"`
+
+	// Continent is the continent the IP address is located in; omitted if unknown.
+	Continent *ContinentInfo `json:"continent,omitempty"`
+
+	// Country is the country the IP address is located in; omitted if unknown.
+	Country *CountryInfo `json:"country,omitempty"`
+
+	// RegisteredCountry is the country the ISP registered the network in; omitted if unknown.
+	// It differs from Country for e.g. mobile roaming or networks of multinational companies.
+	RegisteredCountry *CountryInfo `json:"registered_country,omitempty"`
+
+	// RepresentedCountry is the country represented by users of the IP address, such as the home country
+	// of a military base; omitted unless the database records one.
+	RepresentedCountry *RepresentedCountryInfo `json:"represented_country,omitempty"`
+
+	// City is the city name in the requested locale; omitted unless a City database is loaded.
+	City string `json:"city,omitempty"`
+
+	// Subdivisions lists the subdivisions (states, provinces, ...) of the location, from the largest to the
+	// smallest; omitted unless a City database is loaded.
+	Subdivisions []SubdivisionInfo `json:"subdivisions,omitempty"`
+
+	// PostalCode is the postal code of the location; omitted unless a City database is loaded.
+	PostalCode string `json:"postal_code,omitempty"`
+
+	// AccuracyRadius is the radius in kilometers around the location within which the IP address is likely
+	// to be; omitted unless a City database is loaded.
+	AccuracyRadius uint16 `json:"accuracy_radius,omitempty"`
+
+	// ASN is the autonomous system the IP address is announced by; omitted unless an ASN database is loaded
+	// and has a record for the IP address.
+	ASN *ASNInfo `json:"asn,omitempty"`
+
+	// Traits holds flags describing the network of the IP address.
+	Traits TraitsInfo `json:"traits"`
+}
+
+// ASNInfo identifies an autonomous system.
+//
+// swagger:model ASNInfo
+type ASNInfo struct {
+	// Number is the autonomous system number (e.g., 3320).
+	Number uint `json:"number"`
+
+	// Organization is the organization owning the autonomous system (e.g., "Deutsche Telekom AG").
+	Organization string `json:"organization"`
+}
+
+// SubdivisionInfo identifies a subdivision of a country, such as a state or province.
+//
+// swagger:model SubdivisionInfo
+type SubdivisionInfo struct {
+	// ISOCode is the ISO 3166-2 subdivision code (e.g., "US-CA").
+	ISOCode string `json:"iso_code"`
+
+	// Name is the subdivision name in the requested locale.
+	Name string `json:"name"`
+}
+
+// ContinentInfo identifies a continent.
+//
+// swagger:model ContinentInfo
+type ContinentInfo struct {
+	// Code is the two-letter continent code (e.g., "EU").
+	Code string `json:"code"`
+
+	// Name is the continent name in the requested locale.
+	Name string `json:"name"`
+}
+
+// CountryInfo identifies a country.
+//
+// swagger:model CountryInfo
+type CountryInfo struct {
+	// ISOCode is the ISO 3166-1 alpha-2 country code (e.g., "DE").
+	ISOCode string `json:"iso_code"`
+
+	// Name is the country name in the requested locale.
+	Name string `json:"name"`
+
+	// IsInEuropeanUnion indicates whether the country is a member state of the European Union.
+	IsInEuropeanUnion bool `json:"is_in_european_union"`
+}
+
+// RepresentedCountryInfo identifies the country represented by the users of an IP address.
+//
+// swagger:model RepresentedCountryInfo
+type RepresentedCountryInfo struct {
+	// ISOCode is the ISO 3166-1 alpha-2 country code (e.g., "US").
+	ISOCode string `json:"iso_code"`
+
+	// Name is the country name in the requested locale.
+	Name string `json:"name"`
+
+	// IsInEuropeanUnion indicates whether the country is a member state of the European Union.
+	IsInEuropeanUnion bool `json:"is_in_european_union"`
+
+	// Type describes the kind of representation (e.g., "military").
+	Type string `json:"type"`
+}
+
+// TraitsInfo holds flags describing the network of an IP address.
+//
+// swagger:model TraitsInfo
+type TraitsInfo struct {
+	// IsAnonymousProxy indicates whether the IP address belongs to an anonymous proxy.
+	IsAnonymousProxy bool `json:"is_anonymous_proxy"`
+
+	// IsAnycast indicates whether the network is announced from several locations.
+	IsAnycast bool `json:"is_anycast"`
+
+	// IsSatelliteProvider indicates whether the IP address belongs to a satellite internet provider.
+	IsSatelliteProvider bool `json:"is_satellite_provider"`
+}
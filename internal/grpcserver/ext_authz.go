@@ -0,0 +1,231 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/netip"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/justfairdev/ipchecker/checker"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+// Headers set by the ext_authz service: on the request forwarded upstream when it is allowed, and on the response
+// sent back to the client when it is denied. They overwrite any header of the same name sent by the client; those
+// without a value, such as the country of a client that has none, are removed from the forwarded request instead,
+// so that the upstream service never sees a value forged by the client.
+const (
+	// HeaderCountry carries the ISO 3166-1 alpha-2 country code resolved for the client; empty if none is known.
+	HeaderCountry = "x-ipchecker-country"
+
+	// HeaderReason tells what decided the request: "rule", "default", "asn" or "override".
+	HeaderReason = "x-ipchecker-reason"
+
+	// HeaderPolicy carries the name of the policy the client was checked against.
+	HeaderPolicy = "x-ipchecker-policy"
+
+	// HeaderClientIP carries the client IP address the decision was made for.
+	HeaderClientIP = "x-ipchecker-client-ip"
+)
+
+// ExtAuthzPolicyKey is the context extension, or the field of the ExtAuthzMetadataNamespace metadata, naming the
+// policy the requests of an Envoy route are checked against.
+const ExtAuthzPolicyKey = "policy"
+
+// ExtAuthzMetadataNamespace is the namespace of the route or dynamic metadata the policy of a route can be set in.
+const ExtAuthzMetadataNamespace = "ipchecker"
+
+// ExtAuthzOptions configures the Envoy external authorization service.
+type ExtAuthzOptions struct {
+	// TrustedHops is the number of proxies, Envoy included, that append the address of their peer to the
+	// x-forwarded-for header in front of this service. The client is the TrustedHops-th address from the right of
+	// the header. 0 ignores the header and checks the address of the downstream connection.
+	TrustedHops int
+
+	// DefaultPolicy is the policy used for routes that do not name one; empty requires every route to name one.
+	DefaultPolicy string
+}
+
+// ExtAuthzServer implements the Envoy external authorization service (envoy.service.auth.v3.Authorization), so
+// that Envoy can allow or deny HTTP requests by the country of the client before they reach the upstream service.
+type ExtAuthzServer struct {
+	authv3.UnimplementedAuthorizationServer
+	checker *checker.Checker
+	opts    ExtAuthzOptions
+}
+
+// NewExtAuthzServer constructs a new ExtAuthzServer instance with the provided decision core.
+//
+// Parameters:
+//   - c: The shared checker.Checker that makes the allow/deny decisions.
+//   - opts: How the client address and the policy of a request are found.
+//
+// Returns:
+//   - Pointer to ExtAuthzServer configured with the specified checker.
+func NewExtAuthzServer(c *checker.Checker, opts ExtAuthzOptions) *ExtAuthzServer {
+	return &ExtAuthzServer{checker: c, opts: opts}
+}
+
+// Check decides whether Envoy should forward an HTTP request, by checking the IP address of its client against the
+// policy of its route.
+//
+// The policy is named by the "policy" context extension of the route (set in the per-route ext_authz filter
+// config), otherwise by the "policy" field of the "ipchecker" route or dynamic metadata, otherwise it is the
+// default policy.
+//
+// Parameters:
+//   - ctx: Context carrying metadata and deadlines for the request handling lifecycle.
+//   - req: CheckRequest describing the HTTP request received by Envoy.
+//
+// Returns:
+//   - *authv3.CheckResponse: An OK response adding the x-ipchecker-* headers to the upstream request, and removing
+//     those without a value, if the client is allowed, otherwise a PermissionDenied response answering 403 Forbidden with the same headers. A client
+//     address that is missing or invalid, or has no country when the policy's default action is "error", is denied.
+//   - error: Returns a gRPC status error whose code is mapped from the checker error kind if the policy cannot
+//     be resolved or the lookup fails, so that Envoy applies its failure mode.
+func (s *ExtAuthzServer) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	attributes := req.GetAttributes()
+
+	// A route without a usable policy is a configuration error, not a decision about the client.
+	policy, err := s.checker.ResolvePolicy(ctx, s.policyName(attributes), checker.Policy{})
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	clientIP := s.clientIP(attributes)
+	decision, err := s.checker.Decide(ctx, clientIP, policy)
+	switch {
+	case err != nil && checker.KindOf(err) == checker.KindBackend:
+		return nil, statusFromError(err)
+	case err != nil:
+		headers, _ := decisionHeaders(clientIP, policy.Name, checker.Decision{})
+		return deniedResponse(checker.KindOf(err).GRPCCode(), err.Error(), headers), nil
+	case !decision.Allowed:
+		headers, _ := decisionHeaders(clientIP, policy.Name, decision)
+		return deniedResponse(codes.PermissionDenied, "access denied", headers), nil
+	}
+
+	headers, omitted := decisionHeaders(clientIP, policy.Name, decision)
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{
+			OkResponse: &authv3.OkHttpResponse{Headers: headers, HeadersToRemove: omitted},
+		},
+	}, nil
+}
+
+// policyName returns the name of the policy the route of a request names, or the default policy.
+//
+// Parameters:
+//   - attributes: The attributes of the request received by Envoy.
+//
+// Returns:
+//   - string: The policy name; empty if neither the route nor the options name one.
+func (s *ExtAuthzServer) policyName(attributes *authv3.AttributeContext) string {
+	if name := attributes.GetContextExtensions()[ExtAuthzPolicyKey]; name != "" {
+		return name
+	}
+	for _, md := range []*corev3.Metadata{attributes.GetRouteMetadataContext(), attributes.GetMetadataContext()} {
+		if name := md.GetFilterMetadata()[ExtAuthzMetadataNamespace].GetFields()[ExtAuthzPolicyKey].GetStringValue(); name != "" {
+			return name
+		}
+	}
+	return s.opts.DefaultPolicy
+}
+
+// clientIP returns the IP address of the client of a request: the TrustedHops-th address from the right of its
+// x-forwarded-for header, or the address of the downstream connection if TrustedHops is 0 or the header holds
+// fewer addresses, i.e. the request did not pass through every trusted proxy.
+//
+// Parameters:
+//   - attributes: The attributes of the request received by Envoy.
+//
+// Returns:
+//   - string: The client IP address, unvalidated; empty if Envoy sent no socket address.
+func (s *ExtAuthzServer) clientIP(attributes *authv3.AttributeContext) string {
+	if s.opts.TrustedHops > 0 {
+		// Envoy passes header names in lower case, with repeated headers joined by commas.
+		forwarded := strings.Split(attributes.GetRequest().GetHttp().GetHeaders()["x-forwarded-for"], ",")
+		if forwarded[0] != "" && len(forwarded) >= s.opts.TrustedHops {
+			return normalizeIP(forwarded[len(forwarded)-s.opts.TrustedHops])
+		}
+	}
+	return normalizeIP(attributes.GetSource().GetAddress().GetSocketAddress().GetAddress())
+}
+
+// normalizeIP trims an address taken from a header or socket address, unmapping IPv4-mapped IPv6 addresses.
+// Addresses that cannot be parsed are returned trimmed, to be rejected by the checker.
+func normalizeIP(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		raw = host
+	}
+	if addr, err := netip.ParseAddr(raw); err == nil {
+		return addr.Unmap().String()
+	}
+	return raw
+}
+
+// deniedResponse builds the response telling Envoy to answer a request with 403 Forbidden and a JSON error body.
+//
+// Parameters:
+//   - code: The gRPC code of the response status, e.g. PermissionDenied.
+//   - message: The client-facing reason of the denial.
+//   - headers: The x-ipchecker-* headers describing the decision.
+//
+// Returns:
+//   - *authv3.CheckResponse: The denied response.
+func deniedResponse(code codes.Code, message string, headers []*corev3.HeaderValueOption) *authv3.CheckResponse {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	return &authv3.CheckResponse{
+		Status: &rpcstatus.Status{Code: int32(code), Message: message},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status:  &typev3.HttpStatus{Code: typev3.StatusCode_Forbidden},
+				Headers: append(headers, header("content-type", "application/json")),
+				Body:    string(body),
+			},
+		},
+	}
+}
+
+// decisionHeaders returns the x-ipchecker-* headers describing a decision.
+//
+// Parameters:
+//   - clientIP: The client IP address the decision was made for.
+//   - policy: The name of the policy the client was checked against.
+//   - decision: The decision; zero value if none could be made.
+//
+// Returns:
+//   - []*corev3.HeaderValueOption: The headers that have a value.
+//   - []string: The names of the headers without a value, to be removed from the forwarded request.
+func decisionHeaders(clientIP, policy string, decision checker.Decision) ([]*corev3.HeaderValueOption, []string) {
+	var headers []*corev3.HeaderValueOption
+	var omitted []string
+	for _, h := range [][2]string{
+		{HeaderClientIP, clientIP},
+		{HeaderCountry, decision.Country},
+		{HeaderReason, string(decision.Reason)},
+		{HeaderPolicy, policy},
+	} {
+		if h[1] == "" {
+			omitted = append(omitted, h[0])
+			continue
+		}
+		headers = append(headers, header(h[0], h[1]))
+	}
+	return headers, omitted
+}
+
+// header returns a header option replacing any header of the same name.
+func header(key, value string) *corev3.HeaderValueOption {
+	return &corev3.HeaderValueOption{
+		Header:       &corev3.HeaderValue{Key: key, Value: value},
+		AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+	}
+}
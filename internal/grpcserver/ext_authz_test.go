@@ -0,0 +1,231 @@
+package grpcserver_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/geo/geotest"
+	"github.com/justfairdev/ipchecker/internal/grpcserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newExtAuthzServer returns an ext_authz server locating 81.2.69.0/24 in GB and 128.101.101.0/24 in the US, with
+// the policies "uk-only", "us-only" and "uk-or-unknown", which also allows clients without a country.
+func newExtAuthzServer(t *testing.T, opts grpcserver.ExtAuthzOptions) *grpcserver.ExtAuthzServer {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB", "128.101.101.0/24": "US"})
+	geoSvc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { geoSvc.Close() })
+
+	policyFile := filepath.Join(dir, "policies.yaml")
+	require.NoError(t, os.WriteFile(policyFile, []byte(`policies:
+  uk-only:
+    version: "1"
+    allowed_countries: [GB]
+  us-only:
+    allowed_countries: [US]
+  uk-or-unknown:
+    allowed_countries: [GB]
+    default_action: allow
+`), 0o600))
+	policies, err := checker.LoadPolicyFile(policyFile)
+	require.NoError(t, err)
+
+	return grpcserver.NewExtAuthzServer(checker.NewChecker(geoSvc, policies, nil), opts)
+}
+
+// newCheckRequest builds the CheckRequest Envoy sends for an HTTP request received from source, carrying the
+// given x-forwarded-for header (if not empty) on a route with the given context extensions.
+func newCheckRequest(source, forwardedFor string, extensions map[string]string) *authv3.CheckRequest {
+	headers := map[string]string{":method": "GET", ":path": "/checkout"}
+	if forwardedFor != "" {
+		headers["x-forwarded-for"] = forwardedFor
+	}
+	return &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Source: &authv3.AttributeContext_Peer{
+				Address: &corev3.Address{Address: &corev3.Address_SocketAddress{
+					SocketAddress: &corev3.SocketAddress{
+						Address:       source,
+						PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: 54321},
+					},
+				}},
+			},
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{Method: "GET", Path: "/checkout", Headers: headers},
+			},
+			ContextExtensions: extensions,
+		},
+	}
+}
+
+// responseHeaders returns the headers of an OK or denied response, keyed by name, checking that each replaces the
+// header of the same name sent by the client.
+func responseHeaders(t *testing.T, resp *authv3.CheckResponse) map[string]string {
+	options := resp.GetOkResponse().GetHeaders()
+	if denied := resp.GetDeniedResponse(); denied != nil {
+		options = denied.GetHeaders()
+	}
+	headers := make(map[string]string, len(options))
+	for _, option := range options {
+		headers[option.GetHeader().GetKey()] = option.GetHeader().GetValue()
+		assert.Equal(t, corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD, option.GetAppendAction(),
+			"Expected %s to replace the header sent by the client.", option.GetHeader().GetKey())
+	}
+	return headers
+}
+
+// TestExtAuthz_Check_AllowsAndDenies verifies that clients are checked against the policy named by the context
+// extensions of their route, and that the decision is passed on in x-ipchecker-* headers.
+func TestExtAuthz_Check_AllowsAndDenies(t *testing.T) {
+	srv := newExtAuthzServer(t, grpcserver.ExtAuthzOptions{})
+	ctx := context.Background()
+
+	resp, err := srv.Check(ctx, newCheckRequest("81.2.69.142", "", map[string]string{"policy": "uk-only"}))
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.OK), resp.GetStatus().GetCode())
+	require.NotNil(t, resp.GetOkResponse())
+	assert.Equal(t, map[string]string{
+		grpcserver.HeaderClientIP: "81.2.69.142",
+		grpcserver.HeaderCountry:  "GB",
+		grpcserver.HeaderReason:   "rule",
+		grpcserver.HeaderPolicy:   "uk-only",
+	}, responseHeaders(t, resp))
+
+	resp, err = srv.Check(ctx, newCheckRequest("128.101.101.101", "", map[string]string{"policy": "uk-only"}))
+	require.NoError(t, err)
+	assert.Equal(t, int32(codes.PermissionDenied), resp.GetStatus().GetCode())
+	denied := resp.GetDeniedResponse()
+	require.NotNil(t, denied)
+	assert.Equal(t, typev3.StatusCode_Forbidden, denied.GetStatus().GetCode())
+	assert.JSONEq(t, `{"error": "access denied"}`, denied.GetBody())
+	headers := responseHeaders(t, resp)
+	assert.Equal(t, "US", headers[grpcserver.HeaderCountry])
+	assert.Equal(t, "application/json", headers["content-type"])
+}
+
+// TestExtAuthz_Check_RemovesForgedHeaders verifies that the x-ipchecker-* headers the decision has no value for
+// are removed from the forwarded request, so that values sent by the client never reach the upstream service.
+func TestExtAuthz_Check_RemovesForgedHeaders(t *testing.T) {
+	srv := newExtAuthzServer(t, grpcserver.ExtAuthzOptions{})
+
+	req := newCheckRequest("10.0.0.1", "", map[string]string{"policy": "uk-or-unknown"})
+	req.GetAttributes().GetRequest().GetHttp().GetHeaders()[grpcserver.HeaderCountry] = "GB"
+	resp, err := srv.Check(context.Background(), req)
+	require.NoError(t, err)
+	require.NotNil(t, resp.GetOkResponse())
+
+	headers := responseHeaders(t, resp)
+	assert.NotContains(t, headers, grpcserver.HeaderCountry)
+	assert.Equal(t, "default", headers[grpcserver.HeaderReason])
+	assert.Equal(t, []string{grpcserver.HeaderCountry}, resp.GetOkResponse().GetHeadersToRemove())
+
+	// Headers with a value replace the forged ones instead.
+	resp, err = srv.Check(context.Background(), newCheckRequest("81.2.69.142", "", map[string]string{"policy": "uk-only"}))
+	require.NoError(t, err)
+	assert.Empty(t, resp.GetOkResponse().GetHeadersToRemove())
+}
+
+// TestExtAuthz_Check_ForwardedFor verifies that the client is taken from x-forwarded-for only as far as trusted
+// hops go, so that addresses prepended by the client itself are ignored.
+func TestExtAuthz_Check_ForwardedFor(t *testing.T) {
+	ctx := context.Background()
+	policy := map[string]string{"policy": "uk-only"}
+
+	// Without trusted hops, the header is ignored and the downstream connection decides.
+	resp, err := newExtAuthzServer(t, grpcserver.ExtAuthzOptions{}).
+		Check(ctx, newCheckRequest("128.101.101.101", "81.2.69.142", policy))
+	require.NoError(t, err)
+	assert.NotNil(t, resp.GetDeniedResponse(), "Expected x-forwarded-for to be ignored.")
+
+	srv := newExtAuthzServer(t, grpcserver.ExtAuthzOptions{TrustedHops: 2})
+
+	// A load balancer appended the client, then Envoy appended the load balancer; the client spoofed the first entry.
+	resp, err = srv.Check(ctx, newCheckRequest("10.0.0.2", "128.101.101.101, 81.2.69.142, 10.0.0.2", policy))
+	require.NoError(t, err)
+	require.NotNil(t, resp.GetOkResponse())
+	assert.Equal(t, "81.2.69.142", responseHeaders(t, resp)[grpcserver.HeaderClientIP])
+
+	resp, err = srv.Check(ctx, newCheckRequest("10.0.0.2", "81.2.69.142, 128.101.101.101 ,10.0.0.2", policy))
+	require.NoError(t, err)
+	assert.NotNil(t, resp.GetDeniedResponse(), "Expected the spoofed entry to be ignored.")
+
+	// A request that bypassed the load balancer has fewer entries than trusted hops: its peer decides.
+	resp, err = srv.Check(ctx, newCheckRequest("::ffff:128.101.101.101", "81.2.69.142", policy))
+	require.NoError(t, err)
+	assert.NotNil(t, resp.GetDeniedResponse())
+	assert.Equal(t, "128.101.101.101", responseHeaders(t, resp)[grpcserver.HeaderClientIP])
+}
+
+// TestExtAuthz_Check_PolicySelection verifies that the policy is taken from the context extensions, then the route
+// metadata, then the dynamic metadata, then the default policy, and that a route without one fails the check.
+func TestExtAuthz_Check_PolicySelection(t *testing.T) {
+	ctx := context.Background()
+	metadata := func(policy string) *corev3.Metadata {
+		fields, err := structpb.NewStruct(map[string]interface{}{"policy": policy})
+		require.NoError(t, err)
+		return &corev3.Metadata{FilterMetadata: map[string]*structpb.Struct{"ipchecker": fields}}
+	}
+
+	srv := newExtAuthzServer(t, grpcserver.ExtAuthzOptions{DefaultPolicy: "us-only"})
+
+	req := newCheckRequest("81.2.69.142", "", nil)
+	req.Attributes.RouteMetadataContext = metadata("uk-only")
+	req.Attributes.MetadataContext = metadata("us-only")
+	resp, err := srv.Check(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "uk-only", responseHeaders(t, resp)[grpcserver.HeaderPolicy])
+
+	req.Attributes.ContextExtensions = map[string]string{"policy": "us-only"}
+	resp, err = srv.Check(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "us-only", responseHeaders(t, resp)[grpcserver.HeaderPolicy])
+
+	req = newCheckRequest("81.2.69.142", "", nil)
+	req.Attributes.MetadataContext = metadata("uk-only")
+	resp, err = srv.Check(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "uk-only", responseHeaders(t, resp)[grpcserver.HeaderPolicy])
+
+	resp, err = srv.Check(ctx, newCheckRequest("128.101.101.101", "", nil))
+	require.NoError(t, err)
+	assert.NotNil(t, resp.GetOkResponse())
+	assert.Equal(t, "us-only", responseHeaders(t, resp)[grpcserver.HeaderPolicy])
+
+	// Misconfigured routes fail the check, so that Envoy applies its failure mode.
+	_, err = newExtAuthzServer(t, grpcserver.ExtAuthzOptions{}).Check(ctx, newCheckRequest("81.2.69.142", "", nil))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = srv.Check(ctx, newCheckRequest("81.2.69.142", "", map[string]string{"policy": "missing"}))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// TestExtAuthz_Check_InvalidClient verifies that requests whose client address is missing or invalid are denied.
+func TestExtAuthz_Check_InvalidClient(t *testing.T) {
+	srv := newExtAuthzServer(t, grpcserver.ExtAuthzOptions{TrustedHops: 1})
+	policy := map[string]string{"policy": "uk-only"}
+
+	for _, req := range []*authv3.CheckRequest{
+		newCheckRequest("81.2.69.142", "not-an-ip", policy),
+		{Attributes: &authv3.AttributeContext{ContextExtensions: policy}},
+	} {
+		resp, err := srv.Check(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, int32(codes.InvalidArgument), resp.GetStatus().GetCode())
+		require.NotNil(t, resp.GetDeniedResponse())
+		assert.Equal(t, typev3.StatusCode_Forbidden, resp.GetDeniedResponse().GetStatus().GetCode())
+		assert.JSONEq(t, `{"error": "invalid IP address"}`, resp.GetDeniedResponse().GetBody())
+	}
+}
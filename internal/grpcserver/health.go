@@ -0,0 +1,110 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/justfairdev/ipchecker/internal/health"
+	pb "github.com/justfairdev/ipchecker/proto"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// healthWatchInterval is how often Watch re-evaluates readiness to detect status changes.
+const healthWatchInterval = time.Second
+
+// HealthServerImpl implements the standard gRPC health-checking protocol (grpc.health.v1.Health) on top of the
+// readiness checks of the service, so that gRPC load balancers and Kubernetes gRPC probes see the same status as
+// the HTTP /readyz endpoint.
+//
+// The overall server status (empty service name) and the ipchecker.v1.IPChecker service report SERVING while the
+// service is ready and NOT_SERVING otherwise; other service names are unknown.
+type HealthServerImpl struct {
+	healthpb.UnimplementedHealthServer
+	health *health.Health
+}
+
+// NewHealthServer constructs a new HealthServerImpl reporting the readiness tracked by h.
+//
+// Parameters:
+//   - h: The health state of the service.
+//
+// Returns:
+//   - Pointer to HealthServerImpl configured with the specified health state.
+func NewHealthServer(h *health.Health) *HealthServerImpl {
+	return &HealthServerImpl{health: h}
+}
+
+// Check returns the current serving status of the requested service.
+//
+// Parameters:
+//   - ctx: Context carrying metadata and deadlines for the request handling lifecycle.
+//   - req: HealthCheckRequest naming the service; empty for the overall server status.
+//
+// Returns:
+//   - *healthpb.HealthCheckResponse: SERVING if the service is ready, NOT_SERVING otherwise.
+//   - error: A NotFound gRPC status error if the service is unknown.
+func (s *HealthServerImpl) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if !knownService(req.GetService()) {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+	return &healthpb.HealthCheckResponse{Status: s.servingStatus()}, nil
+}
+
+// Watch streams the serving status of the requested service: the current status first, then every change.
+//
+// Unknown services are reported as SERVICE_UNKNOWN rather than failing the call, as the protocol requires. Once
+// graceful shutdown starts, NOT_SERVING is sent and the stream ends, so open watches do not hold up the drain.
+//
+// Parameters:
+//   - req: HealthCheckRequest naming the service; empty for the overall server status.
+//   - stream: The server stream the statuses are sent on.
+//
+// Returns:
+//   - error: An error if a status could not be sent; nil when the client or shutdown ends the stream.
+func (s *HealthServerImpl) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	current := func() healthpb.HealthCheckResponse_ServingStatus {
+		if !knownService(req.GetService()) {
+			return healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		return s.servingStatus()
+	}
+
+	ticker := time.NewTicker(healthWatchInterval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_ServingStatus(-1)
+	for {
+		if latest := current(); latest != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: latest}); err != nil {
+				return err
+			}
+			last = latest
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.health.ShuttingDown():
+			if last != healthpb.HealthCheckResponse_NOT_SERVING {
+				return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING})
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// servingStatus maps the readiness of the service to a serving status.
+func (s *HealthServerImpl) servingStatus() healthpb.HealthCheckResponse_ServingStatus {
+	if s.health.Readiness().Ready {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+// knownService reports whether the health of the named service is tracked.
+func knownService(service string) bool {
+	return service == "" || service == pb.IPChecker_ServiceDesc.ServiceName
+}
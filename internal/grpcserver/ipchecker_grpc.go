@@ -3,60 +3,219 @@ package grpcserver
 import (
 	"context"
 
-	"github.com/justfairdev/ipchecker/internal/geo"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/clientip"
 	pb "github.com/justfairdev/ipchecker/proto"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
+// MaxBatchSize is the maximum number of IP addresses accepted by a single CheckIPBatch call.
+const MaxBatchSize = 1000
+
 // IPCheckerServerImpl implements the IPChecker gRPC service defined in the protobuf specification.
 // This server handles IP address checks against geographical locations based on provided criteria.
 type IPCheckerServerImpl struct {
 	pb.UnimplementedIPCheckerServer
-	geoService geo.LookupService
+	checker *checker.Checker
 }
 
-// NewIPCheckerServer constructs a new IPCheckerServerImpl instance with the provided geographical lookup service.
+// NewIPCheckerServer constructs a new IPCheckerServerImpl instance with the provided decision core.
 //
 // Parameters:
-//   - gs: An implementation of geo.LookupService for geographical IP address resolution.
+//   - c: The shared checker.Checker that makes the allow/deny decisions.
 //
 // Returns:
-//   - Pointer to IPCheckerServerImpl configured with the specified geo service.
-func NewIPCheckerServer(gs geo.LookupService) *IPCheckerServerImpl {
-	return &IPCheckerServerImpl{geoService: gs}
+//   - Pointer to IPCheckerServerImpl configured with the specified checker.
+func NewIPCheckerServer(c *checker.Checker) *IPCheckerServerImpl {
+	return &IPCheckerServerImpl{checker: c}
 }
 
 // CheckIP processes the IPCheckRequest by performing a geographical lookup of the specified IP address
-// and verifies if it originates from one of the allowed countries provided.
+// and verifies it against the allowed or blocked countries provided.
 //
 // Parameters:
 //   - ctx: Context carrying metadata and deadlines for the request handling lifecycle.
-//   - req: IPCheckRequest containing the target IP address and list of allowed ISO 3166-1 alpha-2 country codes.
+//   - req: IPCheckRequest containing the target IP address and either inline rules (allowed or blocked ISO 3166-1
+//     alpha-2 country codes and an optional default action) or the name of a server-side policy.
 //
 // Returns:
 //   - *pb.IPCheckResponse: Contains the country code associated with the IP and whether it is permitted.
-//   - error: Returns a gRPC status error if IP lookup fails or the IP address format is invalid.
+//   - error: Returns a gRPC status error whose code is mapped from the checker error kind
+//     (InvalidArgument, NotFound or Internal) if the IP address is invalid or the lookup fails.
 func (s *IPCheckerServerImpl) CheckIP(ctx context.Context, req *pb.IPCheckRequest) (*pb.IPCheckResponse, error) {
-	// Perform geographical lookup to obtain the country associated with the provided IP address.
-	country, err := s.geoService.CountryISOCode(req.GetIpAddress())
+	// Resolve the named policy or inline list the request is checked against.
+	policy, err := s.checker.ResolvePolicy(ctx, req.GetPolicy(), inlinePolicy(req))
 	if err != nil {
-		// Return a gRPC error indicating the provided IP address is invalid or geo lookup failed.
-		return nil, status.Errorf(codes.InvalidArgument, "invalid IP address: %v", err)
+		return nil, statusFromError(err)
 	}
 
-	// Check whether the resolved country code is within the allowed countries list.
-	allowed := false
-	for _, allowedCountry := range req.GetAllowedCountries() {
-		if allowedCountry == country {
-			allowed = true
-			break
-		}
+	decision, err := s.checker.Decide(ctx, req.GetIpAddress(), policy)
+	if err != nil {
+		return nil, statusFromError(err)
 	}
 
 	// Return the result indicating if the IP is allowed and its associated country code.
 	return &pb.IPCheckResponse{
-		Allowed: allowed,
-		Country: country,
+		Allowed:       decision.Allowed,
+		Country:       decision.Country,
+		Asn:           uint32(decision.ASN),
+		Reason:        string(decision.Reason),
+		Policy:        decision.Policy,
+		PolicyVersion: decision.PolicyVersion,
+		Override:      toOverrideProto(decision.Override),
+	}, nil
+}
+
+// CheckCaller checks the IP address of the caller itself against the allowed or blocked countries or the
+// server-side policy of the request. The address is the one stored by the client IP interceptors (see
+// clientip.FromContext): the peer address, or the address forwarded in metadata by a trusted proxy. Without the
+// interceptors, the peer address is used.
+//
+// Parameters:
+//   - ctx: Context carrying the peer, metadata and deadlines of the call.
+//   - req: CallerCheckRequest containing inline rules or the name of a server-side policy.
+//
+// Returns:
+//   - *pb.CallerCheckResponse: The decision, with the IP address checked and the source it was taken from.
+//   - error: Returns a gRPC status error whose code is mapped from the checker error kind
+//     (InvalidArgument, NotFound or Internal) if the caller has no valid IP address or the lookup fails.
+func (s *IPCheckerServerImpl) CheckCaller(ctx context.Context, req *pb.CallerCheckRequest) (*pb.CallerCheckResponse, error) {
+	policy, err := s.checker.ResolvePolicy(ctx, req.GetPolicy(), inlinePolicy(req))
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	caller, ok := clientip.FromContext(ctx)
+	if !ok {
+		var remote string
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			remote = p.Addr.String()
+		}
+		caller = (&clientip.Resolver{}).Resolve(remote, func(string) string { return "" })
+	}
+
+	decision, err := s.checker.Decide(ctx, caller.IP, policy)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	return &pb.CallerCheckResponse{
+		Allowed:       decision.Allowed,
+		Country:       decision.Country,
+		Asn:           uint32(decision.ASN),
+		Reason:        string(decision.Reason),
+		Policy:        decision.Policy,
+		PolicyVersion: decision.PolicyVersion,
+		Override:      toOverrideProto(decision.Override),
+		IpAddress:     caller.IP,
+		IpSource:      caller.Source,
+	}, nil
+}
+
+// CheckIPBatch checks every IP address of the IPBatchCheckRequest against the shared country rules.
+// Each item is evaluated exactly like CheckIP; a failure is reported in that item's error field instead of
+// failing the whole call.
+//
+// Parameters:
+//   - ctx: Context carrying metadata and deadlines for the request handling lifecycle.
+//   - req: IPBatchCheckRequest containing up to MaxBatchSize IP addresses and the inline country rules or
+//     server-side policy name shared by all of them.
+//
+// Returns:
+//   - *pb.IPBatchCheckResponse: One result per requested IP address, in request order.
+//   - error: Returns an InvalidArgument gRPC status error if the batch is empty, exceeds MaxBatchSize,
+//     or does not resolve to exactly one policy.
+func (s *IPCheckerServerImpl) CheckIPBatch(ctx context.Context, req *pb.IPBatchCheckRequest) (*pb.IPBatchCheckResponse, error) {
+	ipAddresses := req.GetIpAddresses()
+	if len(ipAddresses) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "ip_addresses must not be empty")
+	}
+	if len(ipAddresses) > MaxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "ip_addresses exceeds the maximum batch size of %d", MaxBatchSize)
+	}
+
+	// Resolve the policy once; it is shared by every IP in the batch.
+	policy, err := s.checker.ResolvePolicy(ctx, req.GetPolicy(), inlinePolicy(req))
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	// Check every IP independently so that one failure is reported on its own item only.
+	results := make([]*pb.IPBatchCheckResult, len(ipAddresses))
+	for i, ipAddress := range ipAddresses {
+		result := &pb.IPBatchCheckResult{IpAddress: ipAddress}
+
+		decision, err := s.checker.Decide(ctx, ipAddress, policy)
+		if err != nil {
+			result.Error = err.Error()
+			result.ErrorCode = int32(checker.KindOf(err).GRPCCode())
+		} else {
+			result.Allowed = decision.Allowed
+			result.Country = decision.Country
+			result.Asn = uint32(decision.ASN)
+			result.Reason = string(decision.Reason)
+			result.Override = toOverrideProto(decision.Override)
+		}
+		results[i] = result
+	}
+
+	return &pb.IPBatchCheckResponse{
+		Results:       results,
+		Policy:        policy.Name,
+		PolicyVersion: policy.Version,
 	}, nil
 }
+
+// inlineRules is implemented by every request message that can carry inline country rules.
+type inlineRules interface {
+	GetAllowedCountries() []string
+	GetBlockedCountries() []string
+	GetDefaultAction() string
+}
+
+// inlinePolicy collects the inline country rules of a request message for checker.Checker.ResolvePolicy.
+//
+// Parameters:
+//   - req: A request message carrying allowed or blocked countries and an optional default action.
+//
+// Returns:
+//   - checker.Policy: The unvalidated inline rules of the request.
+func inlinePolicy(req inlineRules) checker.Policy {
+	return checker.Policy{
+		AllowedCountries: req.GetAllowedCountries(),
+		BlockedCountries: req.GetBlockedCountries(),
+		DefaultAction:    checker.DefaultAction(req.GetDefaultAction()),
+	}
+}
+
+// statusFromError converts an error returned by checker.Checker into a gRPC status error,
+// using the gRPC code mapped from its checker.Kind.
+//
+// Parameters:
+//   - err: A non-nil error returned by checker.Checker.
+//
+// Returns:
+//   - error: A gRPC status error carrying the client-facing message.
+func statusFromError(err error) error {
+	return status.Error(checker.KindOf(err).GRPCCode(), err.Error())
+}
+
+// toOverrideProto converts the override rule of a decision into its protobuf representation.
+//
+// Parameters:
+//   - match: The override rule that decided the request; nil if none did.
+//
+// Returns:
+//   - *pb.OverrideMatch: The protobuf representation, or nil if match is nil.
+func toOverrideProto(match *checker.OverrideMatch) *pb.OverrideMatch {
+	if match == nil {
+		return nil
+	}
+	return &pb.OverrideMatch{
+		Action: string(match.Action),
+		Name:   match.Name,
+		Cidr:   match.CIDR,
+	}
+}
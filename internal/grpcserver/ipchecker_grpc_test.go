@@ -3,15 +3,24 @@ package grpcserver_test
 import (
 	"context"
 	"errors"
+	"io"
 	"log"
 	"net"
+	"path/filepath"
 	"testing"
 
-	"github.com/justfairdev/ipchecker/internal/geo"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/checker/checkertest"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/geo/geotest"
 	"github.com/justfairdev/ipchecker/internal/grpcserver"
 	pb "github.com/justfairdev/ipchecker/proto"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -41,7 +50,7 @@ func TestIPCheckerGRPC_CheckIP_Success(t *testing.T) {
 	mockGeo := geo.NewMockGeoLookupService("US", nil)
 
 	// Instantiate the IPChecker gRPC server implementation with the mock service.
-	ipCheckerSvc := grpcserver.NewIPCheckerServer(mockGeo)
+	ipCheckerSvc := grpcserver.NewIPCheckerServer(checker.NewChecker(mockGeo, nil, nil))
 	pb.RegisterIPCheckerServer(grpcServer, ipCheckerSvc)
 
 	// Serve the gRPC server concurrently for the duration of this test.
@@ -86,7 +95,7 @@ func TestIPCheckerGRPC_CheckIP_Failure(t *testing.T) {
 	mockGeo := geo.NewMockGeoLookupService("", errors.New("geo service error"))
 
 	// Instantiate the IPChecker gRPC server implementation with the failing mock service.
-	ipCheckerSvc := grpcserver.NewIPCheckerServer(mockGeo)
+	ipCheckerSvc := grpcserver.NewIPCheckerServer(checker.NewChecker(mockGeo, nil, nil))
 	pb.RegisterIPCheckerServer(grpcServer, ipCheckerSvc)
 
 	// Run the gRPC server concurrently for the test.
@@ -115,3 +124,195 @@ func TestIPCheckerGRPC_CheckIP_Failure(t *testing.T) {
 	assert.Error(t, err, "Expected error due to simulated geo service error.")
 	assert.Nil(t, resp, "Expected no response due to internal geo service failure.")
 }
+
+// TestIPCheckerGRPC_CheckIPBatch_MixedResults verifies that CheckIPBatch returns one result per IP in request
+// order, reporting a malformed IP on its own item without failing the call.
+func TestIPCheckerGRPC_CheckIPBatch_MixedResults(t *testing.T) {
+	// Build a small real database so each IP resolves to its own country.
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB", "128.101.101.0/24": "US"})
+	geoSvc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	defer geoSvc.Close()
+
+	listener := bufconn.Listen(bufSize)
+	grpcServer := grpc.NewServer()
+	pb.RegisterIPCheckerServer(grpcServer, grpcserver.NewIPCheckerServer(checker.NewChecker(geoSvc, nil, nil)))
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(dialer(listener)), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := pb.NewIPCheckerClient(conn)
+
+	resp, err := client.CheckIPBatch(ctx, &pb.IPBatchCheckRequest{
+		IpAddresses:      []string{"128.101.101.101", "not-an-ip", "81.2.69.142"},
+		AllowedCountries: []string{"US", "CA"},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 3)
+
+	assert.True(t, resp.Results[0].Allowed)
+	assert.Equal(t, "US", resp.Results[0].Country)
+	assert.Empty(t, resp.Results[0].Error)
+
+	assert.Equal(t, "not-an-ip", resp.Results[1].IpAddress)
+	assert.Equal(t, "invalid IP address", resp.Results[1].Error)
+	assert.Equal(t, int32(codes.InvalidArgument), resp.Results[1].ErrorCode)
+
+	assert.False(t, resp.Results[2].Allowed)
+	assert.Equal(t, "GB", resp.Results[2].Country)
+
+	// An empty batch is rejected as a whole.
+	_, err = client.CheckIPBatch(ctx, &pb.IPBatchCheckRequest{AllowedCountries: []string{"US"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// TestIPCheckerGRPC_CheckIPStream verifies that every message on a CheckIPStream call is answered with a response
+// carrying its id, and that a malformed IP produces a per-message error without ending the stream.
+func TestIPCheckerGRPC_CheckIPStream(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB", "128.101.101.0/24": "US"})
+	geoSvc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	defer geoSvc.Close()
+
+	listener := bufconn.Listen(bufSize)
+	grpcServer := grpc.NewServer()
+	pb.RegisterIPCheckerServer(grpcServer, grpcserver.NewIPCheckerServer(checker.NewChecker(geoSvc, nil, nil)))
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(dialer(listener)), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := pb.NewIPCheckerClient(conn).CheckIPStream(ctx)
+	require.NoError(t, err)
+
+	// Send more messages than fit in the server's in-flight window before reading any response,
+	// so the server has to apply backpressure rather than drop or reorder anything.
+	requests := map[string]string{"us": "128.101.101.101", "bad": "not-an-ip", "gb": "81.2.69.142"}
+	total := 0
+	for round := 0; round < grpcserver.MaxStreamInFlight; round++ {
+		for id, ip := range requests {
+			require.NoError(t, stream.Send(&pb.IPStreamCheckRequest{
+				Id:               id,
+				IpAddress:        ip,
+				AllowedCountries: []string{"US"},
+			}))
+			total++
+		}
+	}
+	require.NoError(t, stream.CloseSend())
+
+	received := 0
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		received++
+
+		switch resp.Id {
+		case "us":
+			assert.True(t, resp.Allowed)
+			assert.Equal(t, "US", resp.Country)
+		case "gb":
+			assert.False(t, resp.Allowed)
+			assert.Equal(t, "GB", resp.Country)
+		case "bad":
+			assert.NotEmpty(t, resp.Error, "Expected a per-message error for the malformed IP.")
+			assert.Equal(t, int32(codes.InvalidArgument), resp.ErrorCode)
+		default:
+			t.Fatalf("unexpected response id %q", resp.Id)
+		}
+	}
+	assert.Equal(t, total, received, "Expected exactly one response per request.")
+}
+
+// TestIPCheckerGRPC_Lookup verifies that Lookup returns the localized record of an IP address, leaves unknown places
+// unset, and maps an address without a record to NotFound.
+func TestIPCheckerGRPC_Lookup(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteDatabase(t, dbPath, "GeoLite2-Country", map[string]mmdbtype.Map{"81.2.69.0/24": geotest.SatelliteRecord()})
+	geoSvc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	defer geoSvc.Close()
+
+	listener := bufconn.Listen(bufSize)
+	grpcServer := grpc.NewServer()
+	pb.RegisterIPCheckerServer(grpcServer, grpcserver.NewIPCheckerServer(checker.NewChecker(geoSvc, nil, nil)))
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(dialer(listener)), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := pb.NewIPCheckerClient(conn)
+
+	resp, err := client.Lookup(ctx, &pb.LookupRequest{IpAddress: "81.2.69.142", Locale: "de"})
+	require.NoError(t, err)
+	assert.Equal(t, "de", resp.Locale)
+	assert.Equal(t, "Europa", resp.GetContinent().GetName())
+	assert.Equal(t, "DE", resp.GetCountry().GetIsoCode())
+	assert.Equal(t, "Deutschland", resp.GetCountry().GetName())
+	assert.True(t, resp.GetCountry().GetIsInEuropeanUnion())
+	assert.Equal(t, "CH", resp.GetRegisteredCountry().GetIsoCode())
+	assert.Nil(t, resp.RepresentedCountry, "Expected no represented country when the database has none.")
+	assert.True(t, resp.GetTraits().GetIsSatelliteProvider())
+	assert.False(t, resp.GetTraits().GetIsAnonymousProxy())
+
+	_, err = client.Lookup(ctx, &pb.LookupRequest{IpAddress: "192.0.2.1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// TestIPCheckerGRPC_CheckIP_Conformance runs the shared transport conformance suite against the gRPC server,
+// ensuring its decisions and error status codes match the HTTP handler's.
+func TestIPCheckerGRPC_CheckIP_Conformance(t *testing.T) {
+	checkertest.Run(t, func(t *testing.T, c *checker.Checker, r checkertest.Request) (checker.Decision, error) {
+		listener := bufconn.Listen(bufSize)
+		grpcServer := grpc.NewServer()
+		pb.RegisterIPCheckerServer(grpcServer, grpcserver.NewIPCheckerServer(c))
+		go grpcServer.Serve(listener)
+		defer grpcServer.Stop()
+
+		ctx := context.Background()
+		conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(dialer(listener)), grpc.WithInsecure())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		resp, err := pb.NewIPCheckerClient(conn).CheckIP(ctx, &pb.IPCheckRequest{
+			IpAddress:        r.IP,
+			AllowedCountries: r.AllowedCountries,
+			BlockedCountries: r.BlockedCountries,
+			DefaultAction:    r.DefaultAction,
+			Policy:           r.Policy,
+		})
+
+		// Translate a gRPC status error back into the checker taxonomy.
+		if err != nil {
+			st := status.Convert(err)
+			return checker.Decision{}, &checker.Error{Kind: checker.KindFromGRPCCode(st.Code()), Message: st.Message()}
+		}
+		decision := checker.Decision{
+			Allowed:       resp.Allowed,
+			Country:       resp.Country,
+			Reason:        checker.Reason(resp.Reason),
+			ASN:           uint(resp.GetAsn()),
+			Policy:        resp.Policy,
+			PolicyVersion: resp.PolicyVersion,
+		}
+		if o := resp.GetOverride(); o != nil {
+			decision.Override = &checker.OverrideMatch{Action: checker.OverrideAction(o.Action), Name: o.Name, CIDR: o.Cidr}
+		}
+		return decision, nil
+	})
+}
@@ -0,0 +1,83 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/justfairdev/ipchecker/geo"
+	pb "github.com/justfairdev/ipchecker/proto"
+)
+
+// Lookup returns the full geolocation data of the IP address in the LookupRequest: continent, country,
+// registered and represented country, network traits and, with a City database, city, subdivisions, postal code
+// and accuracy radius. No allow/deny rules are evaluated.
+//
+// Parameters:
+//   - ctx: Context carrying metadata and deadlines for the request handling lifecycle.
+//   - req: LookupRequest containing the target IP address and the locale names are returned in.
+//
+// Returns:
+//   - *pb.LookupResponse: The geolocation data; places the database has no data for are left unset.
+//   - error: Returns a gRPC status error whose code is mapped from the checker error kind
+//     (InvalidArgument, NotFound or Internal) if the IP address is invalid, unknown or the lookup fails.
+func (s *IPCheckerServerImpl) Lookup(ctx context.Context, req *pb.LookupRequest) (*pb.LookupResponse, error) {
+	locale := req.GetLocale()
+	if locale == "" {
+		locale = geo.DefaultLocale
+	}
+
+	location, err := s.checker.Lookup(ctx, req.GetIpAddress(), locale)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+
+	resp := &pb.LookupResponse{
+		IpAddress:         req.GetIpAddress(),
+		Locale:            locale,
+		Country:           toCountryProto(location.Country),
+		RegisteredCountry: toCountryProto(location.RegisteredCountry),
+		City:              location.City,
+		PostalCode:        location.PostalCode,
+		AccuracyRadius:    uint32(location.AccuracyRadius),
+		Traits: &pb.Traits{
+			IsAnonymousProxy:    location.IsAnonymousProxy,
+			IsAnycast:           location.IsAnycast,
+			IsSatelliteProvider: location.IsSatelliteProvider,
+		},
+	}
+	if location.Continent.Code != "" {
+		resp.Continent = &pb.Continent{Code: location.Continent.Code, Name: location.Continent.Name}
+	}
+	if represented := location.RepresentedCountry; represented.ISOCode != "" {
+		resp.RepresentedCountry = &pb.RepresentedCountry{
+			IsoCode:           represented.ISOCode,
+			Name:              represented.Name,
+			IsInEuropeanUnion: represented.IsInEuropeanUnion,
+			Type:              represented.Type,
+		}
+	}
+	if location.ASN != 0 {
+		resp.AutonomousSystem = &pb.AutonomousSystem{Number: uint32(location.ASN), Organization: location.ASOrganization}
+	}
+	for _, subdivision := range location.Subdivisions {
+		resp.Subdivisions = append(resp.Subdivisions, &pb.Subdivision{IsoCode: subdivision.ISOCode, Name: subdivision.Name})
+	}
+	return resp, nil
+}
+
+// toCountryProto converts a country of a geo.Location into its protobuf representation.
+//
+// Parameters:
+//   - country: The country to convert.
+//
+// Returns:
+//   - *pb.Country: The protobuf representation, or nil if the country is unknown.
+func toCountryProto(country geo.Country) *pb.Country {
+	if country.ISOCode == "" {
+		return nil
+	}
+	return &pb.Country{
+		IsoCode:           country.ISOCode,
+		Name:              country.Name,
+		IsInEuropeanUnion: country.IsInEuropeanUnion,
+	}
+}
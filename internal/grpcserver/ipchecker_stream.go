@@ -0,0 +1,100 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+
+	"github.com/justfairdev/ipchecker/checker"
+	pb "github.com/justfairdev/ipchecker/proto"
+	"google.golang.org/grpc"
+)
+
+// MaxStreamInFlight is the number of CheckIPStream responses that may be waiting to be written to a client.
+// Once it is reached the server stops reading requests until the client consumes responses, so a slow
+// reader applies backpressure through HTTP/2 flow control instead of growing server memory.
+const MaxStreamInFlight = 128
+
+// CheckIPStream processes a long-lived bidirectional stream of IP checks.
+//
+// Every request is evaluated exactly like CheckIP and answered with a response carrying the same client-supplied id,
+// so callers can pipeline requests without waiting for each answer. Errors for a single message (e.g. a malformed IP)
+// are reported in that response's error and error_code fields and do not terminate the stream.
+//
+// Parameters:
+//   - stream: The bidirectional stream carrying IPStreamCheckRequest messages in and IPStreamCheckResponse messages out.
+//
+// Returns:
+//   - error: nil once the client closes its send side and all responses are written; otherwise the transport error
+//     that ended the stream.
+func (s *IPCheckerServerImpl) CheckIPStream(stream grpc.BidiStreamingServer[pb.IPStreamCheckRequest, pb.IPStreamCheckResponse]) error {
+	// Responses are written by a single sender goroutine (Send must not be called concurrently) so that lookups
+	// are not held up by individual network writes. The buffer bounds how far reading may run ahead of writing.
+	responses := make(chan *pb.IPStreamCheckResponse, MaxStreamInFlight)
+	sendDone := make(chan error, 1)
+
+	go func() {
+		for resp := range responses {
+			if err := stream.Send(resp); err != nil {
+				sendDone <- err
+				return
+			}
+		}
+		sendDone <- nil
+	}()
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			// The client has finished sending; flush the remaining responses and end the stream.
+			close(responses)
+			return <-sendDone
+		}
+		if err != nil {
+			close(responses)
+			<-sendDone
+			return err
+		}
+
+		select {
+		case responses <- s.checkStreamMessage(stream.Context(), req):
+		case err := <-sendDone:
+			// The sender failed, so the stream is unusable; stop reading.
+			return err
+		}
+	}
+}
+
+// checkStreamMessage evaluates a single stream message and builds its correlated response.
+//
+// Parameters:
+//   - ctx: Context of the stream the message arrived on.
+//   - req: The stream message containing the client-supplied id, IP address and inline country rules or policy name.
+//
+// Returns:
+//   - *pb.IPStreamCheckResponse: The decision for the message, or its error, tagged with the request id.
+func (s *IPCheckerServerImpl) checkStreamMessage(ctx context.Context, req *pb.IPStreamCheckRequest) *pb.IPStreamCheckResponse {
+	resp := &pb.IPStreamCheckResponse{Id: req.GetId()}
+
+	policy, err := s.checker.ResolvePolicy(ctx, req.GetPolicy(), inlinePolicy(req))
+	if err != nil {
+		resp.Error = err.Error()
+		resp.ErrorCode = int32(checker.KindOf(err).GRPCCode())
+		return resp
+	}
+
+	decision, err := s.checker.Decide(ctx, req.GetIpAddress(), policy)
+	if err != nil {
+		resp.Error = err.Error()
+		resp.ErrorCode = int32(checker.KindOf(err).GRPCCode())
+		return resp
+	}
+
+	resp.Allowed = decision.Allowed
+	resp.Country = decision.Country
+	resp.Asn = uint32(decision.ASN)
+	resp.Reason = string(decision.Reason)
+	resp.Policy = decision.Policy
+	resp.PolicyVersion = decision.PolicyVersion
+	resp.Override = toOverrideProto(decision.Override)
+	return resp
+}
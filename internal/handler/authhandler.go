@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/checker"
+)
+
+// Headers of the forward-auth endpoint, for reverse proxies such as NGINX (auth_request) and Traefik (forwardAuth).
+const (
+	// PolicyHeader names the policy the client is checked against, unless the policy query parameter does.
+	PolicyHeader = "X-IPChecker-Policy"
+
+	// CountryHeader carries the ISO 3166-1 alpha-2 country code resolved for the client; omitted if none is known.
+	CountryHeader = "X-Country"
+
+	// ClientIPHeader carries the client IP address the decision was made for.
+	ClientIPHeader = "X-Client-IP"
+
+	// ReasonHeader tells what decided the request: "rule", "default", "asn" or "override".
+	ReasonHeader = "X-IPChecker-Reason"
+
+	// ErrorHeader carries why a request could not be decided, e.g. an unknown policy or a failed lookup.
+	ErrorHeader = "X-IPChecker-Error"
+)
+
+// ForwardAuth godoc
+// @Summary      Allow or deny the client of a proxied request (NGINX auth_request, Traefik forwardAuth).
+// @Description  Checks the IP address of the client of the request, as forwarded by a trusted reverse proxy in X-Forwarded-For or X-Real-IP (otherwise the address of the connection), against the policy named by the policy query parameter or the X-IPChecker-Policy header. Answers 204 if the client is allowed and 403 otherwise, with the X-Country, X-Client-IP and X-IPChecker-Reason headers. Requests that cannot be decided, because of a missing or unknown policy or a failed lookup, are denied with 403 and the X-IPChecker-Error header, as proxies treat other statuses as internal errors. No request body is read.
+// @Tags         IP
+// @Produce      json
+// @Param        policy query string false "Name of the server-side policy; takes precedence over the X-IPChecker-Policy header."
+// @Param        X-IPChecker-Policy header string false "Name of the server-side policy."
+// @Success      204 "The client is allowed."
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Failure      401 {object} map[string]string "Missing or invalid API key or bearer token."
+// @Failure      403 {object} map[string]string "The client is denied, or the request cannot be decided (see X-IPChecker-Error), or the caller lacks the check scope."
+// @Failure      429 {object} map[string]string "Rate limit or daily quota of the caller exceeded; see the Retry-After header."
+// @Header       204,403 {string} X-Country "Country code resolved for the client; omitted if none is known."
+// @Header       204,403 {string} X-Client-IP "Client IP address the decision was made for."
+// @Header       204,403 {string} X-IPChecker-Reason "What decided: rule, asn, default or override."
+// @Header       403 {string} X-IPChecker-Error "Why the request could not be decided, e.g. an unknown policy or a failed lookup."
+// @Router       /auth [get]
+func (c *IPChecker) ForwardAuth(ctx *gin.Context) {
+	name := ctx.Query("policy")
+	if name == "" {
+		name = ctx.GetHeader(PolicyHeader)
+	}
+
+	// Only named policies are accepted: the subrequest carries no body to send inline rules in.
+	policy, err := c.checker.ResolvePolicy(ctx.Request.Context(), name, checker.Policy{})
+	if err != nil {
+		denyUndecided(ctx, err)
+		return
+	}
+
+	// The client IP is taken from the forwarding headers of trusted proxies only (see middleware.GinClientIP).
+	clientIP := clientAddress(ctx).IP
+	ctx.Header(ClientIPHeader, clientIP)
+
+	decision, err := c.checker.Decide(ctx.Request.Context(), clientIP, policy)
+	if err != nil {
+		denyUndecided(ctx, err)
+		return
+	}
+
+	if decision.Country != "" {
+		ctx.Header(CountryHeader, decision.Country)
+	}
+	ctx.Header(ReasonHeader, string(decision.Reason))
+	if !decision.Allowed {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// denyUndecided answers a subrequest that could not be decided with 403 Forbidden and the error in ErrorHeader.
+// NGINX auth_request and Traefik forwardAuth only understand 2xx, 401 and 403, and report any other status as an
+// internal error, so the proxied request is denied (fail closed) with the cause in a header it can log.
+func denyUndecided(ctx *gin.Context, err error) {
+	ctx.Header(ErrorHeader, err.Error())
+	ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,141 @@
+package handler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/clientip"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/geo/geotest"
+	"github.com/justfairdev/ipchecker/internal/handler"
+	"github.com/justfairdev/ipchecker/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newForwardAuthRouter returns a router serving ForwardAuth at /auth, locating 81.2.69.0/24 in GB and
+// 128.101.101.0/24 in the US with the "uk-only" policy, and trusting the forwarding headers of 10.0.0.0/8 only.
+func newForwardAuthRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB", "128.101.101.0/24": "US"})
+	geoSvc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { geoSvc.Close() })
+
+	policyFile := filepath.Join(dir, "policies.yaml")
+	require.NoError(t, os.WriteFile(policyFile, []byte("policies:\n  uk-only:\n    allowed_countries: [GB]\n"), 0o600))
+	policies, err := checker.LoadPolicyFile(policyFile)
+	require.NoError(t, err)
+
+	resolver, err := clientip.NewResolver([]string{"10.0.0.0/8"}, []string{"X-Forwarded-For", "X-Real-IP"})
+	require.NoError(t, err)
+	router := gin.New()
+	router.GET("/auth", middleware.GinClientIP(resolver), handler.NewIPChecker(checker.NewChecker(geoSvc, policies, nil)).ForwardAuth)
+	return router
+}
+
+// forwardAuth sends a subrequest to /auth from peer with the given headers and returns the response.
+func forwardAuth(router *gin.Engine, target, peer string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = peer + ":41000"
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// TestIPChecker_ForwardAuth_AllowsAndDenies verifies that the client forwarded by a trusted proxy is answered with
+// 204 or 403 and the X-Country header, for the policy named by the query parameter or the header.
+func TestIPChecker_ForwardAuth_AllowsAndDenies(t *testing.T) {
+	router := newForwardAuthRouter(t)
+
+	resp := forwardAuth(router, "/auth?policy=uk-only", "10.0.0.5", map[string]string{"X-Real-IP": "81.2.69.142"})
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Empty(t, resp.Body.String())
+	assert.Equal(t, "GB", resp.Header().Get(handler.CountryHeader))
+	assert.Equal(t, "81.2.69.142", resp.Header().Get(handler.ClientIPHeader))
+	assert.Equal(t, "rule", resp.Header().Get(handler.ReasonHeader))
+
+	resp = forwardAuth(router, "/auth", "10.0.0.5", map[string]string{
+		"X-Forwarded-For":    "128.101.101.101",
+		handler.PolicyHeader: "uk-only",
+	})
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Equal(t, "US", resp.Header().Get(handler.CountryHeader))
+	assert.JSONEq(t, `{"error": "access denied"}`, resp.Body.String())
+}
+
+// TestIPChecker_ForwardAuth_TrustedProxies verifies that forwarding headers are only trusted from trusted proxies,
+// and that X-Forwarded-For is read up to the first untrusted address.
+func TestIPChecker_ForwardAuth_TrustedProxies(t *testing.T) {
+	router := newForwardAuthRouter(t)
+
+	// A client connecting directly cannot claim another address.
+	resp := forwardAuth(router, "/auth?policy=uk-only", "128.101.101.101", map[string]string{"X-Forwarded-For": "81.2.69.142"})
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Equal(t, "128.101.101.101", resp.Header().Get(handler.ClientIPHeader))
+
+	// Behind two trusted proxies, the entry the client prepended itself is ignored.
+	resp = forwardAuth(router, "/auth?policy=uk-only", "10.0.0.5", map[string]string{"X-Forwarded-For": "128.101.101.101, 81.2.69.142, 10.1.2.3"})
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "81.2.69.142", resp.Header().Get(handler.ClientIPHeader))
+
+	// Without forwarding headers, the peer of a trusted proxy is the client; it has no country, so the default
+	// action of the policy decides.
+	resp = forwardAuth(router, "/auth?policy=uk-only", "10.0.0.5", nil)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Equal(t, "10.0.0.5", resp.Header().Get(handler.ClientIPHeader))
+	assert.Equal(t, "default", resp.Header().Get(handler.ReasonHeader))
+	assert.Empty(t, resp.Header().Get(handler.CountryHeader))
+
+	// A proxy adding its own X-Forwarded-For line leaves the line forged by the client first; it is ignored.
+	req := httptest.NewRequest(http.MethodGet, "/auth?policy=uk-only", nil)
+	req.RemoteAddr = "10.0.0.5:41000"
+	req.Header.Add("X-Forwarded-For", "81.2.69.142")
+	req.Header.Add("X-Forwarded-For", "128.101.101.101")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Equal(t, "128.101.101.101", recorder.Header().Get(handler.ClientIPHeader))
+}
+
+// TestIPChecker_ForwardAuth_Policy verifies that a missing or unknown policy denies the request with 403 and the
+// cause in the X-IPChecker-Error header, rather than a status the proxy would report as an internal error.
+func TestIPChecker_ForwardAuth_Policy(t *testing.T) {
+	router := newForwardAuthRouter(t)
+
+	resp := forwardAuth(router, "/auth", "81.2.69.142", nil)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.NotEmpty(t, resp.Header().Get(handler.ErrorHeader))
+
+	resp = forwardAuth(router, "/auth?policy=missing", "81.2.69.142", nil)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Header().Get(handler.ErrorHeader), `unknown policy "missing"`)
+}
+
+// TestIPChecker_ForwardAuth_BackendFailure verifies that a failed lookup fails closed with 403 and the
+// X-IPChecker-Error header.
+func TestIPChecker_ForwardAuth_BackendFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	geoSvc := geo.NewMockGeoLookupService("", errors.New("database unavailable"))
+	policyFile := filepath.Join(t.TempDir(), "policies.yaml")
+	require.NoError(t, os.WriteFile(policyFile, []byte("policies:\n  uk-only:\n    allowed_countries: [GB]\n"), 0o600))
+	policies, err := checker.LoadPolicyFile(policyFile)
+	require.NoError(t, err)
+	router := gin.New()
+	router.GET("/auth", handler.NewIPChecker(checker.NewChecker(geoSvc, policies, nil)).ForwardAuth)
+
+	resp := forwardAuth(router, "/auth?policy=uk-only", "81.2.69.142", nil)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Equal(t, "unable to lookup country", resp.Header().Get(handler.ErrorHeader))
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/internal/dtos"
+	"github.com/justfairdev/ipchecker/internal/health"
+)
+
+// Health provides the HTTP liveness and readiness probe handlers.
+type Health struct {
+	health *health.Health
+}
+
+// NewHealth constructs a new Health handler reporting the given health state.
+//
+// Parameters:
+//   - h: The health state of the service.
+//
+// Returns:
+//   - *Health: A pointer to the initialized Health handler instance.
+func NewHealth(h *health.Health) *Health {
+	return &Health{health: h}
+}
+
+// Liveness godoc
+// @Summary      Liveness probe.
+// @Description  Reports that the process is up and serving HTTP requests. It does not depend on the MaxMind database, so an instance is never restarted merely because its database is unavailable.
+// @Tags         Health
+// @Produce      json
+// @Success      200 {object} dtos.HealthResponse "The process is alive."
+// @Router       /healthz [get]
+func (h *Health) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, dtos.HealthResponse{Status: "ok"})
+}
+
+// Readiness godoc
+// @Summary      Readiness probe.
+// @Description  Reports whether the instance should receive traffic. It is not ready while the MaxMind database file is missing, a replacement file was rejected as corrupt, a database is being reloaded, or graceful shutdown has started.
+// @Tags         Health
+// @Produce      json
+// @Success      200 {object} dtos.HealthResponse "The instance is ready to serve traffic."
+// @Failure      503 {object} dtos.HealthResponse "The instance is not ready; checks lists the reasons."
+// @Router       /readyz [get]
+func (h *Health) Readiness(ctx *gin.Context) {
+	report := h.health.Readiness()
+	if !report.Ready {
+		ctx.JSON(http.StatusServiceUnavailable, dtos.HealthResponse{Status: "unavailable", Checks: report.Failures})
+		return
+	}
+	ctx.JSON(http.StatusOK, dtos.HealthResponse{Status: "ok"})
+}
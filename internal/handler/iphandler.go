@@ -4,35 +4,42 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/clientip"
 	"github.com/justfairdev/ipchecker/internal/dtos"
-	"github.com/justfairdev/ipchecker/internal/geo"
 )
 
 // IPChecker provides HTTP handlers for IP address verification against allowed countries.
 type IPChecker struct {
-	geoService geo.LookupService
+	checker *checker.Checker
 }
 
-// NewIPChecker constructs a new IPChecker handler with the given Geo lookup service dependency.
+// NewIPChecker constructs a new IPChecker handler with the given decision core dependency.
 //
 // Parameters:
-//   - geoService: An implementation of geo.LookupService used to determine the country of IP addresses.
+//   - c: The shared checker.Checker that makes the allow/deny decisions.
 //
 // Returns:
 //   - *IPChecker: A pointer to the initialized IPChecker handler instance.
-func NewIPChecker(geoService geo.LookupService) *IPChecker {
-	return &IPChecker{geoService: geoService}
+func NewIPChecker(c *checker.Checker) *IPChecker {
+	return &IPChecker{checker: c}
 }
 
 // CheckIP godoc
 // @Summary      Verify if an IP address originates from allowed countries.
-// @Description  Accepts an IP address and a list of allowed countries; returns whether the IP address is permitted based on its location.
+// @Description  Accepts an IP address and either a list of allowed or blocked countries (with an optional default action for IPs without a known country) or the name of a server-side policy; returns whether the IP address is permitted based on its location and whether a country rule, the default action or a CIDR override decided.
 // @Tags         IP
 // @Accept       json
 // @Produce      json
 // @Param        requestBody body dtos.IPCheckRequest true "IP check request payload."
 // @Success      200 {object} dtos.IPCheckResponse "Successful IP check operation."
-// @Failure      400 {object} map[string]string "Invalid request payload or malformed IP address."
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Failure      400 {object} map[string]string "Invalid request payload, unknown policy or malformed IP address."
+// @Failure      401 {object} map[string]string "Missing or invalid API key or bearer token."
+// @Failure      403 {object} map[string]string "The caller lacks the check scope, or may not use the requested policy."
+// @Failure      429 {object} map[string]string "Rate limit or daily quota of the caller exceeded; see the Retry-After header."
+// @Failure      404 {object} map[string]string "No country is known for the IP address and the default action is error."
 // @Failure      500 {object} map[string]string "Internal server error during IP geolocation lookup."
 // @Router       /ip-check [post]
 func (c *IPChecker) CheckIP(ctx *gin.Context) {
@@ -44,30 +51,188 @@ func (c *IPChecker) CheckIP(ctx *gin.Context) {
 		return
 	}
 
-	// Perform IP classification using the provided geo lookup service.
-	countryCode, err := c.geoService.CountryISOCode(req.IPAddress)
+	// Resolve the named policy or inline list the request is checked against.
+	policy, err := c.checker.ResolvePolicy(ctx.Request.Context(), req.Policy, checker.Policy{
+		AllowedCountries: req.AllowedCountries,
+		BlockedCountries: req.BlockedCountries,
+		DefaultAction:    checker.DefaultAction(req.DefaultAction),
+	})
 	if err != nil {
-		// Handle specific invalid IP format error explicitly.
-		if err == geo.ErrInvalidIP {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid IP address"})
-		} else {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "unable to lookup country"})
-		}
+		respondError(ctx, err)
 		return
 	}
 
-	// Determine if the resolved IP country code is within the allowed countries.
-	allowed := false
-	for _, allowedCountry := range req.AllowedCountries {
-		if allowedCountry == countryCode {
-			allowed = true
-			break
-		}
+	// Classify the IP address using the shared decision core.
+	decision, err := c.checker.Decide(ctx.Request.Context(), req.IPAddress, policy)
+	if err != nil {
+		respondError(ctx, err)
+		return
 	}
 
 	// Return a structured JSON response indicating the IP address permission status and country code.
 	ctx.JSON(http.StatusOK, dtos.IPCheckResponse{
-		Allowed: allowed,
-		Country: countryCode,
+		Allowed:       decision.Allowed,
+		Country:       decision.Country,
+		ASN:           decision.ASN,
+		Reason:        string(decision.Reason),
+		Policy:        decision.Policy,
+		PolicyVersion: decision.PolicyVersion,
+		Override:      toOverrideDTO(decision.Override),
 	})
 }
+
+// CheckCaller godoc
+// @Summary      Verify if the caller itself originates from allowed countries.
+// @Description  Checks the IP address of the request itself, for browser and mobile clients asking whether they are allowed: the address forwarded by a trusted reverse proxy in X-Forwarded-For or X-Real-IP, otherwise the address of the connection. Forwarding headers sent by other peers are ignored. Accepts either a list of allowed or blocked countries or the name of a server-side policy; returns the decision, the IP address checked and the source it was taken from.
+// @Tags         IP
+// @Accept       json
+// @Produce      json
+// @Param        requestBody body dtos.CallerCheckRequest true "Caller check request payload."
+// @Success      200 {object} dtos.CallerCheckResponse "Successful caller check operation."
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Failure      400 {object} map[string]string "Invalid request payload, unknown policy or no valid caller IP address."
+// @Failure      401 {object} map[string]string "Missing or invalid API key or bearer token."
+// @Failure      403 {object} map[string]string "The caller lacks the check scope, or may not use the requested policy."
+// @Failure      429 {object} map[string]string "Rate limit or daily quota of the caller exceeded; see the Retry-After header."
+// @Failure      404 {object} map[string]string "No country is known for the caller and the default action is error."
+// @Failure      500 {object} map[string]string "Internal server error during IP geolocation lookup."
+// @Router       /ip-check/caller [post]
+func (c *IPChecker) CheckCaller(ctx *gin.Context) {
+	var req dtos.CallerCheckRequest
+
+	// Bind the incoming JSON request payload to the CallerCheckRequest struct.
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := c.checker.ResolvePolicy(ctx.Request.Context(), req.Policy, checker.Policy{
+		AllowedCountries: req.AllowedCountries,
+		BlockedCountries: req.BlockedCountries,
+		DefaultAction:    checker.DefaultAction(req.DefaultAction),
+	})
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	caller := clientAddress(ctx)
+
+	decision, err := c.checker.Decide(ctx.Request.Context(), caller.IP, policy)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dtos.CallerCheckResponse{
+		IPCheckResponse: dtos.IPCheckResponse{
+			Allowed:       decision.Allowed,
+			Country:       decision.Country,
+			ASN:           decision.ASN,
+			Reason:        string(decision.Reason),
+			Policy:        decision.Policy,
+			PolicyVersion: decision.PolicyVersion,
+			Override:      toOverrideDTO(decision.Override),
+		},
+		IPAddress: caller.IP,
+		IPSource:  caller.Source,
+	})
+}
+
+// CheckIPBatch godoc
+// @Summary      Verify several IP addresses against one list of allowed countries.
+// @Description  Accepts up to 1000 IP addresses and shared inline country rules or server-side policy name; returns one result per IP address in request order. A malformed IP or failed lookup is reported in that item's error field and does not fail the batch.
+// @Tags         IP
+// @Accept       json
+// @Produce      json
+// @Param        requestBody body dtos.IPBatchCheckRequest true "IP batch check request payload."
+// @Success      200 {object} dtos.IPBatchCheckResponse "Per-item results of the batch check."
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Failure      400 {object} map[string]string "Invalid request payload or unknown policy."
+// @Failure      401 {object} map[string]string "Missing or invalid API key or bearer token."
+// @Failure      403 {object} map[string]string "The caller lacks the check scope, or may not use the requested policy."
+// @Failure      429 {object} map[string]string "Rate limit or daily quota of the caller exceeded; see the Retry-After header."
+// @Router       /ip-check/batch [post]
+func (c *IPChecker) CheckIPBatch(ctx *gin.Context) {
+	var req dtos.IPBatchCheckRequest
+
+	// Bind the incoming JSON request payload to the IPBatchCheckRequest struct.
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Resolve the policy once; it is shared by every IP in the batch.
+	policy, err := c.checker.ResolvePolicy(ctx.Request.Context(), req.Policy, checker.Policy{
+		AllowedCountries: req.AllowedCountries,
+		BlockedCountries: req.BlockedCountries,
+		DefaultAction:    checker.DefaultAction(req.DefaultAction),
+	})
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	// Check every IP independently so that one failure is reported on its own item only.
+	results := make([]dtos.IPBatchCheckResult, len(req.IPAddresses))
+	for i, ipAddress := range req.IPAddresses {
+		decision, err := c.checker.Decide(ctx.Request.Context(), ipAddress, policy)
+
+		results[i] = dtos.IPBatchCheckResult{
+			IPAddress: ipAddress,
+			Allowed:   decision.Allowed,
+			Country:   decision.Country,
+			ASN:       decision.ASN,
+			Reason:    string(decision.Reason),
+			Override:  toOverrideDTO(decision.Override),
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			results[i].ErrorStatus = checker.KindOf(err).HTTPStatus()
+		}
+	}
+
+	ctx.JSON(http.StatusOK, dtos.IPBatchCheckResponse{
+		Results:       results,
+		Policy:        policy.Name,
+		PolicyVersion: policy.Version,
+	})
+}
+
+// respondError writes err as a JSON error body, using the HTTP status code mapped from its checker.Kind.
+//
+// Parameters:
+//   - ctx: The Gin request context to respond on.
+//   - err: A non-nil error returned by checker.Checker.
+func respondError(ctx *gin.Context, err error) {
+	ctx.JSON(checker.KindOf(err).HTTPStatus(), gin.H{"error": err.Error()})
+}
+
+// clientAddress returns the client address of a request: the one stored by middleware.GinClientIP, which takes it
+// from the forwarding headers of trusted proxies, or without that middleware, the peer of the connection.
+func clientAddress(ctx *gin.Context) clientip.Address {
+	if addr, ok := clientip.FromContext(ctx.Request.Context()); ok {
+		return addr
+	}
+	return clientip.Address{IP: ctx.RemoteIP(), Source: clientip.SourcePeer}
+}
+
+// toOverrideDTO converts the override rule of a decision into its JSON representation.
+//
+// Parameters:
+//   - match: The override rule that decided the request; nil if none did.
+//
+// Returns:
+//   - *dtos.OverrideMatch: The JSON representation, or nil if match is nil.
+func toOverrideDTO(match *checker.OverrideMatch) *dtos.OverrideMatch {
+	if match == nil {
+		return nil
+	}
+	return &dtos.OverrideMatch{
+		Action: string(match.Action),
+		Name:   match.Name,
+		CIDR:   match.CIDR,
+	}
+}
@@ -1,15 +1,25 @@
 package handler_test
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
-	"github.com/justfairdev/ipchecker/internal/geo"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/checker/checkertest"
+	"github.com/justfairdev/ipchecker/clientip"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/geo/geotest"
+	"github.com/justfairdev/ipchecker/internal/dtos"
 	"github.com/justfairdev/ipchecker/internal/handler"
+	"github.com/justfairdev/ipchecker/internal/middleware"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestIPChecker_CheckIP_Success ensures that the IPChecker handler responds correctly
@@ -23,7 +33,7 @@ func TestIPChecker_CheckIP_Success(t *testing.T) {
 	mockGeo := geo.NewMockGeoLookupService("US", nil)
 
 	// Instantiate the IPChecker handler using the mocked GeoLookupService.
-	ipChecker := handler.NewIPChecker(mockGeo)
+	ipChecker := handler.NewIPChecker(checker.NewChecker(mockGeo, nil, nil))
 
 	// Configure Gin router with the IP check handler route.
 	router := gin.Default()
@@ -58,7 +68,7 @@ func TestIPChecker_CheckIP_InvalidJSON(t *testing.T) {
 	mockGeo := geo.NewMockGeoLookupService("US", nil)
 
 	// Instantiate the IPChecker handler with the mocked GeoLookupService.
-	ipChecker := handler.NewIPChecker(mockGeo)
+	ipChecker := handler.NewIPChecker(checker.NewChecker(mockGeo, nil, nil))
 
 	// Configure Gin router for handling IP checker requests.
 	router := gin.Default()
@@ -77,3 +87,181 @@ func TestIPChecker_CheckIP_InvalidJSON(t *testing.T) {
 	// Validate that an HTTP 400 Bad Request status code is returned due to invalid JSON syntax.
 	assert.Equal(t, http.StatusBadRequest, recorder.Code)
 }
+
+// TestIPChecker_CheckCaller ensures that the caller check decides on the address stored by the client IP
+// middleware, and falls back to the address of the connection without it, ignoring forwarding headers.
+func TestIPChecker_CheckCaller(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ipChecker := handler.NewIPChecker(checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil))
+	resolver, err := clientip.NewResolver([]string{"10.0.0.0/8"}, []string{"X-Forwarded-For"})
+	require.NoError(t, err)
+
+	checkCaller := func(router *gin.Engine, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/ip-check/caller", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", "128.101.101.101")
+		req.RemoteAddr = "10.0.0.5:41000"
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	router := gin.New()
+	router.POST("/ip-check/caller", middleware.GinClientIP(resolver), ipChecker.CheckCaller)
+	recorder := checkCaller(router, `{"allowed_countries":["US"]}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"allowed":true,"country":"US","reason":"rule","ip_address":"128.101.101.101","ip_source":"x-forwarded-for"}`,
+		recorder.Body.String())
+
+	// Requests without a policy are rejected before any address is checked.
+	assert.Equal(t, http.StatusBadRequest, checkCaller(router, `{}`).Code)
+
+	router = gin.New()
+	router.POST("/ip-check/caller", ipChecker.CheckCaller)
+	recorder = checkCaller(router, `{"blocked_countries":["US"]}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"allowed":false,"country":"US","reason":"rule","ip_address":"10.0.0.5","ip_source":"peer"}`,
+		recorder.Body.String())
+}
+
+// TestIPChecker_CheckIPBatch_MixedResults ensures that the batch handler returns one result per IP in request
+// order, and that a malformed IP is reported on its own item without failing the rest of the batch.
+func TestIPChecker_CheckIPBatch_MixedResults(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Build a small real database so each IP resolves to its own country.
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB", "128.101.101.0/24": "US"})
+	geoSvc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	defer geoSvc.Close()
+
+	ipChecker := handler.NewIPChecker(checker.NewChecker(geoSvc, nil, nil))
+	router := gin.New()
+	router.POST("/ip-check/batch", ipChecker.CheckIPBatch)
+
+	reqBody := `{"ip_addresses":["128.101.101.101","not-an-ip","81.2.69.142"],"allowed_countries":["US","CA"]}`
+	req, err := http.NewRequest(http.MethodPost, "/ip-check/batch", strings.NewReader(reqBody))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	// The batch as a whole succeeds even though one item is malformed.
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp dtos.IPBatchCheckResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, []dtos.IPBatchCheckResult{
+		{IPAddress: "128.101.101.101", Allowed: true, Country: "US", Reason: "rule"},
+		{IPAddress: "not-an-ip", Error: "invalid IP address", ErrorStatus: http.StatusBadRequest},
+		{IPAddress: "81.2.69.142", Allowed: false, Country: "GB", Reason: "rule"},
+	}, resp.Results)
+}
+
+// TestIPChecker_CheckIPBatch_EmptyBatch ensures that a batch without IP addresses is rejected with HTTP 400.
+func TestIPChecker_CheckIPBatch_EmptyBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ipChecker := handler.NewIPChecker(checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil))
+	router := gin.New()
+	router.POST("/ip-check/batch", ipChecker.CheckIPBatch)
+
+	reqBody := `{"ip_addresses":[],"allowed_countries":["US"]}`
+	req, err := http.NewRequest(http.MethodPost, "/ip-check/batch", strings.NewReader(reqBody))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+// TestIPChecker_Lookup verifies that GET /lookup/{ip} returns the localized record of an IP address, omits unknown
+// places, and maps malformed or unknown addresses to 400 and 404.
+func TestIPChecker_Lookup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteDatabase(t, dbPath, "GeoLite2-Country", map[string]mmdbtype.Map{"81.2.69.0/24": geotest.SatelliteRecord()})
+	geoSvc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	defer geoSvc.Close()
+
+	router := gin.New()
+	router.GET("/lookup/:ip", handler.NewIPChecker(checker.NewChecker(geoSvc, nil, nil)).Lookup)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/lookup/81.2.69.142?locale=de", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp dtos.LookupResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, dtos.LookupResponse{
+		IPAddress:         "81.2.69.142",
+		Locale:            "de",
+		Continent:         &dtos.ContinentInfo{Code: "EU", Name: "Europa"},
+		Country:           &dtos.CountryInfo{ISOCode: "DE", Name: "Deutschland", IsInEuropeanUnion: true},
+		RegisteredCountry: &dtos.CountryInfo{ISOCode: "CH", Name: "Schweiz"},
+		Traits:            dtos.TraitsInfo{IsSatelliteProvider: true},
+	}, resp)
+
+	for path, wantStatus := range map[string]int{
+		"/lookup/not-an-ip": http.StatusBadRequest,
+		"/lookup/192.0.2.1": http.StatusNotFound,
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, wantStatus, recorder.Code, path)
+	}
+}
+
+// TestIPChecker_CheckIP_Conformance runs the shared transport conformance suite against the HTTP handler,
+// ensuring its decisions and error status codes match the gRPC server's.
+func TestIPChecker_CheckIP_Conformance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	checkertest.Run(t, func(t *testing.T, c *checker.Checker, r checkertest.Request) (checker.Decision, error) {
+		router := gin.New()
+		router.POST("/ip-check", handler.NewIPChecker(c).CheckIP)
+
+		reqBody, err := json.Marshal(dtos.IPCheckRequest{
+			IPAddress:        r.IP,
+			AllowedCountries: r.AllowedCountries,
+			BlockedCountries: r.BlockedCountries,
+			DefaultAction:    r.DefaultAction,
+			Policy:           r.Policy,
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, "/ip-check", strings.NewReader(string(reqBody)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		// Translate an error response back into the checker taxonomy.
+		if recorder.Code != http.StatusOK {
+			var body map[string]string
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			return checker.Decision{}, &checker.Error{Kind: checker.KindFromHTTPStatus(recorder.Code), Message: body["error"]}
+		}
+
+		var resp dtos.IPCheckResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		decision := checker.Decision{
+			Allowed:       resp.Allowed,
+			Country:       resp.Country,
+			Reason:        checker.Reason(resp.Reason),
+			ASN:           resp.ASN,
+			Policy:        resp.Policy,
+			PolicyVersion: resp.PolicyVersion,
+		}
+		if o := resp.Override; o != nil {
+			decision.Override = &checker.OverrideMatch{Action: checker.OverrideAction(o.Action), Name: o.Name, CIDR: o.CIDR}
+		}
+		return decision, nil
+	})
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/internal/dtos"
+)
+
+// Lookup godoc
+// @Summary      Look up the full geolocation data of an IP address.
+// @Description  Returns the continent, country, registered country and represented country of an IP address, with names in the requested locale, together with the European Union flag and the anonymous-proxy, anycast and satellite-provider traits. When a City database is loaded, the city, subdivisions, postal code and accuracy radius are returned as well. No allow/deny rules are evaluated.
+// @Tags         IP
+// @Produce      json
+// @Param        ip     path  string  true   "IPv4 or IPv6 address to look up."
+// @Param        locale query string  false  "Locale of the returned names (e.g., de, ja, pt-BR); names missing in it fall back to English." default(en)
+// @Success      200 {object} dtos.LookupResponse "Geolocation data of the IP address."
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Failure      400 {object} map[string]string "Malformed IP address."
+// @Failure      401 {object} map[string]string "Missing or invalid API key or bearer token."
+// @Failure      403 {object} map[string]string "The caller lacks the lookup scope."
+// @Failure      429 {object} map[string]string "Rate limit or daily quota of the caller exceeded; see the Retry-After header."
+// @Failure      404 {object} map[string]string "No geolocation data for the IP address."
+// @Failure      500 {object} map[string]string "Internal server error during IP geolocation lookup."
+// @Router       /lookup/{ip} [get]
+func (c *IPChecker) Lookup(ctx *gin.Context) {
+	ipAddress := ctx.Param("ip")
+	locale := ctx.DefaultQuery("locale", geo.DefaultLocale)
+
+	location, err := c.checker.Lookup(ctx.Request.Context(), ipAddress, locale)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	resp := dtos.LookupResponse{
+		IPAddress:         ipAddress,
+		Locale:            locale,
+		Country:           toCountryDTO(location.Country),
+		RegisteredCountry: toCountryDTO(location.RegisteredCountry),
+		City:              location.City,
+		PostalCode:        location.PostalCode,
+		AccuracyRadius:    location.AccuracyRadius,
+		Traits: dtos.TraitsInfo{
+			IsAnonymousProxy:    location.IsAnonymousProxy,
+			IsAnycast:           location.IsAnycast,
+			IsSatelliteProvider: location.IsSatelliteProvider,
+		},
+	}
+	if location.Continent.Code != "" {
+		resp.Continent = &dtos.ContinentInfo{Code: location.Continent.Code, Name: location.Continent.Name}
+	}
+	if represented := location.RepresentedCountry; represented.ISOCode != "" {
+		resp.RepresentedCountry = &dtos.RepresentedCountryInfo{
+			ISOCode:           represented.ISOCode,
+			Name:              represented.Name,
+			IsInEuropeanUnion: represented.IsInEuropeanUnion,
+			Type:              represented.Type,
+		}
+	}
+	if location.ASN != 0 {
+		resp.ASN = &dtos.ASNInfo{Number: location.ASN, Organization: location.ASOrganization}
+	}
+	for _, subdivision := range location.Subdivisions {
+		resp.Subdivisions = append(resp.Subdivisions, dtos.SubdivisionInfo{ISOCode: subdivision.ISOCode, Name: subdivision.Name})
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// toCountryDTO converts a country of a geo.Location into its JSON representation.
+//
+// Parameters:
+//   - country: The country to convert.
+//
+// Returns:
+//   - *dtos.CountryInfo: The JSON representation, or nil if the country is unknown.
+func toCountryDTO(country geo.Country) *dtos.CountryInfo {
+	if country.ISOCode == "" {
+		return nil
+	}
+	return &dtos.CountryInfo{
+		ISOCode:           country.ISOCode,
+		Name:              country.Name,
+		IsInEuropeanUnion: country.IsInEuropeanUnion,
+	}
+}
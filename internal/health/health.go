@@ -0,0 +1,103 @@
+// Package health tracks whether the service is live and ready to serve traffic, for the HTTP probes (/healthz and
+// /readyz) and the gRPC health-checking protocol (grpc.health.v1.Health).
+package health
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrShuttingDown is reported by readiness once graceful shutdown has started.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// Check reports why a dependency cannot serve traffic, or nil if it can.
+type Check func() error
+
+// Health aggregates the readiness checks of the service and its shutdown state.
+//
+// Liveness only means the process is up and serving requests, so it is not tracked here: an instance whose
+// database is unavailable should be taken out of rotation, not restarted.
+type Health struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+
+	shutdownOnce sync.Once
+	shutdown     chan struct{} // Closed by SetShuttingDown.
+}
+
+// Report is the outcome of evaluating every readiness check.
+type Report struct {
+	// Ready is true if every check passed and the service is not shutting down.
+	Ready bool
+
+	// Failures maps the name of each failed check to its error message; "shutdown" is reported once graceful
+	// shutdown has started. Empty if Ready is true.
+	Failures map[string]string
+}
+
+// NewHealth creates a Health without readiness checks; the service is ready until a check is added that fails.
+//
+// Returns:
+//   - *Health: The initialized health state.
+func NewHealth() *Health {
+	return &Health{
+		checks:   make(map[string]Check),
+		shutdown: make(chan struct{}),
+	}
+}
+
+// AddReadinessCheck registers a check that must pass for the service to be ready.
+//
+// Parameters:
+//   - name: Name the check is reported under (e.g., "geo"); a check added under an existing name replaces it.
+//   - check: The check, called on every readiness probe; it must be cheap and safe for concurrent use.
+func (h *Health) AddReadinessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+// SetShuttingDown marks the service as not ready for good, so that load balancers stop routing new traffic to
+// it while requests in flight are drained. Calling it more than once has no further effect.
+func (h *Health) SetShuttingDown() {
+	h.shutdownOnce.Do(func() { close(h.shutdown) })
+}
+
+// ShuttingDown returns a channel that is closed once SetShuttingDown has been called.
+//
+// Returns:
+//   - <-chan struct{}: The channel, e.g. for long-lived health watches to end when the server drains.
+func (h *Health) ShuttingDown() <-chan struct{} {
+	return h.shutdown
+}
+
+// Readiness evaluates every readiness check.
+//
+// Returns:
+//   - Report: Whether the service is ready and, if not, why.
+func (h *Health) Readiness() Report {
+	report := Report{Ready: true, Failures: make(map[string]string)}
+
+	select {
+	case <-h.shutdown:
+		report.Failures["shutdown"] = ErrShuttingDown.Error()
+	default:
+	}
+
+	// Run the checks outside the lock, so a slow check does not hold up AddReadinessCheck.
+	h.mu.RLock()
+	checks := make(map[string]Check, len(h.checks))
+	for name, check := range h.checks {
+		checks[name] = check
+	}
+	h.mu.RUnlock()
+
+	for name, check := range checks {
+		if err := check(); err != nil {
+			report.Failures[name] = err.Error()
+		}
+	}
+
+	report.Ready = len(report.Failures) == 0
+	return report
+}
@@ -0,0 +1,39 @@
+package health_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/internal/health"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHealth_Readiness verifies that readiness reports every failed check by name, and that shutting down makes
+// the service not ready for good.
+func TestHealth_Readiness(t *testing.T) {
+	h := health.NewHealth()
+	assert.Equal(t, health.Report{Ready: true, Failures: map[string]string{}}, h.Readiness())
+
+	var geoErr error
+	h.AddReadinessCheck("geo", func() error { return geoErr })
+	h.AddReadinessCheck("cache", func() error { return nil })
+	assert.True(t, h.Readiness().Ready)
+
+	geoErr = errors.New("database is being reloaded")
+	assert.Equal(t, health.Report{
+		Failures: map[string]string{"geo": "database is being reloaded"},
+	}, h.Readiness())
+
+	geoErr = nil
+	h.SetShuttingDown()
+	h.SetShuttingDown()
+	assert.Equal(t, health.Report{
+		Failures: map[string]string{"shutdown": health.ErrShuttingDown.Error()},
+	}, h.Readiness())
+
+	select {
+	case <-h.ShuttingDown():
+	default:
+		t.Fatal("Expected the shutdown channel to be closed.")
+	}
+}
@@ -0,0 +1,107 @@
+// Package identity describes the client a request was received from, as proven by a verified TLS client
+// certificate, and carries it through request contexts to handlers for logging and authorization.
+package identity
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// Client is the identity of a client proven by its verified certificate.
+type Client struct {
+	// CommonName is the common name (CN) of the certificate subject.
+	CommonName string
+
+	// DNSNames are the DNS subject alternative names of the certificate.
+	DNSNames []string
+
+	// URIs are the URI subject alternative names of the certificate (e.g., SPIFFE IDs).
+	URIs []string
+
+	// EmailAddresses are the email subject alternative names of the certificate.
+	EmailAddresses []string
+}
+
+// Name returns the most specific name of the client: the first URI SAN, DNS SAN or email SAN, in that order,
+// falling back to the common name.
+//
+// Returns:
+//   - string: The name to log and authorize the client by.
+func (c Client) Name() string {
+	switch {
+	case len(c.URIs) > 0:
+		return c.URIs[0]
+	case len(c.DNSNames) > 0:
+		return c.DNSNames[0]
+	case len(c.EmailAddresses) > 0:
+		return c.EmailAddresses[0]
+	default:
+		return c.CommonName
+	}
+}
+
+// FromCertificate returns the identity proven by a client certificate.
+//
+// Parameters:
+//   - cert: The leaf certificate of the client; it must have been verified.
+//
+// Returns:
+//   - Client: The identity named by the certificate's subject and subject alternative names.
+func FromCertificate(cert *x509.Certificate) Client {
+	client := Client{
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+	}
+	for _, uri := range cert.URIs {
+		client.URIs = append(client.URIs, uri.String())
+	}
+	return client
+}
+
+// FromConnectionState returns the identity proven by the client certificate of a TLS connection.
+//
+// Only certificates verified against the client CA count: connections without TLS, without a client certificate
+// or whose certificate was not verified have no identity.
+//
+// Parameters:
+//   - state: The state of the TLS connection; may be nil for plaintext connections.
+//
+// Returns:
+//   - Client: The identity of the client.
+//   - bool: Whether the connection carries a verified client certificate.
+func FromConnectionState(state *tls.ConnectionState) (Client, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return Client{}, false
+	}
+	return FromCertificate(state.VerifiedChains[0][0]), true
+}
+
+// contextKey is the key the client identity is stored under in request contexts.
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the client identity.
+//
+// Parameters:
+//   - ctx: The request context.
+//   - client: The verified identity of the client.
+//
+// Returns:
+//   - context.Context: The context to pass on to handlers.
+func NewContext(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, contextKey{}, client)
+}
+
+// FromContext returns the client identity carried by a request context.
+//
+// Parameters:
+//   - ctx: The request context.
+//
+// Returns:
+//   - Client: The identity of the client.
+//   - bool: Whether the request was made with a verified client certificate.
+func FromContext(ctx context.Context) (Client, bool) {
+	client, ok := ctx.Value(contextKey{}).(Client)
+	return client, ok
+}
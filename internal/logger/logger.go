@@ -5,29 +5,43 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// level is the minimum severity logged by every logger created by NewLogger; see SetLevel.
+var level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+// SetLevel changes the minimum severity logged by every logger created by NewLogger, including existing ones.
+//
+// Parameters:
+//   - name: The level name: "debug", "info", "warn" or "error" (case-insensitive).
+//
+// Returns:
+//   - error: If the name is not a known level; the level is left unchanged.
+func SetLevel(name string) error {
+	parsed, err := zapcore.ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(parsed)
+	return nil
+}
+
 // NewLogger creates and configures a new application logger using the Uber Zap library.
 //
 // By default, the logger is configured to:
 //   - Output structured logs in JSON format.
-//   - Set the minimum log severity level to "Info".
+//   - Set the minimum log severity level to "Info", unless changed with SetLevel (the log.level setting).
 //   - Write standard logs to standard output (stdout).
 //   - Write error logs to standard error (stderr).
 //
-// Consideration:
-//
-//	For production readiness, the logger configuration options—such as log format, log level, and output destinations—
-//	should be externalized, typically through environment variables or a configuration file.
-//
 // Returns:
 //   - *zap.Logger: A configured Zap logger ready for use throughout the application.
 //   - error: An error if logger initialization fails due to misconfiguration.
 func NewLogger() (*zap.Logger, error) {
 	cfg := zap.Config{
-		Encoding:         "json",                                  // Structured logging in JSON format.
-		Level:            zap.NewAtomicLevelAt(zapcore.InfoLevel), // Set minimum logging level to Info.
-		OutputPaths:      []string{"stdout"},                      // Standard output stream for normal log entries.
-		ErrorOutputPaths: []string{"stderr"},                      // Error logs go to standard error output.
-		EncoderConfig:    zap.NewProductionEncoderConfig(),        // Use recommended production encoder settings.
+		Encoding:         "json",                           // Structured logging in JSON format.
+		Level:            level,                            // Shared minimum logging level, see SetLevel.
+		OutputPaths:      []string{"stdout"},               // Standard output stream for normal log entries.
+		ErrorOutputPaths: []string{"stderr"},               // Error logs go to standard error output.
+		EncoderConfig:    zap.NewProductionEncoderConfig(), // Use recommended production encoder settings.
 	}
 
 	return cfg.Build()
@@ -0,0 +1,230 @@
+// Package metrics exposes the Prometheus metrics of the service: request counts and latencies per transport,
+// decisions, geolocation lookup errors, rate-limited requests, and the state of the loaded databases and lookup
+// cache.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the name of every metric of the service.
+const namespace = "ipchecker"
+
+// Transport label values.
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+)
+
+// DatabaseSource reports the databases serving lookups; implemented by geo.GeoLookupService.
+type DatabaseSource interface {
+	Databases() []geo.DatabaseInfo
+}
+
+// CacheSource reports the counters of a lookup cache; implemented by geo.CachedLookupService.
+type CacheSource interface {
+	Stats() geo.CacheStats
+}
+
+// Metrics holds the collectors of the service and the registry they are exposed from.
+//
+// Each Metrics has its own registry rather than using the Prometheus default one, so that several instances
+// (e.g., one per test) can coexist in a process.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	decisions       *prometheus.CounterVec
+	lookupErrors    *prometheus.CounterVec
+	rateLimited     *prometheus.CounterVec
+}
+
+// NewMetrics creates the collectors of the service and registers them, along with the Go runtime and process
+// collectors, in a new registry.
+//
+// Returns:
+//   - *Metrics: The initialized metrics, ready to be recorded and exposed through Handler.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Requests served, by transport, route (HTTP) or method (gRPC), and status code.",
+		}, []string{"transport", "route", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to serve requests, by transport and route (HTTP) or method (gRPC).",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"transport", "route"}),
+		decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "decisions_total",
+			Help:      "Decisions made, by result (allowed or denied), resolved country and reason.",
+		}, []string{"result", "country", "reason"}),
+		lookupErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "geo_lookup_errors_total",
+			Help:      "Checks and lookups that failed, by error class (invalid_input, not_found or backend).",
+		}, []string{"class"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_total",
+			Help:      "Requests rejected by rate limiting, by transport, client kind (api_key, identity or ip) and reason (rate or quota).",
+		}, []string{"transport", "client_kind", "reason"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.decisions,
+		m.lookupErrors,
+		m.rateLimited,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler returns the HTTP handler serving the metrics in the Prometheus exposition format.
+//
+// Returns:
+//   - http.Handler: The handler to mount at /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Registry returns the registry the metrics are registered in, e.g. to gather them in tests.
+//
+// Returns:
+//   - *prometheus.Registry: The registry of this Metrics instance.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// ObserveRequest records a served request.
+//
+// Parameters:
+//   - transport: TransportHTTP or TransportGRPC.
+//   - route: The route template (e.g., "/api/v1/lookup/:ip") or full gRPC method name.
+//   - code: The HTTP status code or gRPC status code name (e.g., "200", "NotFound").
+//   - duration: The time taken to serve the request.
+func (m *Metrics) ObserveRequest(transport, route, code string, duration time.Duration) {
+	m.requests.WithLabelValues(transport, route, code).Inc()
+	m.requestDuration.WithLabelValues(transport, route).Observe(duration.Seconds())
+}
+
+// ObserveDecision records a decision; it implements checker.Observer.
+//
+// Parameters:
+//   - decision: The decision returned by the checker.
+func (m *Metrics) ObserveDecision(decision checker.Decision) {
+	result := "denied"
+	if decision.Allowed {
+		result = "allowed"
+	}
+	m.decisions.WithLabelValues(result, decision.Country, string(decision.Reason)).Inc()
+}
+
+// ObserveLookupError records a failed check or lookup; it implements checker.Observer.
+//
+// Parameters:
+//   - kind: The classification of the failure.
+func (m *Metrics) ObserveLookupError(kind checker.Kind) {
+	m.lookupErrors.WithLabelValues(kind.String()).Inc()
+}
+
+// ObserveRateLimited records a request rejected by rate limiting.
+//
+// Parameters:
+//   - transport: TransportHTTP or TransportGRPC.
+//   - clientKind: How the client was identified: "api_key", "identity" or "ip".
+//   - reason: Why the request was rejected: "rate" or "quota".
+func (m *Metrics) ObserveRateLimited(transport, clientKind, reason string) {
+	m.rateLimited.WithLabelValues(transport, clientKind, reason).Inc()
+}
+
+// WatchDatabases exposes the build time and type of the databases reported by source. The values are read at
+// scrape time, so reloaded databases are reflected immediately.
+//
+// Parameters:
+//   - source: The lookup service whose databases are reported.
+func (m *Metrics) WatchDatabases(source DatabaseSource) {
+	m.registry.MustRegister(&databaseCollector{source: source})
+}
+
+// WatchLookupCache exposes the hit, miss and eviction counters and the size of the lookup cache.
+//
+// Parameters:
+//   - source: The lookup cache whose counters are reported.
+func (m *Metrics) WatchLookupCache(source CacheSource) {
+	m.registry.MustRegister(&cacheCollector{source: source})
+}
+
+// databaseBuildDesc describes the gauge reporting the build time of each loaded database.
+var databaseBuildDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "geo", "database_build_timestamp_seconds"),
+	"Build time of the loaded MaxMind database as a Unix timestamp, by role (location or asn) and database type.",
+	[]string{"role", "type"}, nil,
+)
+
+// databaseCollector reports the databases of a DatabaseSource at scrape time.
+type databaseCollector struct {
+	source DatabaseSource
+}
+
+// Describe sends the descriptor of the database gauge.
+func (c *databaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- databaseBuildDesc
+}
+
+// Collect sends one gauge per loaded database.
+func (c *databaseCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, db := range c.source.Databases() {
+		ch <- prometheus.MustNewConstMetric(databaseBuildDesc, prometheus.GaugeValue,
+			float64(db.BuildEpoch.Unix()), db.Role, db.Type)
+	}
+}
+
+// cacheDescs describes the lookup cache metrics, keyed by the CacheStats field they report.
+var cacheDescs = map[string]*prometheus.Desc{
+	"hits":      cacheDesc("hits_total", "Lookups answered from the lookup cache."),
+	"misses":    cacheDesc("misses_total", "Lookups passed on to the database by the lookup cache."),
+	"evictions": cacheDesc("evictions_total", "Entries evicted from the lookup cache to stay within its size."),
+	"entries":   cacheDesc("entries", "Entries currently held by the lookup cache."),
+}
+
+// cacheDesc builds the descriptor of a lookup cache metric.
+func cacheDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "lookup_cache", name), help, nil, nil)
+}
+
+// cacheCollector reports the counters of a CacheSource at scrape time.
+type cacheCollector struct {
+	source CacheSource
+}
+
+// Describe sends the descriptors of the lookup cache metrics.
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range cacheDescs {
+		ch <- desc
+	}
+}
+
+// Collect sends the current lookup cache counters.
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.source.Stats()
+	ch <- prometheus.MustNewConstMetric(cacheDescs["hits"], prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(cacheDescs["misses"], prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(cacheDescs["evictions"], prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(cacheDescs["entries"], prometheus.GaugeValue, float64(stats.Entries))
+}
@@ -0,0 +1,151 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/geo/geotest"
+	"github.com/justfairdev/ipchecker/internal/metrics"
+	"github.com/justfairdev/ipchecker/internal/middleware"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestMetrics_Decisions verifies that decisions and lookup errors observed through the checker are counted by
+// result, country, reason and error class.
+func TestMetrics_Decisions(t *testing.T) {
+	m := metrics.NewMetrics()
+	mockGeo := geo.NewMockGeoLookupService("US", nil)
+	c := checker.NewChecker(mockGeo, nil, nil)
+	c.SetObserver(m)
+	policy := checker.Policy{AllowedCountries: []string{"US"}}
+
+	_, _ = c.Decide(context.Background(), "128.101.101.101", policy)
+	_, _ = c.Decide(context.Background(), "128.101.101.102", policy)
+	_, _ = c.Decide(context.Background(), "not-an-ip", policy)
+	mockGeo.MockCountryCode = "GB"
+	_, _ = c.Decide(context.Background(), "81.2.69.142", policy)
+	mockGeo.MockError = geo.ErrNotFound
+	_, _ = c.Lookup(context.Background(), "192.0.2.1", "en")
+
+	expected := `
+# HELP ipchecker_decisions_total Decisions made, by result (allowed or denied), resolved country and reason.
+# TYPE ipchecker_decisions_total counter
+ipchecker_decisions_total{country="GB",reason="rule",result="denied"} 1
+ipchecker_decisions_total{country="US",reason="rule",result="allowed"} 2
+# HELP ipchecker_geo_lookup_errors_total Checks and lookups that failed, by error class (invalid_input, not_found or backend).
+# TYPE ipchecker_geo_lookup_errors_total counter
+ipchecker_geo_lookup_errors_total{class="invalid_input"} 1
+ipchecker_geo_lookup_errors_total{class="not_found"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected),
+		"ipchecker_decisions_total", "ipchecker_geo_lookup_errors_total"))
+}
+
+// TestGinMetrics verifies that HTTP requests are counted by route template and status code, and that /metrics
+// serves them in the Prometheus exposition format.
+func TestGinMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := metrics.NewMetrics()
+	r := gin.New()
+	r.Use(middleware.GinMetrics(m))
+	r.GET("/api/v1/lookup/:ip", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	r.GET("/metrics", gin.WrapH(m.Handler()))
+
+	for _, path := range []string{"/api/v1/lookup/192.0.2.1", "/api/v1/lookup/192.0.2.2", "/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	expected := `
+# HELP ipchecker_requests_total Requests served, by transport, route (HTTP) or method (gRPC), and status code.
+# TYPE ipchecker_requests_total counter
+ipchecker_requests_total{code="404",route="/api/v1/lookup/:ip",transport="http"} 2
+ipchecker_requests_total{code="404",route="unmatched",transport="http"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "ipchecker_requests_total"))
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `ipchecker_request_duration_seconds_count{route="/api/v1/lookup/:ip",transport="http"} 2`)
+}
+
+// TestUnaryMetricsInterceptor verifies that RPCs are counted by method and gRPC status code.
+func TestUnaryMetricsInterceptor(t *testing.T) {
+	m := metrics.NewMetrics()
+	interceptor := middleware.UnaryMetricsInterceptor(m)
+	info := &grpc.UnaryServerInfo{FullMethod: "/ipchecker.v1.IPChecker/Lookup"}
+
+	_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "no geolocation data for IP address")
+	})
+	_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+
+	expected := `
+# HELP ipchecker_requests_total Requests served, by transport, route (HTTP) or method (gRPC), and status code.
+# TYPE ipchecker_requests_total counter
+ipchecker_requests_total{code="NotFound",route="/ipchecker.v1.IPChecker/Lookup",transport="grpc"} 1
+ipchecker_requests_total{code="OK",route="/ipchecker.v1.IPChecker/Lookup",transport="grpc"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "ipchecker_requests_total"))
+}
+
+// TestMetrics_WatchDatabasesAndCache verifies that the build time and type of the loaded database and the lookup
+// cache counters are read from their sources at scrape time.
+func TestMetrics_WatchDatabasesAndCache(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB"})
+	svc, err := geo.NewGeoLookupService(dbPath)
+	require.NoError(t, err)
+	cache, err := geo.NewCachedLookupService(svc, geo.CacheOptions{Size: 10})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	m := metrics.NewMetrics()
+	m.WatchDatabases(svc)
+	m.WatchLookupCache(cache)
+
+	_, _ = cache.CountryISOCode("81.2.69.142")
+	_, _ = cache.CountryISOCode("81.2.69.142")
+
+	families, err := m.Registry().Gather()
+	require.NoError(t, err)
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			switch {
+			case metric.GetGauge() != nil:
+				values[family.GetName()] = metric.GetGauge().GetValue()
+			case metric.GetCounter() != nil:
+				values[family.GetName()] = metric.GetCounter().GetValue()
+			}
+			if family.GetName() == "ipchecker_geo_database_build_timestamp_seconds" {
+				labels := make(map[string]string)
+				for _, label := range metric.GetLabel() {
+					labels[label.GetName()] = label.GetValue()
+				}
+				assert.Equal(t, map[string]string{"role": "location", "type": "GeoLite2-Country"}, labels)
+			}
+		}
+	}
+
+	info := svc.Databases()
+	require.Len(t, info, 1)
+	assert.Equal(t, float64(info[0].BuildEpoch.Unix()), values["ipchecker_geo_database_build_timestamp_seconds"])
+	assert.Equal(t, float64(1), values["ipchecker_lookup_cache_hits_total"])
+	assert.Equal(t, float64(1), values["ipchecker_lookup_cache_misses_total"])
+	assert.Equal(t, float64(1), values["ipchecker_lookup_cache_entries"])
+}
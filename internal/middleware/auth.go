@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GinAuthenticate returns a Gin middleware handler that authenticates requests by the API key in the X-API-Key
+// header or the bearer token in the Authorization header, and stores the caller in the request context, where
+// handlers and the decision core read it with auth.FromContext.
+//
+// Requests without credentials, or with rejected ones, are answered with 401 Unauthorized.
+//
+// Parameters:
+//   - authn: The authenticator of the accepted credentials.
+//
+// Returns:
+//   - gin.HandlerFunc: Middleware handler function suitable for inclusion in a Gin router's middleware chain.
+func GinAuthenticate(authn *auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := authn.Authenticate(auth.Credentials{
+			APIKey:      c.GetHeader(auth.APIKeyHeader),
+			BearerToken: auth.BearerToken(c.GetHeader("Authorization")),
+		})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// GinRequireScope returns a Gin middleware handler that rejects requests whose caller was not granted scope with
+// 403 Forbidden. It must run after GinAuthenticate.
+//
+// Parameters:
+//   - scope: The permission the route requires.
+//
+// Returns:
+//   - gin.HandlerFunc: Middleware handler function suitable for inclusion in a Gin route's handler chain.
+func GinRequireScope(scope auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, _ := auth.FromContext(c.Request.Context())
+		if !principal.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "caller lacks the " + string(scope) + " scope"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// UnaryAuthInterceptor creates a gRPC unary-server interceptor that authenticates calls by the API key in the
+// x-api-key metadata or the bearer token in the authorization metadata, and stores the caller in the context
+// passed to the handler.
+//
+// Every method of the protected services is authenticated, requiring its scope; methods of protected services that
+// have no scope are denied with codes.PermissionDenied, so that methods added later are closed until they are given
+// one. Other services (such as the health-checking and reflection services) are passed through. Calls without
+// credentials, or with rejected ones, fail with codes.Unauthenticated, and calls whose caller lacks the scope with
+// codes.PermissionDenied.
+//
+// Parameters:
+//   - authn: The authenticator of the accepted credentials.
+//   - services: The full names of the protected services, e.g. "ipchecker.v1.IPChecker".
+//   - scopes: The scope required by each method of the protected services, keyed by full method name.
+//
+// Returns:
+//   - grpc.UnaryServerInterceptor: A configured interceptor instance ready to be registered with a gRPC server.
+func UnaryAuthInterceptor(authn *auth.Authenticator, services []string, scopes map[string]auth.Scope) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		scope, protected, err := methodScope(services, scopes, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if !protected {
+			return handler(ctx, req)
+		}
+		ctx, err = authenticateCall(ctx, authn, scope)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor creates a gRPC stream-server interceptor that authenticates streams by the API key in the
+// x-api-key metadata or the bearer token in the authorization metadata, and stores the caller in the stream
+// context. It is the streaming counterpart of UnaryAuthInterceptor.
+//
+// Parameters:
+//   - authn: The authenticator of the accepted credentials.
+//   - services: The full names of the protected services, e.g. "ipchecker.v1.IPChecker".
+//   - scopes: The scope required by each method of the protected services, keyed by full method name.
+//
+// Returns:
+//   - grpc.StreamServerInterceptor: A configured interceptor instance ready to be registered with a gRPC server.
+func StreamAuthInterceptor(authn *auth.Authenticator, services []string, scopes map[string]auth.Scope) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		scope, protected, err := methodScope(services, scopes, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		if !protected {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticateCall(ss.Context(), authn, scope)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// methodScope returns the scope a method requires and whether it belongs to a protected service, or a
+// codes.PermissionDenied error if it belongs to one but has no scope.
+func methodScope(services []string, scopes map[string]auth.Scope, fullMethod string) (auth.Scope, bool, error) {
+	if scope, ok := scopes[fullMethod]; ok {
+		return scope, true, nil
+	}
+	for _, service := range services {
+		if inService(service, fullMethod) {
+			return "", false, status.Errorf(codes.PermissionDenied, "method %s requires a scope that is not configured", fullMethod)
+		}
+	}
+	return "", false, nil
+}
+
+// authenticateCall authenticates the credentials in the incoming metadata of a call and checks that its caller
+// holds scope, returning ctx carrying the caller.
+func authenticateCall(ctx context.Context, authn *auth.Authenticator, scope auth.Scope) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	principal, err := authn.Authenticate(auth.Credentials{
+		APIKey:      first(strings.ToLower(auth.APIKeyHeader)),
+		BearerToken: auth.BearerToken(first("authorization")),
+	})
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !principal.HasScope(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "caller lacks the %s scope", scope)
+	}
+	return auth.NewContext(ctx, principal), nil
+}
@@ -0,0 +1,49 @@
+package middleware_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/internal/auth"
+	"github.com/justfairdev/ipchecker/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newAuthenticator returns an authenticator accepting the API key "checker-secret" with the check scope.
+func newAuthenticator(t *testing.T) *auth.Authenticator {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("keys:\n  checker:\n    sha256: "+auth.HashAPIKey("checker-secret")+"\n    scopes: [check]\n"), 0o600))
+	keys, err := auth.LoadKeyFile(path)
+	require.NoError(t, err)
+	return auth.NewAuthenticator(keys, nil)
+}
+
+// TestAuthInterceptors_UnmappedMethods verifies that methods of protected services without a scope are denied even
+// to authenticated callers, while methods of other services stay open.
+func TestAuthInterceptors_UnmappedMethods(t *testing.T) {
+	services := []string{"ipchecker.v1.IPChecker"}
+	scopes := map[string]auth.Scope{"/ipchecker.v1.IPChecker/CheckIP": auth.ScopeCheck}
+	unary := middleware.UnaryAuthInterceptor(newAuthenticator(t), services, scopes)
+	stream := middleware.StreamAuthInterceptor(newAuthenticator(t), services, scopes)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "checker-secret"))
+	unaryHandler := func(ctx context.Context, req interface{}) (interface{}, error) { return "response", nil }
+	streamHandler := func(interface{}, grpc.ServerStream) error { return nil }
+
+	_, err := unary(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/ipchecker.v1.IPChecker/CheckIP"}, unaryHandler)
+	assert.NoError(t, err)
+	_, err = unary(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/ipchecker.v1.IPChecker/Unmapped"}, unaryHandler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	err = stream(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/ipchecker.v1.IPChecker/UnmappedStream"}, streamHandler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Other services, such as health checking, need no credentials.
+	_, err = unary(context.Background(), "request", &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, unaryHandler)
+	assert.NoError(t, err)
+}
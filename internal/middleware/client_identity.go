@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/internal/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// GinClientIdentity returns a Gin middleware handler that stores the identity proven by the verified TLS client
+// certificate of the request in the request context, where handlers read it with identity.FromContext.
+//
+// Requests received without TLS or without a verified client certificate are passed on unchanged.
+//
+// Returns:
+//   - gin.HandlerFunc: Middleware handler function suitable for inclusion in a Gin router's middleware chain.
+func GinClientIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if client, ok := identity.FromConnectionState(c.Request.TLS); ok {
+			c.Request = c.Request.WithContext(identity.NewContext(c.Request.Context(), client))
+		}
+		c.Next()
+	}
+}
+
+// UnaryClientIdentityInterceptor creates a gRPC unary-server interceptor that stores the identity proven by the
+// verified TLS client certificate of the connection in the context passed to the handler.
+//
+// Returns:
+//   - grpc.UnaryServerInterceptor: A configured interceptor instance ready to be registered with a gRPC server.
+func UnaryClientIdentityInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(withPeerIdentity(ctx), req)
+	}
+}
+
+// StreamClientIdentityInterceptor creates a gRPC stream-server interceptor that stores the identity proven by the
+// verified TLS client certificate of the connection in the stream context. It is the streaming counterpart of
+// UnaryClientIdentityInterceptor.
+//
+// Returns:
+//   - grpc.StreamServerInterceptor: A configured interceptor instance ready to be registered with a gRPC server.
+func StreamClientIdentityInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: withPeerIdentity(ss.Context())})
+	}
+}
+
+// withPeerIdentity returns ctx carrying the client identity of the gRPC peer, if it presented a verified
+// certificate; otherwise ctx is returned unchanged.
+func withPeerIdentity(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return ctx
+	}
+	if client, ok := identity.FromConnectionState(&tlsInfo.State); ok {
+		return identity.NewContext(ctx, client)
+	}
+	return ctx
+}
+
+// contextServerStream wraps a grpc.ServerStream to replace its context.
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the replacement context of the stream.
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
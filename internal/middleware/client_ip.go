@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/clientip"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// GinClientIP returns a Gin middleware handler that stores the client address of the request, as found by the
+// resolver from the connection and the forwarding headers of trusted proxies, in the request context, where
+// handlers read it with clientip.FromContext.
+//
+// Parameters:
+//   - resolver: The resolver of client addresses, configured with the trusted proxies and headers.
+//
+// Returns:
+//   - gin.HandlerFunc: Middleware handler function suitable for inclusion in a Gin router's middleware chain.
+func GinClientIP(resolver *clientip.Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		addr := resolver.ResolveRequest(c.Request)
+		c.Request = c.Request.WithContext(clientip.NewContext(c.Request.Context(), addr))
+		c.Next()
+	}
+}
+
+// UnaryClientIPInterceptor creates a gRPC unary-server interceptor that stores the client address of the call, as
+// found by the resolver from the peer address and the forwarding metadata of trusted proxies, in the context passed
+// to the handler.
+//
+// Parameters:
+//   - resolver: The resolver of client addresses, configured with the trusted proxies and metadata keys.
+//
+// Returns:
+//   - grpc.UnaryServerInterceptor: A configured interceptor instance ready to be registered with a gRPC server.
+func UnaryClientIPInterceptor(resolver *clientip.Resolver) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(withClientIP(ctx, resolver), req)
+	}
+}
+
+// StreamClientIPInterceptor creates a gRPC stream-server interceptor that stores the client address of the call in
+// the stream context. It is the streaming counterpart of UnaryClientIPInterceptor.
+//
+// Parameters:
+//   - resolver: The resolver of client addresses, configured with the trusted proxies and metadata keys.
+//
+// Returns:
+//   - grpc.StreamServerInterceptor: A configured interceptor instance ready to be registered with a gRPC server.
+func StreamClientIPInterceptor(resolver *clientip.Resolver) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: withClientIP(ss.Context(), resolver)})
+	}
+}
+
+// withClientIP returns ctx carrying the client address of the gRPC call, resolved from its peer and incoming
+// metadata; repeated metadata values are joined by commas, like repeated HTTP headers.
+func withClientIP(ctx context.Context, resolver *clientip.Resolver) context.Context {
+	var remote string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	addr := resolver.Resolve(remote, func(name string) string {
+		return strings.Join(md.Get(name), ",")
+	})
+	return clientip.NewContext(ctx, addr)
+}
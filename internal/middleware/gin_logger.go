@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/internal/identity"
 	"go.uber.org/zap"
 )
 
@@ -14,6 +15,7 @@ import (
 //   - Request path (endpoint)
 //   - Response HTTP status code
 //   - Request processing latency
+//   - The verified client identity (TLS client certificate), if any
 //
 // These structured logs greatly assist developers and operators with monitoring, debugging, and analysis of request patterns and performance characteristics.
 //
@@ -36,12 +38,16 @@ func GinLogger(logger *zap.Logger) gin.HandlerFunc {
 		latency := time.Since(start)
 
 		// Log structured request and response details
-		logger.Info("HTTP request",
+		fields := []zap.Field{
 			zap.String("method", method),
 			zap.String("path", path),
 			zap.Int("status", status),
 			zap.Duration("latency", latency),
-		)
+		}
+		if client, ok := identity.FromContext(c.Request.Context()); ok {
+			fields = append(fields, zap.String("client", client.Name()))
+		}
+		logger.Info("HTTP request", fields...)
 	}
 }
 
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/internal/metrics"
+)
+
+// GinMetrics returns a Gin middleware handler that records the count, status code and latency of every HTTP
+// request in the provided Metrics.
+//
+// Requests are labelled with their route template (e.g., "/api/v1/lookup/:ip") rather than the request path,
+// so that the number of time series does not grow with the IP addresses looked up. Requests matching no route
+// are labelled "unmatched".
+//
+// Parameters:
+//   - m: The metrics the requests are recorded in.
+//
+// Returns:
+//   - gin.HandlerFunc: Middleware handler function suitable for inclusion in a Gin router's middleware chain.
+func GinMetrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		// Proceed with request processing
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.ObserveRequest(metrics.TransportHTTP, route, strconv.Itoa(c.Writer.Status()), time.Since(start))
+	}
+}
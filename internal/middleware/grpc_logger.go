@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/justfairdev/ipchecker/internal/identity"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -15,9 +17,12 @@ import (
 //
 // This interceptor logs the following details:
 //   - The full RPC method name (e.g., "/package.Service/Method").
-//   - Metadata received from the client.
+//   - Metadata received from the client, with credentials redacted (see redactMetadata).
+//   - The verified client identity (TLS client certificate), if any.
 //   - The request message payload.
 //   - The response message payload.
+//   - Neither payload for the Envoy ext_authz Check method, whose request carries the headers, cookies and URL of
+//     the proxied HTTP request.
 //   - The gRPC status code resulting from RPC handling.
 //   - The total latency taken to process the request.
 //
@@ -44,8 +49,9 @@ func UnaryLoggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
 
 		logger.Info("gRPC request started",
 			zap.String("method", info.FullMethod),
-			zap.Any("metadata", md),
-			zap.Any("request", req),
+			zap.Any("metadata", redactMetadata(md)),
+			clientField(ctx),
+			payloadField(info.FullMethod, "request", req),
 		)
 
 		// Invoke the actual RPC handler method with the provided context and request
@@ -59,10 +65,136 @@ func UnaryLoggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
 			zap.String("method", info.FullMethod),
 			zap.Duration("latency", time.Since(start)),
 			zap.Int32("grpc_code", int32(s.Code())),
-			zap.Any("response", resp),
+			payloadField(info.FullMethod, "response", resp),
 			zap.Error(err),
 		)
 
 		return resp, err
 	}
 }
+
+// StreamLoggingInterceptor creates a gRPC stream-server interceptor that logs the lifecycle of each streaming RPC
+// via the provided Zap logger. It is the streaming counterpart of UnaryLoggingInterceptor.
+//
+// Individual stream messages are not logged, as long-lived streams may carry millions of them. Instead, this
+// interceptor logs:
+//   - The full RPC method name, metadata (credentials redacted) and verified client identity when the stream opens.
+//   - The number of messages received and sent over the stream.
+//   - The gRPC status code the stream ended with.
+//   - The total duration the stream was open.
+//
+// Parameters:
+//   - logger: A Zap logger instance used to output the structured logs.
+//
+// Returns:
+//   - grpc.StreamServerInterceptor: A configured interceptor instance ready to be registered with a gRPC server.
+func StreamLoggingInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+
+		start := time.Now()
+
+		// Extract the incoming metadata (headers) from the stream context, if available.
+		md, _ := metadata.FromIncomingContext(ss.Context())
+
+		logger.Info("gRPC stream started",
+			zap.String("method", info.FullMethod),
+			zap.Any("metadata", redactMetadata(md)),
+			clientField(ss.Context()),
+		)
+
+		// Wrap the stream to count the messages flowing in each direction.
+		counted := &countingServerStream{ServerStream: ss}
+		err := handler(srv, counted)
+
+		// Obtain detailed gRPC status information from the error, if present.
+		s, _ := status.FromError(err)
+
+		// Log the outcome of the stream, including message counts and total stream duration.
+		logger.Info("gRPC stream completed",
+			zap.String("method", info.FullMethod),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int32("grpc_code", int32(s.Code())),
+			zap.Int64("messages_received", counted.received),
+			zap.Int64("messages_sent", counted.sent),
+			zap.Error(err),
+		)
+
+		return err
+	}
+}
+
+// redactedKeys are the metadata keys carrying credentials, whose values are never logged. Calls are logged before
+// they are authenticated, so rejected guesses would otherwise be written to the log as well.
+var redactedKeys = []string{"x-api-key", "authorization", "cookie"}
+
+// redactMetadata returns a copy of md whose credentials are replaced by "[REDACTED]", so that the metadata can be
+// logged.
+//
+// Parameters:
+//   - md: The incoming metadata of a call; it is not modified.
+//
+// Returns:
+//   - metadata.MD: The metadata safe to log.
+func redactMetadata(md metadata.MD) metadata.MD {
+	md = md.Copy()
+	for _, key := range redactedKeys {
+		if values := md.Get(key); len(values) > 0 {
+			redacted := make([]string, len(values))
+			for i := range redacted {
+				redacted[i] = "[REDACTED]"
+			}
+			md.Set(key, redacted...)
+		}
+	}
+	return md
+}
+
+// payloadField returns the log field holding a request or response message; it is skipped for the Envoy ext_authz
+// Check method, whose messages describe the proxied HTTP request of an end user, credentials included.
+func payloadField(method, key string, msg interface{}) zap.Field {
+	if method == authv3.Authorization_Check_FullMethodName {
+		return zap.Skip()
+	}
+	return zap.Any(key, msg)
+}
+
+// clientField returns the log field naming the verified client identity carried by ctx; it is skipped if the
+// client presented no verified certificate.
+func clientField(ctx context.Context) zap.Field {
+	if client, ok := identity.FromContext(ctx); ok {
+		return zap.String("client", client.Name())
+	}
+	return zap.Skip()
+}
+
+// countingServerStream wraps a grpc.ServerStream and counts successfully received and sent messages.
+// gRPC guarantees that RecvMsg and SendMsg are each called from at most one goroutine at a time,
+// but they may run concurrently with each other, hence the separate counters.
+type countingServerStream struct {
+	grpc.ServerStream
+	received int64
+	sent     int64
+}
+
+// RecvMsg receives a message from the client and counts it on success.
+func (c *countingServerStream) RecvMsg(m interface{}) error {
+	err := c.ServerStream.RecvMsg(m)
+	if err == nil {
+		c.received++
+	}
+	return err
+}
+
+// SendMsg sends a message to the client and counts it on success.
+func (c *countingServerStream) SendMsg(m interface{}) error {
+	err := c.ServerStream.SendMsg(m)
+	if err == nil {
+		c.sent++
+	}
+	return err
+}
@@ -0,0 +1,104 @@
+package middleware_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/justfairdev/ipchecker/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// secrets are the credentials sent with the calls of the logging tests.
+var secrets = metadata.Pairs(
+	"x-api-key", "ipk_secret-key",
+	"authorization", "Bearer secret-token",
+	"cookie", "session=secret-session",
+	"x-request-id", "req-42",
+)
+
+// fakeServerStream is a grpc.ServerStream carrying a context, for calling stream interceptors directly.
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream.
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// assertNoCredentials checks that no logged entry contains a credential, and that other metadata is still logged.
+func assertNoCredentials(t *testing.T, logs *observer.ObservedLogs) {
+	require.NotZero(t, logs.Len())
+	var logged string
+	for _, entry := range logs.All() {
+		logged += fmt.Sprint(entry.ContextMap())
+	}
+	for _, secret := range []string{"secret-key", "secret-token", "secret-session"} {
+		assert.NotContains(t, logged, secret)
+	}
+	assert.Contains(t, logged, "[REDACTED]")
+	assert.Contains(t, logged, "req-42")
+}
+
+// TestUnaryLoggingInterceptor_RedactsCredentials verifies that API keys, bearer tokens and cookies sent as
+// metadata never reach the log of unary calls.
+func TestUnaryLoggingInterceptor_RedactsCredentials(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	interceptor := middleware.UnaryLoggingInterceptor(zap.New(core))
+
+	ctx := metadata.NewIncomingContext(context.Background(), secrets)
+	_, err := interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/ipchecker.v1.IPChecker/CheckIP"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "response", nil })
+	require.NoError(t, err)
+
+	assertNoCredentials(t, logs)
+	md, _ := metadata.FromIncomingContext(ctx)
+	assert.Equal(t, []string{"ipk_secret-key"}, md.Get("x-api-key"), "Expected the metadata of the call to be left intact.")
+}
+
+// TestStreamLoggingInterceptor_RedactsCredentials verifies that credentials sent as metadata never reach the log
+// of streaming calls.
+func TestStreamLoggingInterceptor_RedactsCredentials(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	interceptor := middleware.StreamLoggingInterceptor(zap.New(core))
+
+	stream := &fakeServerStream{ctx: metadata.NewIncomingContext(context.Background(), secrets)}
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/ipchecker.v1.IPChecker/CheckIPStream"},
+		func(interface{}, grpc.ServerStream) error { return nil })
+	require.NoError(t, err)
+
+	assertNoCredentials(t, logs)
+}
+
+// TestUnaryLoggingInterceptor_SkipsExtAuthzPayload verifies that the Envoy CheckRequest, which carries the headers
+// and URL of the proxied HTTP request, is not logged.
+func TestUnaryLoggingInterceptor_SkipsExtAuthzPayload(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	interceptor := middleware.UnaryLoggingInterceptor(zap.New(core))
+
+	req := &authv3.CheckRequest{Attributes: &authv3.AttributeContext{Request: &authv3.AttributeContext_Request{
+		Http: &authv3.AttributeContext_HttpRequest{
+			Path:    "/account?token=secret-query",
+			Headers: map[string]string{"cookie": "session=secret-session", "authorization": "Bearer secret-token"},
+		},
+	}}}
+	_, err := interceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: authv3.Authorization_Check_FullMethodName},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return &authv3.CheckResponse{}, nil })
+	require.NoError(t, err)
+
+	require.Equal(t, 2, logs.Len())
+	for _, entry := range logs.All() {
+		assert.Equal(t, authv3.Authorization_Check_FullMethodName, entry.ContextMap()["method"])
+		assert.NotContains(t, entry.ContextMap(), "request")
+		assert.NotContains(t, entry.ContextMap(), "response")
+		assert.NotContains(t, fmt.Sprint(entry.ContextMap()), "secret")
+	}
+}
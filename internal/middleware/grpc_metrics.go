@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/justfairdev/ipchecker/internal/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryMetricsInterceptor creates a gRPC unary-server interceptor that records the count, status code and latency
+// of every RPC in the provided Metrics, labelled with the full method name.
+//
+// Parameters:
+//   - m: The metrics the RPCs are recorded in.
+//
+// Returns:
+//   - grpc.UnaryServerInterceptor: A configured interceptor instance ready to be registered with a gRPC server.
+func UnaryMetricsInterceptor(m *metrics.Metrics) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		m.ObserveRequest(metrics.TransportGRPC, info.FullMethod, status.Code(err).String(), time.Since(start))
+		return resp, err
+	}
+}
+
+// StreamMetricsInterceptor creates a gRPC stream-server interceptor that records every streaming RPC in the provided
+// Metrics once it ends, with the duration the stream was open. Decisions made for individual stream messages are
+// recorded by the checker itself.
+//
+// Parameters:
+//   - m: The metrics the streams are recorded in.
+//
+// Returns:
+//   - grpc.StreamServerInterceptor: A configured interceptor instance ready to be registered with a gRPC server.
+func StreamMetricsInterceptor(m *metrics.Metrics) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+
+		start := time.Now()
+		err := handler(srv, ss)
+
+		m.ObserveRequest(metrics.TransportGRPC, info.FullMethod, status.Code(err).String(), time.Since(start))
+		return err
+	}
+}
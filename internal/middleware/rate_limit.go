@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/clientip"
+	"github.com/justfairdev/ipchecker/internal/metrics"
+	"github.com/justfairdev/ipchecker/internal/ratelimit"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RetryAfterMetadata is the gRPC trailer metadata key carrying the number of seconds to wait before retrying a
+// call rejected with codes.ResourceExhausted, like the Retry-After header of HTTP.
+const RetryAfterMetadata = "retry-after"
+
+// GinRateLimit returns a Gin middleware handler that counts every request against the limits of its client and
+// rejects the requests exceeding them with 429 Too Many Requests and a Retry-After header. A batch request counts
+// once per IP address in its "ip_addresses" list.
+//
+// Clients are identified by their authenticated caller, the identity of their TLS client certificate, or their
+// IP address, in that order (see ratelimit.ClientFromContext), so the middleware must run after GinAuthenticate.
+// The IP address is the one found by GinClientIP, which takes it from the forwarding headers of trusted proxies.
+// Rejections are logged and recorded in the provided Metrics.
+//
+// Parameters:
+//   - limiter: The limits of each client.
+//   - m: The metrics rejections are recorded in.
+//   - logger: A Zap logger used to log rejections.
+//
+// Returns:
+//   - gin.HandlerFunc: Middleware handler function suitable for inclusion in a Gin router's middleware chain.
+func GinRateLimit(limiter *ratelimit.Limiter, m *metrics.Metrics, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := ratelimit.ClientFromContext(c.Request.Context(), clientIPOf(c.Request.Context(), c.RemoteIP()))
+		decision := limiter.AllowN(client, bodyCost(c.Request))
+		if decision.Allowed {
+			c.Next()
+			return
+		}
+
+		retryAfter := retryAfterSeconds(decision.RetryAfter)
+		logRateLimited(logger, metrics.TransportHTTP, c.FullPath(), client, decision, retryAfter)
+		m.ObserveRateLimited(metrics.TransportHTTP, string(client.Kind), string(decision.Reason))
+		c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": rejection(decision)})
+	}
+}
+
+// UnaryRateLimitInterceptor creates a gRPC unary-server interceptor that counts every call to the methods of
+// service against the limits of its client, and rejects the calls exceeding them with codes.ResourceExhausted
+// and a retry-after trailer. A CheckIPBatch call counts once per IP address.
+//
+// Clients are identified as by GinRateLimit, so the interceptor must run after UnaryAuthInterceptor. Other
+// services (such as the health-checking and reflection services) are not limited.
+//
+// Parameters:
+//   - limiter: The limits of each client.
+//   - service: The full name of the limited service, e.g. "ipchecker.v1.IPChecker".
+//   - m: The metrics rejections are recorded in.
+//   - logger: A Zap logger used to log rejections.
+//
+// Returns:
+//   - grpc.UnaryServerInterceptor: A configured interceptor instance ready to be registered with a gRPC server.
+func UnaryRateLimitInterceptor(limiter *ratelimit.Limiter, service string, m *metrics.Metrics, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if !inService(service, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		if trailer, err := limitCall(ctx, limiter, info.FullMethod, messageCost(req), m, logger); err != nil {
+			grpc.SetTrailer(ctx, trailer)
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamRateLimitInterceptor creates a gRPC stream-server interceptor that counts every message received on
+// streams of the methods of service against the limits of its client, so that a single long-lived stream is
+// limited like the equivalent unary calls. The stream fails with codes.ResourceExhausted and a retry-after
+// trailer at the first message exceeding them. It is the streaming counterpart of UnaryRateLimitInterceptor.
+//
+// Parameters:
+//   - limiter: The limits of each client.
+//   - service: The full name of the limited service, e.g. "ipchecker.v1.IPChecker".
+//   - m: The metrics rejections are recorded in.
+//   - logger: A Zap logger used to log rejections.
+//
+// Returns:
+//   - grpc.StreamServerInterceptor: A configured interceptor instance ready to be registered with a gRPC server.
+func StreamRateLimitInterceptor(limiter *ratelimit.Limiter, service string, m *metrics.Metrics, logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if !inService(service, info.FullMethod) {
+			return handler(srv, ss)
+		}
+		return handler(srv, &rateLimitedServerStream{
+			ServerStream: ss,
+			check: func(msg interface{}) (metadata.MD, error) {
+				return limitCall(ss.Context(), limiter, info.FullMethod, messageCost(msg), m, logger)
+			},
+		})
+	}
+}
+
+// inService reports whether fullMethod, e.g. "/ipchecker.v1.IPChecker/CheckIP", is a method of service.
+func inService(service, fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+service+"/")
+}
+
+// limitCall counts a call or stream message, as cost requests, against the limits of its client. If it exceeds
+// them, the rejection is logged and recorded, and the trailer to send along with the returned
+// codes.ResourceExhausted error is returned.
+func limitCall(ctx context.Context, limiter *ratelimit.Limiter, method string, cost int, m *metrics.Metrics, logger *zap.Logger) (metadata.MD, error) {
+	client := ratelimit.ClientFromContext(ctx, clientIPOf(ctx, peerIP(ctx)))
+	decision := limiter.AllowN(client, cost)
+	if decision.Allowed {
+		return nil, nil
+	}
+
+	retryAfter := retryAfterSeconds(decision.RetryAfter)
+	logRateLimited(logger, metrics.TransportGRPC, method, client, decision, retryAfter)
+	m.ObserveRateLimited(metrics.TransportGRPC, string(client.Kind), string(decision.Reason))
+	trailer := metadata.Pairs(RetryAfterMetadata, strconv.FormatInt(retryAfter, 10))
+	return trailer, status.Errorf(codes.ResourceExhausted, "%s, retry in %ds", rejection(decision), retryAfter)
+}
+
+// batchMessage is implemented by the gRPC requests checking several IP addresses at once.
+type batchMessage interface {
+	GetIpAddresses() []string
+}
+
+// messageCost returns the number of requests a gRPC request message counts as: one per IP address of a batch,
+// otherwise one.
+func messageCost(msg interface{}) int {
+	if batch, ok := msg.(batchMessage); ok && len(batch.GetIpAddresses()) > 1 {
+		return len(batch.GetIpAddresses())
+	}
+	return 1
+}
+
+// bodyCost returns the number of requests an HTTP request counts as: one per IP address of the "ip_addresses"
+// list of a JSON batch body, otherwise one. The body is read ahead and replaced by a copy for the handler.
+func bodyCost(r *http.Request) int {
+	if r.Method != http.MethodPost || r.Body == nil || r.Body == http.NoBody {
+		return 1
+	}
+	data, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return 1
+	}
+
+	var batch struct {
+		IPAddresses []string `json:"ip_addresses"`
+	}
+	if json.Unmarshal(data, &batch) != nil || len(batch.IPAddresses) < 1 {
+		return 1
+	}
+	return len(batch.IPAddresses)
+}
+
+// clientIPOf returns the client IP address stored in ctx by the client IP middleware, so that the clients behind
+// a trusted proxy are counted separately rather than as the proxy, or fallback if none was stored.
+func clientIPOf(ctx context.Context, fallback string) string {
+	if addr, ok := clientip.FromContext(ctx); ok && addr.IP != "" {
+		return addr.IP
+	}
+	return fallback
+}
+
+// peerIP returns the IP address of the gRPC peer of ctx, or its address as a whole if it has no IP address.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
+
+// retryAfterSeconds rounds a retry delay up to whole seconds, as sent in Retry-After.
+func retryAfterSeconds(d time.Duration) int64 {
+	return int64(math.Max(1, math.Ceil(d.Seconds())))
+}
+
+// rejection returns the error message of a rejected request.
+func rejection(decision ratelimit.Decision) string {
+	if decision.Reason == ratelimit.ReasonQuota {
+		return "daily quota exceeded"
+	}
+	return "rate limit exceeded"
+}
+
+// logRateLimited logs a rejected request or call.
+func logRateLimited(logger *zap.Logger, transport, route string, client ratelimit.Client, decision ratelimit.Decision, retryAfter int64) {
+	logger.Warn("Request rate limited",
+		zap.String("transport", transport),
+		zap.String("route", route),
+		zap.Stringer("rate_limit_client", client),
+		zap.String("reason", string(decision.Reason)),
+		zap.Int64("retry_after_seconds", retryAfter),
+	)
+}
+
+// rateLimitedServerStream wraps a grpc.ServerStream and counts every received message against the limits of the
+// client, failing the receive of the first message exceeding them.
+type rateLimitedServerStream struct {
+	grpc.ServerStream
+	check func(msg interface{}) (metadata.MD, error)
+}
+
+// RecvMsg receives a message from the client and counts it.
+func (s *rateLimitedServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if trailer, err := s.check(m); err != nil {
+		s.SetTrailer(trailer)
+		return err
+	}
+	return nil
+}
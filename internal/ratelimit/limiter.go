@@ -0,0 +1,256 @@
+package ratelimit
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/justfairdev/ipchecker/internal/auth"
+	"github.com/justfairdev/ipchecker/internal/identity"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// Kind is the way a client is identified.
+type Kind string
+
+const (
+	// KindAPIKey identifies an authenticated caller by the name of its API key or the subject of its bearer token.
+	KindAPIKey Kind = "api_key"
+
+	// KindIdentity identifies a client by the identity of its verified TLS client certificate.
+	KindIdentity Kind = "identity"
+
+	// KindIP identifies a client by the IP address it connected from.
+	KindIP Kind = "ip"
+)
+
+// Client is a client whose requests are counted together.
+type Client struct {
+	Kind Kind
+	Name string
+}
+
+// String returns the client as logged, e.g. "api_key:billing".
+func (c Client) String() string {
+	return string(c.Kind) + ":" + c.Name
+}
+
+// ClientFromContext returns the client a request is counted against: the authenticated caller if any, otherwise
+// the identity of its verified TLS client certificate, otherwise the IP address it connected from.
+//
+// Parameters:
+//   - ctx: The request context, carrying the caller and certificate identity stored by the authentication and
+//     client identity middleware.
+//   - peerIP: The IP address of the connection the request was received on.
+//
+// Returns:
+//   - Client: The client.
+func ClientFromContext(ctx context.Context, peerIP string) Client {
+	if principal, ok := auth.FromContext(ctx); ok {
+		return Client{Kind: KindAPIKey, Name: principal.Name}
+	}
+	if client, ok := identity.FromContext(ctx); ok {
+		return Client{Kind: KindIdentity, Name: client.Name()}
+	}
+	if addr, err := netip.ParseAddr(peerIP); err == nil {
+		peerIP = addr.Unmap().String()
+	}
+	return Client{Kind: KindIP, Name: peerIP}
+}
+
+// Reason tells why a request was rejected.
+type Reason string
+
+const (
+	// ReasonRate rejects a request because the client's bucket is empty.
+	ReasonRate Reason = "rate"
+
+	// ReasonQuota rejects a request because the client used up its daily quota.
+	ReasonQuota Reason = "quota"
+)
+
+// Decision is the outcome of counting a request.
+type Decision struct {
+	// Allowed is true if the request may be served.
+	Allowed bool
+
+	// Reason tells why the request was rejected; empty if it was allowed.
+	Reason Reason
+
+	// RetryAfter is how long the client should wait before retrying a rejected request.
+	RetryAfter time.Duration
+}
+
+// MaxBuckets caps the token buckets a Limiter keeps, so that clients identified by ever new IP addresses cannot
+// grow its memory without bound, whether or not Run maintains it.
+const MaxBuckets = 100_000
+
+// Limiter decides whether the requests of each client are within its limits.
+type Limiter struct {
+	limits *Limits
+	quotas *QuotaStore
+
+	mu      sync.Mutex
+	buckets map[Client]*rate.Limiter // Token buckets, created on first use and evicted once full again
+}
+
+// NewLimiter creates a Limiter enforcing limits.
+//
+// Parameters:
+//   - limits: The allowance of every client.
+//   - quotas: The store of the daily quota counters; nil keeps them in memory only.
+//
+// Returns:
+//   - *Limiter: The limiter.
+func NewLimiter(limits *Limits, quotas *QuotaStore) *Limiter {
+	if quotas == nil {
+		quotas = &QuotaStore{used: make(map[string]int64)}
+	}
+	return &Limiter{limits: limits, quotas: quotas, buckets: make(map[Client]*rate.Limiter)}
+}
+
+// Allow counts a request of client and decides whether it may be served. Rejected requests are not counted
+// against the daily quota, and requests rejected by the quota do not use up tokens.
+//
+// Parameters:
+//   - client: The client the request is counted against.
+//
+// Returns:
+//   - Decision: Whether the request is allowed, and if not, why and when to retry.
+func (l *Limiter) Allow(client Client) Decision {
+	return l.AllowN(client, 1)
+}
+
+// AllowN counts n requests of client at once, e.g. the IP addresses of a batch, and decides whether they may be
+// served; they are allowed or rejected together, like a single request in Allow. More requests than the burst of
+// the client are always rejected, as its bucket never holds enough tokens.
+//
+// Parameters:
+//   - client: The client the requests are counted against.
+//   - n: The number of requests, at least 1.
+//
+// Returns:
+//   - Decision: Whether the requests are allowed, and if not, why and when to retry.
+func (l *Limiter) AllowN(client Client, n int) Decision {
+	limit, ok := l.limits.lookup(client)
+	if !ok || limit.unlimited() {
+		return Decision{Allowed: true}
+	}
+
+	now := time.Now()
+	var reservation *rate.Reservation
+	if limit.Rate > 0 {
+		reservation = l.bucket(client, limit, now).ReserveN(now, n)
+		if !reservation.OK() {
+			// Retrying does not help; advise waiting until the bucket is full, so that smaller requests pass.
+			return Decision{Reason: ReasonRate, RetryAfter: time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second))}
+		}
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			return Decision{Reason: ReasonRate, RetryAfter: delay}
+		}
+	}
+	if limit.DailyQuota > 0 && !l.quotas.take(client.String(), int64(n), limit.DailyQuota, now) {
+		if reservation != nil {
+			reservation.CancelAt(now)
+		}
+		return Decision{Reason: ReasonQuota, RetryAfter: nextDay(now).Sub(now)}
+	}
+	return Decision{Allowed: true}
+}
+
+// bucket returns the token bucket of client, creating a full one on first use. If MaxBuckets are kept already,
+// the full buckets are evicted first, then arbitrary ones down to nine tenths of the cap: their clients get a full
+// bucket again, which is preferable to running out of memory.
+func (l *Limiter) bucket(client Client, limit Limit, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= MaxBuckets {
+			// Make room for a tenth of the cap at once, so that the scan is not repeated for every new client.
+			l.evictFullLocked(now)
+			for other := range l.buckets {
+				if len(l.buckets) < MaxBuckets*9/10 {
+					break
+				}
+				delete(l.buckets, other)
+			}
+		}
+		b = rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+		l.buckets[client] = b
+	}
+	return b
+}
+
+// Buckets returns the number of token buckets the limiter keeps, at most MaxBuckets.
+//
+// Returns:
+//   - int: The number of clients with a partially used bucket, or seen since the last eviction.
+func (l *Limiter) Buckets() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// evictFull forgets the buckets that have refilled completely, which behave like new ones, so that clients
+// seen once (e.g., by IP address) do not accumulate.
+func (l *Limiter) evictFull(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.evictFullLocked(now)
+}
+
+// evictFullLocked is evictFull for callers holding mu.
+func (l *Limiter) evictFullLocked(now time.Time) {
+	for client, b := range l.buckets {
+		if b.TokensAt(now) >= float64(b.Burst()) {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// Run maintains the limiter until ctx is cancelled: every interval, it forgets idle buckets and persists the
+// daily quota counters, so that at most one interval of counts is lost if the process is killed.
+//
+// Parameters:
+//   - ctx: Context whose cancellation stops the maintenance.
+//   - interval: How often the maintenance runs; it is disabled if interval <= 0, in which case the buckets are
+//     only evicted once MaxBuckets are kept, and the counters only persisted by Close.
+//   - logger: A Zap logger used to report counters that cannot be persisted.
+func (l *Limiter) Run(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.evictFull(now)
+			if err := l.quotas.Flush(); err != nil {
+				logger.Error("Failed to persist daily quota counters", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Close persists the daily quota counters; it is called once the servers no longer serve requests.
+//
+// Returns:
+//   - error: An error if the counters cannot be written to the quota store.
+func (l *Limiter) Close() error {
+	return l.quotas.Flush()
+}
+
+// nextDay returns the start of the UTC day after t, when daily quotas are reset.
+func nextDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,148 @@
+package ratelimit_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/justfairdev/ipchecker/internal/auth"
+	"github.com/justfairdev/ipchecker/internal/identity"
+	"github.com/justfairdev/ipchecker/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLimiter returns a Limiter enforcing the limit file content, with quota counters kept in memory.
+func newLimiter(t *testing.T, content string) *ratelimit.Limiter {
+	t.Helper()
+
+	limits, err := ratelimit.LoadLimitFile(writeFile(t, "limits.yaml", content))
+	require.NoError(t, err)
+	return ratelimit.NewLimiter(limits, nil)
+}
+
+// allowed returns how many of n consecutive requests of client are allowed.
+func allowed(limiter *ratelimit.Limiter, client ratelimit.Client, n int) int {
+	count := 0
+	for i := 0; i < n; i++ {
+		if limiter.Allow(client).Allowed {
+			count++
+		}
+	}
+	return count
+}
+
+// TestLimiter_Allow verifies that every client gets its own bucket of its own size, that unlisted clients get the
+// default, and that rejections say when to retry.
+func TestLimiter_Allow(t *testing.T) {
+	limiter := newLimiter(t, `
+default:
+  rate: 0.01
+  burst: 2
+api_keys:
+  billing:
+    rate: 0.01
+    burst: 5
+  ops: {}
+ips:
+  203.0.113.7:
+    rate: 0.01
+    burst: 1
+`)
+
+	billing := ratelimit.Client{Kind: ratelimit.KindAPIKey, Name: "billing"}
+	assert.Equal(t, 5, allowed(limiter, billing, 10))
+	decision := limiter.Allow(billing)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, ratelimit.ReasonRate, decision.Reason)
+	assert.InDelta(t, 100*time.Second, decision.RetryAfter, float64(time.Second), "Expected one token to take 1/rate to refill.")
+
+	assert.Equal(t, 20, allowed(limiter, ratelimit.Client{Kind: ratelimit.KindAPIKey, Name: "ops"}, 20), "Expected an empty limit not to limit.")
+	assert.Equal(t, 1, allowed(limiter, ratelimit.Client{Kind: ratelimit.KindIP, Name: "203.0.113.7"}, 5))
+	assert.Equal(t, 2, allowed(limiter, ratelimit.Client{Kind: ratelimit.KindIP, Name: "203.0.113.8"}, 5))
+	assert.Equal(t, 2, allowed(limiter, ratelimit.Client{Kind: ratelimit.KindIP, Name: "203.0.113.9"}, 5), "Expected each unlisted client to get its own default bucket.")
+
+	unlimited := newLimiter(t, "api_keys:\n  billing:\n    rate: 0.01\n    burst: 1\n")
+	assert.Equal(t, 10, allowed(unlimited, ratelimit.Client{Kind: ratelimit.KindIP, Name: "203.0.113.7"}, 10), "Expected unlisted clients not to be limited without a default.")
+}
+
+// TestLimiter_AllowN verifies that several requests are counted at once against the bucket and the daily quota, and
+// that more requests than the burst are rejected without using up tokens.
+func TestLimiter_AllowN(t *testing.T) {
+	limiter := newLimiter(t, `
+api_keys:
+  batch:
+    rate: 0.01
+    burst: 5
+  quota:
+    daily_quota: 10
+`)
+
+	batch := ratelimit.Client{Kind: ratelimit.KindAPIKey, Name: "batch"}
+	decision := limiter.AllowN(batch, 6)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, ratelimit.ReasonRate, decision.Reason)
+	assert.Positive(t, decision.RetryAfter)
+	assert.True(t, limiter.AllowN(batch, 4).Allowed)
+	assert.False(t, limiter.AllowN(batch, 2).Allowed)
+	assert.True(t, limiter.Allow(batch).Allowed)
+
+	quota := ratelimit.Client{Kind: ratelimit.KindAPIKey, Name: "quota"}
+	assert.True(t, limiter.AllowN(quota, 8).Allowed)
+	assert.Equal(t, ratelimit.ReasonQuota, limiter.AllowN(quota, 3).Reason)
+	assert.True(t, limiter.AllowN(quota, 2).Allowed)
+}
+
+// TestLimiter_MaxBuckets verifies that the buckets of clients seen once are capped even though the limiter is not
+// maintained by Run, and that the clients seen last keep theirs.
+func TestLimiter_MaxBuckets(t *testing.T) {
+	limiter := newLimiter(t, "default:\n  rate: 0.001\n  burst: 1\n")
+
+	for i := 0; i < ratelimit.MaxBuckets+10; i++ {
+		require.True(t, limiter.Allow(ratelimit.Client{Kind: ratelimit.KindIP, Name: fmt.Sprint(i)}).Allowed)
+	}
+	assert.LessOrEqual(t, limiter.Buckets(), ratelimit.MaxBuckets)
+	last := ratelimit.Client{Kind: ratelimit.KindIP, Name: fmt.Sprint(ratelimit.MaxBuckets + 9)}
+	assert.False(t, limiter.Allow(last).Allowed, "Expected the bucket of a recent client to be kept.")
+}
+
+// TestLimiter_DailyQuota verifies that a client is rejected once it used up its daily quota until the next UTC day,
+// and that requests rejected by the rate limit do not count against the quota.
+func TestLimiter_DailyQuota(t *testing.T) {
+	limiter := newLimiter(t, `
+api_keys:
+  billing:
+    daily_quota: 3
+  burst:
+    rate: 0.01
+    burst: 1
+    daily_quota: 2
+`)
+
+	billing := ratelimit.Client{Kind: ratelimit.KindAPIKey, Name: "billing"}
+	assert.Equal(t, 3, allowed(limiter, billing, 10))
+	decision := limiter.Allow(billing)
+	assert.Equal(t, ratelimit.ReasonQuota, decision.Reason)
+	assert.LessOrEqual(t, decision.RetryAfter, 24*time.Hour)
+	assert.Positive(t, decision.RetryAfter)
+
+	burst := ratelimit.Client{Kind: ratelimit.KindAPIKey, Name: "burst"}
+	assert.Equal(t, 1, allowed(limiter, burst, 10))
+	assert.Equal(t, ratelimit.ReasonRate, limiter.Allow(burst).Reason, "Expected the rate limit to reject before the quota is used up.")
+}
+
+// TestClientFromContext verifies that requests are counted against the authenticated caller, then the certificate
+// identity, then the IP address.
+func TestClientFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, ratelimit.Client{Kind: ratelimit.KindIP, Name: "192.0.2.1"}, ratelimit.ClientFromContext(ctx, "::ffff:192.0.2.1"))
+
+	ctx = identity.NewContext(ctx, identity.Client{URIs: []string{"spiffe://example.org/batch"}})
+	assert.Equal(t, ratelimit.Client{Kind: ratelimit.KindIdentity, Name: "spiffe://example.org/batch"}, ratelimit.ClientFromContext(ctx, "192.0.2.1"))
+
+	ctx = auth.NewContext(ctx, auth.Principal{Name: "billing"})
+	client := ratelimit.ClientFromContext(ctx, "192.0.2.1")
+	assert.Equal(t, ratelimit.Client{Kind: ratelimit.KindAPIKey, Name: "billing"}, client)
+	assert.Equal(t, "api_key:billing", client.String())
+}
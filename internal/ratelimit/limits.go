@@ -0,0 +1,184 @@
+// Package ratelimit protects the service from clients sending more requests than their share: every client has a
+// token bucket refilled at its configured rate and, optionally, a daily quota whose counters are persisted to a
+// local file so that they survive restarts.
+package ratelimit
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Limit is the allowance of one client.
+type Limit struct {
+	// Rate is the number of requests per second the bucket is refilled with; 0 disables rate limiting.
+	Rate float64 `yaml:"rate" json:"rate"`
+
+	// Burst is the capacity of the bucket, i.e. how many requests may be sent at once after a quiet period.
+	Burst int `yaml:"burst" json:"burst"`
+
+	// DailyQuota is the number of requests allowed per UTC day; 0 disables the quota. Only listed clients may have
+	// one, so that the counters kept for the day are bounded by the limit file.
+	DailyQuota int64 `yaml:"daily_quota" json:"daily_quota"`
+}
+
+// validate checks that the limit can be enforced.
+func (l Limit) validate() error {
+	switch {
+	case l.Rate < 0:
+		return fmt.Errorf("rate must not be negative")
+	case l.Rate > 0 && l.Burst < 1:
+		return fmt.Errorf("burst must be at least 1 when a rate is set")
+	case l.DailyQuota < 0:
+		return fmt.Errorf("daily_quota must not be negative")
+	}
+	return nil
+}
+
+// unlimited reports whether the limit never rejects requests.
+func (l Limit) unlimited() bool {
+	return l.Rate == 0 && l.DailyQuota == 0
+}
+
+// Limits holds the allowance of every client: the clients listed by API key, certificate identity or IP address,
+// and the default applying to each other client separately.
+type Limits struct {
+	defaultLimit *Limit // nil if clients that are not listed are not limited
+	apiKeys      map[string]Limit
+	identities   map[string]Limit
+	ips          map[netip.Addr]Limit
+}
+
+// limitFile is the on-disk layout of a rate limit file.
+//
+// Example (YAML; the equivalent JSON document is accepted as well):
+//
+//	default:            # every client not listed below gets its own bucket of this size
+//	  rate: 50
+//	  burst: 100
+//	api_keys:           # by API key name or bearer token subject
+//	  billing-batch:
+//	    rate: 5
+//	    burst: 20
+//	    daily_quota: 100000
+//	  ops: {}           # not limited
+//	identities:         # by TLS client certificate identity
+//	  spiffe://example.org/ns/batch/sa/importer:
+//	    rate: 20
+//	    burst: 40
+//	ips:                # by peer IP address
+//	  203.0.113.7:
+//	    rate: 1
+//	    burst: 5
+type limitFile struct {
+	Default    *Limit           `yaml:"default" json:"default"`
+	APIKeys    map[string]Limit `yaml:"api_keys" json:"api_keys"`
+	Identities map[string]Limit `yaml:"identities" json:"identities"`
+	IPs        map[string]Limit `yaml:"ips" json:"ips"`
+}
+
+// LoadLimitFile reads and validates the client limits stored in a YAML or JSON file.
+//
+// Parameters:
+//   - path: Filesystem path to the limit file.
+//
+// Returns:
+//   - *Limits: The validated limits.
+//   - error: An error naming the offending client if the file cannot be read or a limit is invalid.
+func LoadLimitFile(path string) (*Limits, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading rate limit file: %w", err)
+	}
+
+	// YAML is a superset of JSON, so a single decoder handles both formats.
+	var file limitFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing rate limit file %s: %w", path, err)
+	}
+
+	limits := &Limits{
+		defaultLimit: file.Default,
+		apiKeys:      make(map[string]Limit, len(file.APIKeys)),
+		identities:   make(map[string]Limit, len(file.Identities)),
+		ips:          make(map[netip.Addr]Limit, len(file.IPs)),
+	}
+	if file.Default != nil {
+		if err := file.Default.validate(); err != nil {
+			return nil, fmt.Errorf("rate limit file %s: default: %w", path, err)
+		}
+		// The default applies to every IP address separately, so its quota counters, which are kept for the whole
+		// day and persisted, would grow with every new address.
+		if file.Default.DailyQuota > 0 {
+			return nil, fmt.Errorf("rate limit file %s: default: daily_quota is only supported for listed clients", path)
+		}
+	}
+	for name, limit := range file.APIKeys {
+		if err := limit.validate(); err != nil {
+			return nil, fmt.Errorf("rate limit file %s: API key %q: %w", path, name, err)
+		}
+		limits.apiKeys[name] = limit
+	}
+	for name, limit := range file.Identities {
+		if err := limit.validate(); err != nil {
+			return nil, fmt.Errorf("rate limit file %s: identity %q: %w", path, name, err)
+		}
+		limits.identities[name] = limit
+	}
+	for raw, limit := range file.IPs {
+		addr, err := netip.ParseAddr(raw)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit file %s: %q is not an IP address", path, raw)
+		}
+		if err := limit.validate(); err != nil {
+			return nil, fmt.Errorf("rate limit file %s: IP address %q: %w", path, raw, err)
+		}
+		limits.ips[addr.Unmap()] = limit
+	}
+	return limits, nil
+}
+
+// HasQuotas reports whether any client has a daily quota, i.e. whether quota counters need to be kept.
+//
+// Returns:
+//   - bool: true if a listed client has a daily quota; the default has none.
+func (l *Limits) HasQuotas() bool {
+	for _, set := range []map[string]Limit{l.apiKeys, l.identities} {
+		for _, limit := range set {
+			if limit.DailyQuota > 0 {
+				return true
+			}
+		}
+	}
+	for _, limit := range l.ips {
+		if limit.DailyQuota > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the limit of a client: its own if listed, otherwise the default.
+func (l *Limits) lookup(client Client) (Limit, bool) {
+	var limit Limit
+	var ok bool
+	switch client.Kind {
+	case KindAPIKey:
+		limit, ok = l.apiKeys[client.Name]
+	case KindIdentity:
+		limit, ok = l.identities[client.Name]
+	case KindIP:
+		if addr, err := netip.ParseAddr(client.Name); err == nil {
+			limit, ok = l.ips[addr.Unmap()]
+		}
+	}
+	if ok {
+		return limit, true
+	}
+	if l.defaultLimit != nil {
+		return *l.defaultLimit, true
+	}
+	return Limit{}, false
+}
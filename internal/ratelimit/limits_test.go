@@ -0,0 +1,75 @@
+package ratelimit_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes a file named name into a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// TestLoadLimitFile verifies that limits are read per API key, identity and IP address along with a default, and
+// that daily quotas are detected.
+func TestLoadLimitFile(t *testing.T) {
+	limits, err := ratelimit.LoadLimitFile(writeFile(t, "limits.yaml", `
+default:
+  rate: 50
+  burst: 100
+api_keys:
+  billing-batch:
+    rate: 5
+    burst: 20
+    daily_quota: 100000
+  ops: {}
+identities:
+  spiffe://example.org/ns/batch/sa/importer:
+    rate: 20
+    burst: 40
+ips:
+  "2001:db8::7":
+    rate: 1
+    burst: 5
+`))
+	require.NoError(t, err)
+	assert.True(t, limits.HasQuotas())
+
+	limits, err = ratelimit.LoadLimitFile(writeFile(t, "limits.json", `{"default": {"rate": 10, "burst": 10}}`))
+	require.NoError(t, err)
+	assert.False(t, limits.HasQuotas())
+}
+
+// TestLoadLimitFile_RejectsInvalidLimits verifies that malformed limit files are rejected with an error naming the
+// client.
+func TestLoadLimitFile_RejectsInvalidLimits(t *testing.T) {
+	for name, tc := range map[string]struct {
+		content string
+		message string
+	}{
+		"negative rate":   {"api_keys:\n  billing:\n    rate: -1\n    burst: 1\n", `API key "billing": rate must not be negative`},
+		"no burst":        {"identities:\n  batch:\n    rate: 5\n", `identity "batch": burst must be at least 1`},
+		"negative quota":  {"default:\n  daily_quota: -5\n", "default: daily_quota must not be negative"},
+		"default quota":   {"default:\n  rate: 1\n  burst: 1\n  daily_quota: 5\n", "default: daily_quota is only supported for listed clients"},
+		"invalid IP":      {"ips:\n  10.0.0.0/8:\n    rate: 1\n    burst: 1\n", `"10.0.0.0/8" is not an IP address`},
+		"malformed burst": {"api_keys:\n  billing:\n    rate: 1\n    burst: [1]\n", "parsing rate limit file"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ratelimit.LoadLimitFile(writeFile(t, "limits.yaml", tc.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.message)
+		})
+	}
+
+	_, err := ratelimit.LoadLimitFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
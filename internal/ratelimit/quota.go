@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// dayLayout formats the UTC day quota counters belong to.
+const dayLayout = "2006-01-02"
+
+// QuotaStore counts the requests of each client during the current UTC day and persists the counters to a local
+// file, so that a restart does not hand out a fresh quota.
+type QuotaStore struct {
+	path string // File the counters are persisted to; empty keeps them in memory only
+
+	mu    sync.Mutex
+	day   string           // UTC day the counters belong to, e.g. "2024-06-01"
+	used  map[string]int64 // Requests counted per client, keyed by Client.String
+	dirty bool             // Whether the counters changed since they were last persisted
+}
+
+// quotaFile is the on-disk layout of a quota store.
+type quotaFile struct {
+	Day  string           `json:"day"`
+	Used map[string]int64 `json:"used"`
+}
+
+// OpenQuotaStore loads the quota counters persisted at path. Counters of a previous day are discarded on first
+// use.
+//
+// Parameters:
+//   - path: Filesystem path to the counter file; it is created on first Flush if it does not exist, and its
+//     directory must exist.
+//
+// Returns:
+//   - *QuotaStore: The store.
+//   - error: An error if the file exists but cannot be read or parsed.
+func OpenQuotaStore(path string) (*QuotaStore, error) {
+	store := &QuotaStore{path: path, used: make(map[string]int64)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading quota store: %w", err)
+	}
+
+	var file quotaFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing quota store %s: %w", path, err)
+	}
+	store.day = file.Day
+	if file.Used != nil {
+		store.used = file.Used
+	}
+	return store, nil
+}
+
+// take counts n requests of the client keyed by key if they are all within quota, starting new counters at the
+// start of each UTC day.
+func (s *QuotaStore) take(key string, n, quota int64, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if day := now.UTC().Format(dayLayout); day != s.day {
+		s.day, s.used, s.dirty = day, make(map[string]int64), true
+	}
+	if s.used[key]+n > quota {
+		return false
+	}
+	s.used[key] += n
+	s.dirty = true
+	return true
+}
+
+// Flush persists the counters if they changed since the last Flush. The file is replaced atomically, so that a
+// crash while writing leaves the previous counters intact.
+//
+// Returns:
+//   - error: An error if the counter file cannot be written; the counters stay pending for the next Flush.
+func (s *QuotaStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" || !s.dirty {
+		return nil
+	}
+	data, err := json.Marshal(quotaFile{Day: s.day, Used: s.used})
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing quota store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("writing quota store: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
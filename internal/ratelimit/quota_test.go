@@ -0,0 +1,55 @@
+package ratelimit_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestQuotaStore_SurvivesRestart verifies that quota counters persisted by Close are picked up by the next
+// instance, so that a restart does not hand out a fresh quota.
+func TestQuotaStore_SurvivesRestart(t *testing.T) {
+	limits, err := ratelimit.LoadLimitFile(writeFile(t, "limits.yaml", "api_keys:\n  billing:\n    daily_quota: 5\n"))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "quotas.json")
+	billing := ratelimit.Client{Kind: ratelimit.KindAPIKey, Name: "billing"}
+
+	store, err := ratelimit.OpenQuotaStore(path)
+	require.NoError(t, err)
+	limiter := ratelimit.NewLimiter(limits, store)
+	assert.Equal(t, 3, allowed(limiter, billing, 3))
+	require.NoError(t, limiter.Close())
+
+	store, err = ratelimit.OpenQuotaStore(path)
+	require.NoError(t, err)
+	limiter = ratelimit.NewLimiter(limits, store)
+	assert.Equal(t, 2, allowed(limiter, billing, 10), "Expected the counter of the previous run to be restored.")
+}
+
+// TestQuotaStore_ResetsDaily verifies that counters persisted on a previous day are discarded.
+func TestQuotaStore_ResetsDaily(t *testing.T) {
+	limits, err := ratelimit.LoadLimitFile(writeFile(t, "limits.yaml", "api_keys:\n  billing:\n    daily_quota: 5\n"))
+	require.NoError(t, err)
+	path := writeFile(t, "quotas.json", `{"day": "2000-01-01", "used": {"api_key:billing": 5}}`)
+
+	store, err := ratelimit.OpenQuotaStore(path)
+	require.NoError(t, err)
+	limiter := ratelimit.NewLimiter(limits, store)
+	assert.Equal(t, 5, allowed(limiter, ratelimit.Client{Kind: ratelimit.KindAPIKey, Name: "billing"}, 10))
+	require.NoError(t, limiter.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "2000-01-01")
+}
+
+// TestOpenQuotaStore_RejectsCorruptFile verifies that a quota store that cannot be parsed fails startup rather
+// than silently resetting every quota.
+func TestOpenQuotaStore_RejectsCorruptFile(t *testing.T) {
+	_, err := ratelimit.OpenQuotaStore(writeFile(t, "quotas.json", "{not json"))
+	assert.Error(t, err)
+}
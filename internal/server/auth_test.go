@@ -0,0 +1,295 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/internal/auth"
+	"github.com/justfairdev/ipchecker/internal/grpcserver"
+	"github.com/justfairdev/ipchecker/internal/health"
+	"github.com/justfairdev/ipchecker/internal/metrics"
+	"github.com/justfairdev/ipchecker/internal/server"
+	pb "github.com/justfairdev/ipchecker/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newAuthenticatedChecker returns a checker locating every IP address in the US with "europe-only" and "us-only"
+// policies, and an authenticator accepting the API keys "checker-secret" (check scope), "pinned-secret" (check
+// scope, bound to europe-only) and "lookup-secret" (lookup scope), as well as the bearer tokens returned by the
+// returned function for the given scope and policies claims.
+func newAuthenticatedChecker(t *testing.T) (*checker.Checker, *auth.Authenticator, func(scope string, policies ...string) string) {
+	dir := t.TempDir()
+	policyFile := filepath.Join(dir, "policies.yaml")
+	require.NoError(t, os.WriteFile(policyFile, []byte(`policies:
+  europe-only:
+    allowed_countries: [DE, FR]
+  us-only:
+    allowed_countries: [US]
+`), 0o600))
+	policies, err := checker.LoadPolicyFile(policyFile)
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(dir, "keys.yaml")
+	require.NoError(t, os.WriteFile(keyFile, []byte(`keys:
+  checker:
+    sha256: `+auth.HashAPIKey("checker-secret")+`
+    scopes: [check]
+  pinned:
+    sha256: `+auth.HashAPIKey("pinned-secret")+`
+    scopes: [check]
+    policy: europe-only
+  lookup:
+    sha256: `+auth.HashAPIKey("lookup-secret")+`
+    scopes: [lookup]
+`), 0o600))
+	keys, err := auth.LoadKeyFile(keyFile)
+	require.NoError(t, err)
+
+	// Publish the public half of a locally generated signing key as the JWKS of the identity provider.
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	set, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &signingKey.PublicKey, KeyID: "idp-1", Algorithm: string(jose.ES256)}}})
+	require.NoError(t, err)
+	jwksFile := filepath.Join(dir, "jwks.json")
+	require.NoError(t, os.WriteFile(jwksFile, set, 0o600))
+	tokens, err := auth.NewTokenVerifier(auth.TokenOptions{
+		JWKSFile:      jwksFile,
+		Issuer:        "https://idp.example.com",
+		Audience:      "ipchecker",
+		ScopesClaim:   "scope",
+		PoliciesClaim: "policies",
+	})
+	require.NoError(t, err)
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: signingKey},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader(jose.HeaderKey("kid"), "idp-1"))
+	require.NoError(t, err)
+	issue := func(scope string, policies ...string) string {
+		claims := map[string]interface{}{
+			"iss":   "https://idp.example.com",
+			"aud":   "ipchecker",
+			"sub":   "checkout-service",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": scope,
+		}
+		if len(policies) > 0 {
+			claims["policies"] = policies
+		}
+		token, err := jwt.Signed(signer).Claims(claims).Serialize()
+		require.NoError(t, err)
+		return token
+	}
+
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), policies, nil)
+	c.SetPolicyRestriction(auth.PoliciesFromContext)
+	return c, auth.NewAuthenticator(keys, tokens), issue
+}
+
+// TestNewHTTPServer_APIKeys verifies that the versioned API requires a valid API key with the scope of the route,
+// that a policy bound to the key replaces the countries sent by the client, and that probes stay open.
+func TestNewHTTPServer_APIKeys(t *testing.T) {
+	c, authn, _ := newAuthenticatedChecker(t)
+	engine, err := server.NewHTTPServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), authn, nil, server.ClientIPOptions{})
+	require.NoError(t, err)
+
+	send := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(auth.APIKeyHeader, key)
+		}
+		resp := httptest.NewRecorder()
+		engine.ServeHTTP(resp, req)
+		return resp
+	}
+	check := `{"ip_address": "8.8.8.8", "allowed_countries": ["US"]}`
+
+	resp := send(http.MethodPost, "/api/v1/ip-check", "", check)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.JSONEq(t, `{"error": "missing API key or bearer token"}`, resp.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/api/v1/ip-check", "wrong", check).Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/api/v1/ip-check", "lookup-secret", check).Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/api/v1/lookup/8.8.8.8", "checker-secret", "").Code)
+
+	resp = send(http.MethodPost, "/api/v1/ip-check", "checker-secret", check)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"allowed":true`)
+
+	resp = send(http.MethodPost, "/api/v1/ip-check", "pinned-secret", check)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"allowed":false`, "Expected the bound policy to replace the client's countries.")
+	assert.Contains(t, resp.Body.String(), `"policy":"europe-only"`)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/api/v1/lookup/8.8.8.8", "lookup-secret", "").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/healthz", "", "").Code)
+}
+
+// TestNewGRPCServer_APIKeys verifies that IPChecker calls require a valid API key in the metadata with the scope
+// of the method, that a policy bound to the key replaces the countries sent by the client, that Envoy's ext_authz
+// calls are authenticated the same way, and that the health service stays open.
+func TestNewGRPCServer_APIKeys(t *testing.T) {
+	c, authn, _ := newAuthenticatedChecker(t)
+	grpcSrv, err := server.NewGRPCServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), authn, nil, grpcserver.ExtAuthzOptions{}, server.ClientIPOptions{})
+	require.NoError(t, err)
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcSrv.Serve(listener)
+	defer grpcSrv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewIPCheckerClient(conn)
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	}
+	req := &pb.IPCheckRequest{IpAddress: "8.8.8.8", AllowedCountries: []string{"US"}}
+
+	_, err = client.CheckIP(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.CheckIP(withKey("wrong"), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.CheckIP(withKey("lookup-secret"), req)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	resp, err := client.CheckIP(withKey("checker-secret"), req)
+	require.NoError(t, err)
+	assert.True(t, resp.GetAllowed())
+
+	resp, err = client.CheckIP(withKey("pinned-secret"), req)
+	require.NoError(t, err)
+	assert.False(t, resp.GetAllowed(), "Expected the bound policy to replace the client's countries.")
+	assert.Equal(t, "europe-only", resp.GetPolicy())
+
+	stream, err := client.CheckIPStream(context.Background())
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	authz := authv3.NewAuthorizationClient(conn)
+	check := &authv3.CheckRequest{Attributes: &authv3.AttributeContext{
+		Source: &authv3.AttributeContext_Peer{Address: &corev3.Address{Address: &corev3.Address_SocketAddress{
+			SocketAddress: &corev3.SocketAddress{Address: "8.8.8.8"},
+		}}},
+		ContextExtensions: map[string]string{"policy": "us-only"},
+	}}
+	_, err = authz.Check(context.Background(), check)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	authzResp, err := authz.Check(withKey("checker-secret"), check)
+	require.NoError(t, err)
+	assert.NotNil(t, authzResp.GetOkResponse())
+
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err, "Expected the health service not to require an API key.")
+}
+
+// TestNewHTTPServer_BearerTokens verifies that the versioned API accepts bearer tokens signed by the identity
+// provider, grants the scopes of their claims, and restricts checks to the policies their claims permit.
+func TestNewHTTPServer_BearerTokens(t *testing.T) {
+	c, authn, issue := newAuthenticatedChecker(t)
+	engine, err := server.NewHTTPServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), authn, nil, server.ClientIPOptions{})
+	require.NoError(t, err)
+
+	send := func(path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		engine.ServeHTTP(resp, req)
+		return resp
+	}
+	check := `{"ip_address": "8.8.8.8", "allowed_countries": ["US"]}`
+
+	assert.Equal(t, http.StatusUnauthorized, send("/api/v1/ip-check", "not-a-jwt", check).Code)
+	assert.Equal(t, http.StatusForbidden, send("/api/v1/ip-check", issue("lookup", "us-only"), check).Code)
+	assert.Equal(t, http.StatusUnauthorized, send("/api/v1/ip-check", issue("check"), check).Code,
+		"Expected tokens without a policies claim to be rejected.")
+
+	resp := send("/api/v1/ip-check", issue("check", "europe-only", "us-only"), check)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"policy":"europe-only"`, "Expected the first permitted policy to replace the client's countries.")
+
+	resp = send("/api/v1/ip-check", issue("check", "europe-only", "us-only"), `{"ip_address": "8.8.8.8", "policy": "us-only"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"allowed":true`)
+
+	resp = send("/api/v1/ip-check", issue("check", "europe-only"), `{"ip_address": "8.8.8.8", "policy": "us-only"}`)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), `policy \"us-only\" is not permitted`)
+}
+
+// TestNewGRPCServer_BearerTokens verifies that IPChecker calls accept bearer tokens in the authorization metadata
+// and restrict checks to the policies their claims permit.
+func TestNewGRPCServer_BearerTokens(t *testing.T) {
+	c, authn, issue := newAuthenticatedChecker(t)
+	grpcSrv, err := server.NewGRPCServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), authn, nil, grpcserver.ExtAuthzOptions{}, server.ClientIPOptions{})
+	require.NoError(t, err)
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcSrv.Serve(listener)
+	defer grpcSrv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewIPCheckerClient(conn)
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+
+	_, err = client.CheckIP(withToken("not-a-jwt"), &pb.IPCheckRequest{IpAddress: "8.8.8.8", AllowedCountries: []string{"US"}})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.Lookup(withToken(issue("check", "us-only")), &pb.LookupRequest{IpAddress: "8.8.8.8"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	resp, err := client.CheckIP(withToken(issue("check", "us-only")), &pb.IPCheckRequest{IpAddress: "8.8.8.8", Policy: "us-only"})
+	require.NoError(t, err)
+	assert.True(t, resp.GetAllowed())
+
+	_, err = client.CheckIP(withToken(issue("check", "europe-only")), &pb.IPCheckRequest{IpAddress: "8.8.8.8", Policy: "us-only"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// TestMethodScopes verifies that every method of the protected services has a scope, as the methods missing one
+// are denied to every caller.
+func TestMethodScopes(t *testing.T) {
+	for _, desc := range []grpc.ServiceDesc{pb.IPChecker_ServiceDesc, authv3.Authorization_ServiceDesc} {
+		assert.Contains(t, server.ProtectedServices, desc.ServiceName)
+		for _, method := range desc.Methods {
+			assert.Contains(t, server.MethodScopes, "/"+desc.ServiceName+"/"+method.MethodName)
+		}
+		for _, stream := range desc.Streams {
+			assert.Contains(t, server.MethodScopes, "/"+desc.ServiceName+"/"+stream.StreamName)
+		}
+	}
+}
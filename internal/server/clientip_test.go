@@ -0,0 +1,123 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/internal/dtos"
+	"github.com/justfairdev/ipchecker/internal/grpcserver"
+	"github.com/justfairdev/ipchecker/internal/handler"
+	"github.com/justfairdev/ipchecker/internal/health"
+	"github.com/justfairdev/ipchecker/internal/metrics"
+	"github.com/justfairdev/ipchecker/internal/server"
+	pb "github.com/justfairdev/ipchecker/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// TestNewHTTPServer_ClientIP verifies that the client IP headers are ignored unless the peer is a configured
+// trusted proxy, and that only the configured headers are read.
+func TestNewHTTPServer_ClientIP(t *testing.T) {
+	c, _, _ := newAuthenticatedChecker(t)
+	clientIP := func(opts server.ClientIPOptions) string {
+		engine, err := server.NewHTTPServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), nil, nil, opts)
+		require.NoError(t, err)
+
+		// httptest requests come from 192.0.2.1.
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth?policy=us-only", nil)
+		req.Header.Set("X-Forwarded-For", "198.51.100.7")
+		req.Header.Set("X-Real-IP", "203.0.113.9")
+		resp := httptest.NewRecorder()
+		engine.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+		return resp.Header().Get(handler.ClientIPHeader)
+	}
+
+	assert.Equal(t, "192.0.2.1", clientIP(server.ClientIPOptions{}), "Expected no proxy to be trusted by default.")
+	assert.Equal(t, "198.51.100.7", clientIP(server.ClientIPOptions{TrustedProxies: []string{"192.0.2.0/24"}}))
+	assert.Equal(t, "203.0.113.9", clientIP(server.ClientIPOptions{TrustedProxies: []string{"192.0.2.1"}, Headers: []string{"X-Real-IP"}}))
+
+	_, err := server.NewHTTPServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), nil, nil,
+		server.ClientIPOptions{TrustedProxies: []string{"proxy.local"}})
+	assert.Error(t, err)
+}
+
+// TestNewHTTPServer_CheckCaller verifies that the caller check reports the address of the connection, ignoring the
+// forwarding headers of untrusted peers, or the address forwarded by a trusted proxy along with its header.
+func TestNewHTTPServer_CheckCaller(t *testing.T) {
+	c, _, _ := newAuthenticatedChecker(t)
+	checkCaller := func(opts server.ClientIPOptions) dtos.CallerCheckResponse {
+		engine, err := server.NewHTTPServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), nil, nil, opts)
+		require.NoError(t, err)
+
+		// httptest requests come from 192.0.2.1.
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/ip-check/caller", strings.NewReader(`{"policy": "us-only"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.7")
+		resp := httptest.NewRecorder()
+		engine.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+		var body dtos.CallerCheckResponse
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.True(t, body.Allowed)
+		assert.Equal(t, "US", body.Country)
+		assert.Equal(t, "us-only", body.Policy)
+		return body
+	}
+
+	body := checkCaller(server.ClientIPOptions{})
+	assert.Equal(t, "192.0.2.1", body.IPAddress, "Expected the header of an untrusted peer to be ignored.")
+	assert.Equal(t, "peer", body.IPSource)
+
+	body = checkCaller(server.ClientIPOptions{TrustedProxies: []string{"192.0.2.1"}})
+	assert.Equal(t, "198.51.100.7", body.IPAddress)
+	assert.Equal(t, "x-forwarded-for", body.IPSource)
+}
+
+// TestNewGRPCServer_CheckCaller verifies that CheckCaller reports the peer address of the call, ignoring forwarding
+// metadata unless the peer is a trusted proxy.
+func TestNewGRPCServer_CheckCaller(t *testing.T) {
+	c, _, _ := newAuthenticatedChecker(t)
+	checkCaller := func(opts server.ClientIPOptions) *pb.CallerCheckResponse {
+		grpcSrv, err := server.NewGRPCServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), nil, nil, grpcserver.ExtAuthzOptions{}, opts)
+		require.NoError(t, err)
+
+		// A real listener, so that the call has a peer IP address.
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go grpcSrv.Serve(listener)
+		t.Cleanup(grpcSrv.Stop)
+
+		conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-real-ip", "198.51.100.7")
+		resp, err := pb.NewIPCheckerClient(conn).CheckCaller(ctx, &pb.CallerCheckRequest{AllowedCountries: []string{"US"}})
+		require.NoError(t, err)
+		assert.True(t, resp.GetAllowed())
+		return resp
+	}
+
+	resp := checkCaller(server.ClientIPOptions{})
+	assert.Equal(t, "127.0.0.1", resp.GetIpAddress(), "Expected the metadata of an untrusted peer to be ignored.")
+	assert.Equal(t, "peer", resp.GetIpSource())
+
+	resp = checkCaller(server.ClientIPOptions{TrustedProxies: []string{"127.0.0.0/8"}})
+	assert.Equal(t, "198.51.100.7", resp.GetIpAddress())
+	assert.Equal(t, "x-real-ip", resp.GetIpSource())
+
+	_, err := server.NewGRPCServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), nil, nil,
+		grpcserver.ExtAuthzOptions{}, server.ClientIPOptions{TrustedProxies: []string{"proxy.local"}})
+	assert.Error(t, err)
+}
@@ -1,47 +1,135 @@
 package server
 
 import (
-	"github.com/justfairdev/ipchecker/internal/geo"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/internal/auth"
 	"github.com/justfairdev/ipchecker/internal/grpcserver"
+	"github.com/justfairdev/ipchecker/internal/health"
 	"github.com/justfairdev/ipchecker/internal/logger"
+	"github.com/justfairdev/ipchecker/internal/metrics"
 	"github.com/justfairdev/ipchecker/internal/middleware"
+	"github.com/justfairdev/ipchecker/internal/ratelimit"
+	"github.com/justfairdev/ipchecker/internal/tracing"
 	pb "github.com/justfairdev/ipchecker/proto"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
+// ProtectedServices are the gRPC services whose calls are authenticated when authentication is enabled. Their
+// methods missing from MethodScopes are denied to every caller.
+var ProtectedServices = []string{
+	pb.IPChecker_ServiceDesc.ServiceName,
+	authv3.Authorization_ServiceDesc.ServiceName,
+}
+
+// MethodScopes is the API key scope required by each method of the IPChecker and Envoy external authorization
+// services, keyed by full method name.
+var MethodScopes = map[string]auth.Scope{
+	pb.IPChecker_CheckIP_FullMethodName:       auth.ScopeCheck,
+	pb.IPChecker_CheckIPBatch_FullMethodName:  auth.ScopeCheck,
+	pb.IPChecker_CheckIPStream_FullMethodName: auth.ScopeCheck,
+	pb.IPChecker_Lookup_FullMethodName:        auth.ScopeLookup,
+	pb.IPChecker_CheckCaller_FullMethodName:   auth.ScopeCheck,
+	authv3.Authorization_Check_FullMethodName: auth.ScopeCheck,
+}
+
 // NewGRPCServer constructs, configures, and returns a new gRPC server instance.
 //
 // This setup includes the following configurations:
 //   - Structured logging using the configured Zap logger.
+//   - Unary and stream interceptors making the verified TLS client identity (identity.FromContext) available to
+//     handlers and the request log.
+//   - Unary and stream interceptors making the client address (clientip.FromContext) available to handlers, taken
+//     from the forwarding metadata of the configured proxies only.
+//   - Unary and stream interceptors authenticating IPChecker and ext_authz calls, if an authenticator is given, and
+//     requiring the scope of each method (see ProtectedServices and MethodScopes); the health-checking and
+//     reflection services stay open.
 //   - Unary interceptor middleware for detailed logging of RPC requests and responses.
+//   - Stream interceptor middleware logging the lifecycle of streaming RPCs such as CheckIPStream.
+//   - Unary and stream interceptors recording RPC count and latency metrics.
+//   - OpenTelemetry tracing of every RPC, continuing traces started by callers (W3C trace-context).
 //   - Reflection service registration to support clients such as grpcurl and grpc_cli.
 //   - Registration of the IPChecker service implementation for handling IP-check requests.
+//   - Registration of the Envoy external authorization service (envoy.service.auth.v3.Authorization), letting
+//     Envoy allow or deny HTTP requests by the country of their client.
+//   - Registration of the standard health-checking service (grpc.health.v1.Health), reporting readiness.
 //
 // Parameters:
-//   - geoService: a GeoLookupService implementation used by the IPChecker server to perform geographic lookups.
+//   - ipChecker: the shared checker.Checker used by the IPChecker server to make allow/deny decisions.
+//   - m: the metrics RPCs are recorded in.
+//   - tracerProvider: the provider of the RPC spans.
+//   - h: the health state reported by the health-checking service.
+//   - authn: the authenticator of the API keys and bearer tokens accepted by the IPChecker and ext_authz services;
+//     nil disables authentication.
+//   - limiter: the limits of the calls and stream messages each client may send to the IPChecker service; nil
+//     disables rate limiting.
+//   - extAuthz: how the Envoy external authorization service finds the client address and policy of a request.
+//   - clientIP: the proxies whose client IP metadata is trusted, and the metadata keys read, for CheckCaller.
+//   - opts: additional server options, such as grpc.Creds to serve TLS.
 //
 // Returns:
 //   - *grpc.Server: A fully configured gRPC server instance.
-//   - error: An initialization error, if logger or server setup fails.
-func NewGRPCServer(geoService *geo.GeoLookupService) (*grpc.Server, error) {
+//   - error: An initialization error, if logger or server setup fails, or an invalid trusted proxy.
+func NewGRPCServer(ipChecker *checker.Checker, m *metrics.Metrics, tracerProvider trace.TracerProvider, h *health.Health, authn *auth.Authenticator, limiter *ratelimit.Limiter, extAuthz grpcserver.ExtAuthzOptions, clientIP ClientIPOptions, opts ...grpc.ServerOption) (*grpc.Server, error) {
 	// Initialize application logger with structured JSON output.
 	log, err := logger.NewLogger()
 	if err != nil {
 		return nil, err
 	}
+	resolver, err := newClientIPResolver(clientIP)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create gRPC server with logging interceptor middleware for comprehensive request tracing.
-	grpcSrv := grpc.NewServer(
-		grpc.UnaryInterceptor(middleware.UnaryLoggingInterceptor(log)),
-	)
+	// Authenticate after logging and metrics, so that rejected calls are logged and counted too.
+	unary := []grpc.UnaryServerInterceptor{
+		middleware.UnaryClientIdentityInterceptor(),
+		middleware.UnaryClientIPInterceptor(resolver),
+		middleware.UnaryLoggingInterceptor(log),
+		middleware.UnaryMetricsInterceptor(m),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		middleware.StreamClientIdentityInterceptor(),
+		middleware.StreamClientIPInterceptor(resolver),
+		middleware.StreamLoggingInterceptor(log),
+		middleware.StreamMetricsInterceptor(m),
+	}
+	if authn != nil {
+		unary = append(unary, middleware.UnaryAuthInterceptor(authn, ProtectedServices, MethodScopes))
+		stream = append(stream, middleware.StreamAuthInterceptor(authn, ProtectedServices, MethodScopes))
+	}
+	// Rate limit after authentication, so that callers are limited by their API key rather than their address.
+	if limiter != nil {
+		unary = append(unary, middleware.UnaryRateLimitInterceptor(limiter, pb.IPChecker_ServiceDesc.ServiceName, m, log))
+		stream = append(stream, middleware.StreamRateLimitInterceptor(limiter, pb.IPChecker_ServiceDesc.ServiceName, m, log))
+	}
+
+	// Create gRPC server with logging and metrics interceptor middleware for comprehensive request tracing.
+	grpcSrv := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler(
+			otelgrpc.WithTracerProvider(tracerProvider),
+			otelgrpc.WithPropagators(tracing.Propagator()),
+		)),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}, opts...)...)
 
 	// Enable gRPC reflection to facilitate service discovery by reflection-enabled clients.
 	reflection.Register(grpcSrv)
 
 	// Instantiate and register the IPChecker service handler implementation.
-	ipCheckerService := grpcserver.NewIPCheckerServer(geoService)
+	ipCheckerService := grpcserver.NewIPCheckerServer(ipChecker)
 	pb.RegisterIPCheckerServer(grpcSrv, ipCheckerService)
 
+	// Register the Envoy external authorization service, deciding on the same policies.
+	authv3.RegisterAuthorizationServer(grpcSrv, grpcserver.NewExtAuthzServer(ipChecker, extAuthz))
+
+	// Register the standard health-checking service for gRPC load balancers and probes.
+	healthpb.RegisterHealthServer(grpcSrv, grpcserver.NewHealthServer(h))
+
 	return grpcSrv, nil
 }
@@ -0,0 +1,124 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/internal/grpcserver"
+	"github.com/justfairdev/ipchecker/internal/health"
+	"github.com/justfairdev/ipchecker/internal/metrics"
+	"github.com/justfairdev/ipchecker/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newHealthUnderTest returns a health state whose single readiness check fails while the returned flag is set.
+func newHealthUnderTest() (*health.Health, *atomic.Bool) {
+	var reloading atomic.Bool
+	h := health.NewHealth()
+	h.AddReadinessCheck("geo", func() error {
+		if reloading.Load() {
+			return geo.ErrReloading
+		}
+		return nil
+	})
+	return h, &reloading
+}
+
+// TestNewHTTPServer_HealthProbes verifies that /healthz always reports the process as alive, while /readyz follows
+// the readiness checks and reports not-ready once shutdown has started.
+func TestNewHTTPServer_HealthProbes(t *testing.T) {
+	h, reloading := newHealthUnderTest()
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	engine, err := server.NewHTTPServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), h, nil, nil, server.ClientIPOptions{})
+	require.NoError(t, err)
+
+	probe := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		engine.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+
+	resp := probe("/readyz")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, resp.Body.String())
+
+	reloading.Store(true)
+	resp = probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.JSONEq(t, `{"status": "unavailable", "checks": {"geo": "database is being reloaded"}}`, resp.Body.String())
+
+	reloading.Store(false)
+	h.SetShuttingDown()
+	resp = probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.JSONEq(t, `{"status": "unavailable", "checks": {"shutdown": "server is shutting down"}}`, resp.Body.String())
+
+	resp = probe("/healthz")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, resp.Body.String())
+}
+
+// TestNewGRPCServer_HealthService verifies that the standard gRPC health service reports readiness for the server
+// and the IPChecker service, rejects unknown services, and ends watches with NOT_SERVING on shutdown.
+func TestNewGRPCServer_HealthService(t *testing.T) {
+	h, reloading := newHealthUnderTest()
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	grpcSrv, err := server.NewGRPCServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), h, nil, nil, grpcserver.ExtAuthzOptions{}, server.ClientIPOptions{})
+	require.NoError(t, err)
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcSrv.Serve(listener)
+	defer grpcSrv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	ctx := context.Background()
+
+	for _, service := range []string{"", "ipchecker.v1.IPChecker"} {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus(), service)
+	}
+
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown.Service"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	reloading.Store(true)
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+
+	// A watch starts with the current status and ends with NOT_SERVING once shutdown starts.
+	reloading.Store(false)
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	update, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, update.GetStatus())
+
+	h.SetShuttingDown()
+	update, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, update.GetStatus())
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF, "Expected the watch to end on shutdown.")
+}
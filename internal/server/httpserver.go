@@ -2,10 +2,18 @@ package server
 
 import (
 	"github.com/gin-gonic/gin"
-	"github.com/justfairdev/ipchecker/internal/geo"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/clientip"
+	"github.com/justfairdev/ipchecker/internal/auth"
 	"github.com/justfairdev/ipchecker/internal/handler"
+	"github.com/justfairdev/ipchecker/internal/health"
 	"github.com/justfairdev/ipchecker/internal/logger"
+	"github.com/justfairdev/ipchecker/internal/metrics"
 	"github.com/justfairdev/ipchecker/internal/middleware"
+	"github.com/justfairdev/ipchecker/internal/ratelimit"
+	"github.com/justfairdev/ipchecker/internal/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/trace"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -13,21 +21,64 @@ import (
 	_ "github.com/justfairdev/ipchecker/docs" // Required for Swagger documentation initialization
 )
 
+// ClientIPOptions configures how the HTTP and gRPC servers find the IP address of the client behind reverse proxies.
+type ClientIPOptions struct {
+	// TrustedProxies lists the IP addresses or CIDR ranges of the proxies whose client IP headers are trusted;
+	// empty trusts none, so the peer address of the connection is always the client.
+	TrustedProxies []string
+
+	// Headers lists the HTTP headers or gRPC metadata keys carrying the client IP, read in order when the peer is
+	// a trusted proxy; empty defaults to X-Forwarded-For, then X-Real-IP.
+	Headers []string
+}
+
+// defaultClientIPHeaders are the headers read when ClientIPOptions names none, as in Gin.
+var defaultClientIPHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+
+// newClientIPResolver returns the resolver of client addresses configured by opts.
+//
+// Parameters:
+//   - opts: The trusted proxies and the headers or metadata keys read.
+//
+// Returns:
+//   - *clientip.Resolver: The resolver.
+//   - error: An error if a trusted proxy is neither an IP address nor a CIDR range.
+func newClientIPResolver(opts ClientIPOptions) (*clientip.Resolver, error) {
+	headers := opts.Headers
+	if len(headers) == 0 {
+		headers = defaultClientIPHeaders
+	}
+	return clientip.NewResolver(opts.TrustedProxies, headers)
+}
+
 // NewHTTPServer initializes and configures a new Gin HTTP server instance with custom middlewares, route handlers,
 // and Swagger documentation support.
 //
 // The HTTP server is configured with:
 //
+// - OpenTelemetry tracing of every request, continuing traces started by callers (W3C trace-context).
+// - The verified TLS client identity (identity.FromContext) made available to handlers and the request log.
 // - Structured logging and panic recovery middleware based on the Zap logging framework.
-// - A handler (`IPChecker`) for checking IP addresses against allowable country codes, leveraging the provided geographical lookup service.
+// - Request count and latency metrics, exposed with all other metrics at '/metrics' in the Prometheus format.
+// - API key authentication of the versioned API, if keys are given; probes, metrics and Swagger stay open.
+// - Client IP resolution (clientip.FromContext) trusting the configured proxies only.
+// - Liveness and readiness probes at '/healthz' and '/readyz' for Kubernetes and load balancers.
+// - A handler (`IPChecker`) for checking IP addresses against allowable country codes, leveraging the shared decision core.
 // - Automated Swagger API documentation accessible at the '/swagger' endpoint for interactive exploration.
 //
 // Parameters:
-//   - geoService: A geographical lookup implementation that the IPChecker handler utilizes for IP geolocation functionality.
+//   - ipChecker: The shared checker.Checker that the IPChecker handler uses to make allow/deny decisions.
+//   - m: The metrics requests are recorded in and served from.
+//   - tracerProvider: The provider of the request spans.
+//   - h: The health state reported by the probes.
+//   - authn: The authenticator of the API keys and bearer tokens accepted by the versioned API; nil disables
+//     authentication.
+//   - limiter: The limits of the requests each client may send to the versioned API; nil disables rate limiting.
+//   - clientIP: The proxies whose client IP headers are trusted, and the headers read.
 //
 // Returns:
 //   - *gin.Engine:  Fully initialized Gin engine configured with routes, middleware, and Swagger documentation.
-//   - error: Error indicating issue during logger initialization.
+//   - error: Error indicating issue during logger initialization, or an invalid trusted proxy.
 //
 // Usage:
 //
@@ -47,7 +98,7 @@ import (
 //
 //	grpcurl -plaintext -d '{"ip_address":"128.101.101.101","allowed_countries":["US","CA"]}' \
 //	  localhost:50051 ipchecker.v1.IPChecker/CheckIP
-func NewHTTPServer(geoService *geo.GeoLookupService) (*gin.Engine, error) {
+func NewHTTPServer(ipChecker *checker.Checker, m *metrics.Metrics, tracerProvider trace.TracerProvider, h *health.Health, authn *auth.Authenticator, limiter *ratelimit.Limiter, clientIP ClientIPOptions) (*gin.Engine, error) {
 	// Initialize structured Zap logger for consistent and reliable request tracing
 	log, err := logger.NewLogger()
 	if err != nil {
@@ -57,17 +108,45 @@ func NewHTTPServer(geoService *geo.GeoLookupService) (*gin.Engine, error) {
 	// Instantiate Gin router without default middlewares for more control
 	r := gin.New()
 
-	// Attach customized middleware for structured logging and panic recovery
+	// Trust the client IP headers of the configured proxies only. Handlers read the client address resolved by
+	// middleware.GinClientIP rather than gin.Context.ClientIP, so that every route agrees on it; Gin's own header
+	// parsing, which trusts every peer by default, is turned off.
+	r.ForwardedByClientIP = false
+	resolver, err := newClientIPResolver(clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attach customized middleware for tracing, structured logging, metrics and panic recovery
 	r.Use(
+		otelgin.Middleware(tracing.ServiceName,
+			otelgin.WithTracerProvider(tracerProvider),
+			otelgin.WithPropagators(tracing.Propagator()),
+		),
+		middleware.GinClientIdentity(),
+		middleware.GinClientIP(resolver),
 		middleware.GinLogger(log),
+		middleware.GinMetrics(m),
 		middleware.GinRecovery(log),
 	)
 
-	// Initialize the IPChecker route handler with the geo lookup service dependency
-	ipChecker := handler.NewIPChecker(geoService)
+	// Initialize the IPChecker route handler with the shared decision core
+	ipCheckerHandler := handler.NewIPChecker(ipChecker)
+
+	// Register IPChecker routes to the Gin server, rate limited if configured
+	var rateLimit gin.HandlerFunc
+	if limiter != nil {
+		rateLimit = middleware.GinRateLimit(limiter, m, log)
+	}
+	RegisterRoutes(r, ipCheckerHandler, authn, rateLimit)
+
+	// Expose liveness and readiness probes outside the versioned API
+	healthHandler := handler.NewHealth(h)
+	r.GET("/healthz", healthHandler.Liveness)
+	r.GET("/readyz", healthHandler.Readiness)
 
-	// Register IPChecker routes to the Gin server
-	RegisterRoutes(r, ipChecker)
+	// Expose Prometheus metrics for scraping
+	r.GET("/metrics", gin.WrapH(m.Handler()))
 
 	// Optionally enable Swagger UI at '/swagger' for convenient API testing and documentation viewing
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -0,0 +1,211 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/internal/auth"
+	"github.com/justfairdev/ipchecker/internal/grpcserver"
+	"github.com/justfairdev/ipchecker/internal/health"
+	"github.com/justfairdev/ipchecker/internal/metrics"
+	"github.com/justfairdev/ipchecker/internal/middleware"
+	"github.com/justfairdev/ipchecker/internal/ratelimit"
+	"github.com/justfairdev/ipchecker/internal/server"
+	pb "github.com/justfairdev/ipchecker/proto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestLimiter returns a Limiter allowing the API key "checker-secret" bursts of 2 requests, refilled far too
+// slowly to matter during a test, and every other client bursts of 1.
+func newTestLimiter(t *testing.T) *ratelimit.Limiter {
+	path := filepath.Join(t.TempDir(), "limits.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+default:
+  rate: 0.001
+  burst: 1
+api_keys:
+  checker:
+    rate: 0.001
+    burst: 2
+`), 0o600))
+	limits, err := ratelimit.LoadLimitFile(path)
+	require.NoError(t, err)
+	return ratelimit.NewLimiter(limits, nil)
+}
+
+// TestNewHTTPServer_RateLimit verifies that the versioned API rejects requests over the limit of their API key
+// with 429 and Retry-After, counts the rejections, and leaves probes unlimited.
+func TestNewHTTPServer_RateLimit(t *testing.T) {
+	c, authn, _ := newAuthenticatedChecker(t)
+	m := metrics.NewMetrics()
+	engine, err := server.NewHTTPServer(c, m, noop.NewTracerProvider(), health.NewHealth(), authn, newTestLimiter(t), server.ClientIPOptions{})
+	require.NoError(t, err)
+
+	send := func(method, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"ip_address": "8.8.8.8", "allowed_countries": ["US"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(auth.APIKeyHeader, key)
+		resp := httptest.NewRecorder()
+		engine.ServeHTTP(resp, req)
+		return resp
+	}
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/api/v1/ip-check", "checker-secret").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/api/v1/ip-check", "checker-secret").Code)
+	resp := send(http.MethodPost, "/api/v1/ip-check", "checker-secret")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.JSONEq(t, `{"error": "rate limit exceeded"}`, resp.Body.String())
+	assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/api/v1/lookup/8.8.8.8", "lookup-secret").Code,
+		"Expected other API keys to have their own bucket.")
+	assert.Equal(t, http.StatusTooManyRequests, send(http.MethodGet, "/api/v1/lookup/8.8.8.8", "lookup-secret").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/healthz", "").Code)
+
+	expected := `
+# HELP ipchecker_rate_limited_total Requests rejected by rate limiting, by transport, client kind (api_key, identity or ip) and reason (rate or quota).
+# TYPE ipchecker_rate_limited_total counter
+ipchecker_rate_limited_total{client_kind="api_key",reason="rate",transport="http"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "ipchecker_rate_limited_total"))
+}
+
+// TestNewGRPCServer_RateLimit verifies that IPChecker calls and stream messages over the limit of their API key
+// fail with RESOURCE_EXHAUSTED and a retry-after trailer.
+func TestNewGRPCServer_RateLimit(t *testing.T) {
+	c, authn, _ := newAuthenticatedChecker(t)
+	m := metrics.NewMetrics()
+	grpcSrv, err := server.NewGRPCServer(c, m, noop.NewTracerProvider(), health.NewHealth(), authn, newTestLimiter(t), grpcserver.ExtAuthzOptions{}, server.ClientIPOptions{})
+	require.NoError(t, err)
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcSrv.Serve(listener)
+	defer grpcSrv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewIPCheckerClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "checker-secret")
+	req := &pb.IPCheckRequest{IpAddress: "8.8.8.8", AllowedCountries: []string{"US"}}
+
+	_, err = client.CheckIP(ctx, req)
+	require.NoError(t, err)
+
+	// The stream may send one message before the bucket is empty.
+	stream, err := client.CheckIPStream(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.IPStreamCheckRequest{Id: "1", IpAddress: "8.8.8.8", AllowedCountries: []string{"US"}}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "1", resp.GetId())
+	require.NoError(t, stream.Send(&pb.IPStreamCheckRequest{Id: "2", IpAddress: "8.8.8.8", AllowedCountries: []string{"US"}}))
+	_, err = stream.Recv()
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, stream.Trailer().Get(middleware.RetryAfterMetadata))
+	_, err = stream.Recv()
+	assert.NotEqual(t, io.EOF, err)
+
+	var trailer metadata.MD
+	_, err = client.CheckIP(ctx, req, grpc.Trailer(&trailer))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "rate limit exceeded")
+	assert.NotEmpty(t, trailer.Get(middleware.RetryAfterMetadata))
+
+	expected := `
+# HELP ipchecker_rate_limited_total Requests rejected by rate limiting, by transport, client kind (api_key, identity or ip) and reason (rate or quota).
+# TYPE ipchecker_rate_limited_total counter
+ipchecker_rate_limited_total{client_kind="api_key",reason="rate",transport="grpc"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected), "ipchecker_rate_limited_total"))
+}
+
+// TestNewGRPCServer_RateLimitBehindProxy verifies that anonymous clients behind a trusted proxy are counted by the
+// address the proxy forwarded, each with its own bucket, rather than all together as the proxy.
+func TestNewGRPCServer_RateLimitBehindProxy(t *testing.T) {
+	c, _, _ := newAuthenticatedChecker(t)
+	grpcSrv, err := server.NewGRPCServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), nil, newTestLimiter(t),
+		grpcserver.ExtAuthzOptions{}, server.ClientIPOptions{TrustedProxies: []string{"127.0.0.0/8"}})
+	require.NoError(t, err)
+
+	// A real listener, so that the calls have a peer IP address the proxy metadata is trusted from.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go grpcSrv.Serve(listener)
+	defer grpcSrv.Stop()
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewIPCheckerClient(conn)
+
+	checkFrom := func(clientIP string) error {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-forwarded-for", clientIP)
+		_, err := client.CheckIP(ctx, &pb.IPCheckRequest{IpAddress: "8.8.8.8", AllowedCountries: []string{"US"}})
+		return err
+	}
+	require.NoError(t, checkFrom("198.51.100.7"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(checkFrom("198.51.100.7")))
+	assert.NoError(t, checkFrom("203.0.113.9"), "Expected another client behind the proxy to have its own bucket.")
+}
+
+// TestNewServers_RateLimitBatch verifies that batches count once per IP address, so that a batch larger than the
+// burst of its API key is rejected over HTTP and gRPC, while one that fits uses up the bucket.
+func TestNewServers_RateLimitBatch(t *testing.T) {
+	c, authn, _ := newAuthenticatedChecker(t)
+	engine, err := server.NewHTTPServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), authn, newTestLimiter(t), server.ClientIPOptions{})
+	require.NoError(t, err)
+
+	send := func(ips string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/ip-check/batch", strings.NewReader(`{"ip_addresses": [`+ips+`], "allowed_countries": ["US"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(auth.APIKeyHeader, "checker-secret")
+		resp := httptest.NewRecorder()
+		engine.ServeHTTP(resp, req)
+		return resp
+	}
+	assert.Equal(t, http.StatusTooManyRequests, send(`"8.8.8.8", "8.8.4.4", "1.1.1.1"`).Code)
+	resp := send(`"8.8.8.8", "8.8.4.4"`)
+	assert.Equal(t, http.StatusOK, resp.Code, "Expected the handler to read the batch after the limiter.")
+	assert.Contains(t, resp.Body.String(), `"ip_address":"8.8.4.4"`)
+	assert.Equal(t, http.StatusTooManyRequests, send(`"8.8.8.8"`).Code)
+
+	grpcSrv, err := server.NewGRPCServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), authn, newTestLimiter(t), grpcserver.ExtAuthzOptions{}, server.ClientIPOptions{})
+	require.NoError(t, err)
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcSrv.Serve(listener)
+	defer grpcSrv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewIPCheckerClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "checker-secret")
+
+	_, err = client.CheckIPBatch(ctx, &pb.IPBatchCheckRequest{IpAddresses: []string{"8.8.8.8", "8.8.4.4", "1.1.1.1"}, AllowedCountries: []string{"US"}})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = client.CheckIPBatch(ctx, &pb.IPBatchCheckRequest{IpAddresses: []string{"8.8.8.8", "8.8.4.4"}, AllowedCountries: []string{"US"}})
+	require.NoError(t, err)
+	_, err = client.CheckIP(ctx, &pb.IPCheckRequest{IpAddress: "8.8.8.8", AllowedCountries: []string{"US"}})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
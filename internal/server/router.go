@@ -2,7 +2,9 @@ package server
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/internal/auth"
 	"github.com/justfairdev/ipchecker/internal/handler"
+	"github.com/justfairdev/ipchecker/internal/middleware"
 )
 
 // RegisterRoutes sets up and attaches HTTP endpoints (routes) to the provided Gin engine.
@@ -13,9 +15,19 @@ import (
 // Parameters:
 //   - r: The Gin HTTP engine instance to which the routes will be attached.
 //   - ipChecker: An instance of the IPChecker handler responsible for handling IP-checking requests.
+//   - authn: The authenticator of the API keys and bearer tokens accepted by the routes; nil disables
+//     authentication.
+//   - rateLimit: The middleware limiting the requests of each client, run after authentication; nil disables
+//     rate limiting.
 //
-// Current endpoints registered:
-//   - POST /api/v1/ip-check : Verifies whether an IP address is within a list of allowed country codes.
+// Current endpoints registered, with the scope they require when authentication is enabled:
+//   - POST /api/v1/ip-check (check) : Verifies whether an IP address is within a list of allowed country codes.
+//   - POST /api/v1/ip-check/batch (check) : Verifies a list of IP addresses against one shared list of allowed country codes.
+//   - POST /api/v1/ip-check/caller (check) : Verifies the IP address the request itself was sent from.
+//   - GET  /api/v1/lookup/{ip} (lookup) : Returns the full geolocation data of an IP address.
+//   - GET  /api/v1/auth (check) : Answers 204 or 403 for the client of a request proxied by NGINX or Traefik.
+//
+// The /metrics and /swagger endpoints are registered by NewHTTPServer, outside the versioned API.
 //
 // Example JSON request payload:
 //
@@ -26,12 +38,36 @@ import (
 //
 // Future endpoints can be efficiently added within this function following the existing structure,
 // ensuring ease of management and readability.
-func RegisterRoutes(r *gin.Engine, ipChecker *handler.IPChecker) {
+func RegisterRoutes(r *gin.Engine, ipChecker *handler.IPChecker, authn *auth.Authenticator, rateLimit gin.HandlerFunc) {
 	// Group routes under API Version 1 prefix for version control and structured endpoint management.
 	v1 := r.Group("/api/v1")
 
+	// Authenticate every versioned route by API key or bearer token, if enabled, and require the scope of each route.
+	if authn != nil {
+		v1.Use(middleware.GinAuthenticate(authn))
+	}
+
+	// Limit the requests of each client, identified by the authenticated caller if any.
+	if rateLimit != nil {
+		v1.Use(rateLimit)
+	}
+	route := func(scope auth.Scope, handler gin.HandlerFunc) []gin.HandlerFunc {
+		if authn == nil {
+			return []gin.HandlerFunc{handler}
+		}
+		return []gin.HandlerFunc{middleware.GinRequireScope(scope), handler}
+	}
+
 	// IP address checking route.
-	v1.POST("/ip-check", ipChecker.CheckIP)
+	v1.POST("/ip-check", route(auth.ScopeCheck, ipChecker.CheckIP)...)
+	v1.POST("/ip-check/batch", route(auth.ScopeCheck, ipChecker.CheckIPBatch)...)
+	v1.POST("/ip-check/caller", route(auth.ScopeCheck, ipChecker.CheckCaller)...)
+
+	// Geolocation lookup route.
+	v1.GET("/lookup/:ip", route(auth.ScopeLookup, ipChecker.Lookup)...)
+
+	// Forward-auth route for the subrequests of reverse proxies (NGINX auth_request, Traefik forwardAuth).
+	v1.GET("/auth", route(auth.ScopeCheck, ipChecker.ForwardAuth)...)
 
 	// Additional API routes may be defined here as needed.
 	// Example:
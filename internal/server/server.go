@@ -1,14 +1,31 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/internal/auth"
 	"github.com/justfairdev/ipchecker/internal/config"
-	"github.com/justfairdev/ipchecker/internal/geo"
+	"github.com/justfairdev/ipchecker/internal/grpcserver"
+	"github.com/justfairdev/ipchecker/internal/health"
+	"github.com/justfairdev/ipchecker/internal/logger"
+	"github.com/justfairdev/ipchecker/internal/metrics"
+	"github.com/justfairdev/ipchecker/internal/ratelimit"
+	"github.com/justfairdev/ipchecker/internal/tlsconfig"
+	"github.com/justfairdev/ipchecker/internal/tracing"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // AppServer encapsulates both HTTP and gRPC server instances along with the shared GeoLookupService dependency.
@@ -16,17 +33,50 @@ import (
 // This struct allows simultaneous management of the HTTP REST endpoints (via Gin) and gRPC endpoints, providing a unified
 // approach to serving multiple types of clients with shared underlying services.
 type AppServer struct {
-	HTTPServer *gin.Engine           // Instance of the Gin-powered HTTP server
-	GRPCServer *grpc.Server          // Instance of the gRPC server
-	geoService *geo.GeoLookupService // Shared GeoLookup service instance used by both servers
+	HTTPServer *gin.Engine              // Instance of the Gin-powered HTTP server
+	GRPCServer *grpc.Server             // Instance of the gRPC server
+	geoService *geo.GeoLookupService    // Shared GeoLookup service instance used by both servers
+	geoCache   *geo.CachedLookupService // Lookup cache in front of geoService; nil if caching is disabled
+	tracer     *tracing.Provider        // Tracer provider of both servers and the decision core
+	health     *health.Health           // Readiness reported by the HTTP probes and the gRPC health service
+	httpTLS    *tlsconfig.Reloader      // Certificates of the HTTP listener; nil if it serves plaintext
+	grpcTLS    *tlsconfig.Reloader      // Certificates of the gRPC listener; nil if it serves plaintext
+	tokens     *auth.TokenVerifier      // Verifier of bearer tokens; nil if bearer tokens are not accepted
+	limiter    *ratelimit.Limiter       // Per-client rate limits and daily quotas; nil if rate limiting is disabled
+
+	httpSrv        *http.Server // Serves HTTPServer on the configured address with the configured timeouts
+	grpcAddr       string       // Address the gRPC server listens on
+	httpListenAddr net.Addr     // Address the HTTP listener is bound to, set by Start
+	grpcListenAddr net.Addr     // Address the gRPC listener is bound to, set by Start
+	serveErr       chan error   // Errors of servers that stopped serving unexpectedly, see Err
+
+	reloadInterval    time.Duration      // How often the GeoIP database file is checked for changes
+	tlsReloadInterval time.Duration      // How often the TLS certificate files are checked for changes
+	jwksRefresh       time.Duration      // How often the JSON Web Key Set of bearer tokens is reloaded
+	quotaFlush        time.Duration      // How often the daily quota counters are persisted
+	drainDelay        time.Duration      // How long readiness fails before the servers stop accepting connections
+	shutdownTimeout   time.Duration      // Deadline for in-flight requests and RPCs to complete on Stop
+	log               *zap.Logger        // Logger used by the database and certificate watchers
+	stopWatch         context.CancelFunc // Stops the watchers started by Start
 }
 
 // NewAppServer initializes an AppServer instance configured for both HTTP and gRPC servers.
 //
 // The initialization process involves:
 //   - Creating a single shared GeoLookupService instance with the specified MaxMind database.
+//   - Wrapping it in an LRU lookup cache, unless caching is disabled.
+//   - Loading the named server-side policies and CIDR overrides, if their files are configured.
+//   - Loading the hashed API keys callers authenticate with, if configured.
+//   - Loading the JSON Web Key Set bearer tokens are verified against, if configured.
+//   - Loading the per-client rate limits and the persisted daily quota counters, if configured.
+//   - Creating the shared decision core (checker.Checker) used by both transports.
+//   - Creating the Prometheus metrics recorded by the decision core and both transports.
+//   - Setting up the OpenTelemetry tracer provider for the configured exporter.
+//   - Tracking readiness, which depends on the MaxMind databases being present, valid and not mid-reload.
+//   - Loading the TLS certificates of each listener that is configured to serve TLS or mutual TLS.
 //   - Constructing and configuring the Gin HTTP server with routes, middleware, and handlers.
 //   - Constructing and configuring the gRPC server instance with appropriate service handlers.
+//   - Preparing the watcher that hot-reloads the MaxMind database when it changes on disk or on SIGHUP.
 //
 // Parameters:
 //   - cfg: A configuration struct containing critical parameters (e.g., path to MaxMind Geo database).
@@ -34,77 +84,410 @@ type AppServer struct {
 // Returns:
 //   - *AppServer: A fully initialized AppServer instance ready for operation.
 //   - error: If initialization fails, returns an error describing the issue.
-func NewAppServer(cfg *config.Config) (*AppServer, error) {
+func NewAppServer(cfg *config.Config) (_ *AppServer, err error) {
 	// Initialize shared GeoLookupService dependency
-	geoSvc, err := geo.NewGeoLookupService(cfg.MaxMindDBPath)
+	geoSvc, err := geo.NewGeoLookupService(cfg.GeoIP.DatabasePath, geo.WithASNDatabase(cfg.GeoIP.ASNDatabasePath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize GeoLookupService: %w", err)
 	}
 
+	// If a later step fails, release what was set up so far: the database files, the tracer exporter and the
+	// quota counters
+	var limiter *ratelimit.Limiter
+	var tracerProvider *tracing.Provider
+	defer func() {
+		if err == nil {
+			return
+		}
+		if tracerProvider != nil {
+			tracerProvider.Shutdown(context.Background())
+		}
+		if limiter != nil {
+			limiter.Close()
+		}
+		geoSvc.Close()
+	}()
+
+	// Cache lookups of frequently seen IPs; the cache is invalidated whenever a database is reloaded
+	var lookupSvc geo.LookupService = geoSvc
+	var geoCache *geo.CachedLookupService
+	if cfg.Cache.Size > 0 {
+		geoCache, err = geo.NewCachedLookupService(geoSvc, geo.CacheOptions{Size: cfg.Cache.Size, TTL: cfg.Cache.TTL})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize lookup cache: %w", err)
+		}
+		lookupSvc = geoCache
+	}
+
+	// Load named server-side policies, if configured
+	var policies *checker.PolicySet
+	if cfg.Policies.FilePath != "" {
+		policies, err = checker.LoadPolicyFile(cfg.Policies.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load policies: %w", err)
+		}
+	}
+
+	// Load CIDR allow/deny overrides, if configured
+	var overrides *checker.Overrides
+	if cfg.Policies.OverrideFilePath != "" {
+		overrides, err = checker.LoadOverrideFile(cfg.Policies.OverrideFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load overrides: %w", err)
+		}
+	}
+
+	// Load the API keys callers authenticate with, if configured; every policy bound to a key must exist
+	var keys *auth.KeyStore
+	if cfg.Auth.APIKeysFile != "" {
+		keys, err = auth.LoadKeyFile(cfg.Auth.APIKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load API keys: %w", err)
+		}
+		for _, name := range keys.Policies() {
+			if _, ok := policies.Get(name); !ok {
+				return nil, fmt.Errorf("failed to load API keys: a key is bound to policy %q, which is not in the policy file", name)
+			}
+		}
+	}
+
+	// Load the keys bearer tokens are signed with, if configured; the policies a token names are checked per request
+	var tokens *auth.TokenVerifier
+	if cfg.Auth.JWT.Enabled() {
+		tokens, err = auth.NewTokenVerifier(auth.TokenOptions{
+			JWKSFile:      cfg.Auth.JWT.JWKSFile,
+			JWKSURL:       cfg.Auth.JWT.JWKSURL,
+			Issuer:        cfg.Auth.JWT.Issuer,
+			Audience:      cfg.Auth.JWT.Audience,
+			ScopesClaim:   cfg.Auth.JWT.ScopesClaim,
+			PoliciesClaim: cfg.Auth.JWT.PoliciesClaim,
+			Leeway:        cfg.Auth.JWT.Leeway,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize bearer-token authentication: %w", err)
+		}
+	}
+	authn := auth.NewAuthenticator(keys, tokens)
+
+	// Load the per-client rate limits, if configured, along with the daily quota counters of previous runs
+	var unpersistedQuotas bool
+	if cfg.RateLimit.FilePath != "" {
+		limits, err := ratelimit.LoadLimitFile(cfg.RateLimit.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rate limits: %w", err)
+		}
+		var quotas *ratelimit.QuotaStore
+		if cfg.RateLimit.QuotaStore != "" {
+			if quotas, err = ratelimit.OpenQuotaStore(cfg.RateLimit.QuotaStore); err != nil {
+				return nil, fmt.Errorf("failed to load rate limits: %w", err)
+			}
+		}
+		limiter = ratelimit.NewLimiter(limits, quotas)
+		unpersistedQuotas = limits.HasQuotas() && quotas == nil
+	}
+
+	// Initialize the decision core shared by both transports
+	ipChecker := checker.NewChecker(lookupSvc, policies, overrides)
+	ipChecker.SetPolicyRestriction(auth.PoliciesFromContext)
+
+	// Record decisions, lookup errors, database and cache state as Prometheus metrics
+	m := metrics.NewMetrics()
+	ipChecker.SetObserver(m)
+	m.WatchDatabases(geoSvc)
+	if geoCache != nil {
+		m.WatchLookupCache(geoCache)
+	}
+
+	// Trace both transports and the geolocation lookups, exporting spans as configured
+	tracerProvider, err = tracing.Setup(context.Background(), cfg.Tracing.Exporter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	ipChecker.SetTracerProvider(tracerProvider)
+
+	// Report the instance as ready only while the databases can be relied on
+	h := health.NewHealth()
+	h.AddReadinessCheck("geo", geoSvc.Ready)
+
+	// Load the certificates of the listeners configured for TLS; the others serve plaintext
+	var httpTLS, grpcTLS *tlsconfig.Reloader
+	if cfg.HTTP.TLS.Enabled() {
+		if httpTLS, err = tlsconfig.NewReloader(cfg.HTTP.TLS); err != nil {
+			return nil, fmt.Errorf("failed to load HTTP TLS certificates: %w", err)
+		}
+	}
+	grpcOpts := []grpc.ServerOption{grpc.ConnectionTimeout(cfg.GRPC.ConnectionTimeout)}
+	if cfg.GRPC.TLS.Enabled() {
+		if grpcTLS, err = tlsconfig.NewReloader(cfg.GRPC.TLS); err != nil {
+			return nil, fmt.Errorf("failed to load gRPC TLS certificates: %w", err)
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(grpcTLS.TLSConfig("h2"))))
+	}
+
 	// Initialize and configure HTTP server (Gin engine)
-	httpServer, err := NewHTTPServer(geoSvc)
+	httpServer, err := NewHTTPServer(ipChecker, m, tracerProvider, h, authn, limiter, ClientIPOptions{
+		TrustedProxies: cfg.HTTP.TrustedProxies,
+		Headers:        cfg.HTTP.ClientIPHeaders,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize HTTP server: %w", err)
 	}
 
 	// Initialize and configure gRPC server
-	grpcSrv, err := NewGRPCServer(geoSvc)
+	grpcSrv, err := NewGRPCServer(ipChecker, m, tracerProvider, h, authn, limiter, grpcserver.ExtAuthzOptions{
+		TrustedHops:   cfg.ExtAuthz.TrustedHops,
+		DefaultPolicy: cfg.ExtAuthz.DefaultPolicy,
+	}, ClientIPOptions{
+		TrustedProxies: cfg.GRPC.TrustedProxies,
+		Headers:        cfg.GRPC.ClientIPMetadata,
+	}, grpcOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize gRPC server: %w", err)
 	}
 
+	// Initialize logger for the database watcher
+	zapLogger, err := logger.NewLogger()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	// Daily quotas are only enforced across restarts when their counters are persisted
+	if unpersistedQuotas {
+		zapLogger.Warn("Rate limits set daily quotas, but no quota store is configured (ratelimit.quota_store); " +
+			"quota counters restart from zero whenever the service restarts")
+	}
+
+	// Subdivision rules never match without city-level data, so flag the misconfiguration at startup
+	if names := policies.SubdivisionPolicies(); len(names) > 0 && !geoSvc.HasCityData() {
+		zapLogger.Warn("Policies use ISO 3166-2 subdivision rules, but the MaxMind database has no city-level data",
+			zap.Strings("policies", names), zap.String("path", cfg.GeoIP.DatabasePath))
+	}
+	// Likewise, ASN rules never match unless an ASN database is loaded
+	if names := policies.ASNPolicies(); len(names) > 0 && !geoSvc.HasASNData() {
+		zapLogger.Warn("Policies use ASN rules, but no ASN database is configured (geoip.asn_database)",
+			zap.Strings("policies", names))
+	}
+
 	// Return the fully configured AppServer instance
 	return &AppServer{
 		HTTPServer: httpServer,
 		GRPCServer: grpcSrv,
-		geoService: geoSvc,
+		httpSrv: &http.Server{
+			Addr:              cfg.HTTP.ListenAddress(),
+			Handler:           httpServer,
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			ReadTimeout:       cfg.HTTP.ReadTimeout,
+			WriteTimeout:      cfg.HTTP.WriteTimeout,
+			IdleTimeout:       cfg.HTTP.IdleTimeout,
+		},
+		grpcAddr:          cfg.GRPC.ListenAddress(),
+		geoService:        geoSvc,
+		geoCache:          geoCache,
+		tracer:            tracerProvider,
+		health:            h,
+		httpTLS:           httpTLS,
+		grpcTLS:           grpcTLS,
+		tokens:            tokens,
+		limiter:           limiter,
+		reloadInterval:    cfg.GeoIP.ReloadInterval,
+		tlsReloadInterval: cfg.TLSReloadInterval,
+		jwksRefresh:       cfg.Auth.JWT.RefreshInterval,
+		quotaFlush:        cfg.RateLimit.FlushInterval,
+		drainDelay:        cfg.Shutdown.DrainDelay,
+		shutdownTimeout:   cfg.Shutdown.Timeout,
+		serveErr:          make(chan error, 2),
+		log:               zapLogger,
 	}, nil
 }
 
-// Start concurrently launches the HTTP server and the gRPC server, handling requests on their respective ports.
+// Start binds the HTTP and gRPC listeners on their configured addresses and serves both in the background.
 //
 // Execution flow:
-//   - gRPC server startup occurs asynchronously in a separate goroutine.
-//   - HTTP server startup occurs on the main thread and blocks until stopped.
-//
-// Parameters:
-//   - httpPort: TCP port number for HTTP server (e.g., "8080").
-//   - grpcPort: TCP port number for gRPC server (e.g., "50051").
+//   - The GeoIP database watcher starts in a separate goroutine and runs until Stop is called, as do the TLS
+//     certificate watchers of the listeners serving TLS, the JWKS watcher if bearer tokens are accepted, and the
+//     persistence of the daily quota counters if rate limiting is enabled.
+//   - Both listeners are bound before Start returns, so an address already in use is reported to the caller.
+//   - Each server then serves in its own goroutine; if one stops serving other than through Stop, its error is
+//     delivered on Err.
 //
 // Returns:
-//   - error: If the HTTP server fails during runtime initialization, it returns an error.
-//     (gRPC server initialization errors will result in process exit via log.Fatal within the goroutine.)
-func (s *AppServer) Start(httpPort, grpcPort string) error {
-	// Start the gRPC server in its own goroutine concurrently with HTTP server
+//   - error: If either listener cannot be bound; nothing is left running in that case.
+func (s *AppServer) Start() error {
+	grpcListener, err := net.Listen("tcp", s.grpcAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on gRPC address %s: %w", s.grpcAddr, err)
+	}
+	httpListener, err := net.Listen("tcp", s.httpSrv.Addr)
+	if err != nil {
+		grpcListener.Close()
+		return fmt.Errorf("failed to listen on HTTP address %s: %w", s.httpSrv.Addr, err)
+	}
+	s.grpcListenAddr, s.httpListenAddr = grpcListener.Addr(), httpListener.Addr()
+
+	// Hot-reload the GeoIP database in the background so refreshed files are picked up without a restart
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	s.stopWatch = stopWatch
+	go s.geoService.Watch(watchCtx, s.reloadInterval, s.log)
+
+	// Likewise pick up rotated TLS certificates; new connections use them, established ones are unaffected
+	for _, reloader := range []*tlsconfig.Reloader{s.httpTLS, s.grpcTLS} {
+		if reloader != nil {
+			go reloader.Watch(watchCtx, s.tlsReloadInterval, s.log)
+		}
+	}
+
+	// Likewise pick up rotated token signing keys, so that tokens signed with a new key are accepted
+	if s.tokens != nil {
+		go s.tokens.Watch(watchCtx, s.jwksRefresh, s.log)
+	}
+
+	// Persist the daily quota counters periodically, so that a restart does not reset them
+	if s.limiter != nil {
+		go s.limiter.Run(watchCtx, s.quotaFlush, s.log)
+	}
+
+	// Serve gRPC; Serve returns nil once GracefulStop or Stop is called
+	log.Printf("gRPC server is running and listening on %s%s", s.grpcListenAddr, describeTLS(s.grpcTLS))
 	go func() {
-		listener, err := net.Listen("tcp", fmt.Sprintf(":%s", grpcPort))
-		if err != nil {
-			log.Fatalf("Failed to listen on gRPC port %s: %v", grpcPort, err)
+		if err := s.GRPCServer.Serve(grpcListener); err != nil {
+			s.serveErr <- fmt.Errorf("gRPC server: %w", err)
 		}
-		log.Printf("gRPC server is running and listening on port :%s", grpcPort)
+	}()
 
-		if serveErr := s.GRPCServer.Serve(listener); serveErr != nil {
-			log.Fatalf("Failed to serve the gRPC server: %v", serveErr)
+	// Serve HTTP, over TLS if configured; Serve returns http.ErrServerClosed once Shutdown or Close is called
+	if s.httpTLS != nil {
+		httpListener = tls.NewListener(httpListener, s.httpTLS.TLSConfig("h2", "http/1.1"))
+	}
+	log.Printf("HTTP server is running and listening on %s%s", s.httpListenAddr, describeTLS(s.httpTLS))
+	go func() {
+		if err := s.httpSrv.Serve(httpListener); !errors.Is(err, http.ErrServerClosed) {
+			s.serveErr <- fmt.Errorf("HTTP server: %w", err)
 		}
 	}()
+	return nil
+}
+
+// Err returns a channel receiving the error of a server that stopped serving unexpectedly, after Start. The
+// caller is expected to Stop the AppServer when it receives one.
+//
+// Returns:
+//   - <-chan error: The channel; nothing is sent when the servers are stopped through Stop.
+func (s *AppServer) Err() <-chan error {
+	return s.serveErr
+}
+
+// HTTPAddr returns the address the HTTP listener is bound to, once started.
+//
+// Returns:
+//   - net.Addr: The bound address, with the actual port when port 0 was configured; nil before Start.
+func (s *AppServer) HTTPAddr() net.Addr {
+	return s.httpListenAddr
+}
+
+// GRPCAddr returns the address the gRPC listener is bound to, once started.
+//
+// Returns:
+//   - net.Addr: The bound address, with the actual port when port 0 was configured; nil before Start.
+func (s *AppServer) GRPCAddr() net.Addr {
+	return s.grpcListenAddr
+}
 
-	// Start the HTTP server; this call is blocking
-	log.Printf("HTTP server is running and listening on port :%s", httpPort)
-	return s.HTTPServer.Run(":" + httpPort)
+// describeTLS returns the suffix of the startup log line of a listener, describing its TLS settings.
+//
+// Parameters:
+//   - reloader: The certificates of the listener; nil if it serves plaintext.
+//
+// Returns:
+//   - string: The description, e.g. " (TLS, client certificates: require)"; empty for plaintext listeners.
+func describeTLS(reloader *tlsconfig.Reloader) string {
+	if reloader == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (TLS, client certificates: %s)", reloader.ClientAuth())
 }
 
-// Stop performs a graceful shutdown of the gRPC server and closes related services.
+// Stop performs a graceful shutdown of both servers and closes related services.
 //
 // This method ensures:
-//   - Graceful stopping of the gRPC server, allowing ongoing operations to complete.
-//   - Proper closure of the GeoLookupService handle (releasing database resources).
+//   - Both readiness probes report not-ready from the start, and keep doing so for the configured drain delay
+//     while both listeners still accept connections, so load balancers stop routing new traffic.
+//   - The GeoIP database, TLS certificate and JWKS watchers are stopped so no reload races with shutdown.
+//   - Both servers then stop accepting connections and drain concurrently: the HTTP server waits for in-flight
+//     requests and the gRPC server for in-flight RPCs and streams, until the configured shutdown timeout. Whatever
+//     is still running at the deadline is cut off.
+//   - Only once both servers have drained: persisting of the final daily quota counters (if rate limiting is
+//     enabled), flushing of trace spans not exported yet, logging of the final lookup cache hit/miss counters (if
+//     caching is enabled), and closure of the GeoLookupService handle.
 //
-// Note that the HTTP server (Gin engine) currently does not have explicit graceful shutdown logic in this method.
-// Developers may choose to add HTTP server graceful shutdown support if needed in the future.
-func (s *AppServer) Stop() {
-	log.Println("Initiating graceful shutdown of gRPC server...")
-	s.GRPCServer.GracefulStop()
+// Returns:
+//   - error: If either server had to be cut off at the deadline or failed to stop; resources are released
+//     regardless.
+func (s *AppServer) Stop() error {
+	s.health.SetShuttingDown()
+	if s.drainDelay > 0 {
+		log.Printf("Reporting not-ready for %s before draining...", s.drainDelay)
+		time.Sleep(s.drainDelay)
+	}
+
+	if s.stopWatch != nil {
+		s.stopWatch()
+	}
+
+	log.Printf("Draining HTTP and gRPC servers (timeout %s)...", s.shutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var httpErr, grpcErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := s.httpSrv.Shutdown(ctx); err != nil {
+			s.httpSrv.Close()
+			httpErr = fmt.Errorf("HTTP server did not drain within %s: %w", s.shutdownTimeout, err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		drained := make(chan struct{})
+		go func() {
+			s.GRPCServer.GracefulStop()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			s.GRPCServer.Stop()
+			<-drained
+			grpcErr = fmt.Errorf("gRPC server did not drain within %s: %w", s.shutdownTimeout, ctx.Err())
+		}
+	}()
+	wg.Wait()
+
+	if s.limiter != nil {
+		if err := s.limiter.Close(); err != nil {
+			s.log.Error("Failed to persist daily quota counters", zap.Error(err))
+		}
+	}
+
+	// Give the exporter a bounded amount of time to send the remaining spans
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := s.tracer.Shutdown(flushCtx); err != nil {
+		s.log.Warn("Failed to flush trace spans", zap.Error(err))
+	}
+	cancelFlush()
+
+	if s.geoCache != nil {
+		stats := s.geoCache.Stats()
+		s.log.Info("Lookup cache statistics",
+			zap.Uint64("hits", stats.Hits),
+			zap.Uint64("misses", stats.Misses),
+			zap.Uint64("evictions", stats.Evictions),
+			zap.Int("entries", stats.Entries),
+		)
+	}
 
 	log.Println("Closing GeoLookupService database connection...")
 	s.geoService.Close()
+	return errors.Join(httpErr, grpcErr)
 }
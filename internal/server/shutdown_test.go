@@ -0,0 +1,118 @@
+package server_test
+
+import (
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/geo/geotest"
+	"github.com/justfairdev/ipchecker/internal/config"
+	"github.com/justfairdev/ipchecker/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startAppServer starts an AppServer on loopback ports with the given shutdown settings. Requests to /slow block
+// until release is closed or the request is cancelled; started receives a value once such a request is in flight.
+func startAppServer(t *testing.T, shutdown config.ShutdownConfig) (app *server.AppServer, started <-chan struct{}, release chan struct{}) {
+	dbPath := filepath.Join(t.TempDir(), "country.mmdb")
+	geotest.WriteCountryDB(t, dbPath, map[string]string{"81.2.69.0/24": "GB"})
+
+	app, err := server.NewAppServer(&config.Config{
+		HTTP:     config.HTTPConfig{Address: "127.0.0.1"},
+		GRPC:     config.GRPCConfig{Address: "127.0.0.1", ConnectionTimeout: 5 * time.Second},
+		GeoIP:    config.GeoIPConfig{DatabasePath: dbPath},
+		Tracing:  config.TracingConfig{Exporter: "none"},
+		Shutdown: shutdown,
+	})
+	require.NoError(t, err)
+
+	inFlight := make(chan struct{}, 1)
+	release = make(chan struct{})
+	app.HTTPServer.GET("/slow", func(ctx *gin.Context) {
+		inFlight <- struct{}{}
+		select {
+		case <-release:
+			ctx.Status(http.StatusNoContent)
+		case <-ctx.Request.Context().Done():
+		}
+	})
+	require.NoError(t, app.Start())
+	return app, inFlight, release
+}
+
+// TestAppServer_StopDrainsInFlightRequests verifies that Stop reports not-ready during the drain delay, waits for
+// in-flight HTTP requests to complete, and then closes both listeners.
+func TestAppServer_StopDrainsInFlightRequests(t *testing.T) {
+	app, started, release := startAppServer(t, config.ShutdownConfig{DrainDelay: 300 * time.Millisecond, Timeout: 5 * time.Second})
+	baseURL := "http://" + app.HTTPAddr().String()
+
+	slow := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(baseURL + "/slow")
+		assert.NoError(t, err)
+		slow <- resp
+	}()
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- app.Stop() }()
+
+	// Readiness fails while the listener still accepts connections, so load balancers can notice.
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(baseURL + "/readyz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusServiceUnavailable
+	}, 250*time.Millisecond, 10*time.Millisecond, "Expected readiness to fail during the drain delay.")
+
+	select {
+	case err := <-stopped:
+		t.Fatalf("Expected Stop to wait for the in-flight request, returned %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	close(release)
+	resp := <-slow
+	require.NotNil(t, resp)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.NoError(t, <-stopped)
+
+	for _, addr := range []net.Addr{app.HTTPAddr(), app.GRPCAddr()} {
+		_, err := net.Dial("tcp", addr.String())
+		assert.Error(t, err, "Expected %s to be closed after Stop.", addr)
+	}
+	select {
+	case err := <-app.Err():
+		t.Fatalf("Expected no server error after a graceful stop, got %v", err)
+	default:
+	}
+}
+
+// TestAppServer_StopCutsOffAtDeadline verifies that requests still in flight at the shutdown timeout are cut off
+// and reported by Stop.
+func TestAppServer_StopCutsOffAtDeadline(t *testing.T) {
+	app, started, release := startAppServer(t, config.ShutdownConfig{Timeout: 200 * time.Millisecond})
+	defer close(release)
+
+	slow := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + app.HTTPAddr().String() + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		slow <- err
+	}()
+	<-started
+
+	err := app.Stop()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP server did not drain within 200ms")
+	assert.Error(t, <-slow, "Expected the in-flight request to be cut off.")
+}
@@ -0,0 +1,116 @@
+package server_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/internal/grpcserver"
+	"github.com/justfairdev/ipchecker/internal/health"
+	"github.com/justfairdev/ipchecker/internal/identity"
+	"github.com/justfairdev/ipchecker/internal/metrics"
+	"github.com/justfairdev/ipchecker/internal/server"
+	"github.com/justfairdev/ipchecker/internal/tlsconfig"
+	"github.com/justfairdev/ipchecker/internal/tlsconfig/tlstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// newMutualTLS issues a server certificate and a client certificate named by a SPIFFE ID, and returns the
+// reloader of a listener requiring client certificates along with the TLS configuration of the client.
+func newMutualTLS(t *testing.T) (*tlsconfig.Reloader, *tls.Config) {
+	ca := tlstest.NewCA(t, "internal")
+	dir := t.TempDir()
+	certFile, keyFile := ca.IssueFiles(t, dir, "server", tlstest.Leaf{CommonName: "ipchecker"})
+	caFile := filepath.Join(dir, "ca.crt")
+	tlstest.WriteFile(t, caFile, ca.PEM)
+
+	reloader, err := tlsconfig.NewReloader(tlsconfig.Options{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+	require.NoError(t, err)
+
+	client := ca.IssueTLS(t, tlstest.Leaf{
+		CommonName: "billing",
+		URIs:       []string{"spiffe://example.org/billing"},
+		Client:     true,
+	})
+	return reloader, &tls.Config{RootCAs: ca.Pool(), ServerName: "127.0.0.1", Certificates: []tls.Certificate{client}}
+}
+
+// TestNewHTTPServer_ClientIdentity verifies that handlers of the HTTP server see the identity proven by the TLS
+// client certificate, and that clients without a certificate are rejected.
+func TestNewHTTPServer_ClientIdentity(t *testing.T) {
+	reloader, clientTLS := newMutualTLS(t)
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	engine, err := server.NewHTTPServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), nil, nil, server.ClientIPOptions{})
+	require.NoError(t, err)
+
+	var seen identity.Client
+	engine.GET("/whoami", func(ctx *gin.Context) {
+		seen, _ = identity.FromContext(ctx.Request.Context())
+		ctx.Status(http.StatusNoContent)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	httpSrv := &http.Server{Handler: engine}
+	go httpSrv.Serve(tls.NewListener(listener, reloader.TLSConfig("h2", "http/1.1")))
+	defer httpSrv.Close()
+	url := "https://" + listener.Addr().String() + "/whoami"
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS, ForceAttemptHTTP2: true}}
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, 2, resp.ProtoMajor, "Expected HTTP/2 to be negotiated.")
+	assert.Equal(t, "billing", seen.CommonName)
+	assert.Equal(t, "spiffe://example.org/billing", seen.Name())
+
+	anonymous := clientTLS.Clone()
+	anonymous.Certificates = nil
+	_, err = (&http.Client{Transport: &http.Transport{TLSClientConfig: anonymous}}).Get(url)
+	assert.Error(t, err, "Expected a client without a certificate to be rejected.")
+}
+
+// TestNewGRPCServer_ClientIdentity verifies that handlers of the gRPC server see the identity proven by the TLS
+// client certificate.
+func TestNewGRPCServer_ClientIdentity(t *testing.T) {
+	reloader, clientTLS := newMutualTLS(t)
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+
+	// Capture the context the handler runs with through an interceptor placed after the built-in ones.
+	var seen identity.Client
+	capture := func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		seen, _ = identity.FromContext(ctx)
+		return handler(ctx, req)
+	}
+	grpcSrv, err := server.NewGRPCServer(c, metrics.NewMetrics(), noop.NewTracerProvider(), health.NewHealth(), nil, nil, grpcserver.ExtAuthzOptions{}, server.ClientIPOptions{},
+		grpc.Creds(credentials.NewTLS(reloader.TLSConfig("h2"))),
+		grpc.ChainUnaryInterceptor(capture),
+	)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go grpcSrv.Serve(listener)
+	defer grpcSrv.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "billing", seen.CommonName)
+	assert.Equal(t, []string{"spiffe://example.org/billing"}, seen.URIs)
+}
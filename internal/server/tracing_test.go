@@ -0,0 +1,105 @@
+package server_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/checker"
+	"github.com/justfairdev/ipchecker/geo"
+	"github.com/justfairdev/ipchecker/internal/grpcserver"
+	"github.com/justfairdev/ipchecker/internal/health"
+	"github.com/justfairdev/ipchecker/internal/metrics"
+	"github.com/justfairdev/ipchecker/internal/server"
+	pb "github.com/justfairdev/ipchecker/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// callerTraceParent is the W3C trace context sent by the simulated caller.
+const (
+	callerTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	callerTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+)
+
+// newTracedChecker returns a checker backed by a mock lookup service, creating its spans from provider.
+func newTracedChecker(provider *sdktrace.TracerProvider) *checker.Checker {
+	c := checker.NewChecker(geo.NewMockGeoLookupService("US", nil), nil, nil)
+	c.SetTracerProvider(provider)
+	return c
+}
+
+// assertContinuesCallerTrace verifies that every recorded span belongs to the caller's trace, and that the lookup
+// span is a child of the span of the transport.
+func assertContinuesCallerTrace(t *testing.T, recorder *tracetest.SpanRecorder) {
+	t.Helper()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	lookup, request := spans[0], spans[1]
+
+	assert.Equal(t, "geo.LookupService/CountryISOCode", lookup.Name())
+	for _, span := range spans {
+		assert.Equal(t, callerTraceID, span.SpanContext().TraceID().String(), span.Name())
+	}
+	assert.Equal(t, request.SpanContext().SpanID(), lookup.Parent().SpanID())
+	assert.Equal(t, "00f067aa0ba902b7", request.Parent().SpanID().String())
+}
+
+// TestNewHTTPServer_Tracing verifies that HTTP requests continue the trace of the caller.
+func TestNewHTTPServer_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	engine, err := server.NewHTTPServer(newTracedChecker(provider), metrics.NewMetrics(), provider, health.NewHealth(), nil, nil, server.ClientIPOptions{})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ip-check",
+		strings.NewReader(`{"ip_address": "128.101.101.101", "allowed_countries": ["US"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", callerTraceParent)
+	resp := httptest.NewRecorder()
+	engine.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	assertContinuesCallerTrace(t, recorder)
+}
+
+// TestNewGRPCServer_Tracing verifies that RPCs continue the trace of the caller.
+func TestNewGRPCServer_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	grpcSrv, err := server.NewGRPCServer(newTracedChecker(provider), metrics.NewMetrics(), provider, health.NewHealth(), nil, nil, grpcserver.ExtAuthzOptions{}, server.ClientIPOptions{})
+	require.NoError(t, err)
+	listener := bufconn.Listen(1024 * 1024)
+	go grpcSrv.Serve(listener)
+	defer grpcSrv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "traceparent", callerTraceParent)
+	_, err = pb.NewIPCheckerClient(conn).CheckIP(ctx, &pb.IPCheckRequest{
+		IpAddress:        "128.101.101.101",
+		AllowedCountries: []string{"US"},
+	})
+	require.NoError(t, err)
+
+	// The server span ends after the response has been sent; stop the server to make sure it has ended.
+	grpcSrv.GracefulStop()
+	assertContinuesCallerTrace(t, recorder)
+}
@@ -0,0 +1,298 @@
+// Package tlsconfig builds the TLS configuration of the HTTP and gRPC listeners from certificate files, with
+// optional or required client certificates (mutual TLS), and reloads the files when they are rotated.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ClientAuth is how a listener treats client certificates.
+type ClientAuth string
+
+const (
+	// ClientAuthNone does not request client certificates.
+	ClientAuthNone ClientAuth = "none"
+
+	// ClientAuthOptional requests a client certificate and verifies it against the client CA if one is sent;
+	// clients without a certificate are still accepted.
+	ClientAuthOptional ClientAuth = "optional"
+
+	// ClientAuthRequire rejects clients that do not send a certificate signed by the client CA.
+	ClientAuthRequire ClientAuth = "require"
+)
+
+// Options are the files and client-certificate policy of one listener.
+type Options struct {
+	// CertFile is the PEM-encoded server certificate, followed by any intermediate certificates.
+	CertFile string
+
+	// KeyFile is the PEM-encoded private key of the server certificate.
+	KeyFile string
+
+	// ClientCAFile is the PEM-encoded bundle of CA certificates client certificates are verified against.
+	ClientCAFile string
+
+	// ClientAuth is how client certificates are treated. Empty means ClientAuthRequire if ClientCAFile is set,
+	// and ClientAuthNone otherwise.
+	ClientAuth ClientAuth
+}
+
+// Enabled reports whether TLS is configured, i.e. whether a certificate or key file is set.
+//
+// Returns:
+//   - bool: true if the listener should serve TLS.
+func (o Options) Enabled() bool {
+	return o.CertFile != "" || o.KeyFile != ""
+}
+
+// Reloader serves the certificates of one listener and swaps in new ones when the files are replaced.
+//
+// Handshakes always use a consistent pair of server certificate and client CA pool; a rotation that leaves the
+// files invalid (e.g. a key that does not match the certificate, or a half-written file) is rejected, and the
+// previous certificates keep serving until the files change again.
+type Reloader struct {
+	opts       Options
+	clientAuth tls.ClientAuthType
+
+	mu      sync.RWMutex
+	current *certificates // Served to new handshakes; guarded by mu.
+
+	reloadMu   sync.Mutex  // Serializes reload calls.
+	lastStamps []fileStamp // Stamps of the files most recently loaded or rejected; guarded by reloadMu.
+}
+
+// certificates are the server certificate and client CA pool loaded together from the files.
+type certificates struct {
+	server    *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// fileStamp identifies a version of a file on disk.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader validates the options and loads the certificate files.
+//
+// Parameters:
+//   - opts: The files and client-certificate policy of the listener; TLS must be enabled (see Options.Enabled).
+//
+// Returns:
+//   - *Reloader: The reloader, serving the loaded certificates.
+//   - error: An error if the options are inconsistent or the files cannot be loaded.
+func NewReloader(opts Options) (*Reloader, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("both a certificate file and a key file are required for TLS")
+	}
+
+	if opts.ClientAuth == "" {
+		opts.ClientAuth = ClientAuthNone
+		if opts.ClientCAFile != "" {
+			opts.ClientAuth = ClientAuthRequire
+		}
+	}
+
+	r := &Reloader{opts: opts}
+	switch opts.ClientAuth {
+	case ClientAuthNone:
+		r.clientAuth = tls.NoClientCert
+	case ClientAuthOptional:
+		r.clientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		r.clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth mode %q: must be none, optional or require", opts.ClientAuth)
+	}
+	if opts.ClientAuth != ClientAuthNone && opts.ClientCAFile == "" {
+		return nil, fmt.Errorf("client auth mode %q requires a client CA file", opts.ClientAuth)
+	}
+
+	certs, stamps, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.current = certs
+	r.lastStamps = stamps
+	return r, nil
+}
+
+// TLSConfig returns the TLS configuration of the listener. Every handshake uses the certificates loaded most
+// recently, so rotations take effect for new connections without restarting the listener.
+//
+// Parameters:
+//   - nextProtos: The application protocols offered through ALPN (e.g., "h2", "http/1.1").
+//
+// Returns:
+//   - *tls.Config: The configuration to serve TLS with; TLS 1.2 is the minimum version.
+func (r *Reloader) TLSConfig(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			certs := r.current
+			r.mu.RUnlock()
+
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   nextProtos,
+				Certificates: []tls.Certificate{*certs.server},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    certs.clientCAs,
+			}, nil
+		},
+	}
+}
+
+// ClientAuth returns the effective client-certificate policy of the listener.
+//
+// Returns:
+//   - ClientAuth: The configured mode, with an empty mode resolved to none or require.
+func (r *Reloader) ClientAuth() ClientAuth {
+	return r.opts.ClientAuth
+}
+
+// Reload loads the certificate files again and swaps them in for new handshakes.
+//
+// Returns:
+//   - error: An error if the files are missing or invalid; the previous certificates keep serving.
+func (r *Reloader) Reload() error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	certs, stamps, err := r.load()
+	if err != nil {
+		// Remember the rejected files so the watcher does not retry them until they change again.
+		r.lastStamps = r.stamps()
+		return fmt.Errorf("rejected replacement certificates: %w", err)
+	}
+	r.lastStamps = stamps
+
+	r.mu.Lock()
+	r.current = certs
+	r.mu.Unlock()
+	return nil
+}
+
+// Watch keeps the served certificates in sync with the files on disk until ctx is cancelled.
+//
+// A reload is triggered when:
+//   - The modification time or size of any file changes (checked every interval; polling is disabled if
+//     interval <= 0).
+//   - The process receives SIGHUP, which forces a reload even if the files look unchanged.
+//
+// Rejected replacement files are logged and the current certificates keep serving; the same files are not
+// retried until they change again or another SIGHUP arrives.
+//
+// Parameters:
+//   - ctx: Context whose cancellation stops the watcher.
+//   - interval: How often the files are checked for changes.
+//   - logger: A Zap logger used to report reload outcomes.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// A nil channel blocks forever, which disables polling when no interval is configured.
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reloadAndLog(logger, "SIGHUP")
+		case <-tick:
+			if r.changedOnDisk() {
+				r.reloadAndLog(logger, "file change")
+			}
+		}
+	}
+}
+
+// reloadAndLog reloads the certificates and records the outcome.
+func (r *Reloader) reloadAndLog(logger *zap.Logger, trigger string) {
+	if err := r.Reload(); err != nil {
+		logger.Error("TLS certificate reload failed; keeping current certificates",
+			zap.String("cert_file", r.opts.CertFile),
+			zap.String("trigger", trigger),
+			zap.Error(err),
+		)
+		return
+	}
+
+	logger.Info("TLS certificates reloaded",
+		zap.String("cert_file", r.opts.CertFile),
+		zap.String("trigger", trigger),
+	)
+}
+
+// changedOnDisk reports whether any file differs from the version last loaded or rejected.
+func (r *Reloader) changedOnDisk() bool {
+	stamps := r.stamps()
+
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	for i, stamp := range stamps {
+		if !stamp.modTime.Equal(r.lastStamps[i].modTime) || stamp.size != r.lastStamps[i].size {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads and validates the certificate files, returning them along with the stamps of the files read.
+func (r *Reloader) load() (*certificates, []fileStamp, error) {
+	// Stat before reading, so a file replaced while it is read is seen as changed on the next check.
+	stamps := r.stamps()
+
+	server, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading server certificate: %w", err)
+	}
+	certs := &certificates{server: &server}
+
+	if r.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading client CA file: %w", err)
+		}
+		certs.clientCAs = x509.NewCertPool()
+		if !certs.clientCAs.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("client CA file %s contains no PEM certificates", r.opts.ClientCAFile)
+		}
+	}
+	return certs, stamps, nil
+}
+
+// stamps returns the current stamps of the certificate, key and client CA files; missing files get a zero stamp.
+func (r *Reloader) stamps() []fileStamp {
+	paths := []string{r.opts.CertFile, r.opts.KeyFile, r.opts.ClientCAFile}
+	stamps := make([]fileStamp, len(paths))
+	for i, path := range paths {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			stamps[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
@@ -0,0 +1,177 @@
+package tlsconfig_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/justfairdev/ipchecker/internal/identity"
+	"github.com/justfairdev/ipchecker/internal/tlsconfig"
+	"github.com/justfairdev/ipchecker/internal/tlsconfig/tlstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// handshake performs a TLS handshake between the server and client configurations over a loopback connection.
+// It returns the connection state seen by the server, the common name of the certificate served to the client,
+// and the error of either side.
+func handshake(t *testing.T, server, client *tls.Config) (tls.ConnectionState, string, error) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	type result struct {
+		state tls.ConnectionState
+		err   error
+	}
+	serverResult := make(chan result, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			serverResult <- result{err: err}
+			return
+		}
+		defer conn.Close()
+		serverSide := tls.Server(conn, server)
+		err = serverSide.Handshake()
+		serverResult <- result{state: serverSide.ConnectionState(), err: err}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	clientSide := tls.Client(conn, client)
+	clientErr := clientSide.Handshake()
+
+	res := <-serverResult
+	if res.err != nil {
+		return tls.ConnectionState{}, "", res.err
+	}
+	if clientErr != nil {
+		return tls.ConnectionState{}, "", clientErr
+	}
+	return res.state, clientSide.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+}
+
+// newServerFiles issues a server certificate and returns the options of a listener verifying clients against ca.
+func newServerFiles(t *testing.T, ca *tlstest.CA, mode tlsconfig.ClientAuth) tlsconfig.Options {
+	dir := t.TempDir()
+	certFile, keyFile := ca.IssueFiles(t, dir, "server", tlstest.Leaf{CommonName: "server-1"})
+	caFile := filepath.Join(dir, "clients.crt")
+	tlstest.WriteFile(t, caFile, ca.PEM)
+	return tlsconfig.Options{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: mode}
+}
+
+// TestReloader_ClientAuth verifies that required client certificates reject clients without one or with one from
+// another CA, that optional client certificates accept clients without one, and that the verified identity is
+// available from the connection state.
+func TestReloader_ClientAuth(t *testing.T) {
+	ca := tlstest.NewCA(t, "clients")
+	otherCA := tlstest.NewCA(t, "other")
+	client := ca.IssueTLS(t, tlstest.Leaf{
+		CommonName: "billing",
+		URIs:       []string{"spiffe://example.org/billing"},
+		Client:     true,
+	})
+	clientConfig := func(certs ...tls.Certificate) *tls.Config {
+		return &tls.Config{RootCAs: ca.Pool(), ServerName: "127.0.0.1", Certificates: certs}
+	}
+
+	t.Run("require", func(t *testing.T) {
+		reloader, err := tlsconfig.NewReloader(newServerFiles(t, ca, tlsconfig.ClientAuthRequire))
+		require.NoError(t, err)
+		server := reloader.TLSConfig()
+
+		_, _, err = handshake(t, server, clientConfig())
+		assert.Error(t, err, "Expected a client without a certificate to be rejected.")
+		_, _, err = handshake(t, server, clientConfig(otherCA.IssueTLS(t, tlstest.Leaf{CommonName: "x", Client: true})))
+		assert.Error(t, err, "Expected a certificate from an untrusted CA to be rejected.")
+
+		state, _, err := handshake(t, server, clientConfig(client))
+		require.NoError(t, err)
+		id, ok := identity.FromConnectionState(&state)
+		require.True(t, ok)
+		assert.Equal(t, "billing", id.CommonName)
+		assert.Equal(t, "spiffe://example.org/billing", id.Name())
+	})
+
+	t.Run("optional", func(t *testing.T) {
+		reloader, err := tlsconfig.NewReloader(newServerFiles(t, ca, tlsconfig.ClientAuthOptional))
+		require.NoError(t, err)
+		server := reloader.TLSConfig()
+
+		state, _, err := handshake(t, server, clientConfig())
+		require.NoError(t, err)
+		_, ok := identity.FromConnectionState(&state)
+		assert.False(t, ok, "Expected no identity without a client certificate.")
+
+		state, _, err = handshake(t, server, clientConfig(client))
+		require.NoError(t, err)
+		_, ok = identity.FromConnectionState(&state)
+		assert.True(t, ok)
+	})
+}
+
+// TestReloader_Watch verifies that rotated certificate files are served to new connections, and that an invalid
+// rotation is rejected while the previous certificate keeps serving.
+func TestReloader_Watch(t *testing.T) {
+	ca := tlstest.NewCA(t, "servers")
+	opts := newServerFiles(t, ca, tlsconfig.ClientAuthNone)
+	opts.ClientCAFile = ""
+	reloader, err := tlsconfig.NewReloader(opts)
+	require.NoError(t, err)
+	client := &tls.Config{RootCAs: ca.Pool(), ServerName: "127.0.0.1"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Watch(ctx, 10*time.Millisecond, zap.NewNop())
+
+	_, served, err := handshake(t, reloader.TLSConfig(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "server-1", served)
+
+	// A key that does not match the certificate is rejected.
+	_, otherKey := ca.Issue(t, tlstest.Leaf{CommonName: "unrelated"})
+	tlstest.WriteFile(t, opts.KeyFile, otherKey)
+	time.Sleep(50 * time.Millisecond)
+	_, served, err = handshake(t, reloader.TLSConfig(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "server-1", served, "Expected the previous certificate to keep serving.")
+
+	certPEM, keyPEM := ca.Issue(t, tlstest.Leaf{CommonName: "server-2"})
+	tlstest.WriteFile(t, opts.CertFile, certPEM)
+	tlstest.WriteFile(t, opts.KeyFile, keyPEM)
+	assert.Eventually(t, func() bool {
+		_, served, err := handshake(t, reloader.TLSConfig(), client)
+		return err == nil && served == "server-2"
+	}, 2*time.Second, 10*time.Millisecond, "Expected the watcher to reload the rotated certificate.")
+}
+
+// TestNewReloader_RejectsInvalidOptions verifies that incomplete or inconsistent options are rejected.
+func TestNewReloader_RejectsInvalidOptions(t *testing.T) {
+	ca := tlstest.NewCA(t, "clients")
+	valid := newServerFiles(t, ca, tlsconfig.ClientAuthRequire)
+
+	for name, opts := range map[string]tlsconfig.Options{
+		"missing key":        {CertFile: valid.CertFile},
+		"unknown mode":       {CertFile: valid.CertFile, KeyFile: valid.KeyFile, ClientAuth: "sometimes"},
+		"mode without CA":    {CertFile: valid.CertFile, KeyFile: valid.KeyFile, ClientAuth: tlsconfig.ClientAuthOptional},
+		"mismatched key":     {CertFile: valid.CertFile, KeyFile: valid.ClientCAFile},
+		"CA file without CA": {CertFile: valid.CertFile, KeyFile: valid.KeyFile, ClientCAFile: valid.KeyFile},
+	} {
+		_, err := tlsconfig.NewReloader(opts)
+		assert.Error(t, err, name)
+	}
+
+	reloader, err := tlsconfig.NewReloader(tlsconfig.Options{
+		CertFile: valid.CertFile, KeyFile: valid.KeyFile, ClientCAFile: valid.ClientCAFile,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, tlsconfig.ClientAuthRequire, reloader.ClientAuth(), "Expected a client CA to require client certificates.")
+}
@@ -0,0 +1,204 @@
+// Package tlstest issues throwaway certificates for tests that need real TLS or mutual-TLS connections.
+package tlstest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// CA is a self-signed certificate authority issuing server and client certificates.
+type CA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+
+	// PEM is the PEM-encoded CA certificate, as written to client CA files.
+	PEM []byte
+}
+
+// Leaf describes a certificate to issue.
+type Leaf struct {
+	// CommonName is the subject common name.
+	CommonName string
+
+	// DNSNames are the DNS subject alternative names; server certificates are also valid for 127.0.0.1.
+	DNSNames []string
+
+	// URIs are the URI subject alternative names (e.g., "spiffe://example.org/service").
+	URIs []string
+
+	// Client marks the certificate for client authentication instead of server authentication.
+	Client bool
+}
+
+// NewCA creates a certificate authority.
+//
+// Parameters:
+//   - tb: The running test; any failure aborts it.
+//   - name: The common name of the CA certificate.
+//
+// Returns:
+//   - *CA: The certificate authority.
+func NewCA(tb testing.TB, name string) *CA {
+	tb.Helper()
+
+	key := newKey(tb)
+	template := &x509.Certificate{
+		SerialNumber:          newSerial(tb),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		tb.Fatalf("tlstest: creating CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		tb.Fatalf("tlstest: parsing CA certificate: %v", err)
+	}
+	return &CA{cert: cert, key: key, PEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// Pool returns a certificate pool trusting only the CA.
+//
+// Returns:
+//   - *x509.CertPool: The pool, for RootCAs of clients or ClientCAs of servers.
+func (ca *CA) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// Issue issues a certificate signed by the CA.
+//
+// Parameters:
+//   - tb: The running test; any failure aborts it.
+//   - leaf: The certificate to issue.
+//
+// Returns:
+//   - certPEM: The PEM-encoded certificate.
+//   - keyPEM: The PEM-encoded private key.
+func (ca *CA) Issue(tb testing.TB, leaf Leaf) (certPEM, keyPEM []byte) {
+	tb.Helper()
+
+	key := newKey(tb)
+	template := &x509.Certificate{
+		SerialNumber: newSerial(tb),
+		Subject:      pkix.Name{CommonName: leaf.CommonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     leaf.DNSNames,
+	}
+	if leaf.Client {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	} else {
+		template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+	}
+	for _, raw := range leaf.URIs {
+		uri, err := url.Parse(raw)
+		if err != nil {
+			tb.Fatalf("tlstest: parsing URI %q: %v", raw, err)
+		}
+		template.URIs = append(template.URIs, uri)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		tb.Fatalf("tlstest: creating certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		tb.Fatalf("tlstest: encoding key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// IssueFiles issues a certificate signed by the CA and writes it and its key to dir.
+//
+// Parameters:
+//   - tb: The running test; any failure aborts it.
+//   - dir: Destination directory; existing files are replaced.
+//   - name: Base name of the files, written as <name>.crt and <name>.key.
+//   - leaf: The certificate to issue.
+//
+// Returns:
+//   - certFile: Path of the certificate file.
+//   - keyFile: Path of the key file.
+func (ca *CA) IssueFiles(tb testing.TB, dir, name string, leaf Leaf) (certFile, keyFile string) {
+	tb.Helper()
+
+	certPEM, keyPEM := ca.Issue(tb, leaf)
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	WriteFile(tb, certFile, certPEM)
+	WriteFile(tb, keyFile, keyPEM)
+	return certFile, keyFile
+}
+
+// IssueTLS issues a certificate signed by the CA, ready to present in a tls.Config.
+//
+// Parameters:
+//   - tb: The running test; any failure aborts it.
+//   - leaf: The certificate to issue.
+//
+// Returns:
+//   - tls.Certificate: The certificate and its private key.
+func (ca *CA) IssueTLS(tb testing.TB, leaf Leaf) tls.Certificate {
+	tb.Helper()
+
+	cert, err := tls.X509KeyPair(ca.Issue(tb, leaf))
+	if err != nil {
+		tb.Fatalf("tlstest: loading key pair: %v", err)
+	}
+	return cert
+}
+
+// WriteFile writes data to path, replacing an existing file.
+//
+// Parameters:
+//   - tb: The running test; any failure aborts it.
+//   - path: Destination file path.
+//   - data: The file contents.
+func WriteFile(tb testing.TB, path string, data []byte) {
+	tb.Helper()
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		tb.Fatalf("tlstest: writing %s: %v", path, err)
+	}
+}
+
+// newKey generates a P-256 private key.
+func newKey(tb testing.TB) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatalf("tlstest: generating key: %v", err)
+	}
+	return key
+}
+
+// newSerial returns a random certificate serial number.
+func newSerial(tb testing.TB) *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		tb.Fatalf("tlstest: generating serial number: %v", err)
+	}
+	return serial
+}
@@ -0,0 +1,115 @@
+// Package tracing sets up OpenTelemetry tracing: the tracer provider spans are exported through and the W3C
+// trace-context propagation used by both transports.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// ServiceName is the service.name resource attribute reported with every span, unless overridden through the
+// OTEL_SERVICE_NAME or OTEL_RESOURCE_ATTRIBUTES environment variables.
+const ServiceName = "ipchecker"
+
+// Exporter names accepted by Setup.
+const (
+	// ExporterNone disables tracing; spans are neither recorded nor exported.
+	ExporterNone = "none"
+
+	// ExporterStdout writes finished spans to standard output as JSON, for local debugging.
+	ExporterStdout = "stdout"
+
+	// ExporterOTLP sends spans to an OpenTelemetry collector over OTLP/gRPC. The endpoint, headers and TLS
+	// settings are read from the standard OTEL_EXPORTER_OTLP_* environment variables.
+	ExporterOTLP = "otlp"
+)
+
+// Provider is the tracer provider spans of the service are created from.
+type Provider struct {
+	trace.TracerProvider
+	shutdown func(context.Context) error
+}
+
+// Shutdown flushes the spans not exported yet and stops the exporter.
+//
+// Parameters:
+//   - ctx: Context bounding how long flushing may take.
+//
+// Returns:
+//   - error: An error if the remaining spans could not be exported.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return p.shutdown(ctx)
+}
+
+// Propagator returns the propagator used to read and write trace context on incoming and outgoing requests:
+// W3C trace-context ("traceparent"/"tracestate" headers) and W3C baggage.
+//
+// Returns:
+//   - propagation.TextMapPropagator: The composite propagator.
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
+
+// Setup creates the tracer provider for the given exporter and installs it, along with Propagator, as the
+// OpenTelemetry global.
+//
+// Parameters:
+//   - ctx: Context used while creating the exporter.
+//   - exporter: ExporterNone, ExporterStdout or ExporterOTLP; empty means ExporterNone.
+//
+// Returns:
+//   - *Provider: The installed provider; call Shutdown before the process exits to flush pending spans.
+//   - error: An error if the exporter name is unknown or the exporter cannot be created.
+func Setup(ctx context.Context, exporter string) (*Provider, error) {
+	var spanExporter sdktrace.SpanExporter
+	var err error
+
+	switch exporter {
+	case "", ExporterNone:
+		// No exporter: hand out non-recording spans, so instrumentation costs next to nothing.
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New()
+	case ExporterOTLP:
+		spanExporter, err = otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q: must be none, stdout or otlp", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s trace exporter: %w", exporter, err)
+	}
+
+	provider := &Provider{
+		TracerProvider: noop.NewTracerProvider(),
+		shutdown:       func(context.Context) error { return nil },
+	}
+	if spanExporter != nil {
+		res, err := resource.New(ctx,
+			resource.WithAttributes(attribute.String("service.name", ServiceName)),
+			resource.WithFromEnv(),
+			resource.WithTelemetrySDK(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("building trace resource: %w", err)
+		}
+
+		sdkProvider := sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(spanExporter),
+			sdktrace.WithResource(res),
+		)
+		provider = &Provider{TracerProvider: sdkProvider, shutdown: sdkProvider.Shutdown}
+	}
+
+	otel.SetTracerProvider(provider.TracerProvider)
+	otel.SetTextMapPropagator(Propagator())
+	return provider, nil
+}
@@ -0,0 +1,37 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/justfairdev/ipchecker/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TestSetup verifies that the supported exporters can be set up and shut down, and that unknown exporters are
+// rejected.
+func TestSetup(t *testing.T) {
+	for _, exporter := range []string{"", tracing.ExporterNone, tracing.ExporterStdout} {
+		provider, err := tracing.Setup(context.Background(), exporter)
+		require.NoError(t, err, exporter)
+		assert.NoError(t, provider.Shutdown(context.Background()), exporter)
+	}
+
+	_, err := tracing.Setup(context.Background(), "zipkin")
+	assert.EqualError(t, err, `unknown tracing exporter "zipkin": must be none, stdout or otlp`)
+}
+
+// TestPropagator verifies that trace context is read from W3C traceparent headers.
+func TestPropagator(t *testing.T) {
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx := tracing.Propagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+	spanContext := trace.SpanContextFromContext(ctx)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID().String())
+	assert.True(t, spanContext.IsRemote())
+}
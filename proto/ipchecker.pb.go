@@ -21,13 +21,19 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// The IPCheckRequest message includes the IP address and a list of allowed countries.
+// The IPCheckRequest message includes the IP address and either inline country rules
+// (allowed or blocked countries and an optional default action) or the name of a server-side policy.
+// The country lists may also hold ISO 3166-2 subdivision codes (e.g., "US-CA") when a City database is loaded.
 type IPCheckRequest struct {
 	state            protoimpl.MessageState `protogen:"open.v1"`
 	IpAddress        string                 `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
 	AllowedCountries []string               `protobuf:"bytes,2,rep,name=allowed_countries,json=allowedCountries,proto3" json:"allowed_countries,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	Policy           string                 `protobuf:"bytes,3,opt,name=policy,proto3" json:"policy,omitempty"`
+	BlockedCountries []string               `protobuf:"bytes,4,rep,name=blocked_countries,json=blockedCountries,proto3" json:"blocked_countries,omitempty"`
+	// default_action decides IPs without a known country: "allow", "deny" (default) or "error".
+	DefaultAction string `protobuf:"bytes,5,opt,name=default_action,json=defaultAction,proto3" json:"default_action,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *IPCheckRequest) Reset() {
@@ -74,18 +80,113 @@ func (x *IPCheckRequest) GetAllowedCountries() []string {
 	return nil
 }
 
+func (x *IPCheckRequest) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *IPCheckRequest) GetBlockedCountries() []string {
+	if x != nil {
+		return x.BlockedCountries
+	}
+	return nil
+}
+
+func (x *IPCheckRequest) GetDefaultAction() string {
+	if x != nil {
+		return x.DefaultAction
+	}
+	return ""
+}
+
+// The OverrideMatch message identifies the CIDR override rule that decided a request
+// instead of the country lookup.
+type OverrideMatch struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// action is "allow" or "deny".
+	Action        string `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
+	Name          string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Cidr          string `protobuf:"bytes,3,opt,name=cidr,proto3" json:"cidr,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OverrideMatch) Reset() {
+	*x = OverrideMatch{}
+	mi := &file_ipchecker_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OverrideMatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OverrideMatch) ProtoMessage() {}
+
+func (x *OverrideMatch) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OverrideMatch.ProtoReflect.Descriptor instead.
+func (*OverrideMatch) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *OverrideMatch) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *OverrideMatch) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *OverrideMatch) GetCidr() string {
+	if x != nil {
+		return x.Cidr
+	}
+	return ""
+}
+
 // The IPCheckResponse message indicates if the IP is allowed and the resulting country code.
+// When a server-side policy was used, its name and version are echoed back.
+// When a CIDR override decided the request, override is set and no country is reported.
+// reason is "rule", "asn" (the autonomous system is listed in the policy's ASN rules),
+// "default" (no country known, the default action decided) or "override".
+// asn is only resolved for server-side policies with ASN rules; 0 otherwise.
 type IPCheckResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
 	Country       string                 `protobuf:"bytes,2,opt,name=country,proto3" json:"country,omitempty"`
+	Policy        string                 `protobuf:"bytes,3,opt,name=policy,proto3" json:"policy,omitempty"`
+	PolicyVersion string                 `protobuf:"bytes,4,opt,name=policy_version,json=policyVersion,proto3" json:"policy_version,omitempty"`
+	Override      *OverrideMatch         `protobuf:"bytes,5,opt,name=override,proto3" json:"override,omitempty"`
+	Reason        string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	Asn           uint32                 `protobuf:"varint,7,opt,name=asn,proto3" json:"asn,omitempty"`
+	ErrorCode     int32                  `protobuf:"varint,8,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *IPCheckResponse) Reset() {
 	*x = IPCheckResponse{}
-	mi := &file_ipchecker_proto_msgTypes[1]
+	mi := &file_ipchecker_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -97,7 +198,7 @@ func (x *IPCheckResponse) String() string {
 func (*IPCheckResponse) ProtoMessage() {}
 
 func (x *IPCheckResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_ipchecker_proto_msgTypes[1]
+	mi := &file_ipchecker_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -110,7 +211,7 @@ func (x *IPCheckResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IPCheckResponse.ProtoReflect.Descriptor instead.
 func (*IPCheckResponse) Descriptor() ([]byte, []int) {
-	return file_ipchecker_proto_rawDescGZIP(), []int{1}
+	return file_ipchecker_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *IPCheckResponse) GetAllowed() bool {
@@ -127,20 +228,1354 @@ func (x *IPCheckResponse) GetCountry() string {
 	return ""
 }
 
-var File_ipchecker_proto protoreflect.FileDescriptor
+func (x *IPCheckResponse) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
 
-const file_ipchecker_proto_rawDesc = "" +
-	"\n" +
-	"\x0fipchecker.proto\x12\fipchecker.v1\"\\\n" +
-	"\x0eIPCheckRequest\x12\x1d\n" +
-	"\n" +
-	"ip_address\x18\x01 \x01(\tR\tipAddress\x12+\n" +
-	"\x11allowed_countries\x18\x02 \x03(\tR\x10allowedCountries\"E\n" +
-	"\x0fIPCheckResponse\x12\x18\n" +
-	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x18\n" +
-	"\acountry\x18\x02 \x01(\tR\acountry2S\n" +
+func (x *IPCheckResponse) GetPolicyVersion() string {
+	if x != nil {
+		return x.PolicyVersion
+	}
+	return ""
+}
+
+func (x *IPCheckResponse) GetOverride() *OverrideMatch {
+	if x != nil {
+		return x.Override
+	}
+	return nil
+}
+
+func (x *IPCheckResponse) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *IPCheckResponse) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *IPCheckResponse) GetErrorCode() int32 {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return 0
+}
+
+// The IPBatchCheckRequest message includes a list of IP addresses checked against one shared list of allowed
+// countries or one server-side policy.
+type IPBatchCheckRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	IpAddresses      []string               `protobuf:"bytes,1,rep,name=ip_addresses,json=ipAddresses,proto3" json:"ip_addresses,omitempty"`
+	AllowedCountries []string               `protobuf:"bytes,2,rep,name=allowed_countries,json=allowedCountries,proto3" json:"allowed_countries,omitempty"`
+	Policy           string                 `protobuf:"bytes,3,opt,name=policy,proto3" json:"policy,omitempty"`
+	BlockedCountries []string               `protobuf:"bytes,4,rep,name=blocked_countries,json=blockedCountries,proto3" json:"blocked_countries,omitempty"`
+	DefaultAction    string                 `protobuf:"bytes,5,opt,name=default_action,json=defaultAction,proto3" json:"default_action,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *IPBatchCheckRequest) Reset() {
+	*x = IPBatchCheckRequest{}
+	mi := &file_ipchecker_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IPBatchCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPBatchCheckRequest) ProtoMessage() {}
+
+func (x *IPBatchCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPBatchCheckRequest.ProtoReflect.Descriptor instead.
+func (*IPBatchCheckRequest) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *IPBatchCheckRequest) GetIpAddresses() []string {
+	if x != nil {
+		return x.IpAddresses
+	}
+	return nil
+}
+
+func (x *IPBatchCheckRequest) GetAllowedCountries() []string {
+	if x != nil {
+		return x.AllowedCountries
+	}
+	return nil
+}
+
+func (x *IPBatchCheckRequest) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *IPBatchCheckRequest) GetBlockedCountries() []string {
+	if x != nil {
+		return x.BlockedCountries
+	}
+	return nil
+}
+
+func (x *IPBatchCheckRequest) GetDefaultAction() string {
+	if x != nil {
+		return x.DefaultAction
+	}
+	return ""
+}
+
+// The IPBatchCheckResult message holds the outcome for a single IP address of a batch.
+// When error is set, the lookup for this item failed and allowed/country are not meaningful; error_code is then
+// the gRPC status code the error would have as a single CheckIP call, e.g. 3 (INVALID_ARGUMENT).
+type IPBatchCheckResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IpAddress     string                 `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	Allowed       bool                   `protobuf:"varint,2,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Country       string                 `protobuf:"bytes,3,opt,name=country,proto3" json:"country,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	Override      *OverrideMatch         `protobuf:"bytes,5,opt,name=override,proto3" json:"override,omitempty"`
+	Reason        string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	Asn           uint32                 `protobuf:"varint,7,opt,name=asn,proto3" json:"asn,omitempty"`
+	ErrorCode     int32                  `protobuf:"varint,8,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IPBatchCheckResult) Reset() {
+	*x = IPBatchCheckResult{}
+	mi := &file_ipchecker_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IPBatchCheckResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPBatchCheckResult) ProtoMessage() {}
+
+func (x *IPBatchCheckResult) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPBatchCheckResult.ProtoReflect.Descriptor instead.
+func (*IPBatchCheckResult) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *IPBatchCheckResult) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *IPBatchCheckResult) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *IPBatchCheckResult) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *IPBatchCheckResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *IPBatchCheckResult) GetOverride() *OverrideMatch {
+	if x != nil {
+		return x.Override
+	}
+	return nil
+}
+
+func (x *IPBatchCheckResult) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *IPBatchCheckResult) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *IPBatchCheckResult) GetErrorCode() int32 {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return 0
+}
+
+// The IPBatchCheckResponse message holds one result per requested IP address, in request order,
+// and the server-side policy applied to all of them, if any.
+type IPBatchCheckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*IPBatchCheckResult  `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	Policy        string                 `protobuf:"bytes,2,opt,name=policy,proto3" json:"policy,omitempty"`
+	PolicyVersion string                 `protobuf:"bytes,3,opt,name=policy_version,json=policyVersion,proto3" json:"policy_version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IPBatchCheckResponse) Reset() {
+	*x = IPBatchCheckResponse{}
+	mi := &file_ipchecker_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IPBatchCheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPBatchCheckResponse) ProtoMessage() {}
+
+func (x *IPBatchCheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPBatchCheckResponse.ProtoReflect.Descriptor instead.
+func (*IPBatchCheckResponse) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *IPBatchCheckResponse) GetResults() []*IPBatchCheckResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *IPBatchCheckResponse) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *IPBatchCheckResponse) GetPolicyVersion() string {
+	if x != nil {
+		return x.PolicyVersion
+	}
+	return ""
+}
+
+// The IPStreamCheckRequest message is one IP check sent on a CheckIPStream call.
+// The id is chosen by the client and echoed on the matching response.
+type IPStreamCheckRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	IpAddress        string                 `protobuf:"bytes,2,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	AllowedCountries []string               `protobuf:"bytes,3,rep,name=allowed_countries,json=allowedCountries,proto3" json:"allowed_countries,omitempty"`
+	Policy           string                 `protobuf:"bytes,4,opt,name=policy,proto3" json:"policy,omitempty"`
+	BlockedCountries []string               `protobuf:"bytes,5,rep,name=blocked_countries,json=blockedCountries,proto3" json:"blocked_countries,omitempty"`
+	DefaultAction    string                 `protobuf:"bytes,6,opt,name=default_action,json=defaultAction,proto3" json:"default_action,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *IPStreamCheckRequest) Reset() {
+	*x = IPStreamCheckRequest{}
+	mi := &file_ipchecker_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IPStreamCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPStreamCheckRequest) ProtoMessage() {}
+
+func (x *IPStreamCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPStreamCheckRequest.ProtoReflect.Descriptor instead.
+func (*IPStreamCheckRequest) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *IPStreamCheckRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *IPStreamCheckRequest) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *IPStreamCheckRequest) GetAllowedCountries() []string {
+	if x != nil {
+		return x.AllowedCountries
+	}
+	return nil
+}
+
+func (x *IPStreamCheckRequest) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *IPStreamCheckRequest) GetBlockedCountries() []string {
+	if x != nil {
+		return x.BlockedCountries
+	}
+	return nil
+}
+
+func (x *IPStreamCheckRequest) GetDefaultAction() string {
+	if x != nil {
+		return x.DefaultAction
+	}
+	return ""
+}
+
+// The IPStreamCheckResponse message answers the IPStreamCheckRequest with the same id.
+// When error is set, the check for this message failed and allowed/country are not meaningful; error_code is then
+// the gRPC status code the error would have as a single CheckIP call.
+type IPStreamCheckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Allowed       bool                   `protobuf:"varint,2,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Country       string                 `protobuf:"bytes,3,opt,name=country,proto3" json:"country,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	Policy        string                 `protobuf:"bytes,5,opt,name=policy,proto3" json:"policy,omitempty"`
+	PolicyVersion string                 `protobuf:"bytes,6,opt,name=policy_version,json=policyVersion,proto3" json:"policy_version,omitempty"`
+	Override      *OverrideMatch         `protobuf:"bytes,7,opt,name=override,proto3" json:"override,omitempty"`
+	Reason        string                 `protobuf:"bytes,8,opt,name=reason,proto3" json:"reason,omitempty"`
+	Asn           uint32                 `protobuf:"varint,9,opt,name=asn,proto3" json:"asn,omitempty"`
+	ErrorCode     int32                  `protobuf:"varint,10,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IPStreamCheckResponse) Reset() {
+	*x = IPStreamCheckResponse{}
+	mi := &file_ipchecker_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IPStreamCheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IPStreamCheckResponse) ProtoMessage() {}
+
+func (x *IPStreamCheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IPStreamCheckResponse.ProtoReflect.Descriptor instead.
+func (*IPStreamCheckResponse) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *IPStreamCheckResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *IPStreamCheckResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *IPStreamCheckResponse) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *IPStreamCheckResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *IPStreamCheckResponse) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *IPStreamCheckResponse) GetPolicyVersion() string {
+	if x != nil {
+		return x.PolicyVersion
+	}
+	return ""
+}
+
+func (x *IPStreamCheckResponse) GetOverride() *OverrideMatch {
+	if x != nil {
+		return x.Override
+	}
+	return nil
+}
+
+func (x *IPStreamCheckResponse) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *IPStreamCheckResponse) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *IPStreamCheckResponse) GetErrorCode() int32 {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return 0
+}
+
+// The CallerCheckRequest message checks the caller itself: the IP address is taken from the connection, or from
+// the x-forwarded-for or x-real-ip metadata when the peer is a trusted proxy, rather than from the request.
+// It carries either inline country rules or the name of a server-side policy, as IPCheckRequest does.
+type CallerCheckRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	AllowedCountries []string               `protobuf:"bytes,1,rep,name=allowed_countries,json=allowedCountries,proto3" json:"allowed_countries,omitempty"`
+	Policy           string                 `protobuf:"bytes,2,opt,name=policy,proto3" json:"policy,omitempty"`
+	BlockedCountries []string               `protobuf:"bytes,3,rep,name=blocked_countries,json=blockedCountries,proto3" json:"blocked_countries,omitempty"`
+	DefaultAction    string                 `protobuf:"bytes,4,opt,name=default_action,json=defaultAction,proto3" json:"default_action,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *CallerCheckRequest) Reset() {
+	*x = CallerCheckRequest{}
+	mi := &file_ipchecker_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallerCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallerCheckRequest) ProtoMessage() {}
+
+func (x *CallerCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallerCheckRequest.ProtoReflect.Descriptor instead.
+func (*CallerCheckRequest) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CallerCheckRequest) GetAllowedCountries() []string {
+	if x != nil {
+		return x.AllowedCountries
+	}
+	return nil
+}
+
+func (x *CallerCheckRequest) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *CallerCheckRequest) GetBlockedCountries() []string {
+	if x != nil {
+		return x.BlockedCountries
+	}
+	return nil
+}
+
+func (x *CallerCheckRequest) GetDefaultAction() string {
+	if x != nil {
+		return x.DefaultAction
+	}
+	return ""
+}
+
+// The CallerCheckResponse message answers a CallerCheckRequest like IPCheckResponse, and reports the IP address
+// that was checked and where it was taken from: ip_source is "peer" for the connection, otherwise the name of the
+// forwarding metadata key (e.g., "x-forwarded-for").
+type CallerCheckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Country       string                 `protobuf:"bytes,2,opt,name=country,proto3" json:"country,omitempty"`
+	Policy        string                 `protobuf:"bytes,3,opt,name=policy,proto3" json:"policy,omitempty"`
+	PolicyVersion string                 `protobuf:"bytes,4,opt,name=policy_version,json=policyVersion,proto3" json:"policy_version,omitempty"`
+	Override      *OverrideMatch         `protobuf:"bytes,5,opt,name=override,proto3" json:"override,omitempty"`
+	Reason        string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	Asn           uint32                 `protobuf:"varint,7,opt,name=asn,proto3" json:"asn,omitempty"`
+	IpAddress     string                 `protobuf:"bytes,8,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	IpSource      string                 `protobuf:"bytes,9,opt,name=ip_source,json=ipSource,proto3" json:"ip_source,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallerCheckResponse) Reset() {
+	*x = CallerCheckResponse{}
+	mi := &file_ipchecker_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallerCheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallerCheckResponse) ProtoMessage() {}
+
+func (x *CallerCheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallerCheckResponse.ProtoReflect.Descriptor instead.
+func (*CallerCheckResponse) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CallerCheckResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *CallerCheckResponse) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *CallerCheckResponse) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+func (x *CallerCheckResponse) GetPolicyVersion() string {
+	if x != nil {
+		return x.PolicyVersion
+	}
+	return ""
+}
+
+func (x *CallerCheckResponse) GetOverride() *OverrideMatch {
+	if x != nil {
+		return x.Override
+	}
+	return nil
+}
+
+func (x *CallerCheckResponse) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *CallerCheckResponse) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *CallerCheckResponse) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *CallerCheckResponse) GetIpSource() string {
+	if x != nil {
+		return x.IpSource
+	}
+	return ""
+}
+
+// The LookupRequest message asks for the full geolocation data of an IP address.
+// locale selects the language of names (e.g., "de"); it defaults to "en", which is also the fallback
+// for names missing in the requested locale.
+type LookupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IpAddress     string                 `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	Locale        string                 `protobuf:"bytes,2,opt,name=locale,proto3" json:"locale,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupRequest) Reset() {
+	*x = LookupRequest{}
+	mi := &file_ipchecker_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupRequest) ProtoMessage() {}
+
+func (x *LookupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupRequest.ProtoReflect.Descriptor instead.
+func (*LookupRequest) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *LookupRequest) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *LookupRequest) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+// The Continent message identifies a continent by its two-letter code.
+type Continent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Continent) Reset() {
+	*x = Continent{}
+	mi := &file_ipchecker_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Continent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Continent) ProtoMessage() {}
+
+func (x *Continent) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Continent.ProtoReflect.Descriptor instead.
+func (*Continent) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Continent) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Continent) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// The Country message identifies a country by its ISO 3166-1 alpha-2 code.
+type Country struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	IsoCode           string                 `protobuf:"bytes,1,opt,name=iso_code,json=isoCode,proto3" json:"iso_code,omitempty"`
+	Name              string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	IsInEuropeanUnion bool                   `protobuf:"varint,3,opt,name=is_in_european_union,json=isInEuropeanUnion,proto3" json:"is_in_european_union,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Country) Reset() {
+	*x = Country{}
+	mi := &file_ipchecker_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Country) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Country) ProtoMessage() {}
+
+func (x *Country) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Country.ProtoReflect.Descriptor instead.
+func (*Country) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Country) GetIsoCode() string {
+	if x != nil {
+		return x.IsoCode
+	}
+	return ""
+}
+
+func (x *Country) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Country) GetIsInEuropeanUnion() bool {
+	if x != nil {
+		return x.IsInEuropeanUnion
+	}
+	return false
+}
+
+// The RepresentedCountry message identifies the country represented by the users of an IP address,
+// such as the home country of a military base.
+type RepresentedCountry struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	IsoCode           string                 `protobuf:"bytes,1,opt,name=iso_code,json=isoCode,proto3" json:"iso_code,omitempty"`
+	Name              string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	IsInEuropeanUnion bool                   `protobuf:"varint,3,opt,name=is_in_european_union,json=isInEuropeanUnion,proto3" json:"is_in_european_union,omitempty"`
+	Type              string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *RepresentedCountry) Reset() {
+	*x = RepresentedCountry{}
+	mi := &file_ipchecker_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RepresentedCountry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RepresentedCountry) ProtoMessage() {}
+
+func (x *RepresentedCountry) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RepresentedCountry.ProtoReflect.Descriptor instead.
+func (*RepresentedCountry) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RepresentedCountry) GetIsoCode() string {
+	if x != nil {
+		return x.IsoCode
+	}
+	return ""
+}
+
+func (x *RepresentedCountry) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RepresentedCountry) GetIsInEuropeanUnion() bool {
+	if x != nil {
+		return x.IsInEuropeanUnion
+	}
+	return false
+}
+
+func (x *RepresentedCountry) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+// The Traits message holds flags describing the network of an IP address.
+type Traits struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	IsAnonymousProxy    bool                   `protobuf:"varint,1,opt,name=is_anonymous_proxy,json=isAnonymousProxy,proto3" json:"is_anonymous_proxy,omitempty"`
+	IsAnycast           bool                   `protobuf:"varint,2,opt,name=is_anycast,json=isAnycast,proto3" json:"is_anycast,omitempty"`
+	IsSatelliteProvider bool                   `protobuf:"varint,3,opt,name=is_satellite_provider,json=isSatelliteProvider,proto3" json:"is_satellite_provider,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *Traits) Reset() {
+	*x = Traits{}
+	mi := &file_ipchecker_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Traits) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Traits) ProtoMessage() {}
+
+func (x *Traits) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Traits.ProtoReflect.Descriptor instead.
+func (*Traits) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Traits) GetIsAnonymousProxy() bool {
+	if x != nil {
+		return x.IsAnonymousProxy
+	}
+	return false
+}
+
+func (x *Traits) GetIsAnycast() bool {
+	if x != nil {
+		return x.IsAnycast
+	}
+	return false
+}
+
+func (x *Traits) GetIsSatelliteProvider() bool {
+	if x != nil {
+		return x.IsSatelliteProvider
+	}
+	return false
+}
+
+// The Subdivision message identifies a subdivision of a country, such as a state or province,
+// by its ISO 3166-2 code (e.g., "US-CA").
+type Subdivision struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IsoCode       string                 `protobuf:"bytes,1,opt,name=iso_code,json=isoCode,proto3" json:"iso_code,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Subdivision) Reset() {
+	*x = Subdivision{}
+	mi := &file_ipchecker_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Subdivision) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Subdivision) ProtoMessage() {}
+
+func (x *Subdivision) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Subdivision.ProtoReflect.Descriptor instead.
+func (*Subdivision) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Subdivision) GetIsoCode() string {
+	if x != nil {
+		return x.IsoCode
+	}
+	return ""
+}
+
+func (x *Subdivision) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// The AutonomousSystem message identifies the autonomous system an IP address is announced by.
+type AutonomousSystem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Number        uint32                 `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	Organization  string                 `protobuf:"bytes,2,opt,name=organization,proto3" json:"organization,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AutonomousSystem) Reset() {
+	*x = AutonomousSystem{}
+	mi := &file_ipchecker_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AutonomousSystem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AutonomousSystem) ProtoMessage() {}
+
+func (x *AutonomousSystem) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AutonomousSystem.ProtoReflect.Descriptor instead.
+func (*AutonomousSystem) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *AutonomousSystem) GetNumber() uint32 {
+	if x != nil {
+		return x.Number
+	}
+	return 0
+}
+
+func (x *AutonomousSystem) GetOrganization() string {
+	if x != nil {
+		return x.Organization
+	}
+	return ""
+}
+
+// The LookupResponse message holds the geolocation data of an IP address.
+// Places the database has no data for are left unset. city, subdivisions (largest first), postal_code
+// and accuracy_radius (in kilometers) are only set when a City database is loaded, autonomous_system
+// only when an ASN database is loaded.
+type LookupResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	IpAddress          string                 `protobuf:"bytes,1,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	Locale             string                 `protobuf:"bytes,2,opt,name=locale,proto3" json:"locale,omitempty"`
+	Continent          *Continent             `protobuf:"bytes,3,opt,name=continent,proto3" json:"continent,omitempty"`
+	Country            *Country               `protobuf:"bytes,4,opt,name=country,proto3" json:"country,omitempty"`
+	RegisteredCountry  *Country               `protobuf:"bytes,5,opt,name=registered_country,json=registeredCountry,proto3" json:"registered_country,omitempty"`
+	RepresentedCountry *RepresentedCountry    `protobuf:"bytes,6,opt,name=represented_country,json=representedCountry,proto3" json:"represented_country,omitempty"`
+	Traits             *Traits                `protobuf:"bytes,7,opt,name=traits,proto3" json:"traits,omitempty"`
+	City               string                 `protobuf:"bytes,8,opt,name=city,proto3" json:"city,omitempty"`
+	Subdivisions       []*Subdivision         `protobuf:"bytes,9,rep,name=subdivisions,proto3" json:"subdivisions,omitempty"`
+	PostalCode         string                 `protobuf:"bytes,10,opt,name=postal_code,json=postalCode,proto3" json:"postal_code,omitempty"`
+	AccuracyRadius     uint32                 `protobuf:"varint,11,opt,name=accuracy_radius,json=accuracyRadius,proto3" json:"accuracy_radius,omitempty"`
+	AutonomousSystem   *AutonomousSystem      `protobuf:"bytes,12,opt,name=autonomous_system,json=autonomousSystem,proto3" json:"autonomous_system,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *LookupResponse) Reset() {
+	*x = LookupResponse{}
+	mi := &file_ipchecker_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupResponse) ProtoMessage() {}
+
+func (x *LookupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ipchecker_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupResponse.ProtoReflect.Descriptor instead.
+func (*LookupResponse) Descriptor() ([]byte, []int) {
+	return file_ipchecker_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *LookupResponse) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *LookupResponse) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+func (x *LookupResponse) GetContinent() *Continent {
+	if x != nil {
+		return x.Continent
+	}
+	return nil
+}
+
+func (x *LookupResponse) GetCountry() *Country {
+	if x != nil {
+		return x.Country
+	}
+	return nil
+}
+
+func (x *LookupResponse) GetRegisteredCountry() *Country {
+	if x != nil {
+		return x.RegisteredCountry
+	}
+	return nil
+}
+
+func (x *LookupResponse) GetRepresentedCountry() *RepresentedCountry {
+	if x != nil {
+		return x.RepresentedCountry
+	}
+	return nil
+}
+
+func (x *LookupResponse) GetTraits() *Traits {
+	if x != nil {
+		return x.Traits
+	}
+	return nil
+}
+
+func (x *LookupResponse) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *LookupResponse) GetSubdivisions() []*Subdivision {
+	if x != nil {
+		return x.Subdivisions
+	}
+	return nil
+}
+
+func (x *LookupResponse) GetPostalCode() string {
+	if x != nil {
+		return x.PostalCode
+	}
+	return ""
+}
+
+func (x *LookupResponse) GetAccuracyRadius() uint32 {
+	if x != nil {
+		return x.AccuracyRadius
+	}
+	return 0
+}
+
+func (x *LookupResponse) GetAutonomousSystem() *AutonomousSystem {
+	if x != nil {
+		return x.AutonomousSystem
+	}
+	return nil
+}
+
+var File_ipchecker_proto protoreflect.FileDescriptor
+
+const file_ipchecker_proto_rawDesc = "" +
+	"\n" +
+	"\x0fipchecker.proto\x12\fipchecker.v1\"\xc8\x01\n" +
+	"\x0eIPCheckRequest\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\x12+\n" +
+	"\x11allowed_countries\x18\x02 \x03(\tR\x10allowedCountries\x12\x16\n" +
+	"\x06policy\x18\x03 \x01(\tR\x06policy\x12+\n" +
+	"\x11blocked_countries\x18\x04 \x03(\tR\x10blockedCountries\x12%\n" +
+	"\x0edefault_action\x18\x05 \x01(\tR\rdefaultAction\"O\n" +
+	"\rOverrideMatch\x12\x16\n" +
+	"\x06action\x18\x01 \x01(\tR\x06action\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04cidr\x18\x03 \x01(\tR\x04cidr\"\x86\x02\n" +
+	"\x0fIPCheckResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x18\n" +
+	"\acountry\x18\x02 \x01(\tR\acountry\x12\x16\n" +
+	"\x06policy\x18\x03 \x01(\tR\x06policy\x12%\n" +
+	"\x0epolicy_version\x18\x04 \x01(\tR\rpolicyVersion\x127\n" +
+	"\boverride\x18\x05 \x01(\v2\x1b.ipchecker.v1.OverrideMatchR\boverride\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\x12\x10\n" +
+	"\x03asn\x18\a \x01(\rR\x03asn\x12\x1d\n" +
+	"\n" +
+	"error_code\x18\b \x01(\x05R\terrorCode\"\xd1\x01\n" +
+	"\x13IPBatchCheckRequest\x12!\n" +
+	"\fip_addresses\x18\x01 \x03(\tR\vipAddresses\x12+\n" +
+	"\x11allowed_countries\x18\x02 \x03(\tR\x10allowedCountries\x12\x16\n" +
+	"\x06policy\x18\x03 \x01(\tR\x06policy\x12+\n" +
+	"\x11blocked_countries\x18\x04 \x03(\tR\x10blockedCountries\x12%\n" +
+	"\x0edefault_action\x18\x05 \x01(\tR\rdefaultAction\"\xff\x01\n" +
+	"\x12IPBatchCheckResult\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\x12\x18\n" +
+	"\aallowed\x18\x02 \x01(\bR\aallowed\x12\x18\n" +
+	"\acountry\x18\x03 \x01(\tR\acountry\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x127\n" +
+	"\boverride\x18\x05 \x01(\v2\x1b.ipchecker.v1.OverrideMatchR\boverride\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\x12\x10\n" +
+	"\x03asn\x18\a \x01(\rR\x03asn\x12\x1d\n" +
+	"\n" +
+	"error_code\x18\b \x01(\x05R\terrorCode\"\x91\x01\n" +
+	"\x14IPBatchCheckResponse\x12:\n" +
+	"\aresults\x18\x01 \x03(\v2 .ipchecker.v1.IPBatchCheckResultR\aresults\x12\x16\n" +
+	"\x06policy\x18\x02 \x01(\tR\x06policy\x12%\n" +
+	"\x0epolicy_version\x18\x03 \x01(\tR\rpolicyVersion\"\xde\x01\n" +
+	"\x14IPStreamCheckRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x02 \x01(\tR\tipAddress\x12+\n" +
+	"\x11allowed_countries\x18\x03 \x03(\tR\x10allowedCountries\x12\x16\n" +
+	"\x06policy\x18\x04 \x01(\tR\x06policy\x12+\n" +
+	"\x11blocked_countries\x18\x05 \x03(\tR\x10blockedCountries\x12%\n" +
+	"\x0edefault_action\x18\x06 \x01(\tR\rdefaultAction\"\xb2\x02\n" +
+	"\x15IPStreamCheckResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aallowed\x18\x02 \x01(\bR\aallowed\x12\x18\n" +
+	"\acountry\x18\x03 \x01(\tR\acountry\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x16\n" +
+	"\x06policy\x18\x05 \x01(\tR\x06policy\x12%\n" +
+	"\x0epolicy_version\x18\x06 \x01(\tR\rpolicyVersion\x127\n" +
+	"\boverride\x18\a \x01(\v2\x1b.ipchecker.v1.OverrideMatchR\boverride\x12\x16\n" +
+	"\x06reason\x18\b \x01(\tR\x06reason\x12\x10\n" +
+	"\x03asn\x18\t \x01(\rR\x03asn\x12\x1d\n" +
+	"\n" +
+	"error_code\x18\n" +
+	" \x01(\x05R\terrorCode\"\xad\x01\n" +
+	"\x12CallerCheckRequest\x12+\n" +
+	"\x11allowed_countries\x18\x01 \x03(\tR\x10allowedCountries\x12\x16\n" +
+	"\x06policy\x18\x02 \x01(\tR\x06policy\x12+\n" +
+	"\x11blocked_countries\x18\x03 \x03(\tR\x10blockedCountries\x12%\n" +
+	"\x0edefault_action\x18\x04 \x01(\tR\rdefaultAction\"\xa7\x02\n" +
+	"\x13CallerCheckResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x18\n" +
+	"\acountry\x18\x02 \x01(\tR\acountry\x12\x16\n" +
+	"\x06policy\x18\x03 \x01(\tR\x06policy\x12%\n" +
+	"\x0epolicy_version\x18\x04 \x01(\tR\rpolicyVersion\x127\n" +
+	"\boverride\x18\x05 \x01(\v2\x1b.ipchecker.v1.OverrideMatchR\boverride\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\x12\x10\n" +
+	"\x03asn\x18\a \x01(\rR\x03asn\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\b \x01(\tR\tipAddress\x12\x1b\n" +
+	"\tip_source\x18\t \x01(\tR\bipSource\"F\n" +
+	"\rLookupRequest\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\x12\x16\n" +
+	"\x06locale\x18\x02 \x01(\tR\x06locale\"3\n" +
+	"\tContinent\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"i\n" +
+	"\aCountry\x12\x19\n" +
+	"\biso_code\x18\x01 \x01(\tR\aisoCode\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12/\n" +
+	"\x14is_in_european_union\x18\x03 \x01(\bR\x11isInEuropeanUnion\"\x88\x01\n" +
+	"\x12RepresentedCountry\x12\x19\n" +
+	"\biso_code\x18\x01 \x01(\tR\aisoCode\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12/\n" +
+	"\x14is_in_european_union\x18\x03 \x01(\bR\x11isInEuropeanUnion\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\"\x89\x01\n" +
+	"\x06Traits\x12,\n" +
+	"\x12is_anonymous_proxy\x18\x01 \x01(\bR\x10isAnonymousProxy\x12\x1d\n" +
+	"\n" +
+	"is_anycast\x18\x02 \x01(\bR\tisAnycast\x122\n" +
+	"\x15is_satellite_provider\x18\x03 \x01(\bR\x13isSatelliteProvider\"<\n" +
+	"\vSubdivision\x12\x19\n" +
+	"\biso_code\x18\x01 \x01(\tR\aisoCode\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"N\n" +
+	"\x10AutonomousSystem\x12\x16\n" +
+	"\x06number\x18\x01 \x01(\rR\x06number\x12\"\n" +
+	"\forganization\x18\x02 \x01(\tR\forganization\"\xe0\x04\n" +
+	"\x0eLookupResponse\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x01 \x01(\tR\tipAddress\x12\x16\n" +
+	"\x06locale\x18\x02 \x01(\tR\x06locale\x125\n" +
+	"\tcontinent\x18\x03 \x01(\v2\x17.ipchecker.v1.ContinentR\tcontinent\x12/\n" +
+	"\acountry\x18\x04 \x01(\v2\x15.ipchecker.v1.CountryR\acountry\x12D\n" +
+	"\x12registered_country\x18\x05 \x01(\v2\x15.ipchecker.v1.CountryR\x11registeredCountry\x12Q\n" +
+	"\x13represented_country\x18\x06 \x01(\v2 .ipchecker.v1.RepresentedCountryR\x12representedCountry\x12,\n" +
+	"\x06traits\x18\a \x01(\v2\x14.ipchecker.v1.TraitsR\x06traits\x12\x12\n" +
+	"\x04city\x18\b \x01(\tR\x04city\x12=\n" +
+	"\fsubdivisions\x18\t \x03(\v2\x19.ipchecker.v1.SubdivisionR\fsubdivisions\x12\x1f\n" +
+	"\vpostal_code\x18\n" +
+	" \x01(\tR\n" +
+	"postalCode\x12'\n" +
+	"\x0faccuracy_radius\x18\v \x01(\rR\x0eaccuracyRadius\x12K\n" +
+	"\x11autonomous_system\x18\f \x01(\v2\x1e.ipchecker.v1.AutonomousSystemR\x10autonomousSystem2\xa1\x03\n" +
 	"\tIPChecker\x12F\n" +
-	"\aCheckIP\x12\x1c.ipchecker.v1.IPCheckRequest\x1a\x1d.ipchecker.v1.IPCheckResponseB<Z:github.com/justfairdev/ipchecker/proto/ipchecker;ipcheckerb\x06proto3"
+	"\aCheckIP\x12\x1c.ipchecker.v1.IPCheckRequest\x1a\x1d.ipchecker.v1.IPCheckResponse\x12U\n" +
+	"\fCheckIPBatch\x12!.ipchecker.v1.IPBatchCheckRequest\x1a\".ipchecker.v1.IPBatchCheckResponse\x12\\\n" +
+	"\rCheckIPStream\x12\".ipchecker.v1.IPStreamCheckRequest\x1a#.ipchecker.v1.IPStreamCheckResponse(\x010\x01\x12C\n" +
+	"\x06Lookup\x12\x1b.ipchecker.v1.LookupRequest\x1a\x1c.ipchecker.v1.LookupResponse\x12R\n" +
+	"\vCheckCaller\x12 .ipchecker.v1.CallerCheckRequest\x1a!.ipchecker.v1.CallerCheckResponseB<Z:github.com/justfairdev/ipchecker/proto/ipchecker;ipcheckerb\x06proto3"
 
 var (
 	file_ipchecker_proto_rawDescOnce sync.Once
@@ -154,19 +1589,55 @@ func file_ipchecker_proto_rawDescGZIP() []byte {
 	return file_ipchecker_proto_rawDescData
 }
 
-var file_ipchecker_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_ipchecker_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
 var file_ipchecker_proto_goTypes = []any{
-	(*IPCheckRequest)(nil),  // 0: ipchecker.v1.IPCheckRequest
-	(*IPCheckResponse)(nil), // 1: ipchecker.v1.IPCheckResponse
+	(*IPCheckRequest)(nil),        // 0: ipchecker.v1.IPCheckRequest
+	(*OverrideMatch)(nil),         // 1: ipchecker.v1.OverrideMatch
+	(*IPCheckResponse)(nil),       // 2: ipchecker.v1.IPCheckResponse
+	(*IPBatchCheckRequest)(nil),   // 3: ipchecker.v1.IPBatchCheckRequest
+	(*IPBatchCheckResult)(nil),    // 4: ipchecker.v1.IPBatchCheckResult
+	(*IPBatchCheckResponse)(nil),  // 5: ipchecker.v1.IPBatchCheckResponse
+	(*IPStreamCheckRequest)(nil),  // 6: ipchecker.v1.IPStreamCheckRequest
+	(*IPStreamCheckResponse)(nil), // 7: ipchecker.v1.IPStreamCheckResponse
+	(*CallerCheckRequest)(nil),    // 8: ipchecker.v1.CallerCheckRequest
+	(*CallerCheckResponse)(nil),   // 9: ipchecker.v1.CallerCheckResponse
+	(*LookupRequest)(nil),         // 10: ipchecker.v1.LookupRequest
+	(*Continent)(nil),             // 11: ipchecker.v1.Continent
+	(*Country)(nil),               // 12: ipchecker.v1.Country
+	(*RepresentedCountry)(nil),    // 13: ipchecker.v1.RepresentedCountry
+	(*Traits)(nil),                // 14: ipchecker.v1.Traits
+	(*Subdivision)(nil),           // 15: ipchecker.v1.Subdivision
+	(*AutonomousSystem)(nil),      // 16: ipchecker.v1.AutonomousSystem
+	(*LookupResponse)(nil),        // 17: ipchecker.v1.LookupResponse
 }
 var file_ipchecker_proto_depIdxs = []int32{
-	0, // 0: ipchecker.v1.IPChecker.CheckIP:input_type -> ipchecker.v1.IPCheckRequest
-	1, // 1: ipchecker.v1.IPChecker.CheckIP:output_type -> ipchecker.v1.IPCheckResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1,  // 0: ipchecker.v1.IPCheckResponse.override:type_name -> ipchecker.v1.OverrideMatch
+	1,  // 1: ipchecker.v1.IPBatchCheckResult.override:type_name -> ipchecker.v1.OverrideMatch
+	4,  // 2: ipchecker.v1.IPBatchCheckResponse.results:type_name -> ipchecker.v1.IPBatchCheckResult
+	1,  // 3: ipchecker.v1.IPStreamCheckResponse.override:type_name -> ipchecker.v1.OverrideMatch
+	1,  // 4: ipchecker.v1.CallerCheckResponse.override:type_name -> ipchecker.v1.OverrideMatch
+	11, // 5: ipchecker.v1.LookupResponse.continent:type_name -> ipchecker.v1.Continent
+	12, // 6: ipchecker.v1.LookupResponse.country:type_name -> ipchecker.v1.Country
+	12, // 7: ipchecker.v1.LookupResponse.registered_country:type_name -> ipchecker.v1.Country
+	13, // 8: ipchecker.v1.LookupResponse.represented_country:type_name -> ipchecker.v1.RepresentedCountry
+	14, // 9: ipchecker.v1.LookupResponse.traits:type_name -> ipchecker.v1.Traits
+	15, // 10: ipchecker.v1.LookupResponse.subdivisions:type_name -> ipchecker.v1.Subdivision
+	16, // 11: ipchecker.v1.LookupResponse.autonomous_system:type_name -> ipchecker.v1.AutonomousSystem
+	0,  // 12: ipchecker.v1.IPChecker.CheckIP:input_type -> ipchecker.v1.IPCheckRequest
+	3,  // 13: ipchecker.v1.IPChecker.CheckIPBatch:input_type -> ipchecker.v1.IPBatchCheckRequest
+	6,  // 14: ipchecker.v1.IPChecker.CheckIPStream:input_type -> ipchecker.v1.IPStreamCheckRequest
+	10, // 15: ipchecker.v1.IPChecker.Lookup:input_type -> ipchecker.v1.LookupRequest
+	8,  // 16: ipchecker.v1.IPChecker.CheckCaller:input_type -> ipchecker.v1.CallerCheckRequest
+	2,  // 17: ipchecker.v1.IPChecker.CheckIP:output_type -> ipchecker.v1.IPCheckResponse
+	5,  // 18: ipchecker.v1.IPChecker.CheckIPBatch:output_type -> ipchecker.v1.IPBatchCheckResponse
+	7,  // 19: ipchecker.v1.IPChecker.CheckIPStream:output_type -> ipchecker.v1.IPStreamCheckResponse
+	17, // 20: ipchecker.v1.IPChecker.Lookup:output_type -> ipchecker.v1.LookupResponse
+	9,  // 21: ipchecker.v1.IPChecker.CheckCaller:output_type -> ipchecker.v1.CallerCheckResponse
+	17, // [17:22] is the sub-list for method output_type
+	12, // [12:17] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
 }
 
 func init() { file_ipchecker_proto_init() }
@@ -180,7 +1651,7 @@ func file_ipchecker_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ipchecker_proto_rawDesc), len(file_ipchecker_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   18,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,7 +19,11 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	IPChecker_CheckIP_FullMethodName = "/ipchecker.v1.IPChecker/CheckIP"
+	IPChecker_CheckIP_FullMethodName       = "/ipchecker.v1.IPChecker/CheckIP"
+	IPChecker_CheckIPBatch_FullMethodName  = "/ipchecker.v1.IPChecker/CheckIPBatch"
+	IPChecker_CheckIPStream_FullMethodName = "/ipchecker.v1.IPChecker/CheckIPStream"
+	IPChecker_Lookup_FullMethodName        = "/ipchecker.v1.IPChecker/Lookup"
+	IPChecker_CheckCaller_FullMethodName   = "/ipchecker.v1.IPChecker/CheckCaller"
 )
 
 // IPCheckerClient is the client API for IPChecker service.
@@ -30,6 +34,14 @@ const (
 type IPCheckerClient interface {
 	// CheckIP returns whether the IP is in the allowed list.
 	CheckIP(ctx context.Context, in *IPCheckRequest, opts ...grpc.CallOption) (*IPCheckResponse, error)
+	// CheckIPBatch checks several IPs against the same allowed list, reporting errors per item.
+	CheckIPBatch(ctx context.Context, in *IPBatchCheckRequest, opts ...grpc.CallOption) (*IPBatchCheckResponse, error)
+	// CheckIPStream checks IPs sent continuously over one long-lived stream; responses carry the request id.
+	CheckIPStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[IPStreamCheckRequest, IPStreamCheckResponse], error)
+	// Lookup returns the full geolocation data of an IP, without checking it against any rules.
+	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error)
+	// CheckCaller returns whether the caller's own IP, taken from the connection, is in the allowed list.
+	CheckCaller(ctx context.Context, in *CallerCheckRequest, opts ...grpc.CallOption) (*CallerCheckResponse, error)
 }
 
 type iPCheckerClient struct {
@@ -50,6 +62,49 @@ func (c *iPCheckerClient) CheckIP(ctx context.Context, in *IPCheckRequest, opts
 	return out, nil
 }
 
+func (c *iPCheckerClient) CheckIPBatch(ctx context.Context, in *IPBatchCheckRequest, opts ...grpc.CallOption) (*IPBatchCheckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IPBatchCheckResponse)
+	err := c.cc.Invoke(ctx, IPChecker_CheckIPBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *iPCheckerClient) CheckIPStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[IPStreamCheckRequest, IPStreamCheckResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &IPChecker_ServiceDesc.Streams[0], IPChecker_CheckIPStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[IPStreamCheckRequest, IPStreamCheckResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IPChecker_CheckIPStreamClient = grpc.BidiStreamingClient[IPStreamCheckRequest, IPStreamCheckResponse]
+
+func (c *iPCheckerClient) Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LookupResponse)
+	err := c.cc.Invoke(ctx, IPChecker_Lookup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *iPCheckerClient) CheckCaller(ctx context.Context, in *CallerCheckRequest, opts ...grpc.CallOption) (*CallerCheckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CallerCheckResponse)
+	err := c.cc.Invoke(ctx, IPChecker_CheckCaller_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // IPCheckerServer is the server API for IPChecker service.
 // All implementations must embed UnimplementedIPCheckerServer
 // for forward compatibility.
@@ -58,6 +113,14 @@ func (c *iPCheckerClient) CheckIP(ctx context.Context, in *IPCheckRequest, opts
 type IPCheckerServer interface {
 	// CheckIP returns whether the IP is in the allowed list.
 	CheckIP(context.Context, *IPCheckRequest) (*IPCheckResponse, error)
+	// CheckIPBatch checks several IPs against the same allowed list, reporting errors per item.
+	CheckIPBatch(context.Context, *IPBatchCheckRequest) (*IPBatchCheckResponse, error)
+	// CheckIPStream checks IPs sent continuously over one long-lived stream; responses carry the request id.
+	CheckIPStream(grpc.BidiStreamingServer[IPStreamCheckRequest, IPStreamCheckResponse]) error
+	// Lookup returns the full geolocation data of an IP, without checking it against any rules.
+	Lookup(context.Context, *LookupRequest) (*LookupResponse, error)
+	// CheckCaller returns whether the caller's own IP, taken from the connection, is in the allowed list.
+	CheckCaller(context.Context, *CallerCheckRequest) (*CallerCheckResponse, error)
 	mustEmbedUnimplementedIPCheckerServer()
 }
 
@@ -71,6 +134,18 @@ type UnimplementedIPCheckerServer struct{}
 func (UnimplementedIPCheckerServer) CheckIP(context.Context, *IPCheckRequest) (*IPCheckResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CheckIP not implemented")
 }
+func (UnimplementedIPCheckerServer) CheckIPBatch(context.Context, *IPBatchCheckRequest) (*IPBatchCheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckIPBatch not implemented")
+}
+func (UnimplementedIPCheckerServer) CheckIPStream(grpc.BidiStreamingServer[IPStreamCheckRequest, IPStreamCheckResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method CheckIPStream not implemented")
+}
+func (UnimplementedIPCheckerServer) Lookup(context.Context, *LookupRequest) (*LookupResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
+}
+func (UnimplementedIPCheckerServer) CheckCaller(context.Context, *CallerCheckRequest) (*CallerCheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckCaller not implemented")
+}
 func (UnimplementedIPCheckerServer) mustEmbedUnimplementedIPCheckerServer() {}
 func (UnimplementedIPCheckerServer) testEmbeddedByValue()                   {}
 
@@ -110,6 +185,67 @@ func _IPChecker_CheckIP_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _IPChecker_CheckIPBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IPBatchCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IPCheckerServer).CheckIPBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IPChecker_CheckIPBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IPCheckerServer).CheckIPBatch(ctx, req.(*IPBatchCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IPChecker_CheckIPStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IPCheckerServer).CheckIPStream(&grpc.GenericServerStream[IPStreamCheckRequest, IPStreamCheckResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IPChecker_CheckIPStreamServer = grpc.BidiStreamingServer[IPStreamCheckRequest, IPStreamCheckResponse]
+
+func _IPChecker_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IPCheckerServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IPChecker_Lookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IPCheckerServer).Lookup(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IPChecker_CheckCaller_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CallerCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IPCheckerServer).CheckCaller(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IPChecker_CheckCaller_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IPCheckerServer).CheckCaller(ctx, req.(*CallerCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // IPChecker_ServiceDesc is the grpc.ServiceDesc for IPChecker service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -121,7 +257,26 @@ var IPChecker_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CheckIP",
 			Handler:    _IPChecker_CheckIP_Handler,
 		},
+		{
+			MethodName: "CheckIPBatch",
+			Handler:    _IPChecker_CheckIPBatch_Handler,
+		},
+		{
+			MethodName: "Lookup",
+			Handler:    _IPChecker_Lookup_Handler,
+		},
+		{
+			MethodName: "CheckCaller",
+			Handler:    _IPChecker_CheckCaller_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "CheckIPStream",
+			Handler:       _IPChecker_CheckIPStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "ipchecker.proto",
 }